@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"time"
 
 	"abt-dashboard/internal/config"
+	"abt-dashboard/internal/handlers"
 	"abt-dashboard/internal/middleware"
 	"abt-dashboard/internal/observability"
 	"abt-dashboard/internal/server"
@@ -27,7 +29,7 @@ func handleDashboard(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	w.Header().Set("Cache-Control", cacheMaxAge)
-	if err := templates.Dashboard().Render(ctx, w); err != nil {
+	if err := templates.Dashboard(handlers.DashboardStateFromQuery(r.URL.Query())).Render(ctx, w); err != nil {
 		http.Error(w, "render error", http.StatusInternalServerError)
 	}
 }
@@ -48,6 +50,38 @@ func main() {
 	)
 
 	analytics := services.NewAnalytics()
+	for _, agg := range []services.Aggregator{services.WeekendShare(), services.DiscountRate()} {
+		if err := analytics.RegisterAggregator(agg); err != nil {
+			logger.Error("failed to register metric", "error", err)
+			os.Exit(1)
+		}
+	}
+	for _, def := range cfg.Metrics {
+		m, err := services.ParseDerivedMetric(def.Name, def.Expr)
+		if err == nil {
+			err = analytics.RegisterDerivedMetric(m)
+		}
+		if err != nil {
+			logger.Error("failed to register metric", "metric", def.Name, "error", err)
+			os.Exit(1)
+		}
+	}
+	aliases, err := services.LoadAliases(cfg.Database.AliasesFile)
+	if err != nil {
+		logger.Error("failed to load aliases", "error", err)
+		os.Exit(1)
+	}
+	analytics.SetAliases(aliases)
+	if cfg.Database.CatalogFile != "" {
+		catalog, err := services.LoadCatalog(cfg.Database.CatalogFile)
+		if err != nil {
+			logger.Error("failed to load product catalog", "error", err)
+			os.Exit(1)
+		}
+		analytics.SetCatalog(catalog)
+		logger.Info("product catalog loaded", "products", catalog.Len())
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), csvLoadTimeout)
 	defer cancel()
 
@@ -59,11 +93,26 @@ func main() {
 	duration := time.Since(start)
 	logger.Info("CSV data loaded successfully", "duration", duration)
 
+	// Streaming handlers run on this context so shutdown can end them
+	// instead of waiting for the client to disconnect.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
+	if cfg.Database.ReloadInterval > 0 {
+		go analytics.WatchCSV(baseCtx, cfg.Database.ReloadInterval, csvLoadTimeout)
+	}
+
 	templateHandlers := &server.TemplateHandlers{
 		Dashboard: handleDashboard,
 	}
 
-	srv := server.NewServer(analytics, logger, templateHandlers)
+	views, err := services.OpenViewStore(cfg.Database.ViewsFile)
+	if err != nil {
+		logger.Error("failed to open saved views", "error", err)
+		os.Exit(1)
+	}
+
+	srv := server.NewServer(analytics, views, logger, templateHandlers, cfg.API)
 
 	rateLimiter := middleware.NewRateLimiter(cfg.Security)
 
@@ -86,7 +135,9 @@ func main() {
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 	}
+	httpServer.RegisterOnShutdown(cancelBase)
 
 	gracefulServer := server.NewGracefulServer(httpServer, logger, cfg)
 
@@ -2,19 +2,24 @@ package main
 
 import (
 	"encoding/json"
+	"html"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"abt-dashboard/internal/config"
 	"abt-dashboard/internal/models"
 	"abt-dashboard/internal/server"
 	"abt-dashboard/internal/services"
 )
 
+var testAPIConfig = config.APIConfig{MaxTopN: 100, SQLTimeout: 5 * time.Second, SQLMaxRows: 1000}
+
 // Test helper to create analytics with test data
 func newTestAnalytics() *services.Analytics {
 	a := services.NewAnalytics()
@@ -65,15 +70,26 @@ func newTestAnalytics() *services.Analytics {
 			AddedDate:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
 		},
 	}
+	a.RegisterAggregator(services.WeekendShare())
 	a.SetData(testData)
 	return a
 }
 
+// newTestViews opens an empty saved view store in a temporary directory.
+func newTestViews(t *testing.T) *services.ViewStore {
+	t.Helper()
+	views, err := services.OpenViewStore(filepath.Join(t.TempDir(), "views.json"))
+	if err != nil {
+		t.Fatalf("open views: %v", err)
+	}
+	return views
+}
+
 // Integration tests for HTTP routes
 func TestServer_Routes(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	templateHandlers := &server.TemplateHandlers{Dashboard: handleDashboard}
-	srv := server.NewServer(newTestAnalytics(), logger, templateHandlers)
+	srv := server.NewServer(newTestAnalytics(), newTestViews(t), logger, templateHandlers, testAPIConfig)
 
 	tests := []struct {
 		path           string
@@ -85,6 +101,27 @@ func TestServer_Routes(t *testing.T) {
 		{"/api/top-products", http.StatusOK, "application/json"},
 		{"/api/monthly-sales", http.StatusOK, "application/json"},
 		{"/api/top-regions", http.StatusOK, "application/json"},
+		{"/api/timeseries", http.StatusOK, "application/json"},
+		{"/api/growth", http.StatusOK, "application/json"},
+		{"/api/forecast?horizon=3", http.StatusOK, "application/json"},
+		{"/api/anomalies", http.StatusOK, "application/json"},
+		{"/api/cohorts", http.StatusOK, "application/json"},
+		{"/api/customers/segments", http.StatusOK, "application/json"},
+		{"/api/customers/segments/champions", http.StatusOK, "application/json"},
+		{"/api/customer-metrics?dimension=month", http.StatusOK, "application/json"},
+		{"/api/inventory", http.StatusOK, "application/json"},
+		{"/api/margins?dimension=brand", http.StatusOK, "application/json"},
+		{"/api/margins/missing", http.StatusOK, "application/json"},
+		{"/api/launches?days=365", http.StatusOK, "application/json"},
+		{"/api/drilldown?path=USA", http.StatusOK, "application/json"},
+		{"/api/distribution?metric=quantity&dimension=country", http.StatusOK, "application/json"},
+		{"/api/abc", http.StatusOK, "application/json"},
+		{"/api/abc/products?class=A", http.StatusOK, "application/json"},
+		{"/api/cube?group_by=country,month&category=Electronics", http.StatusOK, "application/json"},
+		{"/api/metrics", http.StatusOK, "application/json"},
+		{"/api/metrics/weekend_share", http.StatusOK, "application/json"},
+		{"/api/views", http.StatusOK, "application/json"},
+		{"/api/compare?from=2023-01-01&to=2023-03-31", http.StatusOK, "application/json"},
 		{"/health", http.StatusOK, "application/json"},
 	}
 
@@ -119,7 +156,7 @@ func TestServer_Routes(t *testing.T) {
 func TestServer_JSONResponse(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	templateHandlers := &server.TemplateHandlers{Dashboard: handleDashboard}
-	srv := server.NewServer(newTestAnalytics(), logger, templateHandlers)
+	srv := server.NewServer(newTestAnalytics(), newTestViews(t), logger, templateHandlers, testAPIConfig)
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/api/top-products", nil)
@@ -164,13 +201,25 @@ func TestServer_JSONResponse(t *testing.T) {
 func TestServer_SSERoutes(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	templateHandlers := &server.TemplateHandlers{Dashboard: handleDashboard}
-	srv := server.NewServer(newTestAnalytics(), logger, templateHandlers)
+	srv := server.NewServer(newTestAnalytics(), newTestViews(t), logger, templateHandlers, testAPIConfig)
 
 	sseRoutes := []string{
 		"/sse/country-revenue",
 		"/sse/top-products",
 		"/sse/monthly-sales",
 		"/sse/top-regions",
+		"/sse/timeseries",
+		"/sse/compare",
+		"/sse/views",
+		"/sse/country-map",
+		"/sse/cohorts",
+		"/sse/inventory",
+		"/sse/margins",
+		"/sse/launches",
+		"/sse/metrics",
+		"/sse/drilldown",
+		"/sse/distribution",
+		"/sse/pareto",
 	}
 
 	for _, route := range sseRoutes {
@@ -200,7 +249,7 @@ func TestServer_SSERoutes(t *testing.T) {
 func TestServer_HandleHealth(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	templateHandlers := &server.TemplateHandlers{Dashboard: handleDashboard}
-	srv := server.NewServer(newTestAnalytics(), logger, templateHandlers)
+	srv := server.NewServer(newTestAnalytics(), newTestViews(t), logger, templateHandlers, testAPIConfig)
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/health", nil)
@@ -238,7 +287,7 @@ func TestServer_HandleHealth(t *testing.T) {
 func TestServer_ErrorHandling(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	templateHandlers := &server.TemplateHandlers{Dashboard: handleDashboard}
-	srv := server.NewServer(newTestAnalytics(), logger, templateHandlers)
+	srv := server.NewServer(newTestAnalytics(), newTestViews(t), logger, templateHandlers, testAPIConfig)
 
 	tests := []struct {
 		method string
@@ -249,6 +298,8 @@ func TestServer_ErrorHandling(t *testing.T) {
 		{"PUT", "/", http.StatusMethodNotAllowed},
 		{"DELETE", "/health", http.StatusMethodNotAllowed},
 		{"PATCH", "/api/top-products", http.StatusMethodNotAllowed},
+		{"PUT", "/api/sql", http.StatusMethodNotAllowed},
+		{"PATCH", "/api/views/emea", http.StatusMethodNotAllowed},
 	}
 
 	for _, tt := range tests {
@@ -289,9 +340,9 @@ func TestDashboardTemplate(t *testing.T) {
 	// Check for key dashboard components
 	expectedComponents := []string{
 		"Country Revenue Analysis",
-		"Top 20 Products by Transactions",
+		"Top Products",
 		"Monthly Sales Volume",
-		"Top 30 Regions by Revenue",
+		"Top Regions",
 	}
 
 	for _, component := range expectedComponents {
@@ -300,3 +351,25 @@ func TestDashboardTemplate(t *testing.T) {
 		}
 	}
 }
+
+// A dashboard link renders with the state it encodes.
+func TestDashboardTemplate_LinkState(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/?from=2023-01-01&to=2023-03-31&filter_category=Electronics&granularity=week&path=USA", nil)
+
+	handleDashboard(w, r)
+
+	body := html.UnescapeString(w.Body.String())
+	for _, want := range []string{
+		`"rangeFrom":"2023-01-01"`,
+		`"rangeTo":"2023-03-31"`,
+		`"filterCategory":"Electronics"`,
+		`"tsGranularity":"week"`,
+		`"drillOpen":true,"drillPath":"USA"`,
+		"syncDashboardURL(",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("dashboard should contain %s", want)
+		}
+	}
+}
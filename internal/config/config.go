@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"abt-dashboard/internal/services/metricexpr"
 )
 
 type Config struct {
@@ -13,6 +15,17 @@ type Config struct {
 	Database DatabaseConfig
 	Logger   LoggerConfig
 	Security SecurityConfig
+	API      APIConfig
+	// Metrics are derived metrics defined by expressions.
+	Metrics []MetricDefinition
+}
+
+// MetricDefinition is one entry of the METRICS setting, a semicolon
+// separated list of "name = expression" definitions such as
+// "avg_unit_price = sum(total_price) / sum(quantity)".
+type MetricDefinition struct {
+	Name string
+	Expr string
 }
 
 type ServerConfig struct {
@@ -26,6 +39,17 @@ type ServerConfig struct {
 
 type DatabaseConfig struct {
 	CSVFile string
+	// ReloadInterval is how often the CSV is checked for changes; zero, the
+	// default, disables reloading.
+	ReloadInterval time.Duration
+	// ViewsFile is the JSON file the saved views are kept in.
+	ViewsFile string
+	// AliasesFile optionally extends or overrides the embedded country and
+	// region alias table.
+	AliasesFile string
+	// CatalogFile optionally names the product catalog CSV joined to the
+	// transactions for costs, brands and subcategories.
+	CatalogFile string
 }
 
 type LoggerConfig struct {
@@ -42,6 +66,15 @@ type SecurityConfig struct {
 	TrustedProxies  []string
 }
 
+type APIConfig struct {
+	// MaxTopN caps the limit parameter of the top-N endpoints.
+	MaxTopN int
+	// SQLTimeout bounds how long a query to the SQL endpoint may run.
+	SQLTimeout time.Duration
+	// SQLMaxRows caps the rows a SQL query returns.
+	SQLMaxRows int
+}
+
 func Load() (*Config, error) {
 	cfg := &Config{
 		Server: ServerConfig{
@@ -53,7 +86,11 @@ func Load() (*Config, error) {
 			ShutdownTimeout: getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
 		},
 		Database: DatabaseConfig{
-			CSVFile: getEnvString("CSV_FILE", "data.csv"),
+			CSVFile:        getEnvString("CSV_FILE", "data.csv"),
+			ReloadInterval: getEnvDuration("CSV_RELOAD_INTERVAL", 0),
+			ViewsFile:      getEnvString("VIEWS_FILE", "views.json"),
+			AliasesFile:    getEnvString("ALIASES_FILE", ""),
+			CatalogFile:    getEnvString("CATALOG_FILE", ""),
 		},
 		Logger: LoggerConfig{
 			Level:  getEnvString("LOG_LEVEL", "info"),
@@ -67,6 +104,12 @@ func Load() (*Config, error) {
 			AllowedOrigins:  getEnvStringSlice("SECURITY_ALLOWED_ORIGINS", []string{"http://localhost:8084"}),
 			TrustedProxies:  getEnvStringSlice("SECURITY_TRUSTED_PROXIES", []string{"127.0.0.1"}),
 		},
+		API: APIConfig{
+			MaxTopN:    getEnvInt("API_MAX_TOP_N", 100),
+			SQLTimeout: getEnvDuration("API_SQL_TIMEOUT", 5*time.Second),
+			SQLMaxRows: getEnvInt("API_SQL_MAX_ROWS", 1000),
+		},
+		Metrics: getEnvMetrics("METRICS"),
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -93,6 +136,14 @@ func (c *Config) validate() error {
 		return fmt.Errorf("CSV file path cannot be empty")
 	}
 
+	if c.Database.ReloadInterval < 0 {
+		return fmt.Errorf("CSV reload interval cannot be negative")
+	}
+
+	if c.Database.ViewsFile == "" {
+		return fmt.Errorf("views file path cannot be empty")
+	}
+
 	validLogLevels := []string{"debug", "info", "warn", "error"}
 	if !contains(validLogLevels, c.Logger.Level) {
 		return fmt.Errorf("invalid log level %q, must be one of: %s", c.Logger.Level, strings.Join(validLogLevels, ", "))
@@ -111,6 +162,32 @@ func (c *Config) validate() error {
 		return fmt.Errorf("rate limit burst must be positive")
 	}
 
+	if c.API.MaxTopN <= 0 {
+		return fmt.Errorf("API max top-N must be positive")
+	}
+
+	if c.API.SQLTimeout <= 0 {
+		return fmt.Errorf("API SQL timeout must be positive")
+	}
+
+	if c.API.SQLMaxRows <= 0 {
+		return fmt.Errorf("API SQL max rows must be positive")
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range c.Metrics {
+		if m.Name == "" {
+			return fmt.Errorf("metric definition %q must be written as name = expression", m.Expr)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("metric %q is defined twice", m.Name)
+		}
+		seen[m.Name] = true
+		if _, err := metricexpr.Parse(m.Name, m.Expr); err != nil {
+			return fmt.Errorf("invalid metric %q: %w", m.Name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -155,6 +232,46 @@ func getEnvStringSlice(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+// getEnvMetrics splits a list of "name = expression" definitions. Entries
+// without a name are kept with the whole entry as expression so validation
+// can report them.
+func getEnvMetrics(key string) []MetricDefinition {
+	var metrics []MetricDefinition
+	for _, entry := range splitMetrics(os.Getenv(key)) {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, expr, ok := strings.Cut(entry, "=")
+		if !ok {
+			metrics = append(metrics, MetricDefinition{Expr: strings.TrimSpace(entry)})
+			continue
+		}
+		metrics = append(metrics, MetricDefinition{Name: strings.TrimSpace(name), Expr: strings.TrimSpace(expr)})
+	}
+	return metrics
+}
+
+// splitMetrics splits metric definitions on the semicolons outside quoted
+// strings, so a condition such as product_name = 'A;B' stays whole. A
+// doubled quote inside a string closes and reopens it, which leaves the
+// split unchanged.
+func splitMetrics(value string) []string {
+	var entries []string
+	quoted, start := false, 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\'':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				entries = append(entries, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(entries, value[start:])
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -15,6 +15,7 @@ const (
 	CodeInternal       ErrorCode = "INTERNAL_ERROR"
 	CodeValidation     ErrorCode = "VALIDATION_ERROR"
 	CodeNotFound       ErrorCode = "NOT_FOUND"
+	CodeConflict       ErrorCode = "CONFLICT"
 	CodeBadRequest     ErrorCode = "BAD_REQUEST"
 	CodeUnauthorized   ErrorCode = "UNAUTHORIZED"
 	CodeForbidden      ErrorCode = "FORBIDDEN"
@@ -82,6 +83,10 @@ func NotFound(message string) *AppError {
 	return New(CodeNotFound, message)
 }
 
+func Conflict(message string) *AppError {
+	return New(CodeConflict, message)
+}
+
 func BadRequest(message string) *AppError {
 	return New(CodeBadRequest, message)
 }
@@ -112,6 +117,8 @@ func getStatusCode(code ErrorCode) int {
 		return http.StatusBadRequest
 	case CodeNotFound:
 		return http.StatusNotFound
+	case CodeConflict:
+		return http.StatusConflict
 	case CodeUnauthorized:
 		return http.StatusUnauthorized
 	case CodeForbidden:
@@ -177,24 +184,36 @@ func WriteError(w http.ResponseWriter, logger *slog.Logger, err error, requestID
 
 type SuccessResponse struct {
 	Data    any  `json:"data"`
+	Meta    any  `json:"meta,omitempty"`
 	Success bool `json:"success"`
 }
 
 func WriteSuccess(w http.ResponseWriter, data any) {
+	writeSuccess(w, data, nil, nil)
+}
+
+func WriteSuccessWithHeaders(w http.ResponseWriter, data any, headers map[string]string) {
+	writeSuccess(w, data, nil, headers)
+}
+
+func WriteSuccessWithMeta(w http.ResponseWriter, data any, meta any, headers map[string]string) {
+	writeSuccess(w, data, meta, headers)
+}
+
+// writeSuccess writes the success envelope, with meta omitted when nil.
+func writeSuccess(w http.ResponseWriter, data any, meta any, headers map[string]string) {
+	for key, value := range headers {
+		w.Header().Set(key, value)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	response := SuccessResponse{
 		Data:    data,
+		Meta:    meta,
 		Success: true,
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
-
-func WriteSuccessWithHeaders(w http.ResponseWriter, data any, headers map[string]string) {
-	for key, value := range headers {
-		w.Header().Set(key, value)
-	}
-	WriteSuccess(w, data)
-}
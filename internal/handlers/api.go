@@ -1,40 +1,140 @@
 package handlers
 
 import (
+	"cmp"
+	stderrors "errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"abt-dashboard/internal/errors"
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/observability"
 	"abt-dashboard/internal/services"
 )
 
 type APIHandlers struct {
 	analytics *services.Analytics
 	logger    *slog.Logger
+	maxTopN   int
+
+	sqlTimeout time.Duration
+	sqlMaxRows int
+
+	views *services.ViewStore
 }
 
 func NewAPIHandlers(analytics *services.Analytics, logger *slog.Logger) *APIHandlers {
 	return &APIHandlers{
 		analytics: analytics,
 		logger:    logger,
+		maxTopN:   DefaultMaxTopN,
+
+		sqlTimeout: DefaultSQLTimeout,
+		sqlMaxRows: DefaultSQLMaxRows,
 	}
 }
 
+// WithMaxTopN sets the largest limit the top-N endpoints accept.
+func (h *APIHandlers) WithMaxTopN(n int) *APIHandlers {
+	h.maxTopN = n
+	return h
+}
+
 func (h *APIHandlers) HandleCountryRevenue(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
 
-	data := h.analytics.CountryRevenue()
+	query, err := parseCountryRevenueQuery(r)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	scope := fmt.Sprintf("%s:%t:%s", query.SortBy, query.Desc, query.Search)
+	page, err := parsePageRequest(r, scope)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+	query.Offset = page.Offset
+	query.Limit = page.PageSize
+
+	data, total := h.analytics.QueryCountryRevenue(query)
+	if metric := r.URL.Query().Get("metric"); metric != "" {
+		if data, err = h.analytics.CountryRevenueMetric(r.Context(), data, metric); err != nil {
+			errors.WriteError(w, h.logger, h.metricParamError(err), requestID)
+			return
+		}
+	}
 
 	headers := map[string]string{
 		"Cache-Control": "public, max-age=300",
 	}
 
-	errors.WriteSuccessWithHeaders(w, data, headers)
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, scope), headers)
 }
 
+// metricParamError reports a failure to evaluate the derived metric named by
+// a metric parameter. A name that is not registered is the caller's mistake.
+func (h *APIHandlers) metricParamError(err error) error {
+	if stderrors.Is(err, services.ErrUnknownMetric) {
+		return errors.Validation("metric must be one of: " + strings.Join(h.analytics.DerivedMetricNames(), ", "))
+	}
+	return errors.InternalWrap(err, "metric evaluation failed")
+}
+
+func parseCountryRevenueQuery(r *http.Request) (services.CountryRevenueQuery, error) {
+	params := r.URL.Query()
+	query := services.CountryRevenueQuery{
+		SortBy: services.SortByRevenue,
+		Search: strings.TrimSpace(params.Get("q")),
+	}
+
+	if sortBy := params.Get("sort"); sortBy != "" {
+		switch sortBy {
+		case services.SortByRevenue, services.SortByTransactions, services.SortByCountry:
+			query.SortBy = sortBy
+		default:
+			return query, errors.Validation("sort must be one of: revenue, transactions, country")
+		}
+	}
+
+	// Numeric columns read best largest-first, names alphabetically
+	query.Desc = query.SortBy != services.SortByCountry
+	switch params.Get("order") {
+	case "":
+	case "asc":
+		query.Desc = false
+	case "desc":
+		query.Desc = true
+	default:
+		return query, errors.Validation("order must be asc or desc")
+	}
+
+	return query, nil
+}
+
+// HandleTopProducts ranks products by revenue, orders, units or distinct
+// customers, as chosen by rank_by, and returns the first limit of them.
 func (h *APIHandlers) HandleTopProducts(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
 
-	data := h.analytics.TopProducts(20)
+	params := r.URL.Query()
+	opts, err := parseTopN(params.Get("limit"), params.Get("rank_by"), defaultProductRanking, h.maxTopN, h.analytics.DerivedMetricNames())
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data, err := h.analytics.RankedProducts(r.Context(), opts.RankBy, opts.Limit)
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "product ranking failed"), requestID)
+		return
+	}
 
 	headers := map[string]string{
 		"Cache-Control": "public, max-age=300",
@@ -54,9 +154,750 @@ func (h *APIHandlers) HandleMonthlySales(w http.ResponseWriter, r *http.Request)
 	errors.WriteSuccessWithHeaders(w, data, headers)
 }
 
+func (h *APIHandlers) HandleTimeSeries(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	granularity := cmp.Or(params.Get("granularity"), services.GranularityMonth)
+	metric := cmp.Or(params.Get("metric"), services.MetricRevenue)
+
+	data, err := h.analytics.TimeSeries(r.Context(), granularity, metric)
+	if err != nil {
+		if r.Context().Err() != nil {
+			errors.WriteError(w, h.logger, errors.InternalWrap(err, "time series cancelled"), observability.GetRequestID(r.Context()))
+			return
+		}
+		errors.WriteError(w, h.logger, errors.Validation(err.Error()), observability.GetRequestID(r.Context()))
+		return
+	}
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, data, headers)
+}
+
+func (h *APIHandlers) HandleForecast(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	params := r.URL.Query()
+	metric := cmp.Or(params.Get("metric"), services.MetricRevenue)
+
+	horizon := defaultForecastHorizon
+	if v := params.Get("horizon"); v != "" {
+		var err error
+		if horizon, err = strconv.Atoi(v); err != nil {
+			errors.WriteError(w, h.logger, errors.Validation("horizon must be a whole number of months"), requestID)
+			return
+		}
+	}
+
+	data, err := h.analytics.Forecast(r.Context(), metric, horizon, params.Get("country"))
+	if err != nil {
+		if r.Context().Err() != nil {
+			errors.WriteError(w, h.logger, errors.InternalWrap(err, "forecast cancelled"), requestID)
+			return
+		}
+		errors.WriteError(w, h.logger, errors.Validation(err.Error()), requestID)
+		return
+	}
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, data, headers)
+}
+
+func (h *APIHandlers) HandleAnomalies(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	params := r.URL.Query()
+	dimension := params.Get("dimension")
+	metric := params.Get("metric")
+
+	if dimension != "" && !slices.Contains(services.AnomalyDimensions, dimension) {
+		errors.WriteError(w, h.logger, errors.Validation(fmt.Sprintf("dimension must be one of: %s", strings.Join(services.AnomalyDimensions, ", "))), requestID)
+		return
+	}
+	if metric != "" && !slices.Contains(services.AnomalyMetrics, metric) {
+		errors.WriteError(w, h.logger, errors.Validation(fmt.Sprintf("metric must be one of: %s", strings.Join(services.AnomalyMetrics, ", "))), requestID)
+		return
+	}
+
+	anomalies, err := h.analytics.Anomalies(r.Context())
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "anomaly detection failed"), requestID)
+		return
+	}
+
+	data := make([]models.Anomaly, 0, len(anomalies))
+	for _, a := range anomalies {
+		if (dimension == "" || a.Dimension == dimension) && (metric == "" || a.Metric == metric) {
+			data = append(data, a)
+		}
+	}
+
+	// Anomalies change only when the data is reloaded, which can happen at
+	// any time, so responses are kept short-lived.
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=60",
+	}
+
+	errors.WriteSuccessWithHeaders(w, data, headers)
+}
+
+func (h *APIHandlers) HandleCohorts(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	params := r.URL.Query()
+
+	months := 0
+	if v := params.Get("months"); v != "" {
+		var err error
+		if months, err = strconv.Atoi(v); err != nil || months < 1 {
+			errors.WriteError(w, h.logger, errors.Validation("months must be a positive whole number"), requestID)
+			return
+		}
+	}
+
+	data, err := h.analytics.Cohorts(r.Context(), params.Get("country"))
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "cohort analysis failed"), requestID)
+		return
+	}
+	if months > 0 {
+		data = truncateCohorts(data, months)
+	}
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, data, headers)
+}
+
+// truncateCohorts limits every cohort to its first months of activity.
+func truncateCohorts(cohorts []models.Cohort, months int) []models.Cohort {
+	result := make([]models.Cohort, len(cohorts))
+	for i, c := range cohorts {
+		n := min(months, len(c.Active))
+		c.Active, c.Retention, c.Revenue = c.Active[:n], c.Retention[:n], c.Revenue[:n]
+		result[i] = c
+	}
+	return result
+}
+
+func (h *APIHandlers) HandleCustomerSegments(w http.ResponseWriter, r *http.Request) {
+	data, err := h.analytics.CustomerSegments(r.Context(), r.URL.Query().Get("country"))
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "customer segmentation failed"), observability.GetRequestID(r.Context()))
+		return
+	}
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, data, headers)
+}
+
+// HandleSegmentCustomers exports the customers of one segment page by page,
+// biggest spenders first.
+func (h *APIHandlers) HandleSegmentCustomers(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	segment := r.PathValue("segment")
+	country := r.URL.Query().Get("country")
+
+	if _, ok := services.SegmentNames[segment]; !ok {
+		errors.WriteError(w, h.logger, errors.New(errors.CodeNotFound, fmt.Sprintf("unknown segment %q, expected one of: %s", segment, strings.Join(services.Segments, ", "))), requestID)
+		return
+	}
+
+	scope := segment + ":" + country
+	page, err := parsePageRequest(r, scope)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data, err := h.analytics.SegmentCustomers(r.Context(), segment, country)
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "customer segmentation failed"), requestID)
+		return
+	}
+
+	total := len(data)
+	data = data[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, scope), headers)
+}
+
+func (h *APIHandlers) HandleCustomerMetrics(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	dimension := cmp.Or(r.URL.Query().Get("dimension"), services.DimensionCountry)
+
+	if !slices.Contains(services.CustomerMetricDimensions, dimension) {
+		errors.WriteError(w, h.logger, errors.Validation(fmt.Sprintf("dimension must be one of: %s", strings.Join(services.CustomerMetricDimensions, ", "))), requestID)
+		return
+	}
+
+	page, err := parsePageRequest(r, dimension)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data, err := h.analytics.CustomerMetrics(r.Context(), dimension)
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "customer metrics failed"), requestID)
+		return
+	}
+
+	total := len(data)
+	data = data[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, dimension), headers)
+}
+
+// HandleMargins lists the gross margin of every value of a dimension,
+// biggest revenue first.
+func (h *APIHandlers) HandleMargins(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	dimension := cmp.Or(r.URL.Query().Get("dimension"), services.DimensionCountry)
+
+	if !slices.Contains(services.MarginDimensions, dimension) {
+		errors.WriteError(w, h.logger, errors.Validation(fmt.Sprintf("dimension must be one of: %s", strings.Join(services.MarginDimensions, ", "))), requestID)
+		return
+	}
+
+	page, err := parsePageRequest(r, dimension)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data, err := h.analytics.Margins(r.Context(), dimension)
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "margin analysis failed"), requestID)
+		return
+	}
+
+	total := len(data)
+	data = data[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, dimension), headers)
+}
+
+// HandleMissingCatalog lists the products sold without a catalog entry,
+// biggest revenue first.
+func (h *APIHandlers) HandleMissingCatalog(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+
+	page, err := parsePageRequest(r, "")
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data := h.analytics.MissingCatalog()
+	total := len(data)
+	data = data[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, ""), headers)
+}
+
+// HandleInventory lists products with their latest stock, sales velocity and
+// days of cover, most urgent first, optionally restricted to one status.
+func (h *APIHandlers) HandleInventory(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	status := r.URL.Query().Get("status")
+
+	if status != "" && !slices.Contains(services.StockStatuses, status) {
+		errors.WriteError(w, h.logger, errors.Validation(fmt.Sprintf("status must be one of: %s", strings.Join(services.StockStatuses, ", "))), requestID)
+		return
+	}
+
+	page, err := parsePageRequest(r, status)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data, err := h.analytics.InventoryByStatus(r.Context(), status)
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "inventory analysis failed"), requestID)
+		return
+	}
+
+	total := len(data)
+	data = data[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, status), headers)
+}
+
+// HandleLaunches ranks the products added to the catalog in the last `days`
+// of data by their first-30-day revenue, with time to first sale and
+// 90-day totals.
+func (h *APIHandlers) HandleLaunches(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+
+	days := defaultLaunchDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		var err error
+		if days, err = strconv.Atoi(v); err != nil {
+			errors.WriteError(w, h.logger, errors.Validation("days must be a whole number"), requestID)
+			return
+		}
+	}
+
+	scope := strconv.Itoa(days)
+	page, err := parsePageRequest(r, scope)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data, err := h.analytics.RecentLaunches(r.Context(), days)
+	if err != nil {
+		if r.Context().Err() != nil {
+			errors.WriteError(w, h.logger, errors.InternalWrap(err, "launch analysis cancelled"), requestID)
+			return
+		}
+		errors.WriteError(w, h.logger, errors.Validation(err.Error()), requestID)
+		return
+	}
+
+	total := len(data)
+	data = data[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, scope), headers)
+}
+
+// HandleDrilldown returns the totals under a country, country/region or
+// country/region/product path with a paginated breakdown by the next level.
+func (h *APIHandlers) HandleDrilldown(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+
+	path, err := services.ParseDrillPath(r.URL.Query().Get("path"))
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.Validation(err.Error()), requestID)
+		return
+	}
+
+	scope := services.DrillPath(path...)
+	page, err := parsePageRequest(r, scope)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	metric := r.URL.Query().Get("metric")
+	node, err := h.analytics.Drilldown(r.Context(), path, metric)
+	if err != nil {
+		if stderrors.Is(err, services.ErrUnknownPath) {
+			errors.WriteError(w, h.logger, errors.NotFound(fmt.Sprintf("no sales under %q", scope)), requestID)
+			return
+		}
+		if stderrors.Is(err, services.ErrUnknownMetric) {
+			errors.WriteError(w, h.logger, h.metricParamError(err), requestID)
+			return
+		}
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "drill-down failed"), requestID)
+		return
+	}
+
+	total := len(node.Children)
+	node.Children = node.Children[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, node, newPageMeta(page, total, scope), headers)
+}
+
+// HandleCube rolls the sales cube up to the dimensions in group_by, a comma
+// separated list, after dicing it by any dimension given as a parameter.
+// Repeating a dimension parameter selects several values.
+func (h *APIHandlers) HandleCube(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	params := r.URL.Query()
+
+	var q services.CubeQuery
+	if v := params.Get("group_by"); v != "" {
+		q.GroupBy = strings.Split(v, ",")
+	}
+	q.Filters = make(map[string][]string)
+	scope := strings.Join(q.GroupBy, ",")
+	for _, dim := range services.CubeDimensions {
+		if values := params[dim]; len(values) > 0 {
+			q.Filters[dim] = values
+			scope += "|" + dim + "=" + strings.Join(values, ",")
+		}
+	}
+
+	if name := params.Get("metric"); name != "" {
+		m, ok := h.analytics.DerivedMetric(name)
+		if !ok {
+			errors.WriteError(w, h.logger, h.metricParamError(services.ErrUnknownMetric), requestID)
+			return
+		}
+		q.Metric = m
+	}
+
+	page, err := parsePageRequest(r, scope)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data, err := h.analytics.Cube().Query(r.Context(), q)
+	if err != nil {
+		if r.Context().Err() != nil {
+			errors.WriteError(w, h.logger, errors.InternalWrap(err, "cube query cancelled"), requestID)
+			return
+		}
+		errors.WriteError(w, h.logger, errors.Validation(err.Error()), requestID)
+		return
+	}
+
+	total := len(data)
+	data = data[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, scope), headers)
+}
+
+// HandleDistribution returns quantiles and a histogram of a transaction
+// field, overall or for one country or category value. With a dimension but
+// no value it lists the quantiles of every value instead.
+func (h *APIHandlers) HandleDistribution(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	params := r.URL.Query()
+	metric := cmp.Or(params.Get("metric"), services.DistributionTotalPrice)
+	dimension := params.Get("dimension")
+	value := params.Get("value")
+
+	bins := services.DefaultHistogramBins
+	if v := params.Get("bins"); v != "" {
+		var err error
+		if bins, err = strconv.Atoi(v); err != nil {
+			errors.WriteError(w, h.logger, errors.Validation("bins must be a whole number"), requestID)
+			return
+		}
+	}
+
+	var data any
+	var err error
+	if dimension != "" && value == "" {
+		data, err = h.analytics.DistributionBreakdown(metric, dimension)
+	} else {
+		data, err = h.analytics.Distribution(metric, dimension, value, bins)
+	}
+	if err != nil {
+		if stderrors.Is(err, services.ErrUnknownValue) {
+			errors.WriteError(w, h.logger, errors.NotFound(fmt.Sprintf("no sales for %s %q", dimension, value)), requestID)
+			return
+		}
+		errors.WriteError(w, h.logger, errors.Validation(err.Error()), requestID)
+		return
+	}
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, data, headers)
+}
+
+func (h *APIHandlers) HandleABCSummary(w http.ResponseWriter, r *http.Request) {
+	data, err := h.analytics.ABCSummary(r.Context())
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "ABC classification failed"), observability.GetRequestID(r.Context()))
+		return
+	}
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, data, headers)
+}
+
+// HandleProductClasses lists products by revenue rank with their ABC class,
+// optionally filtered by class and category.
+func (h *APIHandlers) HandleProductClasses(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	params := r.URL.Query()
+	class := params.Get("class")
+	category := params.Get("category")
+
+	if class != "" && !slices.Contains(services.ABCClasses, class) {
+		errors.WriteError(w, h.logger, errors.Validation(fmt.Sprintf("class must be one of: %s", strings.Join(services.ABCClasses, ", "))), requestID)
+		return
+	}
+
+	scope := class + ":" + category
+	page, err := parsePageRequest(r, scope)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data, err := h.analytics.ProductClasses(r.Context(), class, category)
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "ABC classification failed"), requestID)
+		return
+	}
+
+	total := len(data)
+	data = data[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, scope), headers)
+}
+
+func (h *APIHandlers) HandleProductClass(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	product := r.PathValue("product")
+
+	data, err := h.analytics.ProductClass(r.Context(), product)
+	if err != nil {
+		if stderrors.Is(err, services.ErrUnknownValue) {
+			errors.WriteError(w, h.logger, errors.NotFound(fmt.Sprintf("no sales for product %q", product)), requestID)
+			return
+		}
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "ABC classification failed"), requestID)
+		return
+	}
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, data, headers)
+}
+
+// HandleMetrics lists the registered custom metrics.
+func (h *APIHandlers) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, h.analytics.MetricNames(), headers)
+}
+
+// HandleMetric returns the value of one registered custom metric. Derived
+// metrics can also be restricted with from, to, country, region and category
+// and broken down by a group_by dimension.
+func (h *APIHandlers) HandleMetric(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	params := r.URL.Query()
+	name := r.PathValue("name")
+
+	q := services.Query{Country: params.Get("country"), Region: params.Get("region"), Category: params.Get("category")}
+	var err error
+	if v := params.Get("from"); v != "" {
+		if q.From, err = parseDate("from", v); err != nil {
+			errors.WriteError(w, h.logger, err, requestID)
+			return
+		}
+	}
+	if v := params.Get("to"); v != "" {
+		if q.To, err = parseDate("to", v); err != nil {
+			errors.WriteError(w, h.logger, err, requestID)
+			return
+		}
+	}
+	groupBy := params.Get("group_by")
+
+	result := models.CustomMetric{Name: name}
+	if groupBy == "" && q == (services.Query{}) {
+		result.Value, err = h.analytics.Metric(r.Context(), name)
+	} else {
+		var groups []models.MetricGroup
+		groups, err = h.analytics.EvaluateMetric(r.Context(), name, q, groupBy)
+		if groupBy == "" && err == nil {
+			result.Value = groups[0].Value
+		} else {
+			result.Groups = groups
+		}
+	}
+	if err != nil {
+		switch {
+		case stderrors.Is(err, services.ErrUnknownMetric):
+			errors.WriteError(w, h.logger, errors.NotFound(fmt.Sprintf("no metric named %q", name)), requestID)
+		case r.Context().Err() != nil:
+			errors.WriteError(w, h.logger, errors.InternalWrap(err, "metric evaluation cancelled"), requestID)
+		default:
+			errors.WriteError(w, h.logger, errors.Validation(err.Error()), requestID)
+		}
+		return
+	}
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, result, headers)
+}
+
+func (h *APIHandlers) HandleGrowth(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	params := r.URL.Query()
+	dimension := cmp.Or(params.Get("dimension"), services.DimensionCountry)
+	period := params.Get("period")
+	metric := cmp.Or(params.Get("metric"), services.MetricRevenue)
+
+	scope := fmt.Sprintf("%s:%s:%s", dimension, period, metric)
+	page, err := parsePageRequest(r, scope)
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+
+	data, err := h.analytics.Growth(r.Context(), dimension, period, metric)
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.Validation(err.Error()), requestID)
+		return
+	}
+
+	total := len(data)
+	data = data[min(page.Offset, total):min(page.Offset+page.PageSize, total)]
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithMeta(w, data, newPageMeta(page, total, scope), headers)
+}
+
+func (h *APIHandlers) HandleCompare(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	params := r.URL.Query()
+
+	current, previous, err := parseComparison(params.Get("from"), params.Get("to"), params.Get("compare_from"), params.Get("compare_to"))
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
+	for _, q := range []*services.Query{&current, &previous} {
+		q.Country = params.Get("country")
+		q.Region = params.Get("region")
+		q.Category = params.Get("category")
+	}
+
+	limit := maxTableRows
+	if v := params.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			errors.WriteError(w, h.logger, errors.Validation(fmt.Sprintf("limit must be between 1 and %d", maxPageSize)), requestID)
+			return
+		}
+	}
+
+	data, err := h.analytics.Compare(r.Context(), current, previous, limit)
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "comparison failed"), requestID)
+		return
+	}
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=300",
+	}
+
+	errors.WriteSuccessWithHeaders(w, data, headers)
+}
+
+// parseComparison resolves the two date ranges of a comparison. Without an
+// explicit comparison range the current range is compared to the same dates
+// one year earlier.
+func parseComparison(from, to, compareFrom, compareTo string) (services.Query, services.Query, error) {
+	var current, previous services.Query
+
+	if from == "" || to == "" {
+		return current, previous, errors.Validation("from and to dates are required")
+	}
+	var err error
+	if current.From, err = parseDate("from", from); err != nil {
+		return current, previous, err
+	}
+	if current.To, err = parseDate("to", to); err != nil {
+		return current, previous, err
+	}
+	if current.To.Before(current.From) {
+		return current, previous, errors.Validation("from must not be after to")
+	}
+
+	if compareFrom == "" && compareTo == "" {
+		previous.From = current.From.AddDate(-1, 0, 0)
+		previous.To = current.To.AddDate(-1, 0, 0)
+		return current, previous, nil
+	}
+	if previous.From, err = parseDate("compare_from", compareFrom); err != nil {
+		return current, previous, err
+	}
+	if previous.To, err = parseDate("compare_to", compareTo); err != nil {
+		return current, previous, err
+	}
+	if previous.To.Before(previous.From) {
+		return current, previous, errors.Validation("compare_from must not be after compare_to")
+	}
+	return current, previous, nil
+}
+
+func parseDate(name, value string) (time.Time, error) {
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return t, errors.Validation(fmt.Sprintf("%s must be a date formatted as YYYY-MM-DD", name))
+	}
+	return t, nil
+}
+
+// HandleTopRegions ranks regions by revenue, orders, units or distinct
+// customers, as chosen by rank_by, and returns the first limit of them.
 func (h *APIHandlers) HandleTopRegions(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+
+	params := r.URL.Query()
+	opts, err := parseTopN(params.Get("limit"), params.Get("rank_by"), defaultRegionRanking, h.maxTopN, h.analytics.DerivedMetricNames())
+	if err != nil {
+		errors.WriteError(w, h.logger, err, requestID)
+		return
+	}
 
-	data := h.analytics.TopRegions(30)
+	data, err := h.analytics.RankedRegions(r.Context(), opts.RankBy, opts.Limit)
+	if err != nil {
+		errors.WriteError(w, h.logger, errors.InternalWrap(err, "region ranking failed"), requestID)
+		return
+	}
 
 	headers := map[string]string{
 		"Cache-Control": "public, max-age=300",
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -439,3 +440,1079 @@ func TestAPIHandlers_ResponseFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIHandlers_HandleCountryRevenue_Pagination(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	type page struct {
+		Data []models.CountryRevenue `json:"data"`
+		Meta pageMeta                `json:"meta"`
+	}
+	fetch := func(t *testing.T, target string) (int, page) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handlers.HandleCountryRevenue(w, req)
+
+		var p page
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatalf("failed to decode JSON: %v", err)
+			}
+		}
+		return w.Code, p
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		_, p := fetch(t, "/api/country-revenue?page_size=1")
+		if len(p.Data) != 1 || p.Meta.Total != 2 || p.Meta.TotalPages != 2 {
+			t.Fatalf("unexpected page: %+v", p)
+		}
+		if p.Data[0].Country != "USA" {
+			t.Errorf("expected highest revenue first, got %q", p.Data[0].Country)
+		}
+		if p.Meta.NextCursor == "" {
+			t.Error("expected next cursor")
+		}
+	})
+
+	t.Run("follow cursor", func(t *testing.T) {
+		_, first := fetch(t, "/api/country-revenue?page_size=1")
+		_, second := fetch(t, "/api/country-revenue?page_size=1&cursor="+first.Meta.NextCursor)
+		if len(second.Data) != 1 || second.Data[0].Country != "Canada" {
+			t.Errorf("unexpected second page: %+v", second.Data)
+		}
+		if second.Meta.NextCursor != "" {
+			t.Error("last page should not have a next cursor")
+		}
+	})
+
+	t.Run("sort and search", func(t *testing.T) {
+		_, p := fetch(t, "/api/country-revenue?sort=country&q=MOU")
+		if p.Meta.Total != 1 || p.Data[0].ProductName != "Mouse" {
+			t.Errorf("unexpected search result: %+v", p)
+		}
+	})
+
+	t.Run("cursor bound to sort", func(t *testing.T) {
+		_, first := fetch(t, "/api/country-revenue?page_size=1")
+		code, _ := fetch(t, "/api/country-revenue?page_size=1&sort=country&cursor="+first.Meta.NextCursor)
+		if code != http.StatusBadRequest {
+			t.Errorf("expected 400 for mismatched cursor, got %d", code)
+		}
+	})
+
+	invalid := []string{
+		"/api/country-revenue?page=0",
+		"/api/country-revenue?page=9223372036854775807",
+		"/api/country-revenue?cursor=" + encodeCursor(math.MaxInt, "revenue:true:"),
+		"/api/country-revenue?page_size=100000",
+		"/api/country-revenue?sort=bogus",
+		"/api/country-revenue?order=sideways",
+		"/api/country-revenue?cursor=!!!",
+	}
+	for _, target := range invalid {
+		t.Run(target, func(t *testing.T) {
+			if code, _ := fetch(t, target); code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", code)
+			}
+		})
+	}
+}
+
+func TestAPIHandlers_HandleTimeSeries(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/timeseries?granularity=month&metric=orders", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleTimeSeries(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Data []models.TimeSeriesPoint `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(response.Data) != 2 || response.Data[0].Period != "2023-01" || response.Data[1].Period != "2023-02" {
+		t.Errorf("expected chronological Jan/Feb points, got %+v", response.Data)
+	}
+
+	for _, target := range []string{"/api/timeseries?granularity=hour", "/api/timeseries?metric=margin"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handlers.HandleTimeSeries(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleGrowth(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/growth?dimension=category&period=2023-02", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleGrowth(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Data []models.GrowthMetric `json:"data"`
+		Meta pageMeta              `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if response.Meta.Total != 1 || len(response.Data) != 1 {
+		t.Fatalf("expected one category, got %+v", response)
+	}
+	got := response.Data[0]
+	if got.Value != "Electronics" || got.Current != 59.98 || got.PreviousMonth != 999.99 {
+		t.Errorf("unexpected growth entry: %+v", got)
+	}
+
+	for _, target := range []string{"/api/growth?dimension=planet", "/api/growth?period=2023", "/api/growth?metric=margin"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handlers.HandleGrowth(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleCompare(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/compare?from=2023-02-01&to=2023-02-28&compare_from=2023-01-01&compare_to=2023-01-31", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleCompare(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data models.Comparison `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	c := response.Data
+	if len(c.CountryRevenue) != 1 || c.CountryRevenue[0].Country != "Canada" {
+		t.Errorf("expected only Canada in February, got %+v", c.CountryRevenue)
+	}
+	if len(c.MonthlySales) != 1 || c.MonthlySales[0].Revenue.Previous != 999.99 {
+		t.Errorf("expected January revenue as the comparison month, got %+v", c.MonthlySales)
+	}
+}
+
+func TestAPIHandlers_HandleCompare_DefaultsToPreviousYear(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/compare?from=2024-01-01&to=2024-03-31", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleCompare(w, req)
+
+	var response struct {
+		Data models.Comparison `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if response.Data.Previous.From != "2023-01-01" || response.Data.Previous.To != "2023-03-31" {
+		t.Errorf("expected comparison with the same range last year, got %+v", response.Data.Previous)
+	}
+}
+
+func TestAPIHandlers_HandleCompare_Validation(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	invalid := []string{
+		"/api/compare",
+		"/api/compare?from=2023-01-01",
+		"/api/compare?from=2023-13-01&to=2023-12-31",
+		"/api/compare?from=2023-03-01&to=2023-01-01",
+		"/api/compare?from=2023-01-01&to=2023-01-31&compare_from=2022-01-01",
+		"/api/compare?from=2023-01-01&to=2023-01-31&limit=0",
+	}
+	for _, target := range invalid {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handlers.HandleCompare(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleForecast(t *testing.T) {
+	analytics := services.NewAnalytics()
+	analytics.SetData([]models.Transaction{
+		{Date: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), Country: "USA", TotalPrice: 100, Quantity: 1},
+		{Date: time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC), Country: "USA", TotalPrice: 200, Quantity: 2},
+		{Date: time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC), Country: "USA", TotalPrice: 300, Quantity: 3},
+	})
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/forecast?metric=revenue&horizon=2&country=USA", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleForecast(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data models.Forecast `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	f := response.Data
+	if f.Method != "linear" || f.Country != "USA" || len(f.Points) != 2 {
+		t.Fatalf("unexpected forecast %+v", f)
+	}
+	if p := f.Points[0]; p.Period != "2023-04" || p.Value < 399.99 || p.Value > 400.01 {
+		t.Errorf("first point = %+v, want 2023-04 at 400", p)
+	}
+}
+
+func TestAPIHandlers_HandleForecast_Validation(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	invalid := []string{
+		"/api/forecast?horizon=abc",
+		"/api/forecast?horizon=0",
+		"/api/forecast?horizon=100",
+		"/api/forecast?metric=profit",
+		// two months of history are too few to project from
+		"/api/forecast",
+	}
+	for _, target := range invalid {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handlers.HandleForecast(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleAnomalies(t *testing.T) {
+	analytics := services.NewAnalytics()
+	var txs []models.Transaction
+	end := time.Date(2023, 3, 12, 0, 0, 0, 0, time.UTC)
+	for d := end.AddDate(0, 0, -69); !d.After(end); d = d.AddDate(0, 0, 1) {
+		price := 100.0
+		if d.Equal(end) {
+			price = 2000
+		}
+		txs = append(txs, models.Transaction{Date: d, Country: "USA", Category: "Electronics", TotalPrice: price, Quantity: 1})
+	}
+	analytics.SetData(txs)
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/anomalies?dimension=country", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleAnomalies(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []models.Anomaly `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	// Only the revenue jumps; the order count stays at one a day.
+	if len(response.Data) != 1 {
+		t.Fatalf("expected 1 country anomaly, got %+v", response.Data)
+	}
+	a := response.Data[0]
+	if a.Value != "USA" || a.Metric != "revenue" || a.Direction != "spike" || a.Date != "2023-03-12" {
+		t.Errorf("unexpected anomaly %+v", a)
+	}
+
+	for _, target := range []string{"/api/anomalies?dimension=region", "/api/anomalies?metric=units"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handlers.HandleAnomalies(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleCohorts(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/cohorts?months=1", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleCohorts(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []models.Cohort `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(response.Data) != 2 {
+		t.Fatalf("expected a January and a February cohort, got %+v", response.Data)
+	}
+	for _, c := range response.Data {
+		if c.Customers != 1 || len(c.Retention) != 1 || c.Retention[0] != 100 {
+			t.Errorf("unexpected cohort %+v", c)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/cohorts?months=0", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleCohorts(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for months=0, got %d", w.Code)
+	}
+}
+
+func TestAPIHandlers_HandleCustomerSegments(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/customers/segments", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleCustomerSegments(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data models.CustomerSegments `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if response.Data.Overall.Customers != 2 || len(response.Data.Countries) != 2 {
+		t.Errorf("expected two customers in two countries, got %+v", response.Data)
+	}
+}
+
+func TestAPIHandlers_HandleSegmentCustomers(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	// With two customers U002 ranks middling on recency and lowest on spend
+	req := httptest.NewRequest(http.MethodGet, "/api/customers/segments/about_to_sleep?page_size=1", nil)
+	req.SetPathValue("segment", "about_to_sleep")
+	w := httptest.NewRecorder()
+	handlers.HandleSegmentCustomers(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []models.CustomerRFM `json:"data"`
+		Meta struct {
+			Total int `json:"total"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if response.Meta.Total != 1 || len(response.Data) != 1 || response.Data[0].UserID != "U002" {
+		t.Errorf("unexpected page %+v", response)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/customers/segments/vip", nil)
+	req.SetPathValue("segment", "vip")
+	w = httptest.NewRecorder()
+	handlers.HandleSegmentCustomers(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown segment, got %d", w.Code)
+	}
+}
+
+func TestAPIHandlers_HandleCustomerMetrics(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/customer-metrics?dimension=region&page_size=1", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleCustomerMetrics(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []models.CustomerMetrics `json:"data"`
+		Meta struct {
+			Total int `json:"total"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if response.Meta.Total != 2 || len(response.Data) != 1 {
+		t.Fatalf("expected one of two regions, got %+v", response)
+	}
+	if m := response.Data[0]; m.Value != "California" || m.Customers != 1 || m.AverageOrderValue != 999.99 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/customer-metrics?dimension=product", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleCustomerMetrics(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unsupported dimension, got %d", w.Code)
+	}
+}
+
+func TestAPIHandlers_HandleMargins(t *testing.T) {
+	analytics := services.NewAnalytics()
+	day := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	analytics.SetData([]models.Transaction{
+		{Date: day, Country: "USA", ProductName: "Laptop", Quantity: 1, TotalPrice: 1000, Cataloged: true, UnitCost: 750, Brand: "Acme"},
+		{Date: day, Country: "Canada", ProductName: "Mouse", Quantity: 2, TotalPrice: 50, Cataloged: true, UnitCost: 10, Brand: "Clicky"},
+	})
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/margins?dimension=brand&page_size=1", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleMargins(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []models.Margin `json:"data"`
+		Meta struct {
+			Total int `json:"total"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if response.Meta.Total != 2 || len(response.Data) != 1 {
+		t.Fatalf("expected one of two brands, got %+v", response)
+	}
+	if m := response.Data[0]; m.Value != "Acme" || m.GrossMargin != 250 || m.MarginPercent == nil || *m.MarginPercent != 25 {
+		t.Errorf("unexpected margin %+v", m)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/margins?dimension=region", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleMargins(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unsupported dimension, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/margins/missing", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleMissingCatalog(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"data":[]`) {
+		t.Errorf("expected an empty list without a catalog, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAPIHandlers_HandleInventory(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/inventory?status=overstock", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleInventory(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []models.InventoryItem `json:"data"`
+		Meta struct {
+			Total int `json:"total"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	// Both products sold a unit or two in the last 30 days against stocks
+	// of 50 and 100, which lasts far beyond the overstock horizon.
+	if response.Meta.Total != 2 || len(response.Data) != 2 {
+		t.Fatalf("expected both products overstocked, got %+v", response)
+	}
+	if item := response.Data[0]; item.ProductName != "Laptop" || item.Stock != 50 || item.StockDate != "2023-01-15" {
+		t.Errorf("unexpected inventory item %+v", item)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/inventory?status=backordered", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleInventory(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown status, got %d", w.Code)
+	}
+}
+
+func TestAPIHandlers_HandleLaunches(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/launches?days=60", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleLaunches(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data []models.ProductLaunch `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(response.Data) != 2 {
+		t.Fatalf("expected both products launched on 2023-01-01, got %+v", response.Data)
+	}
+	if l := response.Data[0]; l.ProductName != "Laptop" || l.Revenue30 != 999.99 || l.DaysToFirstSale == nil || *l.DaysToFirstSale != 14 {
+		t.Errorf("unexpected top launch %+v", l)
+	}
+
+	for _, days := range []string{"0", "soon"} {
+		req = httptest.NewRequest(http.MethodGet, "/api/launches?days="+days, nil)
+		w = httptest.NewRecorder()
+		handlers.HandleLaunches(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("days=%s: expected 400, got %d", days, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleDrilldown(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/drilldown?path=Canada/Ontario", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleDrilldown(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data models.DrilldownNode `json:"data"`
+		Meta struct {
+			Total int `json:"total"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	node := response.Data
+	if node.Level != "region" || node.Revenue != 59.98 || node.ChildLevel != "product" || response.Meta.Total != 1 {
+		t.Fatalf("unexpected node %+v", response)
+	}
+	if c := node.Children[0]; c.Name != "Mouse" || c.Path != "Canada/Ontario/P002" || c.Share != 100 {
+		t.Errorf("unexpected child %+v", c)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"Canada/Ontario/P002", http.StatusOK},
+		{"Canada/Ontario/Mouse", http.StatusOK},
+		{"USA/Ontario", http.StatusNotFound},
+		{"a/b/c/d", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req = httptest.NewRequest(http.MethodGet, "/api/drilldown?path="+tt.path, nil)
+		w = httptest.NewRecorder()
+		handlers.HandleDrilldown(w, req)
+		if w.Code != tt.want {
+			t.Errorf("path %q: expected %d, got %d", tt.path, tt.want, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleDistribution(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/distribution?metric=price&bins=4", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleDistribution(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var single struct {
+		Data models.Distribution `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&single); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if d := single.Data; d.Count != 2 || d.Min != 29.99 || d.Max != 999.99 || len(d.Histogram) != 4 {
+		t.Errorf("unexpected distribution %+v", d)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/distribution?dimension=country", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleDistribution(w, req)
+	var breakdown struct {
+		Data []models.Distribution `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&breakdown); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(breakdown.Data) != 2 || breakdown.Data[0].Metric != "total_price" {
+		t.Errorf("expected order totals for both countries, got %+v", breakdown.Data)
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"dimension=country&value=France", http.StatusNotFound},
+		{"metric=margin", http.StatusBadRequest},
+		{"bins=0", http.StatusBadRequest},
+		{"bins=many", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req = httptest.NewRequest(http.MethodGet, "/api/distribution?"+tt.query, nil)
+		w = httptest.NewRecorder()
+		handlers.HandleDistribution(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.query, tt.want, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleABC(t *testing.T) {
+	analytics := createTestAnalytics()
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/abc", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleABCSummary(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var summary struct {
+		Data models.ABCSummary `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&summary); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if summary.Data.Products != 2 || len(summary.Data.Classes) != 3 || len(summary.Data.Curve) != 2 {
+		t.Errorf("unexpected summary %+v", summary.Data)
+	}
+
+	// The Laptop alone is 94% of revenue, so the Mouse falls in class B
+	req = httptest.NewRequest(http.MethodGet, "/api/abc/products?class=B", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleProductClasses(w, req)
+	var list struct {
+		Data []models.ProductClass `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].ProductName != "Mouse" || list.Data[0].Rank != 2 {
+		t.Errorf("expected the Mouse in class B, got %+v", list.Data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/abc/products?class=Z", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleProductClasses(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown class, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/abc/products/Laptop", nil)
+	req.SetPathValue("product", "Laptop")
+	w = httptest.NewRecorder()
+	handlers.HandleProductClass(w, req)
+	var single struct {
+		Data models.ProductClass `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&single); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if single.Data.Class != "A" {
+		t.Errorf("expected the Laptop in class A, got %+v", single.Data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/abc/products/Sofa", nil)
+	req.SetPathValue("product", "Sofa")
+	w = httptest.NewRecorder()
+	handlers.HandleProductClass(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown product, got %d", w.Code)
+	}
+}
+
+func TestAPIHandlers_HandleTopN_RankBy(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.Default()
+	handlers := NewAPIHandlers(analytics, logger).WithMaxTopN(5)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/top-products?rank_by=units&limit=1", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleTopProducts(w, req)
+	var products struct {
+		Data []models.ProductFrequency `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&products); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(products.Data) != 1 || products.Data[0].ProductName != "Mouse" || products.Data[0].Units != 2 {
+		t.Errorf("expected the Mouse alone when ranking by units, got %+v", products.Data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/top-regions?rank_by=customers", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleTopRegions(w, req)
+	var regions struct {
+		Data []models.RegionRevenue `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&regions); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(regions.Data) != 2 || regions.Data[0].Customers != 1 {
+		t.Errorf("expected both regions with one customer each, got %+v", regions.Data)
+	}
+
+	for _, target := range []string{
+		"/api/top-products?limit=6",
+		"/api/top-products?limit=0",
+		"/api/top-regions?limit=ten",
+		"/api/top-regions?rank_by=margin",
+	} {
+		req = httptest.NewRequest(http.MethodGet, target, nil)
+		w = httptest.NewRecorder()
+		if strings.HasPrefix(target, "/api/top-products") {
+			handlers.HandleTopProducts(w, req)
+		} else {
+			handlers.HandleTopRegions(w, req)
+		}
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleCube(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.Default()
+	handlers := NewAPIHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/cube?group_by=country&month=2023-02&month=2023-03", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleCube(w, req)
+	var response struct {
+		Data []models.CubeRow `json:"data"`
+		Meta pageMeta         `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Values[0] != "Canada" || response.Data[0].Units != 2 || response.Meta.Total != 1 {
+		t.Errorf("expected Canada alone in February, got %+v", response)
+	}
+
+	for _, target := range []string{"/api/cube?group_by=city", "/api/cube?month=Feb"} {
+		req = httptest.NewRequest(http.MethodGet, target, nil)
+		w = httptest.NewRecorder()
+		handlers.HandleCube(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleSQL(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.Default()
+	handlers := NewAPIHandlers(analytics, logger)
+
+	body := `{"query": "SELECT country, SUM(quantity) AS units FROM transactions GROUP BY country ORDER BY units DESC"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/sql", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handlers.HandleSQL(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.SQLResult `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(response.Data.Rows) != 2 || response.Data.Rows[0][0] != "Canada" || response.Data.Rows[0][1] != 2.0 {
+		t.Errorf("expected Canada first with 2 units, got %+v", response.Data)
+	}
+
+	query := "SELECT price FROM transactions WHERE price > 'x'"
+	req = httptest.NewRequest(http.MethodPost, "/api/sql", strings.NewReader(`{"query": "`+query+`"}`))
+	w = httptest.NewRecorder()
+	handlers.HandleSQL(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	var errResponse struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+			Details string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&errResponse); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	wantDetails := query + "\n" + strings.Repeat(" ", strings.Index(query, ">")) + "^"
+	if errResponse.Error.Code != "VALIDATION_ERROR" || errResponse.Error.Details != wantDetails {
+		t.Errorf("expected a validation error pointing at >, got %+v", errResponse.Error)
+	}
+
+	for _, body := range []string{"not json", `{"query": ""}`} {
+		req = httptest.NewRequest(http.MethodPost, "/api/sql", strings.NewReader(body))
+		w = httptest.NewRecorder()
+		handlers.HandleSQL(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_HandleMetric(t *testing.T) {
+	analytics := services.NewAnalytics()
+	analytics.RegisterAggregator(services.WeekendShare())
+	analytics.SetData([]models.Transaction{
+		{Date: time.Date(2023, 1, 14, 0, 0, 0, 0, time.UTC), TotalPrice: 30},
+		{Date: time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC), TotalPrice: 90},
+	})
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/weekend_share", nil)
+	req.SetPathValue("name", "weekend_share")
+	w := httptest.NewRecorder()
+	handlers.HandleMetric(w, req)
+	var response struct {
+		Data struct {
+			Name  string             `json:"name"`
+			Value models.RatioMetric `json:"value"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if response.Data.Name != "weekend_share" || response.Data.Value.Ratio != 0.25 {
+		t.Errorf("expected a weekend share of 0.25, got %+v", response.Data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/metrics/nope", nil)
+	req.SetPathValue("name", "nope")
+	w = httptest.NewRecorder()
+	handlers.HandleMetric(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown metric, got %d", w.Code)
+	}
+}
+
+func TestAPIHandlers_HandleMetric_Derived(t *testing.T) {
+	analytics := services.NewAnalytics()
+	analytics.RegisterAggregator(services.WeekendShare())
+	metric, err := services.ParseDerivedMetric("avg_unit_price", "sum(total_price) / sum(quantity)")
+	if err != nil {
+		t.Fatalf("ParseDerivedMetric() error = %v", err)
+	}
+	analytics.RegisterDerivedMetric(metric)
+	analytics.SetData([]models.Transaction{
+		{Date: time.Date(2023, 1, 14, 0, 0, 0, 0, time.UTC), Country: "Spain", Quantity: 2, TotalPrice: 30},
+		{Date: time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC), Country: "Italy", Quantity: 3, TotalPrice: 90},
+	})
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/avg_unit_price?group_by=country", nil)
+	req.SetPathValue("name", "avg_unit_price")
+	w := httptest.NewRecorder()
+	handlers.HandleMetric(w, req)
+	var response struct {
+		Data models.CustomMetric `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	groups := response.Data.Groups
+	if len(groups) != 2 || groups[0].Group != "Italy" || *groups[0].Value != 30 || groups[1].Group != "Spain" || *groups[1].Value != 15 {
+		t.Errorf("unexpected groups %+v", groups)
+	}
+
+	tests := []struct {
+		name   string
+		metric string
+		query  string
+		code   int
+	}{
+		{"filtered", "avg_unit_price", "?country=Spain", http.StatusOK},
+		{"unknown dimension", "avg_unit_price", "?group_by=colour", http.StatusBadRequest},
+		{"ingestion metric", "weekend_share", "?country=Spain", http.StatusBadRequest},
+		{"bad date", "avg_unit_price", "?from=yesterday", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/metrics/"+tt.metric+tt.query, nil)
+			req.SetPathValue("name", tt.metric)
+			w := httptest.NewRecorder()
+			handlers.HandleMetric(w, req)
+			if w.Code != tt.code {
+				t.Errorf("expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIHandlers_DerivedMetricParams(t *testing.T) {
+	analytics := services.NewAnalytics()
+	metric, err := services.ParseDerivedMetric("avg_unit_price", "sum(total_price) / sum(quantity)")
+	if err != nil {
+		t.Fatalf("ParseDerivedMetric() error = %v", err)
+	}
+	analytics.RegisterDerivedMetric(metric)
+	analytics.SetData([]models.Transaction{
+		{Date: time.Date(2023, 1, 14, 0, 0, 0, 0, time.UTC), Country: "Spain", Region: "North", ProductName: "Kite", Category: "Toys", Quantity: 2, TotalPrice: 30},
+		{Date: time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC), Country: "Italy", Region: "Lazio", ProductName: "Lamp", Category: "Home", Quantity: 3, TotalPrice: 90},
+	})
+	handlers := NewAPIHandlers(analytics, slog.Default())
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		code    int
+	}{
+		{"top products", handlers.HandleTopProducts, "/api/top-products?rank_by=avg_unit_price", http.StatusOK},
+		{"top regions", handlers.HandleTopRegions, "/api/top-regions?rank_by=avg_unit_price", http.StatusOK},
+		{"growth", handlers.HandleGrowth, "/api/growth?metric=avg_unit_price", http.StatusOK},
+		{"cube", handlers.HandleCube, "/api/cube?group_by=country&metric=avg_unit_price", http.StatusOK},
+		{"cube unknown", handlers.HandleCube, "/api/cube?group_by=country&metric=margin", http.StatusBadRequest},
+		{"drill-down", handlers.HandleDrilldown, "/api/drilldown?path=Spain&metric=avg_unit_price", http.StatusOK},
+		{"drill-down unknown", handlers.HandleDrilldown, "/api/drilldown?metric=margin", http.StatusBadRequest},
+		{"country table", handlers.HandleCountryRevenue, "/api/country-revenue?metric=avg_unit_price", http.StatusOK},
+		{"country table unknown", handlers.HandleCountryRevenue, "/api/country-revenue?metric=margin", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != tt.code {
+				t.Fatalf("expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			if tt.code == http.StatusOK && !strings.Contains(w.Body.String(), `"metric_value":15`) && !strings.Contains(w.Body.String(), `"current":15`) {
+				t.Errorf("response should report avg_unit_price 15 for Spain: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIHandlers_Views(t *testing.T) {
+	views, err := services.OpenViewStore(t.TempDir() + "/views.json")
+	if err != nil {
+		t.Fatalf("OpenViewStore() error = %v", err)
+	}
+	handlers := NewAPIHandlers(createTestAnalytics(), slog.Default()).WithViews(views)
+
+	save := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/views", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handlers.HandleSaveView(w, req)
+		return w
+	}
+
+	w := save(`{"name": "emea-q3", "from": "2023-07-01", "to": "2023-09-30", "country": "Germany"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.SavedView `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if response.Data.Version != 1 || response.Data.Country != "Germany" {
+		t.Errorf("unexpected saved view %+v", response.Data)
+	}
+
+	if w := save(`{"name": "emea-q3", "version": 1, "country": "France"}`); w.Code != http.StatusOK {
+		t.Errorf("expected 200 updating at the current version, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := save(`{"name": "emea-q3", "version": 1, "country": "Spain"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a stale version, got %d", w.Code)
+	}
+	if w := save(`{"name": "Bad Name"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid name, got %d", w.Code)
+	}
+	if w := save(`not json`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed body, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/views/emea-q3", nil)
+	req.SetPathValue("name", "emea-q3")
+	w = httptest.NewRecorder()
+	handlers.HandleView(w, req)
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if response.Data.Version != 2 || response.Data.Country != "France" {
+		t.Errorf("expected France at version 2, got %+v", response.Data)
+	}
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/api/views/emea-q3?version=1", http.StatusConflict},
+		{"/api/views/emea-q3?version=x", http.StatusBadRequest},
+		{"/api/views/emea-q3?version=2", http.StatusOK},
+		{"/api/views/emea-q3", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, tt.path, nil)
+		req.SetPathValue("name", "emea-q3")
+		w := httptest.NewRecorder()
+		handlers.HandleDeleteView(w, req)
+		if w.Code != tt.code {
+			t.Errorf("DELETE %s: expected %d, got %d", tt.path, tt.code, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handlers.HandleViews(w, httptest.NewRequest(http.MethodGet, "/api/views", nil))
+	if !strings.Contains(w.Body.String(), `"data":[]`) {
+		t.Errorf("expected no views left, got %s", w.Body.String())
+	}
+}
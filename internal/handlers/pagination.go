@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"abt-dashboard/internal/errors"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// pageMeta is returned alongside paginated data so clients can render pagers
+// and follow NextCursor without recomputing offsets themselves.
+type pageMeta struct {
+	Total      int    `json:"total"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+	TotalPages int    `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// pageRequest is the resolved position of a paginated request.
+type pageRequest struct {
+	Offset   int
+	PageSize int
+}
+
+// cursorToken is the opaque payload behind cursor strings. The scope ties a
+// cursor to the sort and search it was issued for.
+type cursorToken struct {
+	Offset int    `json:"o"`
+	Scope  string `json:"s"`
+}
+
+func encodeCursor(offset int, scope string) string {
+	raw, _ := json.Marshal(cursorToken{Offset: offset, Scope: scope})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(cursor, scope string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.ValidationWrap(err, "invalid cursor")
+	}
+
+	var token cursorToken
+	if err := json.Unmarshal(raw, &token); err != nil {
+		return 0, errors.ValidationWrap(err, "invalid cursor")
+	}
+	if token.Offset < 0 || token.Offset > math.MaxInt-maxPageSize {
+		return 0, errors.Validation("invalid cursor")
+	}
+	if token.Scope != scope {
+		return 0, errors.Validation("cursor does not match the requested sort or search")
+	}
+	return token.Offset, nil
+}
+
+// parsePageRequest reads page, page_size and cursor query parameters. A cursor
+// takes precedence over page.
+func parsePageRequest(r *http.Request, scope string) (pageRequest, error) {
+	query := r.URL.Query()
+	req := pageRequest{PageSize: defaultPageSize}
+
+	if v := query.Get("page_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 || size > maxPageSize {
+			return req, errors.Validation(fmt.Sprintf("page_size must be between 1 and %d", maxPageSize))
+		}
+		req.PageSize = size
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		offset, err := decodeCursor(cursor, scope)
+		if err != nil {
+			return req, err
+		}
+		req.Offset = offset
+		return req, nil
+	}
+
+	if v := query.Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return req, errors.Validation("page must be a positive integer")
+		}
+		// The offset of the page's end must fit in an int.
+		if page > math.MaxInt/req.PageSize {
+			return req, errors.Validation("page is out of range")
+		}
+		req.Offset = (page - 1) * req.PageSize
+	}
+
+	return req, nil
+}
+
+func newPageMeta(req pageRequest, total int, scope string) pageMeta {
+	meta := pageMeta{
+		Total:      total,
+		Page:       req.Offset/req.PageSize + 1,
+		PageSize:   req.PageSize,
+		TotalPages: (total + req.PageSize - 1) / req.PageSize,
+	}
+	if next := req.Offset + req.PageSize; next < total {
+		meta.NextCursor = encodeCursor(next, scope)
+	}
+	if req.Offset > 0 {
+		meta.PrevCursor = encodeCursor(max(req.Offset-req.PageSize, 0), scope)
+	}
+	return meta
+}
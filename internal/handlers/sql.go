@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"abt-dashboard/internal/errors"
+	"abt-dashboard/internal/observability"
+	"abt-dashboard/internal/services/sqlquery"
+)
+
+// Limits of the SQL endpoint when the handlers are not configured otherwise.
+const (
+	DefaultSQLTimeout = 5 * time.Second
+	DefaultSQLMaxRows = 1000
+)
+
+// maxSQLBodyBytes bounds the request body of the SQL endpoint.
+const maxSQLBodyBytes = 64 << 10
+
+// WithSQLLimits sets how long a SQL query may run and how many rows it may
+// return.
+func (h *APIHandlers) WithSQLLimits(timeout time.Duration, maxRows int) *APIHandlers {
+	h.sqlTimeout = timeout
+	h.sqlMaxRows = maxRows
+	return h
+}
+
+type sqlRequest struct {
+	Query string `json:"query"`
+}
+
+// HandleSQL runs the SELECT in the JSON body {"query": "..."} over the
+// transactions. Mistakes in the query are validation errors whose details
+// show the query line with a caret under the offending token.
+func (h *APIHandlers) HandleSQL(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+
+	var req sqlRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSQLBodyBytes)).Decode(&req); err != nil {
+		errors.WriteError(w, h.logger, errors.BadRequestWrap(err, `body must be JSON of the form {"query": "SELECT ..."}`), requestID)
+		return
+	}
+	if req.Query == "" {
+		errors.WriteError(w, h.logger, errors.Validation("query is required"), requestID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.sqlTimeout)
+	defer cancel()
+
+	result, err := h.analytics.SQL(ctx, req.Query, h.sqlMaxRows)
+	if err != nil {
+		var qerr *sqlquery.Error
+		switch {
+		case stderrors.As(err, &qerr):
+			appErr := errors.Validation(qerr.Error())
+			appErr.Details = qerr.Pointer(req.Query)
+			errors.WriteError(w, h.logger, appErr, requestID)
+		case stderrors.Is(err, context.DeadlineExceeded) && r.Context().Err() == nil:
+			errors.WriteError(w, h.logger, errors.BadRequestWrap(err, "query exceeded the time limit of "+h.sqlTimeout.String()), requestID)
+		default:
+			errors.WriteError(w, h.logger, errors.InternalWrap(err, "SQL query cancelled"), requestID)
+		}
+		return
+	}
+
+	errors.WriteSuccess(w, result)
+}
@@ -1,14 +1,23 @@
 package handlers
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
+	stderrors "errors"
+	"fmt"
 	"html/template"
 	"log/slog"
+	"math"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	"abt-dashboard/internal/errors"
 	"abt-dashboard/internal/models"
 	"abt-dashboard/internal/services"
+	"abt-dashboard/internal/services/geo"
 	"github.com/starfederation/datastar-go/datastar"
 )
 
@@ -16,42 +25,381 @@ const (
 	maxTableRows = 50
 	maxProducts  = 20
 	maxRegions   = 30
+
+	defaultForecastHorizon = 12
+
+	// The cohort heatmap shows the most recent cohorts over their first year.
+	maxCohortRows   = 12
+	maxCohortMonths = 12
+
+	maxInventoryRows = 20
+	maxDrillRows     = 20
+
+	// The launch leaderboard covers products added in the last quarter.
+	defaultLaunchDays = 90
+	maxLaunchRows     = 10
+
+	// The country map is an equirectangular projection of this size.
+	// Countries without an outline are drawn as dots of the given radius.
+	mapWidth          = 1000
+	mapHeight         = 500
+	mapDotRadius      = 3.0
+	maxUnmatchedNames = 10
+
+	maxMarginRows  = 15
+	maxMissingRows = 5
 )
 
-var countryTableTemplate = template.Must(template.New("countryTable").Parse(`
+var countryTableTemplate = template.Must(template.New("countryTable").Funcs(template.FuncMap{
+	"deltaBadge":  deltaBadge,
+	"drillPath":   drillPath,
+	"metricValue": metricValue,
+}).Parse(`
 <div id="country-content">
 <table class="modern-table">
-<thead><tr><th>Country</th><th>Product</th><th>Category</th><th>Revenue</th><th>Orders</th></tr></thead>
+<thead><tr><th>Country</th><th>Product</th><th>Category</th><th>Revenue</th><th>Orders</th>{{with .Metric}}<th>{{.}}</th>{{end}}</tr></thead>
 <tbody>
-{{range $i, $item := .Data}}{{if lt $i $.MaxRows}}<tr>
-<td>{{.Country}}</td>
-<td>{{.ProductName}}</td>
+{{range $i, $item := .Data}}{{if lt $i $.MaxRows}}<tr class="drillable" data-on-click="$drillPath = {{drillPath .Country}}; $drillOpen = true; @get('/sse/drilldown')">
+<td>{{.Country}}{{with index $.Growth .Country}} {{deltaBadge "MoM" .MoMPercent}} {{deltaBadge "YoY" .YoYPercent}}{{end}}</td>
+<td>{{.ProductName}}{{with .ProductID}} <small>{{.}}</small>{{end}}</td>
 <td><span class="category-badge">{{.Category}}</span></td>
 <td><strong>${{printf "%.2f" .TotalRevenue}}</strong></td>
-<td>{{.Transactions}}</td>
+<td>{{.Transactions}}</td>{{if $.Metric}}
+<td>{{metricValue .MetricValue}}</td>{{end}}
 </tr>{{end}}{{end}}
 </tbody>
 </table>
 </div>`))
 
+var comparisonTableTemplate = template.Must(template.New("comparisonTable").Funcs(template.FuncMap{
+	"deltaBadge":  deltaBadge,
+	"drillPath":   drillPath,
+	"metricValue": metricValue,
+}).Parse(`
+<div id="country-content">
+<table class="modern-table">
+<thead><tr><th>Country</th><th>Product</th><th>Category</th><th>Revenue {{.Current.From}} – {{.Current.To}}</th><th>Revenue {{.Previous.From}} – {{.Previous.To}}</th><th>Change</th><th>Orders</th></tr></thead>
+<tbody>
+{{range .CountryRevenue}}<tr class="drillable" data-on-click="$drillPath = {{drillPath .Country}}; $drillOpen = true; @get('/sse/drilldown')">
+<td>{{.Country}}</td>
+<td>{{.ProductName}}{{with .ProductID}} <small>{{.}}</small>{{end}}</td>
+<td><span class="category-badge">{{.Category}}</span></td>
+<td><strong>${{printf "%.2f" .Revenue.Current}}</strong></td>
+<td>${{printf "%.2f" .Revenue.Previous}}</td>
+<td>{{deltaBadge "Δ" .Revenue.Percent}}</td>
+<td>{{printf "%.0f" .Transactions.Current}} <small>vs {{printf "%.0f" .Transactions.Previous}}</small></td>
+</tr>{{end}}
+</tbody>
+</table>
+{{with .Metrics}}<table class="modern-table">
+<thead><tr><th>Custom metric</th><th>{{$.Current.From}} – {{$.Current.To}}</th><th>{{$.Previous.From}} – {{$.Previous.To}}</th><th>Change</th></tr></thead>
+<tbody>
+{{range .}}<tr>
+<td><strong>{{.Name}}</strong></td>
+<td>{{metricValue .Current}}</td>
+<td>{{metricValue .Previous}}</td>
+<td>{{deltaBadge "Δ" .Percent}}</td>
+</tr>{{end}}
+</tbody>
+</table>{{end}}
+</div>`))
+
+var anomaliesTemplate = template.Must(template.New("anomalies").Funcs(template.FuncMap{
+	"anomalyKey": anomalyKey,
+}).Parse(`
+<div id="anomalies-content">
+{{if .New}}<div class="anomaly-alert">🔔 {{.New}} new {{if eq .New 1}}anomaly{{else}}anomalies{{end}} since the last data reload</div>{{end}}
+{{if .Anomalies}}<table class="modern-table">
+<thead><tr><th>Date</th><th>Dimension</th><th>Value</th><th>Metric</th><th>Actual</th><th>Expected</th><th>Score</th></tr></thead>
+<tbody>
+{{range .Anomalies}}<tr class="anomaly-{{.Direction}}{{if index $.Fresh (anomalyKey .)}} anomaly-new{{end}}">
+<td>{{.Date}}</td>
+<td>{{.Dimension}}</td>
+<td><strong>{{.Value}}</strong></td>
+<td>{{.Metric}}</td>
+<td>{{printf "%.2f" .Actual}}</td>
+<td>{{printf "%.2f" .Expected}}</td>
+<td>{{if eq .Direction "drop"}}▼{{else}}▲{{end}} {{printf "%.1f" .Score}}</td>
+</tr>{{end}}
+</tbody>
+</table>{{else}}<div class="empty-state">✅ No anomalies in the most recent week</div>{{end}}
+</div>`))
+
+var cohortHeatmapTemplate = template.Must(template.New("cohortHeatmap").Funcs(template.FuncMap{
+	"heat": func(pct float64) string { return fmt.Sprintf("%.2f", pct/100) },
+	"sum": func(values []float64) float64 {
+		total := 0.0
+		for _, v := range values {
+			total += v
+		}
+		return total
+	},
+}).Parse(`
+<div id="cohorts-content">
+{{if .Cohorts}}<div class="table-container">
+<table class="modern-table cohort-heatmap">
+<thead><tr><th>Cohort</th><th>Customers</th><th>First-year revenue</th>{{range .Offsets}}<th>M{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Cohorts}}<tr>
+<td><strong>{{.Cohort}}</strong></td>
+<td>{{.Customers}}</td>
+<td>${{printf "%.0f" (sum .Revenue)}}</td>
+{{$c := .}}{{range $k, $pct := .Retention}}<td class="heat-cell" style="background: rgba(59, 130, 246, {{heat $pct}})" title="{{index $c.Active $k}} customers, ${{printf "%.2f" (index $c.Revenue $k)}}">{{printf "%.0f" $pct}}%</td>{{end}}
+</tr>{{end}}
+</tbody>
+</table>
+</div>{{else}}<div class="empty-state">No customers match this filter</div>{{end}}
+</div>`))
+
+var inventoryTemplate = template.Must(template.New("inventory").Parse(`
+<div id="inventory-content">
+{{if .Items}}<table class="modern-table">
+<thead><tr><th>Product</th><th>Category</th><th>Stock</th><th>Sold / day</th><th>Days of cover</th><th>Stock as of</th></tr></thead>
+<tbody>
+{{range .Items}}<tr class="stock-{{.Status}}">
+<td><strong>{{.ProductName}}</strong>{{with .ProductID}} <small>{{.}}</small>{{end}}</td>
+<td><span class="category-badge">{{.Category}}</span></td>
+<td>{{.Stock}}</td>
+<td>{{printf "%.1f" .Velocity}}</td>
+<td>{{if eq .Status "out_of_stock"}}<span class="stock-badge">Out of stock</span>{{else}}{{with .DaysOfCover}}{{printf "%.0f" .}}{{end}}{{end}}</td>
+<td>{{.StockDate}}</td>
+</tr>{{end}}
+</tbody>
+</table>{{if gt .More 0}}<div class="table-note">and {{.More}} more</div>{{end}}{{else}}<div class="empty-state">✅ No products at risk of stocking out</div>{{end}}
+</div>`))
+
+var metricsTemplate = template.Must(template.New("metrics").Funcs(template.FuncMap{
+	"metricValue": metricValue,
+}).Parse(`
+<div id="metrics-content">
+{{if .Metrics}}<table class="modern-table">
+<thead><tr><th>Metric</th><th>Value</th><th></th></tr></thead>
+<tbody>
+{{range .Metrics}}<tr>
+<td><strong>{{.Name}}</strong></td>
+<td>{{metricValue .Value}}</td>
+<td>{{if .Derived}}<button class="btn secondary" data-on-click="$tsMetric = {{.Name}}; @get('/sse/timeseries')">Chart</button>{{end}}</td>
+</tr>{{end}}
+</tbody>
+</table>{{else}}<div class="empty-state">No custom metrics are configured</div>{{end}}
+</div>`))
+
+// metricOptionsTemplate fills the custom metrics group of a metric select.
+var metricOptionsTemplate = template.Must(template.New("metricOptions").Parse(`
+<optgroup id="{{.ID}}" label="Custom metrics">{{range .Names}}<option value="{{.}}">{{.}}</option>{{end}}</optgroup>`))
+
+// metricSelects are the ids of the option groups that list the derived
+// metrics in the dashboard's metric selects.
+var metricSelects = []string{"ts-custom-metrics", "products-custom-metrics", "regions-custom-metrics", "table-custom-metrics"}
+
+var launchesTemplate = template.Must(template.New("launches").Parse(`
+<div id="launches-content">
+{{if .}}<table class="modern-table">
+<thead><tr><th>Product</th><th>Category</th><th>Added</th><th>Days to first sale</th><th>First 30 days</th><th>First 90 days</th></tr></thead>
+<tbody>
+{{range .}}<tr>
+<td><strong>{{.ProductName}}</strong>{{with .ProductID}} <small>{{.}}</small>{{end}}</td>
+<td><span class="category-badge">{{.Category}}</span></td>
+<td>{{.AddedDate}}</td>
+<td>{{with .DaysToFirstSale}}{{.}}{{else}}<span class="empty-state">not sold yet</span>{{end}}</td>
+<td><strong>${{printf "%.2f" .Revenue30}}</strong> <small>{{.Units30}} units</small></td>
+<td>${{printf "%.2f" .Revenue90}}{{if lt .DaysLive 90}} <small title="only {{.DaysLive}} days of data since launch">partial</small>{{end}}</td>
+</tr>{{end}}
+</tbody>
+</table>{{else}}<div class="empty-state">No products were added in the last 90 days of data</div>{{end}}
+</div>`))
+
+var drilldownTemplate = template.Must(template.New("drilldown").Funcs(template.FuncMap{
+	"metricValue": metricValue,
+}).Parse(`
+<div id="drilldown-content">
+<div class="drilldown-header">
+<nav class="breadcrumb">{{range $i, $c := .Crumbs}}{{if $i}} › {{end}}<a href="#" data-on-click__prevent="$drillPath = {{$c.Path}}; @get('/sse/drilldown')">{{$c.Name}}</a>{{end}}</nav>
+<button class="btn secondary" data-on-click="$drillOpen = false">Close</button>
+</div>
+{{with .Node}}<div class="drilldown-totals"><strong>${{printf "%.2f" .Revenue}}</strong> revenue · {{.Orders}} orders · {{.Units}} units{{if $.Metric}} · {{$.Metric}} {{metricValue .MetricValue}}{{end}}</div>
+{{if .Children}}<table class="modern-table">
+<thead><tr><th>{{$.ChildLabel}}</th><th>Revenue</th><th>Share</th><th>Orders</th><th>Units</th>{{with $.Metric}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range $.Children}}<tr{{if $.Deeper}} class="drillable" data-on-click="$drillPath = {{.Path}}; @get('/sse/drilldown')"{{end}}>
+<td><strong>{{.Name}}</strong></td>
+<td>${{printf "%.2f" .Revenue}}</td>
+<td>{{printf "%.1f" .Share}}%</td>
+<td>{{.Orders}}</td>
+<td>{{.Units}}</td>{{if $.Metric}}
+<td>{{metricValue .MetricValue}}</td>{{end}}
+</tr>{{end}}
+</tbody>
+</table>{{if gt $.More 0}}<div class="table-note">and {{$.More}} more</div>{{end}}{{end}}{{end}}
+</div>`))
+
+var distributionTemplate = template.Must(template.New("distribution").Parse(`
+<div id="distribution-content">
+<div class="quantiles">
+<span><small>p50</small> <strong>{{printf "%.2f" .P50}}</strong></span>
+<span><small>p90</small> <strong>{{printf "%.2f" .P90}}</strong></span>
+<span><small>p99</small> <strong>{{printf "%.2f" .P99}}</strong></span>
+<span><small>mean</small> {{printf "%.2f" .Mean}}</span>
+<span><small>range</small> {{printf "%.2f" .Min}} – {{printf "%.2f" .Max}}</span>
+<span><small>transactions</small> {{.Count}}</span>
+</div>
+</div>`))
+
+var abcTemplate = template.Must(template.New("abc").Parse(`
+<div id="pareto-content">
+<div class="quantiles">
+{{range .Classes}}<span><span class="abc-class abc-{{.Class}}">{{.Class}}</span> {{.Products}} products ({{printf "%.1f" .ProductShare}}%) · {{printf "%.1f" .RevenueShare}}% of revenue</span>
+{{end}}</div>
+{{if .Categories}}<table class="modern-table">
+<thead><tr><th>Category</th><th>A</th><th>B</th><th>C</th></tr></thead>
+<tbody>
+{{range .Categories}}<tr>
+<td><span class="category-badge">{{.Category}}</span></td>
+<td>{{.A}}</td>
+<td>{{.B}}</td>
+<td>{{.C}}</td>
+</tr>{{end}}
+</tbody>
+</table>{{end}}
+</div>`))
+
+var countryMapTemplate = template.Must(template.New("countryMap").Funcs(template.FuncMap{
+	// Only data-on-* attributes are escaped as scripts; jsString quotes a
+	// value for the other expression attributes.
+	"jsString": func(s string) (string, error) {
+		b, err := json.Marshal(s)
+		return string(b), err
+	},
+}).Parse(`
+<div id="country-map-content">
+<svg class="country-map" viewBox="0 0 {{.Width}} {{.Height}}" role="img" aria-label="Revenue by country">
+<rect class="map-ocean" width="{{.Width}}" height="{{.Height}}"/>
+<line class="map-equator" x1="0" y1="{{.Equator}}" x2="{{.Width}}" y2="{{.Equator}}"/>
+{{range .Shapes}}{{if .Name}}<g class="map-country drillable" fill-opacity="{{printf "%.2f" .Shade}}" data-class-selected="$drillOpen && ($drillPath + '/').startsWith({{jsString .Path}} + '/')" data-on-click="$drillPath = {{.Path}}; $drillOpen = true; @get('/sse/drilldown')"><title>{{.Label}}: ${{printf "%.2f" .Revenue}} · {{.Transactions}} orders</title>{{else}}<g class="map-country empty"><title>{{.Label}}: no sales</title>{{end}}{{if .D}}<path d="{{.D}}"/>{{else}}<circle cx="{{printf "%.1f" .X}}" cy="{{printf "%.1f" .Y}}" r="{{printf "%.1f" .R}}"/>{{end}}</g>
+{{end}}</svg>
+<div class="table-note">Shade follows revenue; countries too small to outline are dots. Click a country to drill into it.</div>
+{{if .Unmatched}}<div class="table-note">⚠️ Not on the map: {{range $i, $u := .Unmatched}}{{if $i}}, {{end}}{{$u.Name}} (${{printf "%.2f" $u.Revenue}}){{end}}{{if gt .More 0}} and {{.More}} more{{end}}</div>{{end}}
+</div>`))
+
+var marginsTemplate = template.Must(template.New("margins").Funcs(template.FuncMap{
+	"percent": func(pct *float64) string {
+		if pct == nil {
+			return "–"
+		}
+		return fmt.Sprintf("%.1f%%", *pct)
+	},
+}).Parse(`
+<div id="margins-content">
+{{if .Costed}}<table class="modern-table">
+<thead><tr><th>{{.Label}}</th><th>Revenue</th><th>Cost</th><th>Gross margin</th><th>Margin</th></tr></thead>
+<tbody>
+{{range .Margins}}<tr>
+<td><strong>{{if .Value}}{{.Value}}{{else}}Not in catalog{{end}}</strong></td>
+<td>${{printf "%.2f" .Revenue}}{{if .UncostedRevenue}} <small title="revenue of products missing from the catalog">${{printf "%.2f" .UncostedRevenue}} uncosted</small>{{end}}</td>
+<td>${{printf "%.2f" .Cost}}</td>
+<td><strong>${{printf "%.2f" .GrossMargin}}</strong></td>
+<td>{{percent .MarginPercent}}</td>
+</tr>{{end}}
+</tbody>
+</table>{{if gt .More 0}}<div class="table-note">and {{.More}} more</div>{{end}}{{else}}<div class="empty-state">No sales are costed yet; set CATALOG_FILE to a product catalog</div>{{end}}
+{{if .Missing}}<div class="table-note">⚠️ {{.MissingCount}} products are not in the catalog, ${{printf "%.2f" .MissingRevenue}} of revenue without a cost: {{range $i, $m := .Missing}}{{if $i}}, {{end}}{{if $m.ProductID}}{{$m.ProductID}}{{else}}no ID{{end}} ({{$m.ProductName}}){{end}}{{if gt .MissingCount (len .Missing)}} …{{end}}</div>{{end}}
+</div>`))
+
 type SSEHandlers struct {
 	analytics *services.Analytics
 	logger    *slog.Logger
+	maxTopN   int
+	views     *services.ViewStore
 }
 
 func NewSSEHandlers(analytics *services.Analytics, logger *slog.Logger) *SSEHandlers {
 	return &SSEHandlers{
 		analytics: analytics,
 		logger:    logger,
+		maxTopN:   DefaultMaxTopN,
 	}
 }
 
+// WithMaxTopN sets the largest limit the top-N charts accept.
+func (h *SSEHandlers) WithMaxTopN(n int) *SSEHandlers {
+	h.maxTopN = n
+	return h
+}
+
 type templateData struct {
 	Data    interface{}
 	MaxRows int
+	Growth  map[string]*models.GrowthMetric
+	Metric  string
+}
+
+// deltaBadge renders a percent change as a colored badge. Changes against an
+// empty base period have no percentage and render as "new".
+func deltaBadge(label string, pct *float64) template.HTML {
+	if pct == nil {
+		return template.HTML(fmt.Sprintf(`<span class="delta-badge">%s new</span>`, label))
+	}
+	class, arrow := "up", "▲"
+	if *pct < 0 {
+		class, arrow = "down", "▼"
+	}
+	return template.HTML(fmt.Sprintf(`<span class="delta-badge %s" title="%s change">%s %s %.1f%%</span>`,
+		class, label, label, arrow, math.Abs(*pct)))
+}
+
+var drillLevelLabels = map[string]string{
+	services.DimensionCountry: "Country",
+	services.DimensionRegion:  "Region",
+	services.DimensionProduct: "Product",
+}
+
+// drillPath is the drill-down path of a country row.
+func drillPath(country string) string {
+	return services.DrillPath(country)
+}
+
+// metricValue formats a custom metric value for the metrics card.
+func metricValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "–"
+	case *float64:
+		if v == nil {
+			return "–"
+		}
+		return fmt.Sprintf("%.2f", *v)
+	case float64:
+		return fmt.Sprintf("%.2f", v)
+	case models.RatioMetric:
+		return fmt.Sprintf("%.1f%%", v.Ratio*100)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func anomalyKey(a models.Anomaly) string {
+	return a.Dimension + "|" + a.Value + "|" + a.Metric + "|" + a.Date
+}
+
+// countryGrowth indexes the latest month-over-month and year-over-year
+// revenue growth by country for the table badges.
+func (h *SSEHandlers) countryGrowth(ctx context.Context) map[string]*models.GrowthMetric {
+	metrics, err := h.analytics.Growth(ctx, services.DimensionCountry, "", services.MetricRevenue)
+	if err != nil {
+		h.logger.Warn("compute country growth", "error", err)
+		return nil
+	}
+	byCountry := make(map[string]*models.GrowthMetric, len(metrics))
+	for i := range metrics {
+		byCountry[metrics[i].Value] = &metrics[i]
+	}
+	return byCountry
 }
 
-func (h *SSEHandlers) renderCountryTable(data interface{}) (string, error) {
+// renderCountryTable renders the country table, with a column for the
+// derived metric of that name when metric is set. Its values come with the
+// rows.
+func (h *SSEHandlers) renderCountryTable(ctx context.Context, data interface{}, metric string) (string, error) {
 	var buf strings.Builder
 
 	// Limit data slice to avoid processing unnecessary records
@@ -62,16 +410,36 @@ func (h *SSEHandlers) renderCountryTable(data interface{}) (string, error) {
 		limitedData = data
 	}
 
-	tmplData := templateData{Data: limitedData, MaxRows: maxTableRows}
+	tmplData := templateData{Data: limitedData, MaxRows: maxTableRows, Growth: h.countryGrowth(ctx), Metric: metric}
 	err := countryTableTemplate.Execute(&buf, tmplData)
 	return buf.String(), err
 }
 
+// countryTable renders the country revenue table with the derived metric
+// selected in the signals, if any. An unknown metric is logged and dropped.
+func (h *SSEHandlers) countryTable(ctx context.Context, signals dashboardSignals) (string, error) {
+	data := h.analytics.CountryRevenue()
+	metric := signals.TableMetric
+	if metric != "" {
+		rows, err := h.analytics.CountryRevenueMetric(ctx, data[:min(len(data), maxTableRows)], metric)
+		switch {
+		case stderrors.Is(err, services.ErrUnknownMetric):
+			h.logger.Warn("unknown table metric", "metric", metric)
+			metric = ""
+		case err != nil:
+			return "", err
+		default:
+			data = rows
+		}
+	}
+	return h.renderCountryTable(ctx, data, metric)
+}
+
 func (h *SSEHandlers) HandleCountryRevenue(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
 	sse := datastar.NewSSE(w, r)
 
-	data := h.analytics.CountryRevenue()
-	html, err := h.renderCountryTable(data)
+	html, err := h.countryTable(r.Context(), signals)
 	if err != nil {
 		h.logger.Error("render country table", "error", err)
 		return
@@ -84,12 +452,34 @@ func (h *SSEHandlers) HandleCountryRevenue(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// topN resolves a chart's ranking from the limit and rank_by query
+// parameters, falling back to its signals. Invalid values are logged and
+// replaced by the defaults so the chart still renders.
+func (h *SSEHandlers) topN(r *http.Request, limit json.Number, rankBy string, defaults topNOptions) topNOptions {
+	params := r.URL.Query()
+	opts, err := parseTopN(cmp.Or(params.Get("limit"), limit.String()), cmp.Or(params.Get("rank_by"), rankBy), defaults, h.maxTopN, h.analytics.DerivedMetricNames())
+	if err != nil {
+		h.logger.Warn("invalid top-N options", "path", r.URL.Path, "error", err)
+		return defaults
+	}
+	return opts
+}
+
 func (h *SSEHandlers) HandleTopProducts(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
 	sse := datastar.NewSSE(w, r)
 
-	data := h.analytics.TopProducts(maxProducts)
+	opts := h.topN(r, signals.ProductsLimit, signals.ProductsRankBy, defaultProductRanking)
+	data, err := h.analytics.RankedProducts(r.Context(), opts.RankBy, opts.Limit)
+	if err != nil {
+		h.logger.Error("rank products", "error", err)
+		sse.PatchElements(`<div id="products-content">⚠️ Products could not be ranked</div>`)
+		return
+	}
 	jsonData, err := json.Marshal(map[string]any{
-		"productsData": data,
+		"productsData":   data,
+		"productsRankBy": opts.RankBy,
+		"productsLimit":  opts.Limit,
 	})
 	if err != nil {
 		h.logger.Error("marshal products data", "error", err)
@@ -124,12 +514,174 @@ func (h *SSEHandlers) HandleMonthlySales(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// dashboardSignals mirrors the Datastar signals the dashboard sends with each
+// request. Query parameters of a dashboard link take precedence so endpoints
+// can also be driven by plain links.
+type dashboardSignals struct {
+	models.DashboardState
+}
+
+func (h *SSEHandlers) readSignals(r *http.Request) dashboardSignals {
+	var signals dashboardSignals
+	if err := datastar.ReadSignals(r, &signals); err != nil {
+		h.logger.Warn("read datastar signals", "error", err)
+	}
+
+	applyDashboardParams(&signals.DashboardState, r.URL.Query())
+	signals.TSGranularity = cmp.Or(signals.TSGranularity, services.GranularityMonth)
+	signals.TSMetric = cmp.Or(signals.TSMetric, services.MetricRevenue)
+	signals.DistMetric = cmp.Or(signals.DistMetric, services.DistributionTotalPrice)
+	signals.MarginDimension = cmp.Or(signals.MarginDimension, services.DimensionCountry)
+	// A value without a dimension to look it up in means no filter.
+	if signals.DistDimension == "" || strings.TrimSpace(signals.DistValue) == "" {
+		signals.DistDimension, signals.DistValue = "", ""
+	}
+	return signals
+}
+
+func (h *SSEHandlers) timeSeriesSignal(ctx context.Context, signals dashboardSignals) (map[string]any, error) {
+	points, err := h.analytics.TimeSeries(ctx, signals.TSGranularity, signals.TSMetric)
+	if err != nil {
+		return nil, err
+	}
+	series := map[string]any{
+		"granularity": signals.TSGranularity,
+		"metric":      signals.TSMetric,
+		"points":      points,
+	}
+
+	// The forecast extends the monthly view only; other granularities would
+	// need their own seasonal model.
+	if signals.TSGranularity == services.GranularityMonth {
+		forecast, err := h.analytics.Forecast(ctx, signals.TSMetric, defaultForecastHorizon, "")
+		if err != nil {
+			h.logger.Debug("skip forecast", "error", err)
+		} else {
+			series["forecast"] = forecast.Points
+			series["forecastMethod"] = forecast.Method
+		}
+	}
+	return series, nil
+}
+
+func (h *SSEHandlers) HandleTimeSeries(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
+	sse := datastar.NewSSE(w, r)
+
+	series, err := h.timeSeriesSignal(r.Context(), signals)
+	if err != nil {
+		h.logger.Warn("build time series", "error", err)
+		sse.PatchElements(`<div id="monthly-content">⚠️ ` + template.HTMLEscapeString(err.Error()) + `</div>`)
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]any{
+		"timeseriesData": series,
+	})
+	if err != nil {
+		h.logger.Error("marshal time series data", "error", err)
+		return
+	}
+	sse.PatchSignals(jsonData)
+
+	sse.PatchElements(`<div id="monthly-content">✅ Sales time series loaded</div>`)
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// errorMessage extracts the client-facing message of an error for inline
+// display in a patched element.
+func errorMessage(err error) string {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr.Message
+	}
+	return err.Error()
+}
+
+// HandleCompare patches every widget with the current and comparison ranges
+// side by side: the country table gains delta columns and the chart signals
+// carry both series.
+func (h *SSEHandlers) HandleCompare(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
+	sse := datastar.NewSSE(w, r)
+
+	h.patchComparison(r.Context(), sse, signals)
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// patchComparison compares the range in the signals with its comparison
+// range, both narrowed by the filters, and patches the country table, the
+// comparison charts and the status line.
+func (h *SSEHandlers) patchComparison(ctx context.Context, sse *datastar.ServerSentEventGenerator, signals dashboardSignals) {
+	current, previous, err := parseComparison(signals.RangeFrom, signals.RangeTo, signals.CompareFrom, signals.CompareTo)
+	if err != nil {
+		sse.PatchElements(`<div id="compare-status" class="compare-status error">⚠️ ` + template.HTMLEscapeString(errorMessage(err)) + `</div>`)
+		return
+	}
+	for _, q := range []*services.Query{&current, &previous} {
+		q.Country = strings.TrimSpace(signals.FilterCountry)
+		q.Region = strings.TrimSpace(signals.FilterRegion)
+		q.Category = strings.TrimSpace(signals.FilterCategory)
+	}
+
+	comparison, err := h.analytics.Compare(ctx, current, previous, maxTableRows)
+	if err != nil {
+		h.logger.Error("compare ranges", "error", err)
+		sse.PatchElements(`<div id="compare-status" class="compare-status error">⚠️ Comparison failed</div>`)
+		return
+	}
+
+	var buf strings.Builder
+	if err := comparisonTableTemplate.Execute(&buf, comparison); err != nil {
+		h.logger.Error("render comparison table", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	// The country table travels as HTML, so the signal carries only the charts
+	jsonData, err := json.Marshal(map[string]any{
+		"comparisonData": map[string]any{
+			"current":       comparison.Current,
+			"previous":      comparison.Previous,
+			"top_products":  comparison.TopProducts[:min(maxProducts, len(comparison.TopProducts))],
+			"monthly_sales": comparison.MonthlySales,
+			"top_regions":   comparison.TopRegions[:min(maxRegions, len(comparison.TopRegions))],
+		},
+	})
+	if err != nil {
+		h.logger.Error("marshal comparison data", "error", err)
+		return
+	}
+	sse.PatchSignals(jsonData)
+
+	status := fmt.Sprintf("✅ Comparing %s – %s with %s – %s",
+		comparison.Current.From, comparison.Current.To, comparison.Previous.From, comparison.Previous.To)
+	if filters := strings.Join(slices.DeleteFunc([]string{current.Country, current.Region, current.Category}, func(f string) bool { return f == "" }), ", "); filters != "" {
+		status += " for " + filters
+	}
+	sse.PatchElements(`<div id="compare-status" class="compare-status">` + template.HTMLEscapeString(status) + `</div>`)
+}
+
 func (h *SSEHandlers) HandleTopRegions(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
 	sse := datastar.NewSSE(w, r)
 
-	data := h.analytics.TopRegions(maxRegions)
+	opts := h.topN(r, signals.RegionsLimit, signals.RegionsRankBy, defaultRegionRanking)
+	data, err := h.analytics.RankedRegions(r.Context(), opts.RankBy, opts.Limit)
+	if err != nil {
+		h.logger.Error("rank regions", "error", err)
+		sse.PatchElements(`<div id="regions-content">⚠️ Regions could not be ranked</div>`)
+		return
+	}
 	jsonData, err := json.Marshal(map[string]any{
-		"regionsData": data,
+		"regionsData":   data,
+		"regionsRankBy": opts.RankBy,
+		"regionsLimit":  opts.Limit,
 	})
 	if err != nil {
 		h.logger.Error("marshal regions data", "error", err)
@@ -143,12 +695,501 @@ func (h *SSEHandlers) HandleTopRegions(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleCohorts renders the retention heatmap for the most recent cohorts,
+// optionally restricted to one country.
+func (h *SSEHandlers) HandleCohorts(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
+	sse := datastar.NewSSE(w, r)
+
+	cohorts, err := h.analytics.Cohorts(r.Context(), signals.CohortCountry)
+	if err != nil {
+		h.logger.Error("compute cohorts", "error", err)
+		sse.PatchElements(`<div id="cohorts-content">⚠️ Cohort analysis failed</div>`)
+		return
+	}
+	cohorts = truncateCohorts(cohorts[max(0, len(cohorts)-maxCohortRows):], maxCohortMonths)
+
+	width := 0
+	for _, c := range cohorts {
+		width = max(width, len(c.Retention))
+	}
+	offsets := make([]int, width)
+	for i := range offsets {
+		offsets[i] = i
+	}
+
+	var buf strings.Builder
+	if err := cohortHeatmapTemplate.Execute(&buf, map[string]any{
+		"Cohorts": cohorts,
+		"Offsets": offsets,
+	}); err != nil {
+		h.logger.Error("render cohorts", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HandleDrilldown renders the drill-down panel for the path in the drillPath
+// signal: a breadcrumb back up the hierarchy, the totals at that level and
+// the biggest children, which can be clicked to go one level deeper.
+func (h *SSEHandlers) HandleDrilldown(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
+	sse := datastar.NewSSE(w, r)
+
+	path, err := services.ParseDrillPath(signals.DrillPath)
+	if err != nil {
+		sse.PatchElements(fmt.Sprintf(`<div id="drilldown-content">⚠️ %s</div>`, template.HTMLEscapeString(err.Error())))
+		return
+	}
+	metric := signals.TableMetric
+	node, err := h.analytics.Drilldown(r.Context(), path, metric)
+	if stderrors.Is(err, services.ErrUnknownMetric) {
+		h.logger.Warn("unknown table metric", "metric", metric)
+		metric = ""
+		node, err = h.analytics.Drilldown(r.Context(), path, metric)
+	}
+	if err != nil {
+		h.logger.Warn("drill down", "path", signals.DrillPath, "error", err)
+		sse.PatchElements(fmt.Sprintf(`<div id="drilldown-content">⚠️ %s</div>`, template.HTMLEscapeString(errorMessage(err))))
+		return
+	}
+
+	type crumb struct{ Name, Path string }
+	crumbs := []crumb{{Name: "All countries"}}
+	for i, name := range path {
+		// A product segment is its ID; the node carries its name.
+		if i == len(path)-1 {
+			name = node.Name
+		}
+		crumbs = append(crumbs, crumb{Name: name, Path: services.DrillPath(path[:i+1]...)})
+	}
+
+	var buf strings.Builder
+	if err := drilldownTemplate.Execute(&buf, map[string]any{
+		"Crumbs":     crumbs,
+		"Node":       node,
+		"Metric":     metric,
+		"ChildLabel": drillLevelLabels[node.ChildLevel],
+		"Children":   node.Children[:min(len(node.Children), maxDrillRows)],
+		"More":       len(node.Children) - maxDrillRows,
+		// Products are the leaves, so only countries and regions expand.
+		"Deeper": node.ChildLevel != services.DimensionProduct,
+	}); err != nil {
+		h.logger.Error("render drill-down", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HandleDistribution sends the histogram of the selected field to the chart
+// and renders its quantiles.
+func (h *SSEHandlers) HandleDistribution(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
+	sse := datastar.NewSSE(w, r)
+
+	dist, err := h.analytics.Distribution(signals.DistMetric, signals.DistDimension, strings.TrimSpace(signals.DistValue), services.DefaultHistogramBins)
+	if err != nil {
+		msg := errorMessage(err)
+		if stderrors.Is(err, services.ErrUnknownValue) {
+			msg = fmt.Sprintf("No sales for %s %q", signals.DistDimension, signals.DistValue)
+		}
+		sse.PatchElements(fmt.Sprintf(`<div id="distribution-content">⚠️ %s</div>`, template.HTMLEscapeString(msg)))
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]any{
+		"distributionData": dist,
+	})
+	if err != nil {
+		h.logger.Error("marshal distribution data", "error", err)
+		return
+	}
+	sse.PatchSignals(jsonData)
+
+	var buf strings.Builder
+	if err := distributionTemplate.Execute(&buf, dist); err != nil {
+		h.logger.Error("render distribution", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HandlePareto sends the Pareto curve to the chart and renders the ABC class
+// sizes with their counts per category.
+func (h *SSEHandlers) HandlePareto(w http.ResponseWriter, r *http.Request) {
+	sse := datastar.NewSSE(w, r)
+
+	summary, err := h.analytics.ABCSummary(r.Context())
+	if err != nil {
+		h.logger.Error("classify products", "error", err)
+		sse.PatchElements(`<div id="pareto-content">⚠️ ABC classification failed</div>`)
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]any{
+		"paretoData": summary.Curve,
+	})
+	if err != nil {
+		h.logger.Error("marshal pareto data", "error", err)
+		return
+	}
+	sse.PatchSignals(jsonData)
+
+	var buf strings.Builder
+	if err := abcTemplate.Execute(&buf, summary); err != nil {
+		h.logger.Error("render ABC summary", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HandleInventory renders the products that are out of stock or will run out
+// within the low-stock horizon at their current sales velocity.
+func (h *SSEHandlers) HandleInventory(w http.ResponseWriter, r *http.Request) {
+	sse := datastar.NewSSE(w, r)
+
+	inventory, err := h.analytics.Inventory(r.Context())
+	if err != nil {
+		h.logger.Error("compute inventory", "error", err)
+		sse.PatchElements(`<div id="inventory-content">⚠️ Inventory analysis failed</div>`)
+		return
+	}
+
+	atRisk := make([]models.InventoryItem, 0)
+	for _, item := range inventory {
+		if item.Status == services.StockStatusOut || item.Status == services.StockStatusLow {
+			atRisk = append(atRisk, item)
+		}
+	}
+
+	var buf strings.Builder
+	if err := inventoryTemplate.Execute(&buf, map[string]any{
+		"Items": atRisk[:min(len(atRisk), maxInventoryRows)],
+		"More":  len(atRisk) - maxInventoryRows,
+	}); err != nil {
+		h.logger.Error("render inventory", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HandleMetrics renders the custom metrics card and adds the derived metrics
+// to the time series metric picker.
+func (h *SSEHandlers) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	sse := datastar.NewSSE(w, r)
+
+	derived := h.analytics.DerivedMetricNames()
+	type row struct {
+		Name    string
+		Value   any
+		Derived bool
+	}
+	rows := make([]row, 0)
+	for _, name := range h.analytics.MetricNames() {
+		value, err := h.analytics.Metric(r.Context(), name)
+		if err != nil {
+			h.logger.Error("compute metric", "metric", name, "error", err)
+			sse.PatchElements(`<div id="metrics-content">⚠️ Metric ` + template.HTMLEscapeString(name) + ` failed</div>`)
+			return
+		}
+		rows = append(rows, row{Name: name, Value: value, Derived: slices.Contains(derived, name)})
+	}
+
+	var buf strings.Builder
+	if err := metricsTemplate.Execute(&buf, map[string]any{"Metrics": rows}); err != nil {
+		h.logger.Error("render metrics", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if len(derived) > 0 {
+		for _, id := range metricSelects {
+			buf.Reset()
+			if err := metricOptionsTemplate.Execute(&buf, map[string]any{"ID": id, "Names": derived}); err != nil {
+				h.logger.Error("render metric options", "error", err)
+				return
+			}
+			sse.PatchElements(buf.String())
+		}
+	}
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HandleCountryMap renders revenue by country as an SVG choropleth. Each
+// country is filled from its outline, shaded by revenue, with countries too
+// small to outline drawn as dots at their centroid. Clicking one filters the
+// period comparison by it and opens its drill-down in the country card.
+// Country names that cannot be mapped to an ISO-3166 country are listed
+// under the map.
+func (h *SSEHandlers) HandleCountryMap(w http.ResponseWriter, r *http.Request) {
+	sse := datastar.NewSSE(w, r)
+
+	m := h.analytics.CountryMap()
+	if len(m.Unmatched) > 0 {
+		names := make([]string, len(m.Unmatched))
+		for i, u := range m.Unmatched {
+			names[i] = u.Name
+		}
+		h.logger.Debug("country names without an ISO-3166 match", "names", names)
+	}
+
+	type shape struct {
+		// D is the SVG path of the outline; countries without one are a
+		// dot at X, Y of radius R.
+		D       string
+		X, Y, R float64
+		Shade   float64
+		// Name is the value the country filter matches and Path its
+		// drill-down path, both empty for countries without sales.
+		Name         string
+		Path         string
+		Label        string
+		Revenue      float64
+		Transactions int
+	}
+	project := func(lat, lon float64) (float64, float64) {
+		return (lon + 180) / 360 * mapWidth, (90 - lat) / 180 * mapHeight
+	}
+
+	sold := make(map[string]models.MapCountry, len(m.Countries))
+	maxRevenue := 0.0
+	for _, c := range m.Countries {
+		sold[c.Code] = c
+		maxRevenue = max(maxRevenue, c.Revenue)
+	}
+	// Dots go on top of the outlines so small countries stay clickable.
+	shapes, dots := make([]shape, 0), make([]shape, 0)
+	for _, c := range geo.Countries() {
+		s := shape{Label: c.Name}
+		if rings := geo.Outline(c.Code); len(rings) > 0 {
+			var d strings.Builder
+			for _, ring := range rings {
+				for i, p := range ring {
+					x, y := project(p.Lat, p.Lon)
+					cmd := 'L'
+					if i == 0 {
+						cmd = 'M'
+					}
+					fmt.Fprintf(&d, "%c%.1f %.1f", cmd, x, y)
+				}
+				d.WriteByte('Z')
+			}
+			s.D = d.String()
+		} else {
+			s.X, s.Y = project(c.Lat, c.Lon)
+			s.R = mapDotRadius
+		}
+		if mc, ok := sold[c.Code]; ok {
+			share := 0.0
+			if maxRevenue > 0 {
+				share = math.Sqrt(max(mc.Revenue, 0) / maxRevenue)
+			}
+			s.Shade = 0.2 + 0.8*share
+			s.Name = mc.Name
+			s.Path = drillPath(mc.Name)
+			s.Label = strings.Join(mc.Names, " / ") + " (" + c.Code + ")"
+			s.Revenue = mc.Revenue
+			s.Transactions = mc.Transactions
+		}
+		if s.D != "" {
+			shapes = append(shapes, s)
+		} else {
+			dots = append(dots, s)
+		}
+	}
+	shapes = append(shapes, dots...)
+
+	var buf strings.Builder
+	if err := countryMapTemplate.Execute(&buf, map[string]any{
+		"Width":     mapWidth,
+		"Height":    mapHeight,
+		"Equator":   mapHeight / 2,
+		"Shapes":    shapes,
+		"Unmatched": m.Unmatched[:min(len(m.Unmatched), maxUnmatchedNames)],
+		"More":      len(m.Unmatched) - maxUnmatchedNames,
+	}); err != nil {
+		h.logger.Error("render country map", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+var marginDimensionLabels = map[string]string{
+	services.DimensionCountry:     "Country",
+	services.DimensionCategory:    "Category",
+	services.DimensionBrand:       "Brand",
+	services.DimensionSubcategory: "Subcategory",
+}
+
+// HandleMargins renders the gross margin by the dimension in the
+// marginDimension signal and lists the products missing from the catalog.
+func (h *SSEHandlers) HandleMargins(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
+	sse := datastar.NewSSE(w, r)
+
+	margins, err := h.analytics.Margins(r.Context(), signals.MarginDimension)
+	if err != nil {
+		h.logger.Warn("compute margins", "dimension", signals.MarginDimension, "error", err)
+		sse.PatchElements(fmt.Sprintf(`<div id="margins-content">⚠️ %s</div>`, template.HTMLEscapeString(errorMessage(err))))
+		return
+	}
+
+	costed := false
+	for _, m := range margins {
+		costed = costed || m.CostedRevenue != 0
+	}
+	missing := h.analytics.MissingCatalog()
+	missingRevenue := 0.0
+	for _, m := range missing {
+		missingRevenue += m.Revenue
+	}
+
+	var buf strings.Builder
+	if err := marginsTemplate.Execute(&buf, map[string]any{
+		"Label":          marginDimensionLabels[signals.MarginDimension],
+		"Costed":         costed,
+		"Margins":        margins[:min(len(margins), maxMarginRows)],
+		"More":           len(margins) - maxMarginRows,
+		"Missing":        missing[:min(len(missing), maxMissingRows)],
+		"MissingCount":   len(missing),
+		"MissingRevenue": missingRevenue,
+	}); err != nil {
+		h.logger.Error("render margins", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HandleLaunches renders the leaderboard of recently added products by their
+// first-30-day revenue.
+func (h *SSEHandlers) HandleLaunches(w http.ResponseWriter, r *http.Request) {
+	sse := datastar.NewSSE(w, r)
+
+	launches, err := h.analytics.RecentLaunches(r.Context(), defaultLaunchDays)
+	if err != nil {
+		h.logger.Error("compute launches", "error", err)
+		sse.PatchElements(`<div id="launches-content">⚠️ Launch analysis failed</div>`)
+		return
+	}
+
+	var buf strings.Builder
+	if err := launchesTemplate.Execute(&buf, launches[:min(len(launches), maxLaunchRows)]); err != nil {
+		h.logger.Error("render launches", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HandleAnomalies keeps the stream open and re-renders the anomaly card each
+// time the data is reloaded, highlighting anomalies that were not in the
+// previous render. The stream ends when the client disconnects or the server
+// shuts down.
+func (h *SSEHandlers) HandleAnomalies(w http.ResponseWriter, r *http.Request) {
+	sse := datastar.NewSSE(w, r)
+
+	// The stream outlives the server's write timeout by design.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Debug("clear write deadline for anomaly stream", "error", err)
+	}
+
+	var seen map[string]bool
+	for {
+		// Take the channel before reading the data so a reload in between
+		// is not missed.
+		updates := h.analytics.Updates()
+
+		anomalies, err := h.analytics.Anomalies(r.Context())
+		if err != nil {
+			if r.Context().Err() == nil {
+				h.logger.Error("detect anomalies", "error", err)
+			}
+			return
+		}
+
+		fresh := make(map[string]bool)
+		current := make(map[string]bool, len(anomalies))
+		for _, a := range anomalies {
+			key := anomalyKey(a)
+			current[key] = true
+			if seen != nil && !seen[key] {
+				fresh[key] = true
+			}
+		}
+		seen = current
+
+		var buf strings.Builder
+		if err := anomaliesTemplate.Execute(&buf, map[string]any{
+			"Anomalies": anomalies,
+			"Fresh":     fresh,
+			"New":       len(fresh),
+		}); err != nil {
+			h.logger.Error("render anomalies", "error", err)
+			return
+		}
+		sse.PatchElements(buf.String())
+
+		jsonData, err := json.Marshal(map[string]any{
+			"anomalyCount": len(anomalies),
+		})
+		if err != nil {
+			h.logger.Error("marshal anomaly count", "error", err)
+			return
+		}
+		sse.PatchSignals(jsonData)
+
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-updates:
+		}
+	}
+}
+
 func (h *SSEHandlers) HandleRefreshAll(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
 	sse := datastar.NewSSE(w, r)
 
 	// Get fresh data for country revenue
-	countryData := h.analytics.CountryRevenue()
-	html, err := h.renderCountryTable(countryData)
+	html, err := h.countryTable(r.Context(), signals)
 	if err != nil {
 		h.logger.Error("render country table", "error", err)
 		return
@@ -156,16 +1197,29 @@ func (h *SSEHandlers) HandleRefreshAll(w http.ResponseWriter, r *http.Request) {
 	sse.PatchElements(html)
 
 	// Get fresh data for products, monthly sales, and regions
-	productsData := h.analytics.TopProducts(maxProducts)
-	monthlyData := h.analytics.MonthlySales()
-	regionsData := h.analytics.TopRegions(maxRegions)
+	signalData := map[string]any{
+		"monthlyData": h.analytics.MonthlySales(),
+	}
+	products := h.topN(r, signals.ProductsLimit, signals.ProductsRankBy, defaultProductRanking)
+	if data, err := h.analytics.RankedProducts(r.Context(), products.RankBy, products.Limit); err != nil {
+		h.logger.Warn("rank products", "error", err)
+	} else {
+		signalData["productsData"] = data
+	}
+	regions := h.topN(r, signals.RegionsLimit, signals.RegionsRankBy, defaultRegionRanking)
+	if data, err := h.analytics.RankedRegions(r.Context(), regions.RankBy, regions.Limit); err != nil {
+		h.logger.Warn("rank regions", "error", err)
+	} else {
+		signalData["regionsData"] = data
+	}
+	if series, err := h.timeSeriesSignal(r.Context(), signals); err != nil {
+		h.logger.Warn("build time series", "error", err)
+	} else {
+		signalData["timeseriesData"] = series
+	}
 
 	// Send all signals in one call
-	allSignals, err := json.Marshal(map[string]any{
-		"productsData": productsData,
-		"monthlyData":  monthlyData,
-		"regionsData":  regionsData,
-	})
+	allSignals, err := json.Marshal(signalData)
 	if err != nil {
 		h.logger.Error("marshal all signals data", "error", err)
 		return
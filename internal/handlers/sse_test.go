@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services"
 )
 
 func TestNewSSEHandlers(t *testing.T) {
@@ -52,7 +57,7 @@ func TestSSEHandlers_renderCountryTable(t *testing.T) {
 		},
 	}
 
-	html, err := handlers.renderCountryTable(testData)
+	html, err := handlers.renderCountryTable(context.Background(), testData, "")
 	if err != nil {
 		t.Fatalf("renderCountryTable() failed: %v", err)
 	}
@@ -82,6 +87,37 @@ func TestSSEHandlers_renderCountryTable(t *testing.T) {
 	}
 }
 
+func TestSSEHandlers_renderCountryTable_GrowthBadges(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	html, err := handlers.renderCountryTable(context.Background(), analytics.CountryRevenue(), "")
+	if err != nil {
+		t.Fatalf("renderCountryTable() failed: %v", err)
+	}
+
+	// Canada is the only country with sales in the latest month and has no
+	// history to compare against
+	if !strings.Contains(html, "Canada") || !strings.Contains(html, `<span class="delta-badge">MoM new</span>`) {
+		t.Errorf("expected growth badge next to Canada, got %s", html)
+	}
+}
+
+func TestDeltaBadge(t *testing.T) {
+	up, down := 12.34, -5.0
+
+	if got := string(deltaBadge("MoM", &up)); !strings.Contains(got, `class="delta-badge up"`) || !strings.Contains(got, "12.3%") {
+		t.Errorf("unexpected positive badge: %s", got)
+	}
+	if got := string(deltaBadge("YoY", &down)); !strings.Contains(got, `class="delta-badge down"`) || !strings.Contains(got, "5.0%") {
+		t.Errorf("unexpected negative badge: %s", got)
+	}
+	if got := string(deltaBadge("YoY", nil)); !strings.Contains(got, "YoY new") {
+		t.Errorf("unexpected badge without base: %s", got)
+	}
+}
+
 func TestSSEHandlers_renderCountryTable_LargeDataset(t *testing.T) {
 	analytics := createTestAnalytics()
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
@@ -99,7 +135,7 @@ func TestSSEHandlers_renderCountryTable_LargeDataset(t *testing.T) {
 		}
 	}
 
-	html, err := handlers.renderCountryTable(testData)
+	html, err := handlers.renderCountryTable(context.Background(), testData, "")
 	if err != nil {
 		t.Fatalf("renderCountryTable() failed: %v", err)
 	}
@@ -253,6 +289,7 @@ func TestSSEHandlers_HandleRefreshAll(t *testing.T) {
 		"productsData",
 		"monthlyData",
 		"regionsData",
+		"timeseriesData",
 	}
 
 	for _, signal := range expectedSignals {
@@ -267,6 +304,165 @@ func TestSSEHandlers_HandleRefreshAll(t *testing.T) {
 	}
 }
 
+func TestSSEHandlers_HandleTimeSeries(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	// Datastar sends the dashboard signals JSON-encoded in the query string
+	req := httptest.NewRequest(http.MethodGet, `/sse/timeseries?datastar={"tsGranularity":"quarter","tsMetric":"units"}`, nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleTimeSeries(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"timeseriesData", `"granularity":"quarter"`, `"metric":"units"`, `"period":"2023-Q1"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+}
+
+func TestSSEHandlers_HandleTimeSeries_Forecast(t *testing.T) {
+	analytics := services.NewAnalytics()
+	analytics.SetData([]models.Transaction{
+		{Date: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), TotalPrice: 100, Quantity: 1},
+		{Date: time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC), TotalPrice: 200, Quantity: 2},
+		{Date: time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC), TotalPrice: 300, Quantity: 3},
+	})
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/timeseries", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleTimeSeries(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{`"forecast":[`, `"forecastMethod":"linear"`, `"period":"2024-03"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("monthly series should contain %q", want)
+		}
+	}
+
+	// Other granularities are shown without a forecast
+	req = httptest.NewRequest(http.MethodGet, "/sse/timeseries?granularity=week", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleTimeSeries(w, req)
+	if strings.Contains(w.Body.String(), "forecast") {
+		t.Error("weekly series should not include a forecast")
+	}
+}
+
+func TestSSEHandlers_HandleCompare(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	signals := `{"rangeFrom":"2023-02-01","rangeTo":"2023-02-28","compareFrom":"2023-01-01","compareTo":"2023-01-31"}`
+	req := httptest.NewRequest(http.MethodGet, "/sse/compare?datastar="+url.QueryEscape(signals), nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleCompare(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"comparisonData", "<table", "Revenue 2023-02-01 – 2023-02-28", "Canada", "compare-status"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+}
+
+func TestSSEHandlers_HandleCompare_InvalidRange(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/compare", nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleCompare(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "from and to dates are required") || strings.Contains(body, "comparisonData") {
+		t.Errorf("expected inline validation message only, got %s", body)
+	}
+}
+
+func TestSSEHandlers_HandleViews(t *testing.T) {
+	views, err := services.OpenViewStore(t.TempDir() + "/views.json")
+	if err != nil {
+		t.Fatalf("OpenViewStore() error = %v", err)
+	}
+	if _, err := views.Save(models.SavedView{Name: "usa-q1", From: "2023-01-01", To: "2023-03-31", Country: "USA"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(createTestAnalytics(), logger).WithViews(views)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/views?view=usa-q1", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleViews(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"view-switcher", `<option value="usa-q1" selected>`, `"filterCountry":"USA"`, "comparisonData", "for USA"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+	if strings.Contains(body, "Canada") {
+		t.Error("the comparison should be filtered to the USA")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/sse/views?view=gone", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleViews(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "no saved view named") || strings.Contains(body, "comparisonData") {
+		t.Errorf("expected an inline message for an unknown view, got %s", body)
+	}
+}
+
+func TestDashboardStateFromQuery(t *testing.T) {
+	state := DashboardStateFromQuery(url.Values{})
+	if state.TSGranularity != services.GranularityMonth || state.ProductsLimit != "20" || state.RegionsRankBy != services.RankByRevenue {
+		t.Errorf("a link without parameters should open the default dashboard, got %+v", state)
+	}
+
+	params, _ := url.ParseQuery("from=2023-01-01&to=2023-03-31&filter_country=USA&granularity=week&path=USA%2FCalifornia&products_limit=50&dist_dimension=category&dist_value=Toys")
+	state = DashboardStateFromQuery(params)
+	if state.RangeFrom != "2023-01-01" || state.RangeTo != "2023-03-31" || state.FilterCountry != "USA" {
+		t.Errorf("range and filters not read: %+v", state)
+	}
+	if state.TSGranularity != "week" || state.TSMetric != services.MetricRevenue {
+		t.Errorf("time series options = %s/%s", state.TSGranularity, state.TSMetric)
+	}
+	if state.DrillPath != "USA/California" || state.ProductsLimit != "50" || state.DistValue != "Toys" {
+		t.Errorf("drill-down and chart options not read: %+v", state)
+	}
+}
+
+func TestSSEHandlers_LinkParameters(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	// The parameters of a dashboard link drive the SSE endpoints too, and
+	// take precedence over the signals.
+	signals := url.QueryEscape(`{"rangeFrom":"2020-01-01","rangeTo":"2020-12-31"}`)
+	req := httptest.NewRequest(http.MethodGet, "/sse/compare?from=2023-01-01&to=2023-03-31&filter_country=Canada&datastar="+signals, nil)
+	w := httptest.NewRecorder()
+	handlers.HandleCompare(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"comparisonData", "Comparing 2023-01-01 – 2023-03-31", "for Canada"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+	if strings.Contains(body, "USA") {
+		t.Error("the comparison should be filtered to Canada")
+	}
+}
+
 // Test template data structure
 func TestTemplateData(t *testing.T) {
 	data := templateData{
@@ -302,6 +498,7 @@ func TestSSEHandlers_HeaderConsistency(t *testing.T) {
 		{"top-products", handlers.HandleTopProducts},
 		{"monthly-sales", handlers.HandleMonthlySales},
 		{"top-regions", handlers.HandleTopRegions},
+		{"timeseries", handlers.HandleTimeSeries},
 		{"refresh-all", handlers.HandleRefreshAll},
 	}
 
@@ -455,7 +652,7 @@ func TestSSEHandlers_TemplateEdgeCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			html, err := handlers.renderCountryTable(tt.data)
+			html, err := handlers.renderCountryTable(context.Background(), tt.data, "")
 
 			// Should not error (template should handle edge cases gracefully)
 			if err != nil {
@@ -469,3 +666,341 @@ func TestSSEHandlers_TemplateEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestSSEHandlers_HandleCohorts(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, `/sse/cohorts?datastar={"cohortCountry":"Canada"}`, nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleCohorts(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"cohorts-content", "cohort-heatmap", "2023-02", "100%"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+	if strings.Contains(body, "2023-01") {
+		t.Error("the January cohort bought in the USA and should be filtered out")
+	}
+}
+
+func TestSSEHandlers_HandleInventory(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/inventory", nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleInventory(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"inventory-content", "No products at risk"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+}
+
+func TestSSEHandlers_HandleLaunches(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/launches", nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleLaunches(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"launches-content", "Laptop", "Mouse", "2023-01-01", "partial"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+}
+
+func TestSSEHandlers_HandleMetrics(t *testing.T) {
+	// Derived metrics are evaluated on demand, so they cover data loaded
+	// before they were registered.
+	analytics := createTestAnalytics()
+	analytics.RegisterAggregator(services.WeekendShare())
+	metric, err := services.ParseDerivedMetric("big_orders", "count() where total_price > 100")
+	if err != nil {
+		t.Fatalf("ParseDerivedMetric() error = %v", err)
+	}
+	analytics.RegisterDerivedMetric(metric)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleMetrics(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"metrics-content", "weekend_share", "big_orders", "ts-custom-metrics", "products-custom-metrics", "table-custom-metrics", `<option value="big_orders">`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, `/sse/country-revenue?datastar={"tableMetric":"big_orders"}`, nil)
+	w = httptest.NewRecorder()
+	handlers.HandleCountryRevenue(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "<th>big_orders</th>") {
+		t.Errorf("country table should have a big_orders column: %s", body)
+	}
+}
+
+func TestSSEHandlers_HandleCountryMap(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/country-map", nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleCountryMap(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"country-map-content", "<svg", "<path d=\"M", "USA (US)", "Canada (CA)", "$drillPath = ", "$drillOpen = true", "@get('/sse/drilldown')", "France: no sales", "Monaco: no sales</title><circle"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+	if strings.Contains(body, "$filterCountry") {
+		t.Error("clicking a country drills into it rather than filtering")
+	}
+	if strings.Contains(body, "Not on the map") {
+		t.Error("every test country should be mapped")
+	}
+
+	analytics.SetData([]models.Transaction{
+		{Date: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Country: "Atlantis", ProductName: "Trident", TotalPrice: 12},
+	})
+	w = httptest.NewRecorder()
+	handlers.HandleCountryMap(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "Not on the map: Atlantis ($12.00)") {
+		t.Errorf("unmatched countries should be reported, got %s", body)
+	}
+}
+
+func TestSSEHandlers_HandleMargins(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/margins", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleMargins(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "margins-content") || !strings.Contains(body, "CATALOG_FILE") {
+		t.Errorf("uncosted data should point to the catalog setting, got %s", body)
+	}
+
+	day := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	analytics.SetData([]models.Transaction{
+		{Date: day, Country: "USA", Category: "Electronics", ProductName: "Laptop", Quantity: 1, TotalPrice: 1000, Cataloged: true, UnitCost: 750, Brand: "Acme"},
+		{Date: day, Country: "USA", Category: "Electronics", ProductName: "Cable", Quantity: 1, TotalPrice: 20},
+	})
+	req = httptest.NewRequest(http.MethodGet, `/sse/margins?datastar={"marginDimension":"brand"}`, nil)
+	w = httptest.NewRecorder()
+	handlers.HandleMargins(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"<th>Brand</th>", "Acme", "$250.00", "25.0%", "Not in catalog"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/sse/margins?margin_dimension=month", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleMargins(w, req)
+	if !strings.Contains(w.Body.String(), "unknown dimension") {
+		t.Errorf("an unsupported dimension should be reported, got %s", w.Body.String())
+	}
+}
+
+func TestSSEHandlers_HandleDrilldown(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, `/sse/drilldown?datastar={"drillPath":"USA"}`, nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleDrilldown(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"drilldown-content", "All countries", "California", "$999.99", "drillable"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/sse/drilldown?path=USA/California/P001", nil)
+	w = httptest.NewRecorder()
+	handlers.HandleDrilldown(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "Laptop") || strings.Contains(body, "<table") {
+		t.Errorf("a product should render its totals without a breakdown, got %s", body)
+	}
+}
+
+func TestSSEHandlers_HandleDistribution(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, `/sse/distribution?datastar={"distMetric":"quantity","distDimension":"country","distValue":"Canada"}`, nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandleDistribution(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"distributionData", `"metric":"quantity"`, `"value":"Canada"`, "distribution-content", "p99"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, `/sse/distribution?datastar={"distDimension":"country","distValue":"France"}`, nil)
+	w = httptest.NewRecorder()
+	handlers.HandleDistribution(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "No sales for country") || strings.Contains(body, "distributionData") {
+		t.Errorf("an unknown country should render an error, got %s", body)
+	}
+}
+
+func TestSSEHandlers_HandleTopProducts_RankBy(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, `/sse/top-products?datastar={"productsRankBy":"units","productsLimit":"1"}`, nil)
+	w := httptest.NewRecorder()
+	handlers.HandleTopProducts(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"productsData", "Mouse", `"productsRankBy":"units"`, `"productsLimit":1`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+	if strings.Contains(body, "Laptop") {
+		t.Error("limit 1 should leave out the Laptop")
+	}
+
+	// Query parameters win over signals; invalid values fall back to the
+	// defaults instead of failing the chart.
+	req = httptest.NewRequest(http.MethodGet, `/sse/top-regions?rank_by=margin&datastar={"regionsRankBy":"units"}`, nil)
+	w = httptest.NewRecorder()
+	handlers.HandleTopRegions(w, req)
+	if body := w.Body.String(); !strings.Contains(body, `"regionsRankBy":"revenue"`) || !strings.Contains(body, "California") {
+		t.Errorf("an invalid rank_by should fall back to revenue, got %s", body)
+	}
+}
+
+func TestSSEHandlers_HandlePareto(t *testing.T) {
+	analytics := createTestAnalytics()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/pareto", nil)
+	w := httptest.NewRecorder()
+
+	handlers.HandlePareto(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"paretoData", "cumulative_share", "pareto-content", "Electronics", "abc-A"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q", want)
+		}
+	}
+}
+
+// streamRecorder guards the recorded body so a streaming handler can be
+// observed while it is still writing.
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+	mu sync.Mutex
+}
+
+func (s *streamRecorder) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ResponseRecorder.Write(b)
+}
+
+func (s *streamRecorder) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ResponseRecorder.Flush()
+}
+
+func (s *streamRecorder) body() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Body.String()
+}
+
+// waitFor polls the stream until it contains want.
+func (s *streamRecorder) waitFor(t *testing.T, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(s.body(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("stream never contained %q", want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// dailySales returns ten weeks of steady daily USA sales ending 2023-03-12,
+// optionally stopping two days early.
+func dailySales(stopEarly bool) []models.Transaction {
+	var txs []models.Transaction
+	end := time.Date(2023, 3, 12, 0, 0, 0, 0, time.UTC)
+	for d := end.AddDate(0, 0, -69); !d.After(end); d = d.AddDate(0, 0, 1) {
+		country := "USA"
+		if stopEarly && d.After(end.AddDate(0, 0, -2)) {
+			country = "Canada"
+		}
+		txs = append(txs, models.Transaction{Date: d, Country: country, Category: "Electronics", TotalPrice: 100, Quantity: 1})
+	}
+	return txs
+}
+
+func TestSSEHandlers_HandleAnomalies_StreamsNewAnomalies(t *testing.T) {
+	analytics := services.NewAnalytics()
+	analytics.SetData(dailySales(false))
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := NewSSEHandlers(analytics, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/sse/anomalies", nil).WithContext(ctx)
+	w := &streamRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	done := make(chan struct{})
+	go func() {
+		handlers.HandleAnomalies(w, req)
+		close(done)
+	}()
+
+	w.waitFor(t, "anomalyCount")
+	analytics.SetData(dailySales(true))
+	w.waitFor(t, "anomaly-new")
+	cancel()
+	<-done
+
+	body := w.body()
+	for _, want := range []string{"No anomalies in the most recent week", "new anomalies since the last data reload", "anomaly-drop anomaly-new", "USA", "anomalyCount"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stream should contain %q", want)
+		}
+	}
+}
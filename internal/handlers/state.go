@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services"
+)
+
+// dashboardParams names the query parameters of a dashboard link, one per
+// field of models.DashboardState. The dashboard keeps its address bar in
+// sync with the same names, and the SSE endpoints accept them too.
+var dashboardParams = []struct {
+	name  string
+	field func(s *models.DashboardState) *string
+}{
+	{"view", func(s *models.DashboardState) *string { return &s.ViewName }},
+	{"from", func(s *models.DashboardState) *string { return &s.RangeFrom }},
+	{"to", func(s *models.DashboardState) *string { return &s.RangeTo }},
+	{"compare_from", func(s *models.DashboardState) *string { return &s.CompareFrom }},
+	{"compare_to", func(s *models.DashboardState) *string { return &s.CompareTo }},
+	{"filter_country", func(s *models.DashboardState) *string { return &s.FilterCountry }},
+	{"filter_region", func(s *models.DashboardState) *string { return &s.FilterRegion }},
+	{"filter_category", func(s *models.DashboardState) *string { return &s.FilterCategory }},
+	{"granularity", func(s *models.DashboardState) *string { return &s.TSGranularity }},
+	{"metric", func(s *models.DashboardState) *string { return &s.TSMetric }},
+	{"country", func(s *models.DashboardState) *string { return &s.CohortCountry }},
+	{"path", func(s *models.DashboardState) *string { return &s.DrillPath }},
+	{"dist_metric", func(s *models.DashboardState) *string { return &s.DistMetric }},
+	{"dist_dimension", func(s *models.DashboardState) *string { return &s.DistDimension }},
+	{"dist_value", func(s *models.DashboardState) *string { return &s.DistValue }},
+	{"margin_dimension", func(s *models.DashboardState) *string { return &s.MarginDimension }},
+	{"products_rank_by", func(s *models.DashboardState) *string { return &s.ProductsRankBy }},
+	{"products_limit", func(s *models.DashboardState) *string { return (*string)(&s.ProductsLimit) }},
+	{"regions_rank_by", func(s *models.DashboardState) *string { return &s.RegionsRankBy }},
+	{"regions_limit", func(s *models.DashboardState) *string { return (*string)(&s.RegionsLimit) }},
+	{"table_metric", func(s *models.DashboardState) *string { return &s.TableMetric }},
+}
+
+// DashboardStateFromQuery reads the state of a dashboard link. Parameters
+// that are missing keep the dashboard's defaults; values are checked by the
+// endpoints that use them, as they are for signals.
+func DashboardStateFromQuery(params url.Values) models.DashboardState {
+	state := models.DashboardState{
+		TSGranularity:   services.GranularityMonth,
+		TSMetric:        services.MetricRevenue,
+		DistMetric:      services.DistributionTotalPrice,
+		MarginDimension: services.DimensionCountry,
+		ProductsRankBy:  defaultProductRanking.RankBy,
+		ProductsLimit:   json.Number(strconv.Itoa(defaultProductRanking.Limit)),
+		RegionsRankBy:   defaultRegionRanking.RankBy,
+		RegionsLimit:    json.Number(strconv.Itoa(defaultRegionRanking.Limit)),
+	}
+	applyDashboardParams(&state, params)
+	return state
+}
+
+// applyDashboardParams overrides the state with the parameters present.
+func applyDashboardParams(state *models.DashboardState, params url.Values) {
+	for _, p := range dashboardParams {
+		if v := params.Get(p.name); v != "" {
+			*p.field(state) = v
+		}
+	}
+}
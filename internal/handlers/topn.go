@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"abt-dashboard/internal/errors"
+	"abt-dashboard/internal/services"
+)
+
+// DefaultMaxTopN caps top-N limits when the handlers are not configured
+// otherwise.
+const DefaultMaxTopN = 100
+
+// topNOptions is how many items a top-N endpoint returns and what it ranks
+// them by.
+type topNOptions struct {
+	Limit  int
+	RankBy string
+}
+
+// Products have always been ranked by transaction count and regions by
+// revenue; the defaults keep those charts unchanged.
+var (
+	defaultProductRanking = topNOptions{Limit: 20, RankBy: services.RankByOrders}
+	defaultRegionRanking  = topNOptions{Limit: 30, RankBy: services.RankByRevenue}
+)
+
+// parseTopN resolves the limit and rank_by values of a top-N request, with
+// empty values falling back to defaults. rank_by may also name one of the
+// derived metrics.
+func parseTopN(limit, rankBy string, defaults topNOptions, maxTopN int, derived []string) (topNOptions, error) {
+	opts := defaults
+
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxTopN {
+			return opts, errors.Validation(fmt.Sprintf("limit must be between 1 and %d", maxTopN))
+		}
+		opts.Limit = n
+	}
+
+	if rankBy != "" {
+		metrics := append(slices.Clone(services.RankMetrics), derived...)
+		if !slices.Contains(metrics, rankBy) {
+			return opts, errors.Validation("rank_by must be one of: " + strings.Join(metrics, ", "))
+		}
+		opts.RankBy = rankBy
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"abt-dashboard/internal/errors"
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/observability"
+	"abt-dashboard/internal/services"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// maxViewBodyBytes bounds the request body of a view save.
+const maxViewBodyBytes = 16 << 10
+
+// WithViews sets the store behind the saved view endpoints.
+func (h *APIHandlers) WithViews(views *services.ViewStore) *APIHandlers {
+	h.views = views
+	return h
+}
+
+// WithViews sets the store the view switcher reads from.
+func (h *SSEHandlers) WithViews(views *services.ViewStore) *SSEHandlers {
+	h.views = views
+	return h
+}
+
+// viewError maps the errors of the view store to API errors.
+func viewError(err error, name string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, services.ErrUnknownView):
+		return errors.NotFound(fmt.Sprintf("no saved view named %q", name))
+	case stderrors.Is(err, services.ErrViewConflict):
+		return errors.Conflict(fmt.Sprintf("view %q has changed since it was read; load it again and retry", name))
+	default:
+		return errors.InternalWrap(err, "saving views failed")
+	}
+}
+
+func (h *APIHandlers) HandleViews(w http.ResponseWriter, r *http.Request) {
+	if h.views == nil {
+		errors.WriteError(w, h.logger, errors.ServiceUnavailable("saved views are not configured"), observability.GetRequestID(r.Context()))
+		return
+	}
+	errors.WriteSuccess(w, h.views.List())
+}
+
+func (h *APIHandlers) HandleView(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	if h.views == nil {
+		errors.WriteError(w, h.logger, errors.ServiceUnavailable("saved views are not configured"), requestID)
+		return
+	}
+
+	name := r.PathValue("name")
+	view, err := h.views.Get(name)
+	if err != nil {
+		errors.WriteError(w, h.logger, viewError(err, name), requestID)
+		return
+	}
+	errors.WriteSuccess(w, view)
+}
+
+// HandleSaveView creates or replaces the view in the JSON body. Replacing a
+// view needs the version it was read at; a stale version is a conflict.
+func (h *APIHandlers) HandleSaveView(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	if h.views == nil {
+		errors.WriteError(w, h.logger, errors.ServiceUnavailable("saved views are not configured"), requestID)
+		return
+	}
+
+	var view models.SavedView
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxViewBodyBytes)).Decode(&view); err != nil {
+		errors.WriteError(w, h.logger, errors.BadRequestWrap(err, "body must be a JSON view"), requestID)
+		return
+	}
+
+	saved, err := h.views.Save(view)
+	if stderrors.Is(err, services.ErrInvalidView) {
+		errors.WriteError(w, h.logger, errors.Validation(err.Error()), requestID)
+		return
+	}
+	if err != nil {
+		errors.WriteError(w, h.logger, viewError(err, view.Name), requestID)
+		return
+	}
+	errors.WriteSuccess(w, saved)
+}
+
+// HandleDeleteView removes a view. An optional version query parameter
+// guards against deleting a view someone else has changed.
+func (h *APIHandlers) HandleDeleteView(w http.ResponseWriter, r *http.Request) {
+	requestID := observability.GetRequestID(r.Context())
+	if h.views == nil {
+		errors.WriteError(w, h.logger, errors.ServiceUnavailable("saved views are not configured"), requestID)
+		return
+	}
+
+	name := r.PathValue("name")
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			errors.WriteError(w, h.logger, errors.Validation("version must be a positive integer"), requestID)
+			return
+		}
+		version = n
+	}
+
+	if err := h.views.Delete(name, version); err != nil {
+		errors.WriteError(w, h.logger, viewError(err, name), requestID)
+		return
+	}
+	errors.WriteSuccess(w, map[string]string{"deleted": name})
+}
+
+var viewSwitcherTemplate = template.Must(template.New("viewSwitcher").Parse(`
+<select id="view-switcher" data-bind-view-name data-on-change="@get('/sse/views')">
+<option value="">No saved view</option>
+{{range .Views}}<option value="{{.Name}}"{{if eq .Name $.Selected}} selected{{end}}>{{.Name}}</option>{{end}}
+</select>`))
+
+// HandleViews renders the view switcher and applies the view named by the
+// viewName signal, or the view query parameter: its range, comparison range
+// and filters replace those of the period comparison, which is rerun.
+func (h *SSEHandlers) HandleViews(w http.ResponseWriter, r *http.Request) {
+	signals := h.readSignals(r)
+	sse := datastar.NewSSE(w, r)
+
+	if h.views == nil {
+		sse.PatchElements(`<div id="view-status" class="compare-status">Saved views are not configured</div>`)
+		return
+	}
+
+	var buf strings.Builder
+	if err := viewSwitcherTemplate.Execute(&buf, map[string]any{
+		"Views":    h.views.List(),
+		"Selected": signals.ViewName,
+	}); err != nil {
+		h.logger.Error("render view switcher", "error", err)
+		return
+	}
+	sse.PatchElements(buf.String())
+
+	if signals.ViewName == "" {
+		sse.PatchElements(`<div id="view-status" class="compare-status"></div>`)
+		return
+	}
+	view, err := h.views.Get(signals.ViewName)
+	if err != nil {
+		sse.PatchElements(`<div id="view-status" class="compare-status error">⚠️ ` + template.HTMLEscapeString(errorMessage(viewError(err, signals.ViewName))) + `</div>`)
+		return
+	}
+
+	signals.RangeFrom, signals.RangeTo = view.From, view.To
+	signals.CompareFrom, signals.CompareTo = view.CompareFrom, view.CompareTo
+	signals.FilterCountry, signals.FilterRegion, signals.FilterCategory = view.Country, view.Region, view.Category
+	jsonData, err := json.Marshal(map[string]any{
+		"viewName":       view.Name,
+		"rangeFrom":      view.From,
+		"rangeTo":        view.To,
+		"compareFrom":    view.CompareFrom,
+		"compareTo":      view.CompareTo,
+		"filterCountry":  view.Country,
+		"filterRegion":   view.Region,
+		"filterCategory": view.Category,
+	})
+	if err != nil {
+		h.logger.Error("marshal view signals", "error", err)
+		return
+	}
+	sse.PatchSignals(jsonData)
+	sse.PatchElements(fmt.Sprintf(`<div id="view-status" class="compare-status">Showing view <strong>%s</strong> (version %d)</div>`,
+		template.HTMLEscapeString(view.Name), view.Version))
+
+	if view.From != "" && view.To != "" {
+		h.patchComparison(r.Context(), sse, signals)
+	}
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
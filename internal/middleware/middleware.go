@@ -269,6 +269,11 @@ func (rw *responseWriter) Flush() {
 	}
 }
 
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func generateRequestID() string {
 	bytes := make([]byte, 16)
 	rand.Read(bytes)
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Transaction struct {
 	TransactionID string
@@ -16,21 +19,43 @@ type Transaction struct {
 	TotalPrice    float64
 	Stock         int
 	AddedDate     time.Time
+	// Cataloged reports whether the product catalog knows ProductID; the
+	// catalog supplies the unit cost, brand and subcategory.
+	Cataloged   bool
+	UnitCost    float64
+	Brand       string
+	Subcategory string
 }
 
 type CountryRevenue struct {
-	Country      string  `json:"country"`
+	Country string `json:"country"`
+	// ProductID is empty for products whose rows carry no ID.
+	ProductID    string  `json:"product_id"`
 	ProductName  string  `json:"product_name"`
 	Category     string  `json:"category"`
 	TotalRevenue float64 `json:"total_revenue"`
 	Transactions int     `json:"transactions"`
+	// MetricValue is the derived metric a request asked for, nil where it
+	// is undefined.
+	MetricValue *float64 `json:"metric_value,omitempty"`
 }
 
 type ProductFrequency struct {
+	// ProductID is empty for products whose rows carry no ID.
+	ProductID     string `json:"product_id"`
 	ProductName   string `json:"product_name"`
 	Category      string `json:"category"`
 	Frequency     int    `json:"frequency"`
 	StockQuantity int    `json:"stock_quantity"`
+	// StockDate is the transaction date StockQuantity was reported on.
+	StockDate string `json:"stock_date"`
+	// Revenue, Units and Customers are filled in by the ranked top-N
+	// queries only.
+	Revenue   float64 `json:"revenue,omitempty"`
+	Units     int     `json:"units,omitempty"`
+	Customers int     `json:"customers,omitempty"`
+	// MetricValue is the derived metric the products are ranked by.
+	MetricValue *float64 `json:"metric_value,omitempty"`
 }
 
 type MonthlyData struct {
@@ -39,7 +64,486 @@ type MonthlyData struct {
 }
 
 type RegionRevenue struct {
+	// Country is the region's country, as region names are only unique
+	// within a country.
+	Country   string  `json:"country"`
 	Region    string  `json:"region"`
 	Revenue   float64 `json:"total_revenue"`
 	ItemsSold int     `json:"items_sold"`
+	// Orders and Customers are filled in by the ranked top-N queries only.
+	Orders    int `json:"orders,omitempty"`
+	Customers int `json:"customers,omitempty"`
+	// MetricValue is the derived metric the regions are ranked by.
+	MetricValue *float64 `json:"metric_value,omitempty"`
+}
+
+type DailySales struct {
+	Date    string  `json:"date"`
+	Revenue float64 `json:"revenue"`
+	Orders  int     `json:"orders"`
+	Units   int     `json:"units"`
+}
+
+type TimeSeriesPoint struct {
+	Period string  `json:"period"`
+	Start  string  `json:"start"`
+	Value  float64 `json:"value"`
+}
+
+type ForecastPoint struct {
+	Period string  `json:"period"`
+	Start  string  `json:"start"`
+	Value  float64 `json:"value"`
+	Lower  float64 `json:"lower"`
+	Upper  float64 `json:"upper"`
+}
+
+type Forecast struct {
+	Metric     string            `json:"metric"`
+	Country    string            `json:"country,omitempty"`
+	Method     string            `json:"method"`
+	Confidence float64           `json:"confidence"`
+	History    []TimeSeriesPoint `json:"history"`
+	Points     []ForecastPoint   `json:"points"`
+}
+
+type Anomaly struct {
+	Dimension string  `json:"dimension"`
+	Value     string  `json:"value"`
+	Metric    string  `json:"metric"`
+	Date      string  `json:"date"`
+	Actual    float64 `json:"actual"`
+	Expected  float64 `json:"expected"`
+	Score     float64 `json:"score"`
+	Direction string  `json:"direction"`
+}
+
+// Cohort follows the customers whose first purchase fell in one month. Index
+// k of each slice covers the k-th month after the first purchase, so index 0
+// is the cohort month itself.
+type Cohort struct {
+	Cohort    string    `json:"cohort"`
+	Customers int       `json:"customers"`
+	Active    []int     `json:"active"`
+	Retention []float64 `json:"retention"`
+	Revenue   []float64 `json:"revenue"`
+}
+
+// CustomerRFM scores a customer from 1 to 5 on recency, frequency and
+// monetary value relative to all other customers.
+type CustomerRFM struct {
+	UserID      string  `json:"user_id"`
+	Country     string  `json:"country"`
+	LastOrder   string  `json:"last_order"`
+	RecencyDays int     `json:"recency_days"`
+	Frequency   int     `json:"frequency"`
+	Monetary    float64 `json:"monetary"`
+	R           int     `json:"r"`
+	F           int     `json:"f"`
+	M           int     `json:"m"`
+	Segment     string  `json:"segment"`
+}
+
+type SegmentSummary struct {
+	Segment      string  `json:"segment"`
+	Name         string  `json:"name"`
+	Customers    int     `json:"customers"`
+	Revenue      float64 `json:"revenue"`
+	RevenueShare float64 `json:"revenue_share"`
+}
+
+type SegmentBreakdown struct {
+	Country   string           `json:"country,omitempty"`
+	Customers int              `json:"customers"`
+	Revenue   float64          `json:"revenue"`
+	Segments  []SegmentSummary `json:"segments"`
+}
+
+type CustomerSegments struct {
+	ReferenceDate string             `json:"reference_date"`
+	Overall       SegmentBreakdown   `json:"overall"`
+	Countries     []SegmentBreakdown `json:"countries"`
+}
+
+type CustomerMetrics struct {
+	Dimension string `json:"dimension"`
+	Value     string `json:"value"`
+	// Country is the country of a region, as region names are only unique
+	// within a country; it is empty for other dimensions.
+	Country             string  `json:"country,omitempty"`
+	Revenue             float64 `json:"revenue"`
+	Transactions        int     `json:"transactions"`
+	Units               int     `json:"units"`
+	Customers           int     `json:"customers"`
+	RepeatCustomers     int     `json:"repeat_customers"`
+	AverageOrderValue   float64 `json:"average_order_value"`
+	UnitsPerTransaction float64 `json:"units_per_transaction"`
+	RepeatPurchaseRate  float64 `json:"repeat_purchase_rate"`
+}
+
+type InventoryItem struct {
+	// ProductID is empty for products whose rows carry no ID.
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	Category    string  `json:"category"`
+	Stock       int     `json:"stock"`
+	StockDate   string  `json:"stock_date"`
+	UnitsSold   int     `json:"units_sold"`
+	Velocity    float64 `json:"velocity"`
+	// DaysOfCover is how long the stock lasts at the current velocity; it is
+	// null for products that did not sell in the velocity window.
+	DaysOfCover *float64 `json:"days_of_cover"`
+	Status      string   `json:"status"`
+}
+
+type ProductLaunch struct {
+	// ProductID is empty for products whose rows carry no ID.
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Category    string `json:"category"`
+	AddedDate   string `json:"added_date"`
+	// FirstSaleDate and DaysToFirstSale are empty for products that have not
+	// sold since they were added.
+	FirstSaleDate   string `json:"first_sale_date,omitempty"`
+	DaysToFirstSale *int   `json:"days_to_first_sale"`
+	// DaysLive is how many days of data follow the launch; launch windows
+	// longer than that are still filling up.
+	DaysLive  int     `json:"days_live"`
+	Revenue30 float64 `json:"revenue_30d"`
+	Units30   int     `json:"units_30d"`
+	Revenue90 float64 `json:"revenue_90d"`
+	Units90   int     `json:"units_90d"`
+}
+
+// DrilldownNode is one point in the country → region → product hierarchy
+// with its totals and a breakdown by the next level down.
+type DrilldownNode struct {
+	Level string `json:"level"`
+	Path  string `json:"path"`
+	Name  string `json:"name"`
+	PeriodTotals
+	// ChildLevel is empty for products, which have nothing below them.
+	ChildLevel  string           `json:"child_level,omitempty"`
+	Children    []DrilldownChild `json:"children"`
+	MetricValue *float64         `json:"metric_value,omitempty"`
+}
+
+type DrilldownChild struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	PeriodTotals
+	// Share is the child's percentage of its parent's revenue.
+	Share       float64  `json:"share"`
+	MetricValue *float64 `json:"metric_value,omitempty"`
+}
+
+// Distribution describes the spread of one transaction field, overall or for
+// one country or category. Quantiles and histogram counts are estimates from
+// a t-digest.
+type Distribution struct {
+	Metric    string         `json:"metric"`
+	Dimension string         `json:"dimension,omitempty"`
+	Value     string         `json:"value,omitempty"`
+	Count     int            `json:"count"`
+	Min       float64        `json:"min"`
+	Max       float64        `json:"max"`
+	Mean      float64        `json:"mean"`
+	P50       float64        `json:"p50"`
+	P90       float64        `json:"p90"`
+	P99       float64        `json:"p99"`
+	Histogram []HistogramBin `json:"histogram,omitempty"`
+}
+
+// HistogramBin counts the values in [From, To); the last bin includes To.
+type HistogramBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// ProductClass places a product in the ABC classification by its share of
+// total revenue.
+type ProductClass struct {
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	Category    string  `json:"category"`
+	Rank        int     `json:"rank"`
+	Revenue     float64 `json:"revenue"`
+	Share       float64 `json:"share"`
+	// CumulativeShare is the revenue share of this and every higher-ranked
+	// product.
+	CumulativeShare float64 `json:"cumulative_share"`
+	Class           string  `json:"class"`
+}
+
+type ABCClassSummary struct {
+	Class        string  `json:"class"`
+	Products     int     `json:"products"`
+	Revenue      float64 `json:"revenue"`
+	ProductShare float64 `json:"product_share"`
+	RevenueShare float64 `json:"revenue_share"`
+}
+
+type ABCCategoryBreakdown struct {
+	Category string `json:"category"`
+	A        int    `json:"a"`
+	B        int    `json:"b"`
+	C        int    `json:"c"`
+}
+
+// ParetoPoint is one bucket of products, ranked by revenue, on the Pareto
+// curve. Shares are percentages.
+type ParetoPoint struct {
+	ProductShare    float64 `json:"product_share"`
+	RevenueShare    float64 `json:"revenue_share"`
+	CumulativeShare float64 `json:"cumulative_share"`
+}
+
+type ABCSummary struct {
+	Products   int                    `json:"products"`
+	Revenue    float64                `json:"revenue"`
+	Classes    []ABCClassSummary      `json:"classes"`
+	Categories []ABCCategoryBreakdown `json:"categories"`
+	Curve      []ParetoPoint          `json:"curve"`
+}
+
+type PeriodTotals struct {
+	Revenue float64 `json:"revenue"`
+	Orders  int     `json:"orders"`
+	Units   int     `json:"units"`
+}
+
+// CubeRow is one group of a cube query, with the values of the grouped
+// dimensions in the order they were requested.
+type CubeRow struct {
+	Values []string `json:"values"`
+	PeriodTotals
+	MetricValue *float64 `json:"metric_value,omitempty"`
+}
+
+// CustomMetric is the value of a registered custom metric. A derived metric
+// broken down by a dimension lists its Groups instead.
+type CustomMetric struct {
+	Name   string        `json:"name"`
+	Value  any           `json:"value"`
+	Groups []MetricGroup `json:"groups,omitempty"`
+}
+
+// MetricGroup is the value of a derived metric for one group of rows; Value
+// is null where the metric is undefined.
+type MetricGroup struct {
+	Group string `json:"group,omitempty"`
+	// ProductID is set when grouping by product and Country when grouping
+	// by region, as products and regions may share a name.
+	ProductID string   `json:"product_id,omitempty"`
+	Country   string   `json:"country,omitempty"`
+	Value     *float64 `json:"value"`
+}
+
+// RatioMetric divides one total over the transactions by another; Ratio is
+// zero when the denominator is.
+type RatioMetric struct {
+	Numerator   float64 `json:"numerator"`
+	Denominator float64 `json:"denominator"`
+	Ratio       float64 `json:"ratio"`
+}
+
+// SQLResult is the answer to a SQL query. Rows hold one value per column:
+// a number, text, a YYYY-MM-DD date, a boolean or null.
+type SQLResult struct {
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+	// Truncated is set when the query had no LIMIT and more rows matched
+	// than the row limit allows.
+	Truncated bool `json:"truncated"`
+}
+
+type GrowthMetric struct {
+	Dimension string `json:"dimension"`
+	Value     string `json:"value"`
+	// ProductID identifies the product of a product growth row, as products
+	// may share a name; it is empty for other dimensions and for products
+	// whose rows carry no ID.
+	ProductID string `json:"product_id,omitempty"`
+	// Country is the country of a region growth row, as region names are
+	// only unique within a country.
+	Country       string   `json:"country,omitempty"`
+	Period        string   `json:"period"`
+	Current       float64  `json:"current"`
+	PreviousMonth float64  `json:"previous_month"`
+	PreviousYear  float64  `json:"previous_year"`
+	MoMChange     float64  `json:"mom_change"`
+	MoMPercent    *float64 `json:"mom_percent"`
+	YoYChange     float64  `json:"yoy_change"`
+	YoYPercent    *float64 `json:"yoy_percent"`
+}
+
+type Delta struct {
+	Current  float64  `json:"current"`
+	Previous float64  `json:"previous"`
+	Change   float64  `json:"change"`
+	Percent  *float64 `json:"percent"`
+}
+
+type DateRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type CountryRevenueComparison struct {
+	Country string `json:"country"`
+	// ProductID is empty for products whose rows carry no ID.
+	ProductID    string `json:"product_id"`
+	ProductName  string `json:"product_name"`
+	Category     string `json:"category"`
+	Revenue      Delta  `json:"revenue"`
+	Transactions Delta  `json:"transactions"`
+}
+
+type ProductComparison struct {
+	// ProductID is empty for products whose rows carry no ID.
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Category    string `json:"category"`
+	Frequency   Delta  `json:"frequency"`
+}
+
+type RegionComparison struct {
+	Country   string `json:"country"`
+	Region    string `json:"region"`
+	Revenue   Delta  `json:"revenue"`
+	ItemsSold Delta  `json:"items_sold"`
+}
+
+type PeriodComparison struct {
+	CurrentPeriod  string `json:"current_period"`
+	PreviousPeriod string `json:"previous_period"`
+	Revenue        Delta  `json:"revenue"`
+}
+
+// MetricComparison is a derived metric over both ranges of a comparison;
+// values are nil where the metric is undefined.
+type MetricComparison struct {
+	Name     string   `json:"name"`
+	Current  *float64 `json:"current"`
+	Previous *float64 `json:"previous"`
+	Percent  *float64 `json:"percent"`
+}
+
+type Comparison struct {
+	Current        DateRange                  `json:"current"`
+	Previous       DateRange                  `json:"previous"`
+	CountryRevenue []CountryRevenueComparison `json:"country_revenue"`
+	TopProducts    []ProductComparison        `json:"top_products"`
+	MonthlySales   []PeriodComparison         `json:"monthly_sales"`
+	TopRegions     []RegionComparison         `json:"top_regions"`
+	Metrics        []MetricComparison         `json:"metrics"`
+}
+
+// SavedView is a named dashboard set-up: filters, a date range and the range
+// to compare it with. Dates are YYYY-MM-DD; empty fields are not set. Version
+// counts the saves so concurrent edits can be detected.
+type SavedView struct {
+	Name        string    `json:"name"`
+	From        string    `json:"from,omitempty"`
+	To          string    `json:"to,omitempty"`
+	CompareFrom string    `json:"compare_from,omitempty"`
+	CompareTo   string    `json:"compare_to,omitempty"`
+	Country     string    `json:"country,omitempty"`
+	Region      string    `json:"region,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	Version     int       `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DashboardState is what a dashboard link reproduces: the filters, ranges,
+// chart options and drill-down path. Fields are named after the dashboard
+// signals that hold them.
+type DashboardState struct {
+	ViewName    string `json:"viewName"`
+	RangeFrom   string `json:"rangeFrom"`
+	RangeTo     string `json:"rangeTo"`
+	CompareFrom string `json:"compareFrom"`
+	CompareTo   string `json:"compareTo"`
+	// Filters narrow both ranges of the period comparison.
+	FilterCountry   string `json:"filterCountry"`
+	FilterRegion    string `json:"filterRegion"`
+	FilterCategory  string `json:"filterCategory"`
+	TSGranularity   string `json:"tsGranularity"`
+	TSMetric        string `json:"tsMetric"`
+	CohortCountry   string `json:"cohortCountry"`
+	DrillPath       string `json:"drillPath"`
+	DistMetric      string `json:"distMetric"`
+	DistDimension   string `json:"distDimension"`
+	DistValue       string `json:"distValue"`
+	MarginDimension string `json:"marginDimension"`
+	// Limits are bound to selects, which send strings, but are initialized
+	// as numbers; json.Number accepts both.
+	ProductsRankBy string      `json:"productsRankBy"`
+	ProductsLimit  json.Number `json:"productsLimit"`
+	RegionsRankBy  string      `json:"regionsRankBy"`
+	RegionsLimit   json.Number `json:"regionsLimit"`
+	// TableMetric is the derived metric shown in the country table and
+	// its drill-down, if any.
+	TableMetric string `json:"tableMetric"`
+}
+
+// MapCountry is a country's revenue placed on the world map. Names are the
+// spellings the data uses for it, biggest revenue first; the first one is
+// what a filter on the country matches.
+type MapCountry struct {
+	Code         string   `json:"code"`
+	Alpha3       string   `json:"alpha3"`
+	Name         string   `json:"name"`
+	Names        []string `json:"names"`
+	Lat          float64  `json:"lat"`
+	Lon          float64  `json:"lon"`
+	Revenue      float64  `json:"revenue"`
+	Transactions int      `json:"transactions"`
+}
+
+// UnmatchedCountry is a country name of the data that no ISO-3166 country
+// is known by.
+type UnmatchedCountry struct {
+	Name         string  `json:"name"`
+	Revenue      float64 `json:"revenue"`
+	Transactions int     `json:"transactions"`
+}
+
+// CountryMap is revenue by ISO-3166 country, biggest first, with the
+// country names that could not be mapped.
+type CountryMap struct {
+	Countries []MapCountry       `json:"countries"`
+	Unmatched []UnmatchedCountry `json:"unmatched"`
+}
+
+// CatalogEntry is a product catalog row. Cost is the cost of one unit.
+type CatalogEntry struct {
+	ProductID   string  `json:"product_id"`
+	Cost        float64 `json:"cost"`
+	Brand       string  `json:"brand"`
+	Subcategory string  `json:"subcategory"`
+}
+
+// MissingCatalogEntry is a product sold without an entry in the catalog.
+type MissingCatalogEntry struct {
+	ProductID    string  `json:"product_id"`
+	ProductName  string  `json:"product_name"`
+	Transactions int     `json:"transactions"`
+	Revenue      float64 `json:"revenue"`
+}
+
+// Margin is the gross margin of one value of a dimension. Only sales of
+// cataloged products have a cost, so the margin and its percentage cover
+// CostedRevenue; UncostedRevenue is the rest of Revenue.
+type Margin struct {
+	Dimension       string   `json:"dimension"`
+	Value           string   `json:"value"`
+	Transactions    int      `json:"transactions"`
+	Revenue         float64  `json:"revenue"`
+	CostedRevenue   float64  `json:"costed_revenue"`
+	UncostedRevenue float64  `json:"uncosted_revenue"`
+	Cost            float64  `json:"cost"`
+	GrossMargin     float64  `json:"gross_margin"`
+	MarginPercent   *float64 `json:"margin_percent"`
 }
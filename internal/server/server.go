@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"abt-dashboard/internal/config"
 	"abt-dashboard/internal/handlers"
 	"abt-dashboard/internal/services"
 )
@@ -20,13 +21,13 @@ type TemplateHandlers struct {
 	Dashboard http.HandlerFunc
 }
 
-func NewServer(analytics *services.Analytics, logger *slog.Logger, templateHandlers *TemplateHandlers) *Server {
+func NewServer(analytics *services.Analytics, views *services.ViewStore, logger *slog.Logger, templateHandlers *TemplateHandlers, apiCfg config.APIConfig) *Server {
 	s := &Server{
 		analytics:   analytics,
 		mux:         http.NewServeMux(),
 		logger:      logger,
-		apiHandlers: handlers.NewAPIHandlers(analytics, logger),
-		sseHandlers: handlers.NewSSEHandlers(analytics, logger),
+		apiHandlers: handlers.NewAPIHandlers(analytics, logger).WithMaxTopN(apiCfg.MaxTopN).WithSQLLimits(apiCfg.SQLTimeout, apiCfg.SQLMaxRows).WithViews(views),
+		sseHandlers: handlers.NewSSEHandlers(analytics, logger).WithMaxTopN(apiCfg.MaxTopN).WithViews(views),
 	}
 	s.setupRoutes(templateHandlers)
 	return s
@@ -43,12 +44,51 @@ func (s *Server) setupRoutes(templateHandlers *TemplateHandlers) {
 	s.mux.HandleFunc("GET /api/top-products", s.apiHandlers.HandleTopProducts)
 	s.mux.HandleFunc("GET /api/monthly-sales", s.apiHandlers.HandleMonthlySales)
 	s.mux.HandleFunc("GET /api/top-regions", s.apiHandlers.HandleTopRegions)
+	s.mux.HandleFunc("GET /api/timeseries", s.apiHandlers.HandleTimeSeries)
+	s.mux.HandleFunc("GET /api/growth", s.apiHandlers.HandleGrowth)
+	s.mux.HandleFunc("GET /api/forecast", s.apiHandlers.HandleForecast)
+	s.mux.HandleFunc("GET /api/anomalies", s.apiHandlers.HandleAnomalies)
+	s.mux.HandleFunc("GET /api/cohorts", s.apiHandlers.HandleCohorts)
+	s.mux.HandleFunc("GET /api/customers/segments", s.apiHandlers.HandleCustomerSegments)
+	s.mux.HandleFunc("GET /api/customers/segments/{segment}", s.apiHandlers.HandleSegmentCustomers)
+	s.mux.HandleFunc("GET /api/customer-metrics", s.apiHandlers.HandleCustomerMetrics)
+	s.mux.HandleFunc("GET /api/inventory", s.apiHandlers.HandleInventory)
+	s.mux.HandleFunc("GET /api/margins", s.apiHandlers.HandleMargins)
+	s.mux.HandleFunc("GET /api/margins/missing", s.apiHandlers.HandleMissingCatalog)
+	s.mux.HandleFunc("GET /api/launches", s.apiHandlers.HandleLaunches)
+	s.mux.HandleFunc("GET /api/drilldown", s.apiHandlers.HandleDrilldown)
+	s.mux.HandleFunc("GET /api/distribution", s.apiHandlers.HandleDistribution)
+	s.mux.HandleFunc("GET /api/abc", s.apiHandlers.HandleABCSummary)
+	s.mux.HandleFunc("GET /api/abc/products", s.apiHandlers.HandleProductClasses)
+	s.mux.HandleFunc("GET /api/abc/products/{product}", s.apiHandlers.HandleProductClass)
+	s.mux.HandleFunc("GET /api/cube", s.apiHandlers.HandleCube)
+	s.mux.HandleFunc("GET /api/metrics", s.apiHandlers.HandleMetrics)
+	s.mux.HandleFunc("GET /api/metrics/{name}", s.apiHandlers.HandleMetric)
+	s.mux.HandleFunc("POST /api/sql", s.apiHandlers.HandleSQL)
+	s.mux.HandleFunc("GET /api/views", s.apiHandlers.HandleViews)
+	s.mux.HandleFunc("POST /api/views", s.apiHandlers.HandleSaveView)
+	s.mux.HandleFunc("GET /api/views/{name}", s.apiHandlers.HandleView)
+	s.mux.HandleFunc("DELETE /api/views/{name}", s.apiHandlers.HandleDeleteView)
+	s.mux.HandleFunc("GET /api/compare", s.apiHandlers.HandleCompare)
 
 	// Datastar SSE endpoints
 	s.mux.HandleFunc("GET /sse/country-revenue", s.sseHandlers.HandleCountryRevenue)
 	s.mux.HandleFunc("GET /sse/top-products", s.sseHandlers.HandleTopProducts)
 	s.mux.HandleFunc("GET /sse/monthly-sales", s.sseHandlers.HandleMonthlySales)
 	s.mux.HandleFunc("GET /sse/top-regions", s.sseHandlers.HandleTopRegions)
+	s.mux.HandleFunc("GET /sse/timeseries", s.sseHandlers.HandleTimeSeries)
+	s.mux.HandleFunc("GET /sse/compare", s.sseHandlers.HandleCompare)
+	s.mux.HandleFunc("GET /sse/views", s.sseHandlers.HandleViews)
+	s.mux.HandleFunc("GET /sse/anomalies", s.sseHandlers.HandleAnomalies)
+	s.mux.HandleFunc("GET /sse/country-map", s.sseHandlers.HandleCountryMap)
+	s.mux.HandleFunc("GET /sse/cohorts", s.sseHandlers.HandleCohorts)
+	s.mux.HandleFunc("GET /sse/inventory", s.sseHandlers.HandleInventory)
+	s.mux.HandleFunc("GET /sse/margins", s.sseHandlers.HandleMargins)
+	s.mux.HandleFunc("GET /sse/launches", s.sseHandlers.HandleLaunches)
+	s.mux.HandleFunc("GET /sse/metrics", s.sseHandlers.HandleMetrics)
+	s.mux.HandleFunc("GET /sse/drilldown", s.sseHandlers.HandleDrilldown)
+	s.mux.HandleFunc("GET /sse/distribution", s.sseHandlers.HandleDistribution)
+	s.mux.HandleFunc("GET /sse/pareto", s.sseHandlers.HandlePareto)
 	s.mux.HandleFunc("GET /sse/refresh-all", s.sseHandlers.HandleRefreshAll)
 }
 
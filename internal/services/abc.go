@@ -0,0 +1,241 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"abt-dashboard/internal/models"
+)
+
+const (
+	ClassA = "A"
+	ClassB = "B"
+	ClassC = "C"
+)
+
+var ABCClasses = []string{ClassA, ClassB, ClassC}
+
+const (
+	// Class A holds the products that make up the first 80% of revenue and
+	// class B those making up the next 15%. A product that straddles a
+	// boundary belongs to the higher class.
+	classAShare = 80.0
+	classBShare = 95.0
+
+	// paretoBuckets is how many points the Pareto curve is sampled at.
+	paretoBuckets = 100
+)
+
+type abcData struct {
+	products []models.ProductClass
+	byRef    map[string]int
+	byName   map[string]int
+	summary  *models.ABCSummary
+}
+
+// abc ranks every product by revenue and classifies it, once per data set.
+func (a *Analytics) abc(ctx context.Context) (*abcData, error) {
+	precomputed := a.current()
+	return a.abcCache.get(precomputed, func() (*abcData, error) {
+		return computeABC(ctx, precomputed.Store)
+	})
+}
+
+// ProductClasses returns the products ranked by revenue with their ABC
+// class, optionally restricted to one class and category.
+func (a *Analytics) ProductClasses(ctx context.Context, class, category string) ([]models.ProductClass, error) {
+	if class != "" && !slices.Contains(ABCClasses, class) {
+		return nil, fmt.Errorf("class must be one of: %s", strings.Join(ABCClasses, ", "))
+	}
+	data, err := a.abc(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if class == "" && category == "" {
+		return data.products, nil
+	}
+	result := make([]models.ProductClass, 0)
+	for _, p := range data.products {
+		if (class == "" || p.Class == class) && (category == "" || p.Category == category) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// ProductClass returns the classification of one product by product ID, or
+// by display name for products without one, or ErrUnknownValue if it never
+// sold. A display name shared by several products selects the one with the
+// most revenue.
+func (a *Analytics) ProductClass(ctx context.Context, product string) (*models.ProductClass, error) {
+	data, err := a.abc(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i, ok := data.byRef[product]
+	if !ok {
+		i, ok = data.byName[product]
+	}
+	if !ok {
+		return nil, ErrUnknownValue
+	}
+	p := data.products[i]
+	return &p, nil
+}
+
+// ABCSummary returns the size and revenue of each class, the class counts
+// per category and the Pareto curve of cumulative revenue.
+func (a *Analytics) ABCSummary(ctx context.Context) (*models.ABCSummary, error) {
+	data, err := a.abc(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return data.summary, nil
+}
+
+func computeABC(ctx context.Context, store *TransactionStore) (*abcData, error) {
+	revenue := make([]float64, len(store.ProductNames))
+	category := make([]uint32, len(revenue))
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		product := store.Products[i]
+		revenue[product] += store.Totals[i]
+		category[product] = store.Categories[i]
+	}
+
+	total := 0.0
+	for _, r := range revenue {
+		total += r
+	}
+
+	products := make([]models.ProductClass, len(revenue))
+	for code, r := range revenue {
+		products[code] = models.ProductClass{
+			ProductID:   store.ProductIDs[code],
+			ProductName: store.ProductNames[code],
+			Category:    store.CategoryDict.Value(category[code]),
+			Revenue:     r,
+		}
+	}
+	slices.SortFunc(products, func(a, b models.ProductClass) int {
+		if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.ProductName, b.ProductName); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+
+	data := &abcData{
+		products: products,
+		byRef:    make(map[string]int, len(products)),
+		byName:   make(map[string]int, len(products)),
+	}
+	summary := &models.ABCSummary{Products: len(products), Revenue: total}
+	classes := map[string]*models.ABCClassSummary{}
+	for _, class := range ABCClasses {
+		summary.Classes = append(summary.Classes, models.ABCClassSummary{Class: class})
+	}
+	for i := range summary.Classes {
+		classes[summary.Classes[i].Class] = &summary.Classes[i]
+	}
+	categories := map[string]*models.ABCCategoryBreakdown{}
+
+	cumulative := 0.0
+	for i := range products {
+		p := &products[i]
+		before := cumulative
+		cumulative += p.Revenue
+		p.Rank = i + 1
+		p.Class = ClassC
+		if total > 0 {
+			p.Share = p.Revenue / total * 100
+			p.CumulativeShare = cumulative / total * 100
+			switch {
+			case p.Revenue <= 0:
+			case before/total*100 < classAShare:
+				p.Class = ClassA
+			case before/total*100 < classBShare:
+				p.Class = ClassB
+			}
+		}
+		if p.ProductID != "" {
+			data.byRef[p.ProductID] = i
+		}
+		if _, ok := data.byName[p.ProductName]; !ok {
+			data.byName[p.ProductName] = i
+		}
+
+		c := classes[p.Class]
+		c.Products++
+		c.Revenue += p.Revenue
+		cat := categories[p.Category]
+		if cat == nil {
+			cat = &models.ABCCategoryBreakdown{Category: p.Category}
+			categories[p.Category] = cat
+		}
+		switch p.Class {
+		case ClassA:
+			cat.A++
+		case ClassB:
+			cat.B++
+		default:
+			cat.C++
+		}
+	}
+
+	for i := range summary.Classes {
+		c := &summary.Classes[i]
+		if len(products) > 0 {
+			c.ProductShare = float64(c.Products) / float64(len(products)) * 100
+		}
+		if total > 0 {
+			c.RevenueShare = c.Revenue / total * 100
+		}
+	}
+	summary.Categories = make([]models.ABCCategoryBreakdown, 0, len(categories))
+	for _, c := range categories {
+		summary.Categories = append(summary.Categories, *c)
+	}
+	slices.SortFunc(summary.Categories, func(a, b models.ABCCategoryBreakdown) int {
+		return cmp.Compare(a.Category, b.Category)
+	})
+	summary.Curve = paretoCurve(products, total)
+
+	data.summary = summary
+	return data, nil
+}
+
+// paretoCurve splits the ranked products into at most paretoBuckets equal
+// groups and reports each group's revenue share and the running total.
+func paretoCurve(products []models.ProductClass, total float64) []models.ParetoPoint {
+	curve := make([]models.ParetoPoint, 0, paretoBuckets)
+	if len(products) == 0 || total <= 0 {
+		return curve
+	}
+	buckets := min(len(products), paretoBuckets)
+	start, cumulative := 0, 0.0
+	for b := 1; b <= buckets; b++ {
+		end := b * len(products) / buckets
+		bucket := 0.0
+		for _, p := range products[start:end] {
+			bucket += p.Revenue
+		}
+		cumulative += bucket
+		curve = append(curve, models.ParetoPoint{
+			ProductShare:    float64(end) / float64(len(products)) * 100,
+			RevenueShare:    bucket / total * 100,
+			CumulativeShare: cumulative / total * 100,
+		})
+		start = end
+	}
+	return curve
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"abt-dashboard/internal/models"
+)
+
+func abcTestAnalytics() *Analytics {
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		// Revenue 500 + 300 + 100 + 50 + 30 + 20 = 1000
+		{ProductName: "Laptop", Category: "Electronics", TotalPrice: 300},
+		{ProductName: "Laptop", Category: "Electronics", TotalPrice: 200},
+		{ProductName: "Phone", Category: "Electronics", TotalPrice: 300},
+		{ProductName: "Desk", Category: "Home", TotalPrice: 100},
+		{ProductName: "Lamp", Category: "Home", TotalPrice: 50},
+		{ProductName: "Mouse", Category: "Electronics", TotalPrice: 30},
+		{ProductName: "Pen", Category: "Office", TotalPrice: 20},
+	})
+	return a
+}
+
+func TestAnalytics_ProductClasses(t *testing.T) {
+	a := abcTestAnalytics()
+	ctx := context.Background()
+
+	products, err := a.ProductClasses(ctx, "", "")
+	if err != nil {
+		t.Fatalf("ProductClasses() error = %v", err)
+	}
+
+	// Laptop and Phone reach 80% exactly; Desk crosses 95% from below so it
+	// is still B; everything after is C.
+	want := []struct {
+		name  string
+		class string
+		cum   float64
+	}{
+		{"Laptop", ClassA, 50},
+		{"Phone", ClassA, 80},
+		{"Desk", ClassB, 90},
+		{"Lamp", ClassB, 95},
+		{"Mouse", ClassC, 98},
+		{"Pen", ClassC, 100},
+	}
+	if len(products) != len(want) {
+		t.Fatalf("expected %d products, got %d", len(want), len(products))
+	}
+	for i, w := range want {
+		p := products[i]
+		if p.ProductName != w.name || p.Class != w.class || p.CumulativeShare != w.cum || p.Rank != i+1 {
+			t.Errorf("product %d = %+v, want %s in class %s at %v%%", i, p, w.name, w.class, w.cum)
+		}
+	}
+
+	home, err := a.ProductClasses(ctx, ClassB, "Home")
+	if err != nil || len(home) != 2 {
+		t.Errorf("expected Desk and Lamp, got %+v, %v", home, err)
+	}
+	if _, err := a.ProductClasses(ctx, "D", ""); err == nil {
+		t.Error("expected error for unknown class")
+	}
+
+	pen, err := a.ProductClass(ctx, "Pen")
+	if err != nil || pen.Class != ClassC || pen.Share != 2 {
+		t.Errorf("ProductClass(Pen) = %+v, %v", pen, err)
+	}
+	if _, err := a.ProductClass(ctx, "Sofa"); !errors.Is(err, ErrUnknownValue) {
+		t.Errorf("expected ErrUnknownValue, got %v", err)
+	}
+}
+
+func TestAnalytics_ProductClass_SharedName(t *testing.T) {
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{ProductID: "P1", ProductName: "Chair", Category: "Home", TotalPrice: 40},
+		{ProductID: "P2", ProductName: "Chair", Category: "Office", TotalPrice: 60},
+		{ProductName: "Stool", Category: "Home", TotalPrice: 10},
+	})
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		product, id, category string
+	}{
+		{"P1", "P1", "Home"},
+		{"P2", "P2", "Office"},
+		{"Chair", "P2", "Office"},
+		{"Stool", "", "Home"},
+	} {
+		p, err := a.ProductClass(ctx, tt.product)
+		if err != nil || p.ProductID != tt.id || p.Category != tt.category {
+			t.Errorf("ProductClass(%s) = %+v, %v, want %q in %s", tt.product, p, err, tt.id, tt.category)
+		}
+	}
+}
+
+func TestAnalytics_ABCSummary(t *testing.T) {
+	a := abcTestAnalytics()
+
+	summary, err := a.ABCSummary(context.Background())
+	if err != nil {
+		t.Fatalf("ABCSummary() error = %v", err)
+	}
+	if summary.Products != 6 || summary.Revenue != 1000 {
+		t.Errorf("unexpected totals %+v", summary)
+	}
+
+	classA := summary.Classes[0]
+	if classA.Class != ClassA || classA.Products != 2 || classA.RevenueShare != 80 {
+		t.Errorf("unexpected class A summary %+v", classA)
+	}
+
+	want := []models.ABCCategoryBreakdown{
+		{Category: "Electronics", A: 2, C: 1},
+		{Category: "Home", B: 2},
+		{Category: "Office", C: 1},
+	}
+	if len(summary.Categories) != len(want) {
+		t.Fatalf("expected %d categories, got %+v", len(want), summary.Categories)
+	}
+	for i, w := range want {
+		if summary.Categories[i] != w {
+			t.Errorf("category %d = %+v, want %+v", i, summary.Categories[i], w)
+		}
+	}
+
+	// Fewer products than buckets gives one point per product
+	if len(summary.Curve) != 6 || summary.Curve[0].RevenueShare != 50 || summary.Curve[5].CumulativeShare != 100 {
+		t.Errorf("unexpected curve %+v", summary.Curve)
+	}
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/geo"
+)
+
+// Dimensions an alias table canonicalizes.
+const (
+	AliasCountry = "country"
+	AliasRegion  = "region"
+)
+
+//go:embed aliases.csv
+var defaultAliasesCSV string
+
+// AliasTable maps the spellings of country and region names found in the
+// data to one canonical name each. Names are matched regardless of case,
+// punctuation and a leading "the".
+//
+// Countries are recognized through the ISO-3166 table of the geo package and
+// take its English name; table rows for a country take precedence, so they
+// can add spellings or rename it. Regions are only recognized by the table,
+// within the country they are listed under, so "WA" can be Washington in the
+// United States and Western Australia in Australia.
+type AliasTable struct {
+	countries map[string]string
+	// regions maps a country key, then a region spelling, to the canonical
+	// name of the region.
+	regions map[string]map[string]string
+	// fingerprint identifies the table's contents, so data canonicalized
+	// with another table is not mistaken for current.
+	fingerprint string
+}
+
+// aliasRow is a row of an alias table. Country is set for regions only.
+type aliasRow struct {
+	line      int
+	dimension string
+	country   string
+	name      string
+	spellings []string
+}
+
+// DefaultAliases returns the embedded alias table.
+var DefaultAliases = sync.OnceValue(func() *AliasTable {
+	rows, err := readAliases(defaultAliasesCSV)
+	if err == nil {
+		var t *AliasTable
+		if t, err = newAliasTable(rows); err == nil {
+			t.fingerprint = fingerprint(defaultAliasesCSV)
+			return t
+		}
+	}
+	panic("services: embedded alias table: " + err.Error())
+})
+
+// LoadAliases reads an alias file in the format of the embedded table,
+// "dimension,country,name,aliases" with aliases separated by "|" and the
+// country left empty on country rows, and lays it over the embedded table:
+// its rows replace the embedded mappings of the same names. An empty path
+// returns the embedded table.
+func LoadAliases(path string) (*AliasTable, error) {
+	if path == "" {
+		return DefaultAliases(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read aliases: %w", err)
+	}
+	overrides, err := readAliases(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defaults, err := readAliases(defaultAliasesCSV)
+	if err != nil {
+		return nil, err
+	}
+	t, err := newAliasTable(defaults, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	t.fingerprint = fingerprint(defaultAliasesCSV, string(data))
+	return t, nil
+}
+
+func fingerprint(tables ...string) string {
+	h := sha256.New()
+	for _, t := range tables {
+		h.Write([]byte(t))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+// readAliases reads the rows of an alias table.
+func readAliases(data string) ([]aliasRow, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comment = '#'
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || !slices.Equal(records[0], []string{"dimension", "country", "name", "aliases"}) {
+		return nil, fmt.Errorf(`alias table needs a "dimension,country,name,aliases" header`)
+	}
+
+	rows := make([]aliasRow, 0, len(records)-1)
+	for line, rec := range records[1:] {
+		if len(rec) != 4 {
+			return nil, fmt.Errorf("row %d: expected 4 fields, got %d", line+2, len(rec))
+		}
+		row := aliasRow{line: line + 2, dimension: rec[0], country: strings.TrimSpace(rec[1]), name: strings.TrimSpace(rec[2])}
+		switch {
+		case row.dimension != AliasCountry && row.dimension != AliasRegion:
+			return nil, fmt.Errorf("row %d: unknown dimension %q", row.line, row.dimension)
+		case row.dimension == AliasCountry && row.country != "":
+			return nil, fmt.Errorf("row %d: country rows take no country", row.line)
+		case row.dimension == AliasRegion && row.country == "":
+			return nil, fmt.Errorf("row %d: region %q needs the country it lies in", row.line, row.name)
+		case row.name == "":
+			return nil, fmt.Errorf("row %d: name is empty", row.line)
+		}
+		row.spellings = []string{row.name}
+		if rec[3] != "" {
+			row.spellings = append(row.spellings, strings.Split(rec[3], "|")...)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// newAliasTable builds a table from layers of rows, each laid over the ones
+// before it. Countries are indexed first, so the country of a region row is
+// resolved through the complete table.
+func newAliasTable(layers ...[]aliasRow) (*AliasTable, error) {
+	t := &AliasTable{
+		countries: make(map[string]string),
+		regions:   make(map[string]map[string]string),
+	}
+	for _, rows := range layers {
+		index := make(map[string]string)
+		for _, row := range rows {
+			if row.dimension == AliasCountry {
+				if err := indexSpellings(index, row); err != nil {
+					return nil, err
+				}
+			}
+		}
+		layerAliases(t.countries, index)
+	}
+	for _, rows := range layers {
+		byCountry := make(map[string]map[string]string)
+		for _, row := range rows {
+			if row.dimension != AliasRegion {
+				continue
+			}
+			key := t.countryKey(row.country)
+			if byCountry[key] == nil {
+				byCountry[key] = make(map[string]string)
+			}
+			if err := indexSpellings(byCountry[key], row); err != nil {
+				return nil, err
+			}
+		}
+		for key, index := range byCountry {
+			if t.regions[key] == nil {
+				t.regions[key] = make(map[string]string)
+			}
+			layerAliases(t.regions[key], index)
+		}
+	}
+	return t, nil
+}
+
+// indexSpellings maps every spelling of row to its name, rejecting spellings
+// that already name something else.
+func indexSpellings(index map[string]string, row aliasRow) error {
+	for _, spelling := range row.spellings {
+		k := geo.Normalize(spelling)
+		if prev, ok := index[k]; ok && prev != row.name {
+			return fmt.Errorf("row %d: %q already names %s", row.line, spelling, prev)
+		}
+		index[k] = row.name
+	}
+	return nil
+}
+
+// layerAliases lays the mappings of over onto index.
+func layerAliases(index, over map[string]string) {
+	// Spellings of a name over renames follow it.
+	for k, name := range index {
+		if renamed, ok := over[geo.Normalize(name)]; ok {
+			index[k] = renamed
+		}
+	}
+	maps.Copy(index, over)
+}
+
+// Country returns the canonical name of a country and whether it is known.
+func (t *AliasTable) Country(name string) (string, bool) {
+	if canonical, ok := t.countries[geo.Normalize(name)]; ok {
+		return canonical, true
+	}
+	c, ok := geo.Lookup(name)
+	if !ok {
+		return name, false
+	}
+	// A row may rename the country under its ISO name.
+	if canonical, ok := t.countries[geo.Normalize(c.Name)]; ok {
+		return canonical, true
+	}
+	return c.Name, true
+}
+
+// countryKey identifies the country regions are listed under: the alpha-2
+// code of its canonical name, or the normalized name of countries the geo
+// table does not know. Every spelling of a country has the same key.
+func (t *AliasTable) countryKey(country string) string {
+	canonical, _ := t.Country(country)
+	if c, ok := geo.Lookup(canonical); ok {
+		return c.Code
+	}
+	return geo.Normalize(canonical)
+}
+
+// Region returns the canonical name of a region of the given country and
+// whether it is known.
+func (t *AliasTable) Region(country, name string) (string, bool) {
+	return t.region(t.countryKey(country), name)
+}
+
+func (t *AliasTable) region(countryKey, name string) (string, bool) {
+	if canonical, ok := t.regions[countryKey][geo.Normalize(name)]; ok {
+		return canonical, true
+	}
+	return name, false
+}
+
+// canonicalize rewrites the country and region of tx to their canonical
+// names and counts the values the table does not know in unmatched. Unknown
+// regions of one country that differ only in case or punctuation are folded
+// into one spelling: the first one recorded in spellings, keyed by country
+// key and normalized name.
+func (t *AliasTable) canonicalize(tx *models.Transaction, unmatched *unmatchedNames, spellings map[[2]string]string) {
+	var ok bool
+	if tx.Country, ok = t.Country(tx.Country); !ok && tx.Country != "" {
+		unmatched.countries[tx.Country]++
+	}
+	key := t.countryKey(tx.Country)
+	if tx.Region, ok = t.region(key, tx.Region); !ok && tx.Region != "" {
+		folded := [2]string{key, geo.Normalize(tx.Region)}
+		if spelling, seen := spellings[folded]; seen {
+			tx.Region = spelling
+		} else {
+			spellings[folded] = tx.Region
+		}
+		unmatched.addRegion(tx.Country, tx.Region, 1)
+	}
+}
+
+// unmatchedNames counts the rows whose country or region the alias table
+// did not know. Regions are counted per country, as each country's feed
+// spells its own.
+type unmatchedNames struct {
+	// countries holds the unknown country names and their rows.
+	countries map[string]int
+	// regions holds the unknown regions as country -> region -> rows.
+	regions map[string]map[string]int
+}
+
+func newUnmatchedNames() *unmatchedNames {
+	return &unmatchedNames{countries: map[string]int{}, regions: map[string]map[string]int{}}
+}
+
+func (u *unmatchedNames) addRegion(country, region string, n int) {
+	if u.regions[country] == nil {
+		u.regions[country] = make(map[string]int)
+	}
+	u.regions[country][region] += n
+}
+
+func mergeUnmatchedNames(local, global *unmatchedNames) {
+	for name, n := range local.countries {
+		global.countries[name] += n
+	}
+	for country, regions := range local.regions {
+		for region, n := range regions {
+			global.addRegion(country, region, n)
+		}
+	}
+}
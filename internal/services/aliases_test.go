@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestAliasTable(t *testing.T) {
+	aliases := DefaultAliases()
+
+	countries := map[string]string{
+		"USA":                      "United States",
+		"U.S.":                     "United States",
+		"united states":            "United States",
+		"United States of America": "United States",
+		"Deutschland":              "Germany",
+		"GERMANY":                  "Germany",
+		"UK":                       "United Kingdom",
+	}
+	for in, want := range countries {
+		if got, ok := aliases.Country(in); !ok || got != want {
+			t.Errorf("Country(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	if got, ok := aliases.Country("Atlantis"); ok || got != "Atlantis" {
+		t.Errorf("Country(Atlantis) = %q, %v; unknown names should be kept", got, ok)
+	}
+
+	regions := []struct{ country, in, want string }{
+		{"United States", "California", "California"},
+		{"USA", "CALIFORNIA", "California"},
+		{"us", "Calif.", "California"},
+		{"Germany", "bayern", "Bavaria"},
+		{"Canada", "Québec", "Quebec"},
+		{"Deutschland", "north rhine westphalia", "North Rhine-Westphalia"},
+		// Abbreviations mean different regions in different countries.
+		{"United States", "WA", "Washington"},
+		{"Australia", "WA", "Western Australia"},
+		{"Canada", "NT", "Northwest Territories"},
+		{"Australia", "NT", "Northern Territory"},
+	}
+	for _, tt := range regions {
+		if got, ok := aliases.Region(tt.country, tt.in); !ok || got != tt.want {
+			t.Errorf("Region(%q, %q) = %q, %v; want %q", tt.country, tt.in, got, ok, tt.want)
+		}
+	}
+	if got, ok := aliases.Region("United States", "Springfield"); ok || got != "Springfield" {
+		t.Errorf("Region(Springfield) = %q, %v; unknown names should be kept", got, ok)
+	}
+	if got, ok := aliases.Region("Germany", "California"); ok || got != "California" {
+		t.Errorf("Region(Germany, California) = %q, %v; regions belong to their country", got, ok)
+	}
+}
+
+func TestLoadAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.csv")
+	overrides := "dimension,country,name,aliases\ncountry,,USA,United States|America\nregion,USA,CA,California\nregion,America,Springfield,Springfeld\n"
+	if err := os.WriteFile(path, []byte(overrides), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	aliases, err := LoadAliases(path)
+	if err != nil {
+		t.Fatalf("LoadAliases() error = %v", err)
+	}
+	for _, in := range []string{"USA", "U.S.", "United States of America", "america"} {
+		if got, _ := aliases.Country(in); got != "USA" {
+			t.Errorf("Country(%q) = %q, want the override USA", in, got)
+		}
+	}
+	for _, in := range []string{"california", "Calif", "CA"} {
+		if got, _ := aliases.Region("United States", in); got != "CA" {
+			t.Errorf("Region(%q) = %q, want the override CA", in, got)
+		}
+	}
+	if got, ok := aliases.Region("U.S.", "springfeld"); !ok || got != "Springfield" {
+		t.Errorf("Region(springfeld) = %q, %v; want the added Springfield", got, ok)
+	}
+	if got, _ := aliases.Region("Germany", "Bayern"); got != "Bavaria" {
+		t.Errorf("Region(Bayern) = %q, embedded rows should still apply", got)
+	}
+	if aliases.fingerprint == DefaultAliases().fingerprint {
+		t.Error("an overridden table should not share the embedded table's cache")
+	}
+
+	for name, data := range map[string]string{
+		"no header":              "country,,USA,\n",
+		"old header":             "dimension,name,aliases\ncountry,USA,\n",
+		"unknown dimension":      "dimension,country,name,aliases\ncity,France,Paris,\n",
+		"region without country": "dimension,country,name,aliases\nregion,,Georgia,GA\n",
+		"country with country":   "dimension,country,name,aliases\ncountry,France,USA,\n",
+		"duplicate alias":        "dimension,country,name,aliases\nregion,US,Georgia,GA\nregion,United States,Guam,GA\n",
+	} {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadAliases(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := LoadAliases(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("a missing alias file should be an error")
+	}
+}
+
+func TestAnalytics_LoadFromCSV_CanonicalizesNames(t *testing.T) {
+	f := createTempCSV(t, `transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock,added_date
+T001,2023-01-15,U001,USA,California,P001,Laptop,Electronics,100,1,100,50,2023-01-01
+T002,2023-01-16,U002,U.S.,CALIFORNIA,P001,Laptop,Electronics,100,1,100,50,2023-01-01
+T003,2023-01-17,U003,united states,calif.,P001,Laptop,Electronics,100,1,100,50,2023-01-01
+T004,2023-01-18,U004,Atlantis,Deep Trench,P001,Laptop,Electronics,100,1,100,50,2023-01-01
+T005,2023-01-19,U005,Atlantis,DEEP-TRENCH,P001,Laptop,Electronics,100,1,100,50,2023-01-01
+T006,2023-01-20,U006,Australia,WA,P001,Laptop,Electronics,100,1,100,50,2023-01-01
+T007,2023-01-21,U007,USA,WA,P001,Laptop,Electronics,100,1,100,50,2023-01-01
+T008,2023-01-22,U008,Germany,Deep Trench,P001,Laptop,Electronics,100,1,100,50,2023-01-01`)
+	defer os.Remove(f)
+
+	a := NewAnalytics()
+	if err := a.LoadFromCSV(context.Background(), f); err != nil {
+		t.Fatalf("LoadFromCSV() error = %v", err)
+	}
+	defer os.Remove(a.getCacheFilename(f))
+
+	rows := a.CountryRevenue()
+	if len(rows) != 4 || rows[0].Country != "United States" || rows[0].Transactions != 4 {
+		t.Fatalf("expected the spellings of the US merged, got %+v", rows)
+	}
+	regions := a.TopRegions(10)
+	if len(regions) != 5 || regions[0].Region != "California" || regions[0].Revenue != 300 {
+		t.Errorf("expected the spellings of California merged, got %+v", regions)
+	}
+	// Deep Trench in Atlantis and in Germany are different regions.
+	if r := regions[1:3]; r[0].Country != "Atlantis" || r[0].Revenue != 200 || r[1].Country != "Germany" || r[1].Region != "Deep Trench" {
+		t.Errorf("expected Deep Trench kept apart per country, got %+v", r)
+	}
+	names := make([]string, len(regions))
+	for i, r := range regions {
+		names[i] = r.Region
+	}
+	for _, want := range []string{"Deep Trench", "Washington", "Western Australia"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("regions = %v, want %s", names, want)
+		}
+	}
+
+	stats := a.Stats()
+	countries := stats["unmatched_countries"].(map[string]int)
+	if len(countries) != 1 || countries["Atlantis"] != 2 {
+		t.Errorf("unmatched_countries = %v, want Atlantis in 2 rows", countries)
+	}
+	r := stats["unmatched_regions"].(map[string]map[string]int)
+	if len(r) != 2 || len(r["Atlantis"]) != 1 || r["Atlantis"]["Deep Trench"] != 2 || r["Germany"]["Deep Trench"] != 1 {
+		t.Errorf("unmatched_regions = %v, want both spellings of Atlantis's Deep Trench folded into 2 rows apart from Germany's", r)
+	}
+
+	// A cached load reports the same names.
+	cached := NewAnalytics()
+	if err := cached.LoadFromCSV(context.Background(), f); err != nil {
+		t.Fatalf("LoadFromCSV() from cache error = %v", err)
+	}
+	if got := cached.Stats()["unmatched_countries"].(map[string]int); got["Atlantis"] != 2 {
+		t.Errorf("cached unmatched_countries = %v", got)
+	}
+}
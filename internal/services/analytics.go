@@ -2,6 +2,7 @@ package services
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"encoding/gob"
 	"fmt"
@@ -15,13 +16,14 @@ import (
 	"time"
 
 	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/tdigest"
 	"golang.org/x/sync/errgroup"
 )
 
 const (
 	batchSize    = 10000
 	maxWorkers   = 10
-	cacheVersion = "v1"
+	cacheVersion = "v16"
 	cacheDir     = ".cache"
 )
 
@@ -30,46 +32,136 @@ type PrecomputedData struct {
 	TopProducts    []models.ProductFrequency `json:"top_products"`
 	MonthlySales   []models.MonthlyData      `json:"monthly_sales"`
 	TopRegions     []models.RegionRevenue    `json:"top_regions"`
-	LastModified   time.Time                 `json:"last_modified"`
-	RecordCount    int64                     `json:"record_count"`
+	DailySales     []models.DailySales       `json:"daily_sales"`
+	// DimensionMonthly holds per-month totals for every value of each growth
+	// dimension, indexed as dimension -> value -> month. Products are keyed
+	// by productKey.
+	DimensionMonthly map[string]map[string]map[string]models.PeriodTotals `json:"dimension_monthly"`
+	// Distributions sketch the transaction fields as metric -> dimension ->
+	// value, with the overall digest under an empty dimension and value.
+	Distributions map[string]map[string]map[string]*tdigest.Digest `json:"-"`
+	// Store retains the rows themselves for range and filter queries.
+	Store *TransactionStore `json:"-"`
+	// Cube pre-aggregates the rows for roll-ups; the ranked views above are
+	// built from it.
+	Cube *Cube `json:"-"`
+	// UnmatchedCountries counts the rows whose country the alias table did
+	// not know, by name.
+	UnmatchedCountries map[string]int `json:"unmatched_countries"`
+	// UnmatchedRegions counts the rows whose region the alias table did not
+	// know, as country -> region -> rows.
+	UnmatchedRegions map[string]map[string]int `json:"unmatched_regions"`
+	// MissingCatalog lists the products sold without a catalog entry,
+	// biggest revenue first; it is empty when no catalog is loaded.
+	MissingCatalog []models.MissingCatalogEntry `json:"missing_catalog"`
+	// NoProductID counts the rows that could not be joined to the catalog
+	// as they carry no product_id; it is zero when no catalog is loaded.
+	NoProductID int `json:"no_product_id"`
+	// metrics holds the finalized custom metrics by name. They are not
+	// cached but replayed from the store, as the registered set may change.
+	metrics      map[string]any
+	LastModified time.Time `json:"last_modified"`
+	RecordCount  int64     `json:"record_count"`
 }
 
 type Analytics struct {
-	mu               sync.RWMutex
-	precomputed      *PrecomputedData
-	csvPath          string
+	mu          sync.RWMutex
+	precomputed *PrecomputedData
+	csvPath     string
+	// csvModTime is the modification time of the CSV as last loaded.
+	csvModTime       time.Time
 	recordsProcessed atomic.Int64
 	logger           *slog.Logger
+	// updates is closed and replaced whenever the data set is swapped.
+	updates chan struct{}
+	// aliases canonicalizes country and region names as CSV rows are read.
+	aliases *AliasTable
+	// catalog, when set, is joined to the CSV rows on product ID.
+	catalog *Catalog
+	// aggregators and derivedMetrics are the registered custom metrics.
+	aggregators    []Aggregator
+	derivedMetrics []*DerivedMetric
+
+	anomalies derivedCache[[]models.Anomaly]
+	customers derivedCache[[]models.CustomerRFM]
+	// customerMetrics is keyed by dimension.
+	customerMetrics derivedCache[map[string][]models.CustomerMetrics]
+	inventory       derivedCache[[]models.InventoryItem]
+	launches        derivedCache[[]models.ProductLaunch]
+	abcCache        derivedCache[*abcData]
+	// productsByCode indexes TopProducts by product code.
+	productsByCode derivedCache[[]models.ProductFrequency]
+	// productCustomers and regionCustomers count distinct customers by code.
+	productCustomers derivedCache[map[uint32]int]
+	regionCustomers  derivedCache[map[uint32]int]
+	countryMap       derivedCache[*models.CountryMap]
+	// margins is keyed by dimension.
+	margins derivedCache[map[string][]models.Margin]
 }
 
 func NewAnalytics() *Analytics {
 	logger := slog.Default()
+	store := NewTransactionStore()
+	cube := &Cube{}
+	cube.index(store, nil)
 	return &Analytics{
-		precomputed: &PrecomputedData{},
+		precomputed: &PrecomputedData{Store: store, Cube: cube},
+		aliases:     DefaultAliases(),
 		logger:      logger,
+		updates:     make(chan struct{}),
 	}
 }
 
 func (a *Analytics) SetData(data []models.Transaction) {
+	// Convert transaction data to precomputed format for tests
+	precomputed := a.computeAnalytics(data)
+	precomputed.LastModified = time.Now()
+	a.replaceData(precomputed)
+}
+
+// SetAliases replaces the alias table country and region names are
+// canonicalized with. It applies from the next CSV load on.
+func (a *Analytics) SetAliases(t *AliasTable) {
+	a.aliases = t
+}
+
+// SetCatalog sets the product catalog CSV rows are joined to. It applies from
+// the next CSV load on.
+func (a *Analytics) SetCatalog(c *Catalog) {
+	a.catalog = c
+}
+
+// replaceData swaps in a new data set and wakes everyone waiting on Updates.
+func (a *Analytics) replaceData(precomputed *PrecomputedData) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	// Convert transaction data to precomputed format for tests
-	a.precomputed = a.computeAnalytics(data)
-	a.precomputed.RecordCount = int64(len(data))
-	a.precomputed.LastModified = time.Now()
+	a.precomputed = precomputed
+	close(a.updates)
+	a.updates = make(chan struct{})
+}
+
+// Updates returns a channel that is closed the next time the data set is
+// replaced, whether by a reload or SetData. Callers fetch a fresh channel after
+// each notification.
+func (a *Analytics) Updates() <-chan struct{} {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.updates
 }
 
 func (a *Analytics) LoadFromCSV(ctx context.Context, filename string) error {
 	a.csvPath = filename
+	fileInfo, statErr := os.Stat(filename)
 
 	// Check if we have a valid cache
 	if cached, err := a.loadFromCache(filename); err == nil {
-		fileInfo, err := os.Stat(filename)
-		if err == nil && fileInfo.ModTime().Before(cached.LastModified) {
-			a.mu.Lock()
-			a.precomputed = cached
-			a.mu.Unlock()
+		if statErr == nil && fileInfo.ModTime().Before(cached.LastModified) {
+			if cached.metrics, err = a.replayMetrics(ctx, cached.Store); err != nil {
+				return fmt.Errorf("replay metrics: %w", err)
+			}
+			a.csvModTime = fileInfo.ModTime()
+			a.replaceData(cached)
 			a.logger.Info("loaded from cache", "records", cached.RecordCount)
 			return nil
 		}
@@ -83,6 +175,10 @@ func (a *Analytics) LoadFromCSV(ctx context.Context, filename string) error {
 		return fmt.Errorf("process csv: %w", err)
 	}
 
+	if statErr == nil {
+		a.csvModTime = fileInfo.ModTime()
+	}
+
 	// Save to cache
 	if err := a.saveToCache(filename); err != nil {
 		a.logger.Warn("failed to save cache", "error", err)
@@ -114,10 +210,7 @@ func (a *Analytics) streamProcessCSV(ctx context.Context, filename string) error
 	}
 
 	// Aggregation maps for efficient processing
-	countryGroups := make(map[string]*models.CountryRevenue)
-	productGroups := make(map[string]*models.ProductFrequency)
-	monthlyGroups := make(map[string]float64)
-	regionGroups := make(map[string]*models.RegionRevenue)
+	groups := newAggregationGroups(a.newAggregators())
 
 	var mu sync.Mutex
 	recordCount := int64(0)
@@ -135,7 +228,7 @@ func (a *Analytics) streamProcessCSV(ctx context.Context, filename string) error
 		batch = append(batch, scanner.Text())
 
 		if len(batch) >= batchSize {
-			if err := a.processBatch(ctx, batch, &mu, countryGroups, productGroups, monthlyGroups, regionGroups, &recordCount); err != nil {
+			if err := a.processBatch(ctx, batch, &mu, groups, &recordCount); err != nil {
 				return err
 			}
 			batch = batch[:0] // Reset batch
@@ -144,7 +237,7 @@ func (a *Analytics) streamProcessCSV(ctx context.Context, filename string) error
 
 	// Process remaining records
 	if len(batch) > 0 {
-		if err := a.processBatch(ctx, batch, &mu, countryGroups, productGroups, monthlyGroups, regionGroups, &recordCount); err != nil {
+		if err := a.processBatch(ctx, batch, &mu, groups, &recordCount); err != nil {
 			return err
 		}
 	}
@@ -159,42 +252,33 @@ func (a *Analytics) streamProcessCSV(ctx context.Context, filename string) error
 	}
 
 	// Convert maps to sorted slices
-	precomputed := &PrecomputedData{
-		CountryRevenue: a.sortCountryRevenue(countryGroups),
-		TopProducts:    a.sortTopProducts(productGroups),
-		MonthlySales:   a.sortMonthlySales(monthlyGroups),
-		TopRegions:     a.sortTopRegions(regionGroups),
-		RecordCount:    recordCount,
-		LastModified:   time.Now(),
+	precomputed, err := a.buildPrecomputed(ctx, groups, groups.store, nil)
+	if err != nil {
+		return err
 	}
+	precomputed.RecordCount = recordCount
 
-	a.mu.Lock()
-	a.precomputed = precomputed
-	a.mu.Unlock()
+	a.replaceData(precomputed)
 
 	a.recordsProcessed.Store(recordCount)
 	return nil
 }
 
 func (a *Analytics) processBatch(ctx context.Context, batch []string, mu *sync.Mutex,
-	countryGroups map[string]*models.CountryRevenue,
-	productGroups map[string]*models.ProductFrequency,
-	monthlyGroups map[string]float64,
-	regionGroups map[string]*models.RegionRevenue,
-	recordCount *int64) error {
+	groups *aggregationGroups, recordCount *int64) error {
 
 	var wg errgroup.Group
 	wg.SetLimit(maxWorkers)
 
-	// Channel to collect processed transactions
+	// Parsed rows keep their position in the batch
 	type processedTx struct {
 		tx    models.Transaction
 		valid bool
 	}
 
-	txChan := make(chan processedTx, len(batch))
+	parsed := make([]processedTx, len(batch))
 
-	for _, line := range batch {
+	for i, line := range batch {
 		wg.Go(func() error {
 			select {
 			case <-ctx.Done():
@@ -205,42 +289,52 @@ func (a *Analytics) processBatch(ctx context.Context, batch []string, mu *sync.M
 			record := strings.Split(line, ",")
 			tx, err := parseTransactionFast(record)
 			if err != nil {
-				txChan <- processedTx{valid: false}
 				return nil // Skip invalid records
 			}
 
-			txChan <- processedTx{tx: tx, valid: true}
+			parsed[i] = processedTx{tx: tx, valid: true}
 			return nil
 		})
 	}
 
 	if err := wg.Wait(); err != nil {
-		close(txChan)
 		return err
 	}
-	close(txChan)
 
 	// Process all transactions sequentially to avoid race conditions
-	localCountry := make(map[string]*models.CountryRevenue)
-	localProduct := make(map[string]*models.ProductFrequency)
-	localMonthly := make(map[string]float64)
-	localRegion := make(map[string]*models.RegionRevenue)
-	localCount := int64(0)
+	local := newAggregationGroups(a.newAggregators())
+	rows := make([]models.Transaction, 0, len(batch))
 
-	for ptx := range txChan {
+	// Names are canonicalized in file order, so the spelling unknown regions
+	// are folded into does not depend on scheduling.
+	mu.Lock()
+	for i := range parsed {
+		if parsed[i].valid {
+			a.aliases.canonicalize(&parsed[i].tx, local.unmatched, groups.regionSpellings)
+		}
+	}
+	mu.Unlock()
+
+	for _, ptx := range parsed {
 		if ptx.valid {
-			a.aggregateTransaction(ptx.tx, localCountry, localProduct, localMonthly, localRegion)
-			localCount++
+			if a.catalog != nil {
+				a.catalog.join(&ptx.tx, local.missingCatalog)
+				if ptx.tx.ProductID == "" {
+					local.noProductID++
+				}
+			}
+			a.aggregateTransaction(ptx.tx, local)
+			rows = append(rows, ptx.tx)
 		}
 	}
 
 	// Merge local results into global maps
 	mu.Lock()
-	a.mergeResults(localCountry, countryGroups)
-	a.mergeProductResults(localProduct, productGroups)
-	a.mergeMonthlyResults(localMonthly, monthlyGroups)
-	a.mergeRegionResults(localRegion, regionGroups)
-	*recordCount += localCount
+	a.mergeGroups(local, groups)
+	for _, tx := range rows {
+		groups.store.Append(tx)
+	}
+	*recordCount += int64(len(rows))
 	mu.Unlock()
 
 	return nil
@@ -277,193 +371,295 @@ func parseTransactionFast(record []string) (models.Transaction, error) {
 		return models.Transaction{}, err
 	}
 
+	// The catalog addition date is optional; rows without a valid one are
+	// kept but left out of launch analytics.
+	var addedDate time.Time
+	if added := strings.TrimSpace(record[12]); added != "" {
+		if t, err := time.Parse("2006-01-02", added); err == nil {
+			addedDate = t
+		}
+	}
+
 	return models.Transaction{
 		Date:        transactionDate,
+		UserID:      strings.TrimSpace(record[2]),
 		Country:     strings.TrimSpace(record[3]),
 		Region:      strings.TrimSpace(record[4]),
+		ProductID:   strings.TrimSpace(record[5]),
 		ProductName: strings.TrimSpace(record[6]),
 		Category:    strings.TrimSpace(record[7]),
 		Price:       price,
 		Quantity:    quantity,
 		TotalPrice:  totalPrice,
 		Stock:       stock,
+		AddedDate:   addedDate,
 	}, nil
 }
 
-func (a *Analytics) aggregateTransaction(tx models.Transaction,
-	countryGroups map[string]*models.CountryRevenue,
-	productGroups map[string]*models.ProductFrequency,
-	monthlyGroups map[string]float64,
-	regionGroups map[string]*models.RegionRevenue) {
+// aggregationGroups holds the keyed partial aggregates built during ingestion.
+// Each batch fills its own set which is then merged into the global one.
+type aggregationGroups struct {
+	daily  map[string]*models.DailySales
+	dimMon map[string]map[string]map[string]models.PeriodTotals
+	dist   map[string]map[string]map[string]*tdigest.Digest
+	store  *TransactionStore
+	custom []Aggregator
+	// unmatched counts the names the alias table did not know.
+	unmatched *unmatchedNames
+	// regionSpellings holds the spelling each unknown region is folded
+	// into; see AliasTable.canonicalize.
+	regionSpellings map[[2]string]string
+	// missingCatalog totals the rows of products missing from the catalog
+	// by product ID.
+	missingCatalog map[string]*models.MissingCatalogEntry
+	// noProductID counts the rows the catalog could not be joined to.
+	noProductID int
+}
 
-	// Country revenue aggregation
-	countryKey := tx.Country + "|" + tx.ProductName + "|" + tx.Category
-	if countryGroups[countryKey] == nil {
-		countryGroups[countryKey] = &models.CountryRevenue{
-			Country:     tx.Country,
-			ProductName: tx.ProductName,
-			Category:    tx.Category,
-		}
-	}
-	countryGroups[countryKey].TotalRevenue += tx.TotalPrice
-	countryGroups[countryKey].Transactions++
+// newAggregationGroups starts an empty set; custom holds fresh aggregators
+// for the registered metrics.
+func newAggregationGroups(custom []Aggregator) *aggregationGroups {
+	return &aggregationGroups{
+		daily:  make(map[string]*models.DailySales),
+		dimMon: newDimensionMonthly(),
+		dist:   newDistributions(),
+		store:  NewTransactionStore(),
+		custom: custom,
 
-	// Product frequency aggregation
-	if productGroups[tx.ProductName] == nil {
-		productGroups[tx.ProductName] = &models.ProductFrequency{
-			ProductName:   tx.ProductName,
-			Category:      tx.Category,
-			StockQuantity: tx.Stock,
-		}
+		unmatched:       newUnmatchedNames(),
+		regionSpellings: make(map[[2]string]string),
+		missingCatalog:  make(map[string]*models.MissingCatalogEntry),
 	}
-	productGroups[tx.ProductName].Frequency++
+}
 
-	// Monthly sales aggregation
+func (a *Analytics) aggregateTransaction(tx models.Transaction, groups *aggregationGroups) {
+	day := tx.Date.Format(time.DateOnly)
 	month := tx.Date.Format("2006-01")
-	monthlyGroups[month] += tx.TotalPrice
 
-	// Region revenue aggregation
-	if regionGroups[tx.Region] == nil {
-		regionGroups[tx.Region] = &models.RegionRevenue{Region: tx.Region}
+	// Daily sales aggregation, the base for every time-series granularity
+	addDailySale(groups.daily, day, tx.TotalPrice, tx.Quantity)
+
+	// Per-dimension monthly totals feeding the growth metrics
+	for _, dim := range GrowthDimensions {
+		addDimensionMonthly(groups.dimMon[dim], dimensionValue(tx, dim), month, tx)
+	}
+
+	// Value distributions for quantiles and histograms
+	addDistributions(groups.dist, tx)
+
+	for _, agg := range groups.custom {
+		agg.Observe(tx)
 	}
-	regionGroups[tx.Region].Revenue += tx.TotalPrice
-	regionGroups[tx.Region].ItemsSold += tx.Quantity
 }
 
-func (a *Analytics) mergeResults(local, global map[string]*models.CountryRevenue) {
-	for k, v := range local {
-		if global[k] == nil {
-			global[k] = &models.CountryRevenue{
-				Country:     v.Country,
-				ProductName: v.ProductName,
-				Category:    v.Category,
-			}
-		}
-		global[k].TotalRevenue += v.TotalRevenue
-		global[k].Transactions += v.Transactions
+// addDailySale adds one transaction to the sales of its day.
+func addDailySale(daily map[string]*models.DailySales, day string, revenue float64, units int) {
+	if daily[day] == nil {
+		daily[day] = &models.DailySales{Date: day}
 	}
+	daily[day].Revenue += revenue
+	daily[day].Orders++
+	daily[day].Units += units
 }
 
-func (a *Analytics) mergeProductResults(local, global map[string]*models.ProductFrequency) {
-	for k, v := range local {
-		if global[k] == nil {
-			global[k] = &models.ProductFrequency{
-				ProductName:   v.ProductName,
-				Category:      v.Category,
-				StockQuantity: v.StockQuantity,
-			}
-		}
-		global[k].Frequency += v.Frequency
+func (a *Analytics) mergeGroups(local, global *aggregationGroups) {
+	a.mergeDailyResults(local.daily, global.daily)
+	a.mergeDimensionMonthly(local.dimMon, global.dimMon)
+	a.mergeDistributions(local.dist, global.dist)
+	mergeUnmatchedNames(local.unmatched, global.unmatched)
+	mergeMissingCatalog(local.missingCatalog, global.missingCatalog)
+	global.noProductID += local.noProductID
+	for i, agg := range global.custom {
+		agg.Merge(local.custom[i])
 	}
 }
 
-func (a *Analytics) mergeMonthlyResults(local, global map[string]float64) {
-	for k, v := range local {
-		global[k] += v
+// buildPrecomputed assembles a data set from the ingestion aggregates and a
+// cube over the store rows accepted by match, or all of them when match is
+// nil. The ranked views are roll-ups of the cube.
+func (a *Analytics) buildPrecomputed(ctx context.Context, groups *aggregationGroups, store *TransactionStore, match func(i int) bool) (*PrecomputedData, error) {
+	compressDistributions(groups.dist)
+
+	data, err := rankedViews(ctx, store, match)
+	if err != nil {
+		return nil, err
 	}
+	data.DailySales = a.sortDailySales(groups.daily)
+	data.LastModified = time.Now()
+	data.DimensionMonthly = groups.dimMon
+	data.Distributions = groups.dist
+	data.Store = groups.store
+	data.UnmatchedCountries = groups.unmatched.countries
+	data.UnmatchedRegions = groups.unmatched.regions
+	data.MissingCatalog = sortMissingCatalog(groups.missingCatalog)
+	data.NoProductID = groups.noProductID
+	data.metrics = finalizeMetrics(groups.custom)
+	return data, nil
 }
 
-func (a *Analytics) mergeRegionResults(local, global map[string]*models.RegionRevenue) {
+// rankedViews builds a cube over the store rows accepted by match, or all
+// of them when match is nil, and the ranked views rolled up from it.
+func rankedViews(ctx context.Context, store *TransactionStore, match func(i int) bool) (*PrecomputedData, error) {
+	cube, err := buildCube(ctx, store, match)
+	if err != nil {
+		return nil, err
+	}
+	stock, err := latestStock(ctx, store, match)
+	if err != nil {
+		return nil, err
+	}
+	countryRevenue, err := cubeCountryRevenue(ctx, cube)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrecomputedData{
+		CountryRevenue: countryRevenue,
+		TopProducts:    cubeTopProducts(cube, stock),
+		MonthlySales:   cubeMonthlySales(cube),
+		TopRegions:     cubeTopRegions(cube),
+		Cube:           cube,
+	}, nil
+}
+
+func (a *Analytics) mergeDailyResults(local, global map[string]*models.DailySales) {
 	for k, v := range local {
 		if global[k] == nil {
-			global[k] = &models.RegionRevenue{Region: v.Region}
+			global[k] = &models.DailySales{Date: v.Date}
 		}
 		global[k].Revenue += v.Revenue
-		global[k].ItemsSold += v.ItemsSold
+		global[k].Orders += v.Orders
+		global[k].Units += v.Units
 	}
 }
 
 func (a *Analytics) computeAnalytics(data []models.Transaction) *PrecomputedData {
-	countryGroups := make(map[string]*models.CountryRevenue)
-	productGroups := make(map[string]*models.ProductFrequency)
-	monthlyGroups := make(map[string]float64)
-	regionGroups := make(map[string]*models.RegionRevenue)
+	groups := newAggregationGroups(a.newAggregators())
 
 	for _, tx := range data {
-		a.aggregateTransaction(tx, countryGroups, productGroups, monthlyGroups, regionGroups)
+		a.aggregateTransaction(tx, groups)
+		groups.store.Append(tx)
 	}
 
-	return &PrecomputedData{
-		CountryRevenue: a.sortCountryRevenue(countryGroups),
-		TopProducts:    a.sortTopProducts(productGroups),
-		MonthlySales:   a.sortMonthlySales(monthlyGroups),
-		TopRegions:     a.sortTopRegions(regionGroups),
-		LastModified:   time.Now(),
-		RecordCount:    int64(len(data)),
-	}
+	// Nothing cancels the background context, so building cannot fail.
+	precomputed, _ := a.buildPrecomputed(context.Background(), groups, groups.store, nil)
+	precomputed.RecordCount = int64(len(data))
+	return precomputed
 }
 
-func (a *Analytics) sortCountryRevenue(groups map[string]*models.CountryRevenue) []models.CountryRevenue {
+func cubeCountryRevenue(ctx context.Context, cube *Cube) ([]models.CountryRevenue, error) {
+	groups, err := cube.rollUp(ctx, []int{cubeCountry, cubeCategory, cubeProduct}, [cubeDims][]uint32{})
+	if err != nil {
+		return nil, err
+	}
 	result := make([]models.CountryRevenue, 0, len(groups))
-	for _, cr := range groups {
-		result = append(result, *cr)
+	for key, t := range groups {
+		result = append(result, models.CountryRevenue{
+			Country:      cube.label(cubeCountry, key[cubeCountry]),
+			ProductID:    cube.store.ProductIDs[key[cubeProduct]],
+			ProductName:  cube.label(cubeProduct, key[cubeProduct]),
+			Category:     cube.label(cubeCategory, key[cubeCategory]),
+			TotalRevenue: t.Revenue,
+			Transactions: t.Orders,
+		})
 	}
 	slices.SortFunc(result, func(a, b models.CountryRevenue) int {
-		if a.TotalRevenue > b.TotalRevenue {
-			return -1
+		if c := cmp.Compare(b.TotalRevenue, a.TotalRevenue); c != 0 {
+			return c
 		}
-		if a.TotalRevenue < b.TotalRevenue {
-			return 1
+		if c := cmp.Compare(a.Country, b.Country); c != 0 {
+			return c
 		}
-		return 0
+		if c := cmp.Compare(a.ProductName, b.ProductName); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProductID, b.ProductID)
 	})
-	return result
+	return result, nil
 }
 
-func (a *Analytics) sortTopProducts(groups map[string]*models.ProductFrequency) []models.ProductFrequency {
-	result := make([]models.ProductFrequency, 0, len(groups))
-	for _, pf := range groups {
-		result = append(result, *pf)
+func cubeTopProducts(cube *Cube, stock []stockReading) []models.ProductFrequency {
+	result := make([]models.ProductFrequency, 0, len(cube.marginals[cubeProduct]))
+	for code, t := range cube.marginals[cubeProduct] {
+		r := stock[code]
+		result = append(result, models.ProductFrequency{
+			ProductID:     cube.store.ProductIDs[code],
+			ProductName:   cube.label(cubeProduct, code),
+			Category:      cube.label(cubeCategory, r.category),
+			Frequency:     t.Orders,
+			StockQuantity: int(r.stock),
+			StockDate:     dayTime(r.day).Format(time.DateOnly),
+		})
 	}
 	slices.SortFunc(result, func(a, b models.ProductFrequency) int {
-		if a.Frequency > b.Frequency {
-			return -1
+		if c := cmp.Compare(b.Frequency, a.Frequency); c != 0 {
+			return c
 		}
-		if a.Frequency < b.Frequency {
-			return 1
+		if c := cmp.Compare(a.ProductName, b.ProductName); c != 0 {
+			return c
 		}
-		return 0
+		return cmp.Compare(a.ProductID, b.ProductID)
 	})
 	return result
 }
 
-func (a *Analytics) sortMonthlySales(groups map[string]float64) []models.MonthlyData {
-	result := make([]models.MonthlyData, 0, len(groups))
-	for month, volume := range groups {
-		result = append(result, models.MonthlyData{Month: month, Volume: volume})
+func cubeMonthlySales(cube *Cube) []models.MonthlyData {
+	result := make([]models.MonthlyData, 0, len(cube.marginals[cubeMonth]))
+	for code, t := range cube.marginals[cubeMonth] {
+		result = append(result, models.MonthlyData{Month: cube.label(cubeMonth, code), Volume: t.Revenue})
 	}
 	slices.SortFunc(result, func(a, b models.MonthlyData) int {
-		if a.Volume > b.Volume {
-			return -1
+		if c := cmp.Compare(b.Volume, a.Volume); c != 0 {
+			return c
 		}
-		if a.Volume < b.Volume {
-			return 1
-		}
-		return 0
+		return cmp.Compare(a.Month, b.Month)
 	})
 	return result
 }
 
-func (a *Analytics) sortTopRegions(groups map[string]*models.RegionRevenue) []models.RegionRevenue {
-	result := make([]models.RegionRevenue, 0, len(groups))
-	for _, rr := range groups {
-		result = append(result, *rr)
+func cubeTopRegions(cube *Cube) []models.RegionRevenue {
+	result := make([]models.RegionRevenue, 0, len(cube.marginals[cubeRegion]))
+	for code, t := range cube.marginals[cubeRegion] {
+		result = append(result, models.RegionRevenue{
+			Country:   cube.store.CountryDict.Value(cube.store.RegionCountries[code]),
+			Region:    cube.label(cubeRegion, code),
+			Revenue:   t.Revenue,
+			ItemsSold: t.Units,
+		})
 	}
 	slices.SortFunc(result, func(a, b models.RegionRevenue) int {
-		if a.Revenue > b.Revenue {
-			return -1
+		if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+			return c
 		}
-		if a.Revenue < b.Revenue {
-			return 1
+		if c := cmp.Compare(a.Region, b.Region); c != 0 {
+			return c
 		}
-		return 0
+		return cmp.Compare(a.Country, b.Country)
+	})
+	return result
+}
+
+func (a *Analytics) sortDailySales(groups map[string]*models.DailySales) []models.DailySales {
+	result := make([]models.DailySales, 0, len(groups))
+	for _, ds := range groups {
+		result = append(result, *ds)
+	}
+	// ISO dates sort chronologically as strings
+	slices.SortFunc(result, func(a, b models.DailySales) int {
+		return strings.Compare(a.Date, b.Date)
 	})
 	return result
 }
 
 // Cache management
 func (a *Analytics) getCacheFilename(csvPath string) string {
-	return fmt.Sprintf("%s/%s_%s.gob", cacheDir, strings.ReplaceAll(csvPath, "/", "_"), cacheVersion)
+	// Rows are cached canonicalized and joined to the catalog, so another
+	// alias table or catalog needs its own cache.
+	tables := a.aliases.fingerprint
+	if a.catalog != nil {
+		tables += "_" + a.catalog.fingerprint
+	}
+	return fmt.Sprintf("%s/%s_%s_%s.gob", cacheDir, strings.ReplaceAll(csvPath, "/", "_"), cacheVersion, tables)
 }
 
 func (a *Analytics) saveToCache(csvPath string) error {
@@ -498,6 +694,14 @@ func (a *Analytics) loadFromCache(csvPath string) (*PrecomputedData, error) {
 	if err := decoder.Decode(&data); err != nil {
 		return nil, err
 	}
+	if data.Store == nil {
+		data.Store = NewTransactionStore()
+	}
+	data.Store.reindex()
+	if data.Cube == nil {
+		data.Cube = &Cube{}
+	}
+	data.Cube.index(data.Store, nil)
 
 	return &data, nil
 }
@@ -509,6 +713,112 @@ func (a *Analytics) CountryRevenue() []models.CountryRevenue {
 	return a.precomputed.CountryRevenue
 }
 
+// CountryRevenueQuery selects a window of the country revenue rows. Search is a
+// case-insensitive substring match on the product name, or an exact match on
+// the product ID.
+type CountryRevenueQuery struct {
+	Offset int
+	Limit  int
+	SortBy string
+	Desc   bool
+	Search string
+}
+
+const (
+	SortByRevenue      = "revenue"
+	SortByTransactions = "transactions"
+	SortByCountry      = "country"
+)
+
+// QueryCountryRevenue returns the requested page of country revenue rows along
+// with the number of rows matching the search before paging.
+func (a *Analytics) QueryCountryRevenue(q CountryRevenueQuery) ([]models.CountryRevenue, int) {
+	a.mu.RLock()
+	rows := a.precomputed.CountryRevenue
+	a.mu.RUnlock()
+
+	if q.Search != "" {
+		needle := strings.ToLower(q.Search)
+		matched := make([]models.CountryRevenue, 0)
+		for _, row := range rows {
+			if strings.Contains(strings.ToLower(row.ProductName), needle) || strings.EqualFold(row.ProductID, q.Search) {
+				matched = append(matched, row)
+			}
+		}
+		rows = matched
+	}
+
+	// Rows are stored by revenue descending, so only other orders need a copy
+	if q.SortBy != "" && (q.SortBy != SortByRevenue || !q.Desc) {
+		if q.Search == "" {
+			rows = slices.Clone(rows)
+		}
+		slices.SortStableFunc(rows, countryRevenueCompare(q.SortBy, q.Desc))
+	}
+
+	total := len(rows)
+	offset := min(max(q.Offset, 0), total)
+	if offset == total {
+		return []models.CountryRevenue{}, total
+	}
+	end := total
+	if q.Limit > 0 && q.Limit < total-offset {
+		end = offset + q.Limit
+	}
+	return rows[offset:end], total
+}
+
+// CountryRevenueMetric returns a copy of rows with MetricValue set to the
+// named derived metric over each row's country, category and product. It
+// returns ErrUnknownMetric for a metric that is not registered.
+func (a *Analytics) CountryRevenueMetric(ctx context.Context, rows []models.CountryRevenue, metric string) ([]models.CountryRevenue, error) {
+	m, ok := a.DerivedMetric(metric)
+	if !ok {
+		return nil, ErrUnknownMetric
+	}
+	store := a.current().Store
+	type key struct{ country, category, product string }
+	values, err := metricBy(ctx, store, m, nil, func(i int) key {
+		return key{
+			store.CountryDict.Value(store.Countries[i]),
+			store.CategoryDict.Value(store.Categories[i]),
+			store.ProductDict.Value(store.Products[i]),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := slices.Clone(rows)
+	for i := range result {
+		r := &result[i]
+		r.MetricValue = metricValue(values, key{r.Country, r.Category, productKey(r.ProductID, r.ProductName)})
+	}
+	return result, nil
+}
+
+func countryRevenueCompare(sortBy string, desc bool) func(a, b models.CountryRevenue) int {
+	return func(a, b models.CountryRevenue) int {
+		var c int
+		switch sortBy {
+		case SortByTransactions:
+			c = cmp.Compare(a.Transactions, b.Transactions)
+		case SortByCountry:
+			c = strings.Compare(a.Country, b.Country)
+			if c == 0 {
+				// Within a country keep the biggest earners first
+				return cmp.Compare(b.TotalRevenue, a.TotalRevenue)
+			}
+		default:
+			c = cmp.Compare(a.TotalRevenue, b.TotalRevenue)
+		}
+		if desc {
+			return -c
+		}
+		return c
+	}
+}
+
 func (a *Analytics) TopProducts(limit int) []models.ProductFrequency {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
@@ -547,5 +857,12 @@ func (a *Analytics) Stats() map[string]any {
 		"products":       len(a.precomputed.TopProducts),
 		"months":         len(a.precomputed.MonthlySales),
 		"regions":        len(a.precomputed.TopRegions),
+		// Names the alias table did not know, with their row counts, so
+		// they can be fixed upstream or added to the table.
+		"unmatched_countries": a.precomputed.UnmatchedCountries,
+		"unmatched_regions":   a.precomputed.UnmatchedRegions,
+		// Products sold without a catalog entry have no cost.
+		"missing_catalog_products": len(a.precomputed.MissingCatalog),
+		"rows_without_product_id":  a.precomputed.NoProductID,
 	}
 }
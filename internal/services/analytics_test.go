@@ -2,7 +2,9 @@ package services
 
 import (
 	"context"
+	"math"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
@@ -244,6 +246,128 @@ func TestAnalytics_TopProducts(t *testing.T) {
 	}
 }
 
+func TestAnalytics_ProductsKeyedByID(t *testing.T) {
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Date: time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC), Country: "USA", Region: "Texas", ProductID: "P1", ProductName: "Laptop", Category: "Electronics", TotalPrice: 100},
+		{Date: time.Date(2023, 1, 20, 0, 0, 0, 0, time.UTC), Country: "USA", Region: "Texas", ProductID: "P1", ProductName: "Laptop Pro", Category: "Electronics", TotalPrice: 100},
+		{Date: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), Country: "USA", Region: "Texas", ProductID: "P2", ProductName: "Cable", Category: "Electronics", TotalPrice: 30, Stock: 5, AddedDate: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Date: time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC), Country: "USA", Region: "Texas", ProductID: "P3", ProductName: "Cable", Category: "Electronics", TotalPrice: 20, Stock: 5, AddedDate: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	ctx := context.Background()
+
+	products := a.TopProducts(10)
+	if len(products) != 3 || products[0].ProductID != "P1" || products[0].ProductName != "Laptop Pro" || products[0].Frequency != 2 {
+		t.Fatalf("TopProducts() = %+v, want the renamed P1 first with both sales", products)
+	}
+	if products[1].ProductID != "P2" || products[2].ProductID != "P3" {
+		t.Errorf("products sharing a name should stay apart, got %+v", products[1:])
+	}
+
+	rows := a.CountryRevenue()
+	if len(rows) != 3 || rows[0].ProductID != "P1" || rows[0].TotalRevenue != 200 {
+		t.Errorf("CountryRevenue() = %+v, want P1 with 200", rows)
+	}
+
+	growth, err := a.Growth(ctx, DimensionProduct, "2023-01", MetricRevenue)
+	if err != nil {
+		t.Fatalf("Growth() error = %v", err)
+	}
+	if len(growth) != 3 || growth[0].Value != "Laptop Pro" || growth[0].ProductID != "P1" || growth[0].Current != 200 {
+		t.Errorf("Growth() = %+v, want Laptop Pro (P1) with 200", growth)
+	}
+	if growth[1].ProductID != "P2" || growth[2].ProductID != "P3" {
+		t.Errorf("Growth() = %+v, want the two Cables told apart by ID", growth[1:])
+	}
+
+	// Every product view carries the ID, so two Cables are not duplicates.
+	cables := func(ids ...string) bool {
+		return slices.Equal(ids, []string{"P2", "P3"})
+	}
+	inventory, err := a.Inventory(ctx)
+	if err != nil {
+		t.Fatalf("Inventory() error = %v", err)
+	}
+	var ids []string
+	for _, item := range inventory {
+		if item.ProductName == "Cable" {
+			ids = append(ids, item.ProductID)
+		}
+	}
+	if !cables(ids...) {
+		t.Errorf("Inventory() = %+v, want both Cables by ID", inventory)
+	}
+	launches, err := a.Launches(ctx)
+	if err != nil || len(launches) != 2 || !cables(launches[0].ProductID, launches[1].ProductID) {
+		t.Errorf("Launches() = %+v, %v, want both Cables by ID", launches, err)
+	}
+	comparison, err := a.Compare(ctx,
+		Query{From: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)},
+		Query{From: time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)}, 10)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if p := comparison.TopProducts; len(p) != 3 || p[0].ProductID != "P1" || !cables(p[1].ProductID, p[2].ProductID) {
+		t.Errorf("compared products = %+v, want P1, P2, P3", p)
+	}
+	if r := comparison.CountryRevenue; len(r) != 3 || r[0].ProductID != "P1" || !cables(r[1].ProductID, r[2].ProductID) {
+		t.Errorf("compared country rows = %+v, want P1, P2, P3", r)
+	}
+
+	node, err := a.Drilldown(context.Background(), []string{"USA", "Texas"}, "")
+	if err != nil {
+		t.Fatalf("Drilldown() error = %v", err)
+	}
+	if c := node.Children[0]; c.Name != "Laptop Pro" || c.Path != "USA/Texas/P1" {
+		t.Errorf("child = %+v, want a path by product ID", c)
+	}
+	leaf, err := a.Drilldown(context.Background(), []string{"USA", "Texas", "P1"}, "")
+	if err != nil || leaf.Name != "Laptop Pro" || leaf.Revenue != 200 {
+		t.Errorf("Drilldown(P1) = %+v, %v", leaf, err)
+	}
+}
+
+func TestAnalytics_RegionsKeyedByCountry(t *testing.T) {
+	a := NewAnalytics()
+	jan := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	a.SetData([]models.Transaction{
+		{Date: jan, Country: "India", Region: "Punjab", UserID: "U1", ProductName: "Tea", Category: "Food", Quantity: 1, TotalPrice: 100},
+		{Date: jan, Country: "India", Region: "Punjab", UserID: "U2", ProductName: "Tea", Category: "Food", Quantity: 1, TotalPrice: 50},
+		{Date: jan, Country: "Pakistan", Region: "Punjab", UserID: "U3", ProductName: "Tea", Category: "Food", Quantity: 1, TotalPrice: 30},
+	})
+	ctx := context.Background()
+
+	punjabs := func(countries ...string) bool {
+		return slices.Equal(countries, []string{"India", "Pakistan"})
+	}
+	regions := a.TopRegions(10)
+	if len(regions) != 2 || !punjabs(regions[0].Country, regions[1].Country) || regions[0].Revenue != 150 {
+		t.Errorf("TopRegions() = %+v, want Punjab per country", regions)
+	}
+	ranked, err := a.RankedRegions(ctx, RankByCustomers, 10)
+	if err != nil || len(ranked) != 2 || !punjabs(ranked[0].Country, ranked[1].Country) || ranked[0].Customers != 2 {
+		t.Errorf("RankedRegions() = %+v, %v, want Punjab per country", ranked, err)
+	}
+	metrics, err := a.CustomerMetrics(ctx, DimensionRegion)
+	if err != nil || len(metrics) != 2 || !punjabs(metrics[0].Country, metrics[1].Country) {
+		t.Errorf("CustomerMetrics() = %+v, %v, want Punjab per country", metrics, err)
+	}
+	growth, err := a.Growth(ctx, DimensionRegion, "2023-01", MetricRevenue)
+	if err != nil || len(growth) != 2 || growth[0].Value != "Punjab" || !punjabs(growth[0].Country, growth[1].Country) {
+		t.Errorf("Growth() = %+v, %v, want Punjab per country", growth, err)
+	}
+
+	// A region name alone still filters every region of that name.
+	snapshot, err := a.Snapshot(ctx, Query{Region: "Punjab"})
+	if err != nil || snapshot.RecordCount != 3 {
+		t.Errorf("Snapshot(Punjab) = %v rows, %v, want 3", snapshot.RecordCount, err)
+	}
+	node, err := a.Drilldown(ctx, []string{"Pakistan", "Punjab"}, "")
+	if err != nil || node.Revenue != 30 {
+		t.Errorf("Drilldown(Pakistan/Punjab) = %+v, %v, want 30", node, err)
+	}
+}
+
 func TestAnalytics_MonthlySales(t *testing.T) {
 	a := NewAnalytics()
 	testData := []models.Transaction{
@@ -428,3 +552,70 @@ func BenchmarkAnalytics_TopProducts(b *testing.B) {
 		_ = a.TopProducts(20)
 	}
 }
+
+func TestAnalytics_QueryCountryRevenue(t *testing.T) {
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Country: "USA", ProductName: "Laptop", Category: "Electronics", TotalPrice: 900},
+		{Country: "USA", ProductName: "Laptop", Category: "Electronics", TotalPrice: 900},
+		{Country: "Canada", ProductName: "Mouse", Category: "Electronics", TotalPrice: 30},
+		{Country: "Germany", ProductName: "Gaming Laptop", Category: "Electronics", TotalPrice: 1500},
+	})
+
+	t.Run("paging", func(t *testing.T) {
+		rows, total := a.QueryCountryRevenue(CountryRevenueQuery{Offset: 1, Limit: 1, SortBy: SortByRevenue, Desc: true})
+		if total != 3 {
+			t.Errorf("total = %d, want 3", total)
+		}
+		if len(rows) != 1 || rows[0].Country != "Germany" {
+			t.Errorf("second page = %+v, want Germany row", rows)
+		}
+	})
+
+	t.Run("offset past end", func(t *testing.T) {
+		rows, total := a.QueryCountryRevenue(CountryRevenueQuery{Offset: 10, Limit: 5})
+		if total != 3 || len(rows) != 0 {
+			t.Errorf("got %d rows, total %d", len(rows), total)
+		}
+	})
+
+	t.Run("out of range offset and limit", func(t *testing.T) {
+		if rows, _ := a.QueryCountryRevenue(CountryRevenueQuery{Offset: -5, Limit: math.MaxInt}); len(rows) != 3 {
+			t.Errorf("got %d rows, want the offset clamped to 0", len(rows))
+		}
+	})
+
+	t.Run("sort by country ascending", func(t *testing.T) {
+		rows, _ := a.QueryCountryRevenue(CountryRevenueQuery{SortBy: SortByCountry})
+		want := []string{"Canada", "Germany", "USA"}
+		for i, row := range rows {
+			if row.Country != want[i] {
+				t.Errorf("rows[%d].Country = %q, want %q", i, row.Country, want[i])
+			}
+		}
+	})
+
+	t.Run("sort by transactions descending", func(t *testing.T) {
+		rows, _ := a.QueryCountryRevenue(CountryRevenueQuery{SortBy: SortByTransactions, Desc: true})
+		if rows[0].Transactions != 2 {
+			t.Errorf("first row transactions = %d, want 2", rows[0].Transactions)
+		}
+	})
+
+	t.Run("search is case insensitive", func(t *testing.T) {
+		rows, total := a.QueryCountryRevenue(CountryRevenueQuery{SortBy: SortByRevenue, Desc: true, Search: "laptop"})
+		if total != 2 {
+			t.Fatalf("total = %d, want 2", total)
+		}
+		if rows[0].ProductName != "Laptop" || rows[1].ProductName != "Gaming Laptop" {
+			t.Errorf("matches = %+v, want Laptop then Gaming Laptop", rows)
+		}
+	})
+
+	t.Run("does not reorder stored rows", func(t *testing.T) {
+		a.QueryCountryRevenue(CountryRevenueQuery{SortBy: SortByRevenue})
+		if stored := a.CountryRevenue(); stored[0].TotalRevenue < stored[1].TotalRevenue {
+			t.Error("ascending query should not reorder the precomputed slice")
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+const (
+	// anomalyThreshold is the modified z-score beyond which a day is flagged,
+	// following Iglewicz and Hoaglin.
+	anomalyThreshold = 3.5
+	// anomalyWindowDays is how many of the most recent days are checked.
+	anomalyWindowDays = 7
+	// baselineWeeks is how many preceding same-weekday values form the
+	// baseline, and minBaselinePoints how many of them must exist.
+	baselineWeeks     = 8
+	minBaselinePoints = 4
+	// madScale makes the median absolute deviation consistent with the
+	// standard deviation of normally distributed data.
+	madScale = 1.4826
+	// minRelativeSpread floors the spread at a fraction of the baseline so a
+	// perfectly steady series does not flag every small wobble.
+	minRelativeSpread = 0.1
+)
+
+const (
+	DirectionSpike = "spike"
+	DirectionDrop  = "drop"
+)
+
+var (
+	AnomalyDimensions = []string{DimensionCountry, DimensionCategory}
+	AnomalyMetrics    = []string{MetricRevenue, MetricOrders}
+)
+
+// Anomalies returns the days in the most recent week whose revenue or order
+// count for a country or category deviates sharply from the same weekday in
+// the preceding weeks. Days without sales count as zero, so a series that
+// stops reporting shows up as a drop. The result is computed once per data
+// set and ordered by date, newest first, then by severity.
+func (a *Analytics) Anomalies(ctx context.Context) ([]models.Anomaly, error) {
+	precomputed := a.current()
+	return a.anomalies.get(precomputed, func() ([]models.Anomaly, error) {
+		return detectAnomalies(ctx, precomputed.Store)
+	})
+}
+
+func detectAnomalies(ctx context.Context, store *TransactionStore) ([]models.Anomaly, error) {
+	result := make([]models.Anomaly, 0)
+	if store.Len() == 0 {
+		return result, nil
+	}
+
+	lastDay := slices.Max(store.Dates)
+	windowStart := lastDay - anomalyWindowDays + 1
+	historyStart := windowStart - baselineWeeks*7
+	span := int(lastDay-historyStart) + 1
+
+	for _, dim := range AnomalyDimensions {
+		column, labels := store.dimensionColumn(dim)
+
+		// Daily revenue and orders per value over the history span, and the
+		// first day each value ever sold so gaps before it are not zeros.
+		revenue := make([][]float64, len(labels))
+		orders := make([][]float64, len(labels))
+		firstDay := make([]int32, len(labels))
+		for code := range labels {
+			revenue[code] = make([]float64, span)
+			orders[code] = make([]float64, span)
+			firstDay[code] = math.MaxInt32
+		}
+
+		for i := range store.Len() {
+			if i%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			code, day := column[i], store.Dates[i]
+			firstDay[code] = min(firstDay[code], day)
+			if day >= historyStart {
+				revenue[code][day-historyStart] += store.Totals[i]
+				orders[code][day-historyStart]++
+			}
+		}
+
+		for code, value := range labels {
+			for metric, series := range map[string][]float64{MetricRevenue: revenue[code], MetricOrders: orders[code]} {
+				for day := windowStart; day <= lastDay; day++ {
+					anomaly, ok := scoreDay(series, historyStart, firstDay[code], day)
+					if !ok {
+						continue
+					}
+					anomaly.Dimension = dim
+					anomaly.Value = value
+					anomaly.Metric = metric
+					anomaly.Date = dayTime(day).Format(time.DateOnly)
+					result = append(result, anomaly)
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(result, func(a, b models.Anomaly) int {
+		if c := cmp.Compare(b.Date, a.Date); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(math.Abs(b.Score), math.Abs(a.Score)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Dimension+a.Value+a.Metric, b.Dimension+b.Value+b.Metric)
+	})
+	return result, nil
+}
+
+// scoreDay compares one day of a series against the same weekday in the
+// preceding weeks and reports it when the modified z-score crosses the
+// threshold.
+func scoreDay(series []float64, historyStart, firstDay, day int32) (models.Anomaly, bool) {
+	baseline := make([]float64, 0, baselineWeeks)
+	for w := int32(1); w <= baselineWeeks; w++ {
+		d := day - 7*w
+		if d < firstDay || d < historyStart {
+			break
+		}
+		baseline = append(baseline, series[d-historyStart])
+	}
+	if len(baseline) < minBaselinePoints {
+		return models.Anomaly{}, false
+	}
+
+	expected := median(baseline)
+	deviations := make([]float64, len(baseline))
+	for i, v := range baseline {
+		deviations[i] = math.Abs(v - expected)
+	}
+	spread := max(madScale*median(deviations), minRelativeSpread*math.Abs(expected))
+	if spread == 0 {
+		return models.Anomaly{}, false
+	}
+
+	actual := series[day-historyStart]
+	score := (actual - expected) / spread
+	if math.Abs(score) < anomalyThreshold {
+		return models.Anomaly{}, false
+	}
+
+	direction := DirectionSpike
+	if score < 0 {
+		direction = DirectionDrop
+	}
+	return models.Anomaly{Actual: actual, Expected: expected, Score: score, Direction: direction}, true
+}
+
+// median sorts values in place.
+func median(values []float64) float64 {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+// steadySales returns daily sales for USA and Canada over ten weeks ending on
+// 2023-03-12, with USA selling nothing on the final two days.
+func steadySales() []models.Transaction {
+	var txs []models.Transaction
+	start := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2023, 3, 12, 0, 0, 0, 0, time.UTC)
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if d.Before(end.AddDate(0, 0, -1)) {
+			txs = append(txs, models.Transaction{Date: d, Country: "USA", Category: "Electronics", TotalPrice: 1000 + float64(d.Day()%3)*10, Quantity: 1})
+		}
+		txs = append(txs, models.Transaction{Date: d, Country: "Canada", Category: "Books", TotalPrice: 200, Quantity: 1})
+	}
+	return txs
+}
+
+func TestAnalytics_Anomalies_DetectsDrop(t *testing.T) {
+	a := NewAnalytics()
+	a.SetData(steadySales())
+
+	anomalies, err := a.Anomalies(context.Background())
+	if err != nil {
+		t.Fatalf("Anomalies() error = %v", err)
+	}
+
+	// USA and its only category drop to zero on both days, in both metrics.
+	if len(anomalies) != 8 {
+		t.Fatalf("expected 8 anomalies, got %d: %+v", len(anomalies), anomalies)
+	}
+	for _, an := range anomalies {
+		if an.Direction != DirectionDrop || an.Actual != 0 {
+			t.Errorf("expected a drop to zero, got %+v", an)
+		}
+		if an.Value != "USA" && an.Value != "Electronics" {
+			t.Errorf("unexpected anomaly for %q", an.Value)
+		}
+	}
+	if anomalies[0].Date != "2023-03-12" || anomalies[len(anomalies)-1].Date != "2023-03-11" {
+		t.Errorf("expected newest first, got %s..%s", anomalies[0].Date, anomalies[len(anomalies)-1].Date)
+	}
+}
+
+func TestAnalytics_Anomalies_DetectsSpike(t *testing.T) {
+	txs := steadySales()
+	txs = append(txs, models.Transaction{Date: time.Date(2023, 3, 12, 0, 0, 0, 0, time.UTC), Country: "Canada", Category: "Books", TotalPrice: 5000, Quantity: 1})
+
+	a := NewAnalytics()
+	a.SetData(txs)
+
+	anomalies, err := a.Anomalies(context.Background())
+	if err != nil {
+		t.Fatalf("Anomalies() error = %v", err)
+	}
+
+	var found bool
+	for _, an := range anomalies {
+		if an.Value == "Canada" && an.Metric == MetricRevenue {
+			found = true
+			if an.Direction != DirectionSpike || an.Expected != 200 || an.Actual != 5200 {
+				t.Errorf("unexpected Canada anomaly %+v", an)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected a Canada revenue spike")
+	}
+}
+
+func TestAnalytics_Anomalies_ShortHistory(t *testing.T) {
+	a := timeSeriesTestAnalytics()
+
+	anomalies, err := a.Anomalies(context.Background())
+	if err != nil {
+		t.Fatalf("Anomalies() error = %v", err)
+	}
+	if len(anomalies) != 0 {
+		t.Errorf("expected no anomalies without a baseline, got %+v", anomalies)
+	}
+}
+
+func TestAnalytics_UpdatesNotifiesOnReplace(t *testing.T) {
+	a := NewAnalytics()
+	updates := a.Updates()
+
+	select {
+	case <-updates:
+		t.Fatal("updates should not fire before data changes")
+	default:
+	}
+
+	a.SetData(steadySales())
+
+	select {
+	case <-updates:
+	case <-time.After(time.Second):
+		t.Fatal("expected notification after SetData")
+	}
+	if a.Updates() == updates {
+		t.Error("expected a fresh channel after notification")
+	}
+}
+
+func TestAnalytics_WatchCSV(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+	header := "TransactionID,Date,UserID,Country,Region,ProductID,ProductName,Category,Price,Quantity,TotalPrice,Stock,AddedDate\n"
+	row := "T1,2023-01-15,U1,USA,California,P1,Laptop,Electronics,10,1,10,5,2023-01-01\n"
+	if err := os.WriteFile(path, []byte(header+row), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	a := NewAnalytics()
+	if err := a.LoadFromCSV(context.Background(), path); err != nil {
+		t.Fatalf("LoadFromCSV() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := a.Updates()
+	go a.WatchCSV(ctx, 10*time.Millisecond, time.Second)
+
+	later := time.Now().Add(time.Hour)
+	if err := os.WriteFile(path, []byte(header+row+row), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-updates:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a reload after the CSV changed")
+	}
+	if got := a.Stats()["record_count"]; got != int64(2) {
+		t.Errorf("record_count = %v, want 2", got)
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"abt-dashboard/internal/models"
+)
+
+// Catalog is the product catalog transactions are joined to on product_id.
+// It supplies what the sales data lacks: unit cost, brand and subcategory.
+type Catalog struct {
+	entries map[string]models.CatalogEntry
+	// fingerprint identifies the catalog's contents, so data joined with
+	// another catalog is not mistaken for current.
+	fingerprint string
+}
+
+// catalogColumns are the columns a catalog file must have; brand and
+// subcategory may be empty.
+var catalogColumns = []string{"product_id", "cost", "brand", "subcategory"}
+
+// LoadCatalog reads a product catalog CSV with a header naming the columns
+// product_id, cost, brand and subcategory in any order. Costs are per unit
+// and may not be negative.
+func LoadCatalog(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read catalog: %w", err)
+	}
+	c, err := parseCatalog(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.fingerprint = fingerprint(string(data))
+	return c, nil
+}
+
+func parseCatalog(r io.Reader) (*Catalog, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	index := make([]int, len(catalogColumns))
+	for i, name := range catalogColumns {
+		col, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+		index[i] = col
+	}
+
+	c := &Catalog{entries: make(map[string]models.CatalogEntry)}
+	for line := 2; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		id := strings.TrimSpace(rec[index[0]])
+		if id == "" {
+			return nil, fmt.Errorf("line %d: product_id is empty", line)
+		}
+		if _, ok := c.entries[id]; ok {
+			return nil, fmt.Errorf("line %d: duplicate product_id %q", line, id)
+		}
+		cost, err := strconv.ParseFloat(strings.TrimSpace(rec[index[1]]), 64)
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("line %d: invalid cost %q", line, rec[index[1]])
+		}
+		c.entries[id] = models.CatalogEntry{
+			ProductID:   id,
+			Cost:        cost,
+			Brand:       strings.TrimSpace(rec[index[2]]),
+			Subcategory: strings.TrimSpace(rec[index[3]]),
+		}
+	}
+	return c, nil
+}
+
+// Len returns the number of products in the catalog.
+func (c *Catalog) Len() int {
+	return len(c.entries)
+}
+
+// Lookup returns the catalog entry of a product.
+func (c *Catalog) Lookup(productID string) (models.CatalogEntry, bool) {
+	e, ok := c.entries[productID]
+	return e, ok
+}
+
+// join fills in the catalog fields of tx and, for products the catalog does
+// not know, adds the row to missing by product ID. Rows without a product ID
+// cannot be joined and are left for the caller to count.
+func (c *Catalog) join(tx *models.Transaction, missing map[string]*models.MissingCatalogEntry) {
+	if tx.ProductID == "" {
+		return
+	}
+	if e, ok := c.entries[tx.ProductID]; ok {
+		tx.Cataloged = true
+		tx.UnitCost = e.Cost
+		tx.Brand = e.Brand
+		tx.Subcategory = e.Subcategory
+		return
+	}
+	m := missing[tx.ProductID]
+	if m == nil {
+		m = &models.MissingCatalogEntry{ProductID: tx.ProductID}
+		missing[tx.ProductID] = m
+	}
+	m.ProductName = firstName(m.ProductName, tx.ProductName)
+	m.Transactions++
+	m.Revenue += tx.TotalPrice
+}
+
+func mergeMissingCatalog(local, global map[string]*models.MissingCatalogEntry) {
+	for id, l := range local {
+		g := global[id]
+		if g == nil {
+			global[id] = l
+			continue
+		}
+		g.ProductName = firstName(g.ProductName, l.ProductName)
+		g.Transactions += l.Transactions
+		g.Revenue += l.Revenue
+	}
+}
+
+// firstName picks one of the names rows of a product carry, whatever order
+// the batches are merged in.
+func firstName(a, b string) string {
+	if a == "" || (b != "" && b < a) {
+		return b
+	}
+	return a
+}
+
+// sortMissingCatalog lists the products missing from the catalog, the
+// biggest revenue first.
+func sortMissingCatalog(missing map[string]*models.MissingCatalogEntry) []models.MissingCatalogEntry {
+	result := make([]models.MissingCatalogEntry, 0, len(missing))
+	for _, m := range missing {
+		result = append(result, *m)
+	}
+	slices.SortFunc(result, func(a, b models.MissingCatalogEntry) int {
+		if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+	return result
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCatalog(t *testing.T) {
+	c, err := parseCatalog(strings.NewReader("brand,product_id,subcategory,cost\nAcme, P1 ,Laptops,600\n,P2,,0\n"))
+	if err != nil {
+		t.Fatalf("parseCatalog() error = %v", err)
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 products, got %d", c.Len())
+	}
+	if e, ok := c.Lookup("P1"); !ok || e.Cost != 600 || e.Brand != "Acme" || e.Subcategory != "Laptops" {
+		t.Errorf("Lookup(P1) = %+v, %v", e, ok)
+	}
+	if e, ok := c.Lookup("P2"); !ok || e.Cost != 0 || e.Brand != "" {
+		t.Errorf("Lookup(P2) = %+v, %v; brand may be empty and cost zero", e, ok)
+	}
+
+	for name, data := range map[string]string{
+		"missing column": "product_id,cost,brand\nP1,1,Acme\n",
+		"empty id":       "product_id,cost,brand,subcategory\n,1,Acme,\n",
+		"duplicate id":   "product_id,cost,brand,subcategory\nP1,1,,\nP1,2,,\n",
+		"bad cost":       "product_id,cost,brand,subcategory\nP1,cheap,,\n",
+		"negative cost":  "product_id,cost,brand,subcategory\nP1,-1,,\n",
+		"empty":          "",
+	} {
+		if _, err := parseCatalog(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestAnalytics_LoadFromCSV_JoinsCatalog(t *testing.T) {
+	catalogPath := filepath.Join(t.TempDir(), "catalog.csv")
+	catalog := "product_id,cost,brand,subcategory\nP001,600,Acme,Laptops\nP002,10,Clicky,Mice\n"
+	if err := os.WriteFile(catalogPath, []byte(catalog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+
+	f := createTempCSV(t, `transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock,added_date
+T001,2023-01-15,U001,USA,California,P001,Laptop,Electronics,1000,1,1000,50,2023-01-01
+T002,2023-01-16,U002,Canada,Ontario,P002,Mouse,Electronics,25,2,50,100,2023-01-01
+T003,2023-01-17,U003,Canada,Ontario,P009,Cable,Electronics,5,4,20,100,2023-01-01
+T004,2023-01-18,U004,Canada,Ontario,P009,Cable,Electronics,5,2,10,100,2023-01-01`)
+	defer os.Remove(f)
+
+	a := NewAnalytics()
+	a.SetCatalog(c)
+	if err := a.LoadFromCSV(context.Background(), f); err != nil {
+		t.Fatalf("LoadFromCSV() error = %v", err)
+	}
+	defer os.Remove(a.getCacheFilename(f))
+
+	ctx := context.Background()
+	countries, err := a.Margins(ctx, DimensionCountry)
+	if err != nil {
+		t.Fatalf("Margins() error = %v", err)
+	}
+	if len(countries) != 2 {
+		t.Fatalf("expected 2 countries, got %+v", countries)
+	}
+	us, ca := countries[0], countries[1]
+	if us.Value != "United States" || us.Cost != 600 || us.GrossMargin != 400 || us.MarginPercent == nil || *us.MarginPercent != 40 {
+		t.Errorf("US margin = %+v, want 400 on 1000", us)
+	}
+	if ca.Revenue != 80 || ca.CostedRevenue != 50 || ca.UncostedRevenue != 30 || ca.Cost != 20 || ca.GrossMargin != 30 {
+		t.Errorf("Canada margin = %+v, want 30 on the 50 costed", ca)
+	}
+
+	brands, err := a.Margins(ctx, DimensionBrand)
+	if err != nil {
+		t.Fatalf("Margins() error = %v", err)
+	}
+	if len(brands) != 3 || brands[0].Value != "Acme" || brands[2].Value != "" || brands[2].MarginPercent != nil {
+		t.Errorf("brands = %+v, want Acme first and the uncataloged rows without a margin", brands)
+	}
+	if _, err := a.Margins(ctx, DimensionRegion); err == nil {
+		t.Error("expected an error for an unsupported dimension")
+	}
+
+	missing := a.MissingCatalog()
+	if len(missing) != 1 || missing[0].ProductID != "P009" || missing[0].ProductName != "Cable" || missing[0].Transactions != 2 || missing[0].Revenue != 30 {
+		t.Errorf("missing = %+v, want P009 in 2 rows", missing)
+	}
+	if n := a.Stats()["missing_catalog_products"]; n != 1 {
+		t.Errorf("missing_catalog_products = %v, want 1", n)
+	}
+
+	// Rows come back from the store with their catalog fields.
+	store := a.current().Store
+	for i := range store.Len() {
+		tx := store.Row(i)
+		switch tx.ProductName {
+		case "Laptop":
+			if !tx.Cataloged || tx.UnitCost != 600 || tx.Brand != "Acme" || tx.Subcategory != "Laptops" {
+				t.Errorf("stored laptop row = %+v", tx)
+			}
+		case "Cable":
+			if tx.Cataloged || tx.Brand != "" {
+				t.Errorf("stored cable row = %+v, should not be cataloged", tx)
+			}
+		}
+	}
+}
+
+func TestAnalytics_LoadFromCSV_CatalogWithoutProductID(t *testing.T) {
+	c, err := parseCatalog(strings.NewReader("product_id,cost,brand,subcategory\nP001,600,Acme,Laptops\n"))
+	if err != nil {
+		t.Fatalf("parseCatalog() error = %v", err)
+	}
+
+	f := createTempCSV(t, `transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock,added_date
+T001,2023-01-15,U001,USA,California,P001,Laptop,Electronics,1000,1,1000,50,2023-01-01
+T002,2023-01-16,U002,Canada,Ontario,,Mouse,Electronics,25,2,50,100,2023-01-01
+T003,2023-01-17,U003,Canada,Ontario,,Cable,Electronics,5,4,20,100,2023-01-01`)
+	defer os.Remove(f)
+
+	a := NewAnalytics()
+	a.SetCatalog(c)
+	if err := a.LoadFromCSV(context.Background(), f); err != nil {
+		t.Fatalf("LoadFromCSV() error = %v", err)
+	}
+	defer os.Remove(a.getCacheFilename(f))
+
+	// Rows without a product ID are counted, not reported as one product.
+	if missing := a.MissingCatalog(); len(missing) != 0 {
+		t.Errorf("missing = %+v, want none", missing)
+	}
+	if n := a.Stats()["rows_without_product_id"]; n != 2 {
+		t.Errorf("rows_without_product_id = %v, want 2", n)
+	}
+}
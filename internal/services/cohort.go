@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+// Cohorts groups customers by the month of their first purchase and tracks,
+// for every following month up to the last month of data, how many of them
+// bought again and how much the cohort spent. With a country, only purchases
+// in that country count, including the one that defines the cohort. Rows
+// without a user_id belong to no customer and are left out. Cohorts are
+// returned oldest first.
+func (a *Analytics) Cohorts(ctx context.Context, country string) ([]models.Cohort, error) {
+	a.mu.RLock()
+	store := a.precomputed.Store
+	a.mu.RUnlock()
+
+	result := make([]models.Cohort, 0)
+	match, ok := Query{Country: country}.matcher(store)
+	if !ok || store.Len() == 0 {
+		return result, nil
+	}
+
+	anonymous, hasAnonymous := store.UserDict.Lookup("")
+	counted := func(i int) bool {
+		return match(i) && (!hasAnonymous || store.Users[i] != anonymous)
+	}
+
+	// First pass: each customer's first purchase month and the data's span.
+	first := make([]int32, len(store.UserDict.Values))
+	for i := range first {
+		first[i] = math.MaxInt32
+	}
+	minMonth, maxMonth := int32(math.MaxInt32), int32(math.MinInt32)
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if !counted(i) {
+			continue
+		}
+		m := monthIndex(store.Dates[i])
+		user := store.Users[i]
+		first[user] = min(first[user], m)
+		minMonth = min(minMonth, m)
+		maxMonth = max(maxMonth, m)
+	}
+	if minMonth > maxMonth {
+		return result, nil
+	}
+
+	cohorts := make([]models.Cohort, maxMonth-minMonth+1)
+	for c := range cohorts {
+		width := len(cohorts) - c
+		cohorts[c] = models.Cohort{
+			Cohort:    monthLabel(minMonth + int32(c)),
+			Active:    make([]int, width),
+			Retention: make([]float64, width),
+			Revenue:   make([]float64, width),
+		}
+	}
+	for _, m := range first {
+		if m != math.MaxInt32 {
+			cohorts[m-minMonth].Customers++
+		}
+	}
+
+	// Second pass: activity and revenue by months since the first purchase,
+	// counting each customer once per month.
+	seen := make(map[uint64]struct{})
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if !counted(i) {
+			continue
+		}
+		user := store.Users[i]
+		m := monthIndex(store.Dates[i])
+		cohort := &cohorts[first[user]-minMonth]
+		offset := m - first[user]
+		cohort.Revenue[offset] += store.Totals[i]
+
+		key := uint64(user)<<32 | uint64(uint32(m))
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			cohort.Active[offset]++
+		}
+	}
+
+	for _, c := range cohorts {
+		if c.Customers == 0 {
+			continue
+		}
+		for k, active := range c.Active {
+			c.Retention[k] = float64(active) / float64(c.Customers) * 100
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// monthIndex numbers calendar months consecutively so month arithmetic is a
+// subtraction.
+func monthIndex(day int32) int32 {
+	t := dayTime(day)
+	return int32(t.Year())*12 + int32(t.Month()) - 1
+}
+
+func monthLabel(index int32) string {
+	return time.Date(int(index/12), time.Month(index%12+1), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+}
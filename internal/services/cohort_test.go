@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func cohortTestAnalytics() *Analytics {
+	day := func(m time.Month, d int) time.Time { return time.Date(2023, m, d, 0, 0, 0, 0, time.UTC) }
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		// January cohort: U1 returns twice in March, U2 never returns
+		{Date: day(1, 5), UserID: "U1", Country: "USA", TotalPrice: 100},
+		{Date: day(1, 9), UserID: "U2", Country: "USA", TotalPrice: 50},
+		{Date: day(3, 1), UserID: "U1", Country: "USA", TotalPrice: 30},
+		{Date: day(3, 20), UserID: "U1", Country: "USA", TotalPrice: 20},
+		// February cohort: U3 buys in Canada, then in the USA
+		{Date: day(2, 14), UserID: "U3", Country: "Canada", TotalPrice: 10},
+		{Date: day(3, 2), UserID: "U3", Country: "USA", TotalPrice: 40},
+		// Anonymous sales belong to no cohort
+		{Date: day(1, 7), Country: "USA", TotalPrice: 500},
+		{Date: day(2, 7), Country: "USA", TotalPrice: 500},
+	})
+	return a
+}
+
+func TestAnalytics_Cohorts(t *testing.T) {
+	a := cohortTestAnalytics()
+
+	cohorts, err := a.Cohorts(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Cohorts() error = %v", err)
+	}
+	if len(cohorts) != 2 {
+		t.Fatalf("expected 2 cohorts, got %+v", cohorts)
+	}
+
+	jan := cohorts[0]
+	if jan.Cohort != "2023-01" || jan.Customers != 2 {
+		t.Errorf("January cohort = %+v", jan)
+	}
+	if !slices.Equal(jan.Active, []int{2, 0, 1}) {
+		t.Errorf("January active = %v, want [2 0 1]", jan.Active)
+	}
+	if !slices.Equal(jan.Retention, []float64{100, 0, 50}) {
+		t.Errorf("January retention = %v, want [100 0 50]", jan.Retention)
+	}
+	if !slices.Equal(jan.Revenue, []float64{150, 0, 50}) {
+		t.Errorf("January revenue = %v, want [150 0 50]", jan.Revenue)
+	}
+
+	feb := cohorts[1]
+	if feb.Cohort != "2023-02" || feb.Customers != 1 || !slices.Equal(feb.Active, []int{1, 1}) {
+		t.Errorf("February cohort = %+v", feb)
+	}
+}
+
+func TestAnalytics_Cohorts_ByCountry(t *testing.T) {
+	a := cohortTestAnalytics()
+
+	cohorts, err := a.Cohorts(context.Background(), "USA")
+	if err != nil {
+		t.Fatalf("Cohorts() error = %v", err)
+	}
+
+	// U3's first USA purchase is in March, so it starts a cohort there
+	if len(cohorts) != 2 || cohorts[1].Cohort != "2023-03" || cohorts[1].Customers != 1 {
+		t.Errorf("unexpected USA cohorts %+v", cohorts)
+	}
+
+	none, err := a.Cohorts(context.Background(), "Atlantis")
+	if err != nil || len(none) != 0 {
+		t.Errorf("expected no cohorts for an unknown country, got %+v, %v", none, err)
+	}
+}
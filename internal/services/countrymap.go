@@ -0,0 +1,92 @@
+package services
+
+import (
+	"cmp"
+	"slices"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/geo"
+)
+
+// CountryMap totals revenue by ISO-3166 country. Country names of the data
+// are mapped through the geo table, so spellings of the same country are
+// merged; names it does not know are reported as unmatched. The map is
+// computed once per data set.
+func (a *Analytics) CountryMap() *models.CountryMap {
+	precomputed := a.current()
+	m, _ := a.countryMap.get(precomputed, func() (*models.CountryMap, error) {
+		return computeCountryMap(precomputed.CountryRevenue), nil
+	})
+	return m
+}
+
+func computeCountryMap(rows []models.CountryRevenue) *models.CountryMap {
+	type nameTotals struct {
+		revenue      float64
+		transactions int
+	}
+	byName := make(map[string]*nameTotals)
+	for _, row := range rows {
+		t := byName[row.Country]
+		if t == nil {
+			t = &nameTotals{}
+			byName[row.Country] = t
+		}
+		t.revenue += row.TotalRevenue
+		t.transactions += row.Transactions
+	}
+
+	result := &models.CountryMap{
+		Countries: make([]models.MapCountry, 0),
+		Unmatched: make([]models.UnmatchedCountry, 0),
+	}
+	byCode := make(map[string]int)
+	for name, t := range byName {
+		country, ok := geo.Lookup(name)
+		if !ok {
+			result.Unmatched = append(result.Unmatched, models.UnmatchedCountry{
+				Name: name, Revenue: t.revenue, Transactions: t.transactions,
+			})
+			continue
+		}
+		i, ok := byCode[country.Code]
+		if !ok {
+			i = len(result.Countries)
+			byCode[country.Code] = i
+			result.Countries = append(result.Countries, models.MapCountry{
+				Code:   country.Code,
+				Alpha3: country.Alpha3,
+				Lat:    country.Lat,
+				Lon:    country.Lon,
+			})
+		}
+		c := &result.Countries[i]
+		c.Names = append(c.Names, name)
+		c.Revenue += t.revenue
+		c.Transactions += t.transactions
+	}
+
+	for i := range result.Countries {
+		c := &result.Countries[i]
+		slices.SortFunc(c.Names, func(a, b string) int {
+			if n := cmp.Compare(byName[b].revenue, byName[a].revenue); n != 0 {
+				return n
+			}
+			return cmp.Compare(a, b)
+		})
+		c.Name = c.Names[0]
+	}
+	slices.SortFunc(result.Countries, func(a, b models.MapCountry) int {
+		if n := cmp.Compare(b.Revenue, a.Revenue); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Code, b.Code)
+	})
+	slices.SortFunc(result.Unmatched, func(a, b models.UnmatchedCountry) int {
+		if n := cmp.Compare(b.Revenue, a.Revenue); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return result
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func TestAnalytics_CountryMap(t *testing.T) {
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Date: day, Country: "Germany", ProductName: "Kite", TotalPrice: 30},
+		{Date: day, Country: "Deutschland", ProductName: "Kite", TotalPrice: 10},
+		{Date: day, Country: "Deutschland", ProductName: "Lamp", TotalPrice: 5},
+		{Date: day, Country: "USA", ProductName: "Kite", TotalPrice: 100},
+		{Date: day, Country: "Atlantis", ProductName: "Kite", TotalPrice: 7},
+		{Date: day, Country: "Atlantis", ProductName: "Lamp", TotalPrice: 1},
+	})
+
+	m := a.CountryMap()
+	if len(m.Countries) != 2 {
+		t.Fatalf("expected 2 mapped countries, got %+v", m.Countries)
+	}
+
+	us, de := m.Countries[0], m.Countries[1]
+	if us.Code != "US" || us.Alpha3 != "USA" || us.Name != "USA" || us.Revenue != 100 {
+		t.Errorf("first country = %+v, want USA with 100", us)
+	}
+	if de.Code != "DE" || de.Revenue != 45 || de.Transactions != 3 {
+		t.Errorf("second country = %+v, want DE with 45 over 3 transactions", de)
+	}
+	if len(de.Names) != 2 || de.Name != "Germany" || de.Names[1] != "Deutschland" {
+		t.Errorf("DE names = %q (%q), want Germany then Deutschland", de.Names, de.Name)
+	}
+	if de.Lat == 0 || de.Lon == 0 {
+		t.Errorf("DE should be placed on the map, got %v, %v", de.Lat, de.Lon)
+	}
+
+	if len(m.Unmatched) != 1 || m.Unmatched[0].Name != "Atlantis" || m.Unmatched[0].Revenue != 8 || m.Unmatched[0].Transactions != 2 {
+		t.Errorf("unmatched = %+v, want Atlantis with 8 over 2 transactions", m.Unmatched)
+	}
+
+	if a.CountryMap() != m {
+		t.Error("the map should be computed once per data set")
+	}
+}
@@ -0,0 +1,424 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+// CubeDimensions are the axes of the sales cube, in cell key order.
+var CubeDimensions = []string{DimensionMonth, DimensionCountry, DimensionRegion, DimensionCategory, DimensionProduct}
+
+// Positions of the dimensions in a cell key.
+const (
+	cubeMonth = iota
+	cubeCountry
+	cubeRegion
+	cubeCategory
+	cubeProduct
+	cubeDims
+)
+
+// cubeKey holds one code per cube dimension. Months are numbered by
+// monthIndex; the other dimensions use the store's dictionary codes.
+type cubeKey [cubeDims]uint32
+
+// CubeCell is the total of every transaction sharing one combination of
+// dimension values.
+type CubeCell struct {
+	Key cubeKey
+	models.PeriodTotals
+}
+
+// Cube pre-aggregates revenue, orders and units over month × country ×
+// region × category × product. Only combinations that occur are stored, so
+// its size follows the data rather than the product of the dimension sizes.
+// Posting lists per dimension value let slices and dice touch only the cells
+// they select, and the single-dimension roll-ups are kept ready. Each cell
+// also lists its store rows, so what the cells cannot answer, such as
+// distinct customers or a derived metric, is computed from the rows of the
+// selected cells only.
+type Cube struct {
+	Cells []CubeCell
+
+	store     *TransactionStore
+	postings  [cubeDims]map[uint32][]int32
+	marginals [cubeDims]map[uint32]models.PeriodTotals
+	total     models.PeriodTotals
+	// rows holds the store rows of cell n at rows[rowStart[n]:rowStart[n+1]].
+	rows     []int32
+	rowStart []int32
+}
+
+// CubeQuery dices the cube to the listed values of each filtered dimension,
+// a single value being a slice, and rolls up every dimension not in GroupBy.
+// Months are given as YYYY-MM. With a Metric, every row also reports that
+// derived metric over its transactions.
+type CubeQuery struct {
+	GroupBy []string
+	Filters map[string][]string
+	Metric  *DerivedMetric
+}
+
+// buildCube aggregates the store rows accepted by match, or all rows when
+// match is nil, into cells.
+func buildCube(ctx context.Context, store *TransactionStore, match func(i int) bool) (*Cube, error) {
+	index := make(map[cubeKey]int32)
+	cells := make([]CubeCell, 0)
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if match != nil && !match(i) {
+			continue
+		}
+		key := cubeRowKey(store, i)
+		n, ok := index[key]
+		if !ok {
+			n = int32(len(cells))
+			index[key] = n
+			cells = append(cells, CubeCell{Key: key})
+		}
+		addTotals(&cells[n].PeriodTotals, models.PeriodTotals{Revenue: store.Totals[i], Orders: 1, Units: int(store.Quantities[i])})
+	}
+
+	// Cells in key order keep posting lists, and so query results, independent
+	// of row order.
+	slices.SortFunc(cells, func(a, b CubeCell) int { return slices.Compare(a.Key[:], b.Key[:]) })
+	cube := &Cube{Cells: cells}
+	cube.index(store, match)
+	return cube, nil
+}
+
+// cubeRowKey returns the cube cell of store row i.
+func cubeRowKey(store *TransactionStore, i int) cubeKey {
+	return cubeKey{
+		uint32(monthIndex(store.Dates[i])),
+		store.Countries[i],
+		store.Regions[i],
+		store.Categories[i],
+		store.Products[i],
+	}
+}
+
+// index rebuilds the posting lists, roll-ups and cell rows, which are not
+// part of the cache, and binds the cube to the store whose dictionaries its
+// codes index. match is the filter the cube was built with.
+func (c *Cube) index(store *TransactionStore, match func(i int) bool) {
+	c.store = store
+	c.total = models.PeriodTotals{}
+	for d := range cubeDims {
+		c.postings[d] = make(map[uint32][]int32)
+		c.marginals[d] = make(map[uint32]models.PeriodTotals)
+	}
+	for n, cell := range c.Cells {
+		addTotals(&c.total, cell.PeriodTotals)
+		for d, code := range cell.Key {
+			c.postings[d][code] = append(c.postings[d][code], int32(n))
+			m := c.marginals[d][code]
+			addTotals(&m, cell.PeriodTotals)
+			c.marginals[d][code] = m
+		}
+	}
+	c.indexRows(store, match)
+}
+
+// indexRows lists the store rows of each cell, in row order.
+func (c *Cube) indexRows(store *TransactionStore, match func(i int) bool) {
+	cellOf := make(map[cubeKey]int32, len(c.Cells))
+	for n, cell := range c.Cells {
+		cellOf[cell.Key] = int32(n)
+	}
+
+	// Count the rows of each cell, then place them by a running offset.
+	rowCells := make([]int32, store.Len())
+	c.rowStart = make([]int32, len(c.Cells)+1)
+	for i := range rowCells {
+		rowCells[i] = -1
+		if match != nil && !match(i) {
+			continue
+		}
+		if n, ok := cellOf[cubeRowKey(store, i)]; ok {
+			rowCells[i] = n
+			c.rowStart[n+1]++
+		}
+	}
+	for n := range c.Cells {
+		c.rowStart[n+1] += c.rowStart[n]
+	}
+	c.rows = make([]int32, c.rowStart[len(c.Cells)])
+	next := slices.Clone(c.rowStart[:len(c.Cells)])
+	for i, n := range rowCells {
+		if n >= 0 {
+			c.rows[next[n]] = int32(i)
+			next[n]++
+		}
+	}
+}
+
+// cellRows returns the store rows of cell n.
+func (c *Cube) cellRows(n int32) []int32 {
+	return c.rows[c.rowStart[n]:c.rowStart[n+1]]
+}
+
+func addTotals(t *models.PeriodTotals, add models.PeriodTotals) {
+	t.Revenue += add.Revenue
+	t.Orders += add.Orders
+	t.Units += add.Units
+}
+
+// Total is the roll-up of the whole cube.
+func (c *Cube) Total() models.PeriodTotals {
+	return c.total
+}
+
+// Query runs a roll-up, slice or dice. Rows come back by revenue, largest
+// first, with values listed in GroupBy order.
+func (c *Cube) Query(ctx context.Context, q CubeQuery) ([]models.CubeRow, error) {
+	groupBy := make([]int, len(q.GroupBy))
+	for i, dim := range q.GroupBy {
+		d := slices.Index(CubeDimensions, dim)
+		if d < 0 {
+			return nil, fmt.Errorf("unknown dimension %q, must be one of: %s", dim, strings.Join(CubeDimensions, ", "))
+		}
+		if slices.Contains(groupBy[:i], d) {
+			return nil, fmt.Errorf("dimension %q is grouped by twice", dim)
+		}
+		groupBy[i] = d
+	}
+
+	var filters [cubeDims][]uint32
+	for dim, values := range q.Filters {
+		d := slices.Index(CubeDimensions, dim)
+		if d < 0 {
+			return nil, fmt.Errorf("unknown dimension %q, must be one of: %s", dim, strings.Join(CubeDimensions, ", "))
+		}
+		codes, err := c.codes(d, values)
+		if err != nil {
+			return nil, err
+		}
+		if len(codes) == 0 {
+			// Only values that never occur: nothing can match.
+			return []models.CubeRow{}, nil
+		}
+		filters[d] = codes
+	}
+
+	groups, err := c.rollUp(ctx, groupBy, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[cubeKey]float64
+	if q.Metric != nil {
+		if values, err = c.metricValues(ctx, q.Metric, groupBy, filters); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := make([]models.CubeRow, 0, len(groups))
+	for key, totals := range groups {
+		row := models.CubeRow{Values: make([]string, len(groupBy)), PeriodTotals: totals}
+		for i, d := range groupBy {
+			row.Values[i] = c.label(d, key[d])
+		}
+		if q.Metric != nil {
+			row.MetricValue = metricValue(values, key)
+		}
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b models.CubeRow) int {
+		if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+			return c
+		}
+		return slices.Compare(a.Values, b.Values)
+	})
+	return rows, nil
+}
+
+// rollUp totals the cells passing the filters by the grouped dimensions.
+// Ungrouped positions of the result keys are zero. A nil filter accepts
+// every value of its dimension.
+func (c *Cube) rollUp(ctx context.Context, groupBy []int, filters [cubeDims][]uint32) (map[cubeKey]models.PeriodTotals, error) {
+	filtered := slices.IndexFunc(filters[:], func(codes []uint32) bool { return codes != nil }) >= 0
+	groups := make(map[cubeKey]models.PeriodTotals)
+
+	if !filtered && len(groupBy) <= 1 {
+		if len(groupBy) == 0 {
+			if c.total.Orders > 0 {
+				groups[cubeKey{}] = c.total
+			}
+			return groups, nil
+		}
+		for code, totals := range c.marginals[groupBy[0]] {
+			var key cubeKey
+			key[groupBy[0]] = code
+			groups[key] = totals
+		}
+		return groups, nil
+	}
+
+	err := c.selectCells(ctx, filters, func(n int32) {
+		cell := &c.Cells[n]
+		var key cubeKey
+		for _, d := range groupBy {
+			key[d] = cell.Key[d]
+		}
+		totals := groups[key]
+		addTotals(&totals, cell.PeriodTotals)
+		groups[key] = totals
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// selectCells calls visit with every cell passing the filters.
+func (c *Cube) selectCells(ctx context.Context, filters [cubeDims][]uint32, visit func(n int32)) error {
+	add := func(n int32) {
+		cell := &c.Cells[n]
+		for d, codes := range filters {
+			if codes != nil && !slices.Contains(codes, cell.Key[d]) {
+				return
+			}
+		}
+		visit(n)
+	}
+
+	// Walk the postings of the most selective filter and check the others
+	// cell by cell; without filters every cell is visited.
+	best, size := -1, len(c.Cells)
+	for d, codes := range filters {
+		if codes == nil {
+			continue
+		}
+		n := 0
+		for _, code := range codes {
+			n += len(c.postings[d][code])
+		}
+		if best < 0 || n < size {
+			best, size = d, n
+		}
+	}
+	if best < 0 {
+		for n := range c.Cells {
+			if n%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			add(int32(n))
+		}
+		return nil
+	}
+	visited := 0
+	for _, code := range filters[best] {
+		for _, n := range c.postings[best][code] {
+			if visited%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			visited++
+			add(n)
+		}
+	}
+	return nil
+}
+
+// metricValues evaluates a derived metric over the rows of the cells passing
+// the filters, keyed like the groups of rollUp.
+func (c *Cube) metricValues(ctx context.Context, m *DerivedMetric, groupBy []int, filters [cubeDims][]uint32) (map[cubeKey]float64, error) {
+	groups, err := newMetricGroups[cubeKey](c.store, m)
+	if err != nil {
+		return nil, err
+	}
+	err = c.selectCells(ctx, filters, func(n int32) {
+		var key cubeKey
+		for _, d := range groupBy {
+			key[d] = c.Cells[n].Key[d]
+		}
+		for _, i := range c.cellRows(n) {
+			groups.add(key, int(i))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups.values(), nil
+}
+
+// customers counts the distinct customers of each listed value of dimension
+// d from the rows of its cells, or of every value when codes is nil.
+// Transactions without a user ID are not counted, as in CustomerMetrics.
+func (c *Cube) customers(ctx context.Context, d int, codes []uint32) (map[uint32]int, error) {
+	if codes == nil {
+		codes = slices.Collect(maps.Keys(c.postings[d]))
+	}
+	anonymous, hasAnonymous := c.store.UserDict.Lookup("")
+
+	counts := make(map[uint32]int, len(codes))
+	var users []uint32
+	for _, code := range codes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		users = users[:0]
+		for _, n := range c.postings[d][code] {
+			for _, i := range c.cellRows(n) {
+				if user := c.store.Users[i]; !hasAnonymous || user != anonymous {
+					users = append(users, user)
+				}
+			}
+		}
+		slices.Sort(users)
+		counts[code] = len(slices.Compact(users))
+	}
+	return counts, nil
+}
+
+// codes resolves filter values to codes, dropping values that never occur.
+// Products are matched by product_id or display name.
+func (c *Cube) codes(d int, values []string) ([]uint32, error) {
+	codes := make([]uint32, 0, len(values))
+	for _, v := range values {
+		if CubeDimensions[d] == DimensionMonth {
+			t, err := time.Parse("2006-01", v)
+			if err != nil {
+				return nil, fmt.Errorf("month must be formatted as YYYY-MM")
+			}
+			if code := uint32(monthIndex(dayNumber(t))); len(c.postings[d][code]) > 0 {
+				codes = append(codes, code)
+			}
+			continue
+		}
+		for _, code := range c.store.dimensionLookup(CubeDimensions[d], v) {
+			if len(c.postings[d][code]) > 0 {
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes, nil
+}
+
+func (c *Cube) label(d int, code uint32) string {
+	if CubeDimensions[d] == DimensionMonth {
+		return monthLabel(int32(code))
+	}
+	_, labels := c.store.dimensionColumn(CubeDimensions[d])
+	return labels[code]
+}
+
+// Cube returns the sales cube of the current data set.
+func (a *Analytics) Cube() *Cube {
+	return a.current().Cube
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func cubeTestAnalytics() *Analytics {
+	jan := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Date: jan, Country: "Germany", Region: "Bavaria", ProductName: "Kite", Category: "Toys", Quantity: 1, TotalPrice: 10},
+		{Date: jan, Country: "Germany", Region: "Bavaria", ProductName: "Kite", Category: "Toys", Quantity: 2, TotalPrice: 20},
+		{Date: feb, Country: "Germany", Region: "Berlin", ProductName: "Lamp", Category: "Home", Quantity: 1, TotalPrice: 50},
+		{Date: feb, Country: "Spain", Region: "North", ProductName: "Kite", Category: "Toys", Quantity: 4, TotalPrice: 40},
+		{Date: feb, Country: "Spain", Region: "North", ProductName: "Vase", Category: "Home", Quantity: 1, TotalPrice: 5},
+	})
+	return a
+}
+
+func TestCube_Query(t *testing.T) {
+	a := cubeTestAnalytics()
+	cube := a.Cube()
+	ctx := context.Background()
+
+	if len(cube.Cells) != 4 {
+		t.Errorf("expected the two January Kite sales to share a cell, got %d cells", len(cube.Cells))
+	}
+	if total := cube.Total(); total.Revenue != 125 || total.Orders != 5 || total.Units != 9 {
+		t.Errorf("Total() = %+v", total)
+	}
+
+	tests := []struct {
+		name  string
+		query CubeQuery
+		want  []models.CubeRow
+	}{
+		{
+			name:  "roll-up",
+			query: CubeQuery{GroupBy: []string{DimensionCategory}},
+			want: []models.CubeRow{
+				{Values: []string{"Toys"}, PeriodTotals: models.PeriodTotals{Revenue: 70, Orders: 3, Units: 7}},
+				{Values: []string{"Home"}, PeriodTotals: models.PeriodTotals{Revenue: 55, Orders: 2, Units: 2}},
+			},
+		},
+		{
+			name:  "slice",
+			query: CubeQuery{GroupBy: []string{DimensionMonth}, Filters: map[string][]string{DimensionProduct: {"Kite"}}},
+			want: []models.CubeRow{
+				{Values: []string{"2023-02"}, PeriodTotals: models.PeriodTotals{Revenue: 40, Orders: 1, Units: 4}},
+				{Values: []string{"2023-01"}, PeriodTotals: models.PeriodTotals{Revenue: 30, Orders: 2, Units: 3}},
+			},
+		},
+		{
+			name: "dice",
+			query: CubeQuery{
+				GroupBy: []string{DimensionCountry, DimensionProduct},
+				Filters: map[string][]string{DimensionMonth: {"2023-02"}, DimensionCategory: {"Home", "Garden"}},
+			},
+			want: []models.CubeRow{
+				{Values: []string{"Germany", "Lamp"}, PeriodTotals: models.PeriodTotals{Revenue: 50, Orders: 1, Units: 1}},
+				{Values: []string{"Spain", "Vase"}, PeriodTotals: models.PeriodTotals{Revenue: 5, Orders: 1, Units: 1}},
+			},
+		},
+		{
+			name:  "grand total",
+			query: CubeQuery{Filters: map[string][]string{DimensionCountry: {"Spain"}}},
+			want:  []models.CubeRow{{Values: []string{}, PeriodTotals: models.PeriodTotals{Revenue: 45, Orders: 2, Units: 5}}},
+		},
+		{
+			name:  "unknown value",
+			query: CubeQuery{GroupBy: []string{DimensionRegion}, Filters: map[string][]string{DimensionCountry: {"France"}}},
+			want:  []models.CubeRow{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := cube.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if !slices.EqualFunc(rows, tt.want, func(a, b models.CubeRow) bool {
+				return slices.Equal(a.Values, b.Values) && a.PeriodTotals == b.PeriodTotals
+			}) {
+				t.Errorf("Query() = %+v, want %+v", rows, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []CubeQuery{
+		{GroupBy: []string{"city"}},
+		{GroupBy: []string{DimensionCountry, DimensionCountry}},
+		{Filters: map[string][]string{DimensionMonth: {"February"}}},
+	} {
+		if _, err := cube.Query(ctx, bad); err == nil {
+			t.Errorf("Query(%+v) should fail", bad)
+		}
+	}
+}
+
+func TestCube_CellRows(t *testing.T) {
+	a := cubeTestAnalytics()
+	cube := a.Cube()
+	store := a.current().Store
+
+	seen := make([]bool, store.Len())
+	for n, cell := range cube.Cells {
+		rows := cube.cellRows(int32(n))
+		if len(rows) != cell.Orders {
+			t.Errorf("cell %v lists %d rows for %d orders", cell.Key, len(rows), cell.Orders)
+		}
+		for _, i := range rows {
+			if cubeRowKey(store, int(i)) != cell.Key || seen[i] {
+				t.Errorf("row %d listed under cell %v", i, cell.Key)
+			}
+			seen[i] = true
+		}
+	}
+	if slices.Contains(seen, false) {
+		t.Errorf("rows missing from every cell: %v", seen)
+	}
+
+	// A cube over some rows lists only those.
+	spain, _ := store.CountryDict.Lookup("Spain")
+	sub, err := buildCube(context.Background(), store, func(i int) bool { return store.Countries[i] == spain })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.rows) != 2 {
+		t.Errorf("expected the Spanish cube to list 2 rows, got %v", sub.rows)
+	}
+}
+
+func TestAnalytics_RankedViewsFromCube(t *testing.T) {
+	a := cubeTestAnalytics()
+
+	regions := a.TopRegions(10)
+	if len(regions) != 3 || regions[0].Region != "Berlin" || regions[1].Region != "North" || regions[1].ItemsSold != 5 {
+		t.Errorf("TopRegions() = %+v", regions)
+	}
+	products := a.TopProducts(1)
+	if len(products) != 1 || products[0].ProductName != "Kite" || products[0].Frequency != 3 || products[0].StockDate != "2023-02-10" {
+		t.Errorf("TopProducts() = %+v", products)
+	}
+	if rows := a.CountryRevenue(); len(rows) != 4 || rows[0].Country != "Germany" || rows[0].ProductName != "Lamp" {
+		t.Errorf("CountryRevenue() = %+v", rows)
+	}
+}
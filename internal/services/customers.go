@@ -0,0 +1,138 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"abt-dashboard/internal/models"
+)
+
+const DimensionMonth = "month"
+
+var CustomerMetricDimensions = []string{DimensionCountry, DimensionRegion, DimensionCategory, DimensionMonth}
+
+// CustomerMetrics returns distinct customers, average order value, units per
+// transaction and repeat-purchase rate for every value of a dimension. Each
+// transaction counts as one order, and the repeat-purchase rate is the share
+// of customers with two or more transactions within that value.
+//
+// Distinct counts are exact rather than estimated: every (value, customer)
+// pair is packed into a 64-bit key, the keys are sorted and runs counted, so
+// memory stays at eight bytes per transaction. Transactions without a user ID
+// count towards revenue and orders but not towards customers.
+//
+// Months are listed chronologically, other dimensions by revenue. Results are
+// computed once per data set.
+func (a *Analytics) CustomerMetrics(ctx context.Context, dimension string) ([]models.CustomerMetrics, error) {
+	if !slices.Contains(CustomerMetricDimensions, dimension) {
+		return nil, fmt.Errorf("unknown dimension %q", dimension)
+	}
+
+	precomputed := a.current()
+	all, err := a.customerMetrics.get(precomputed, func() (map[string][]models.CustomerMetrics, error) {
+		return computeCustomerMetrics(ctx, precomputed.Store)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all[dimension], nil
+}
+
+func computeCustomerMetrics(ctx context.Context, store *TransactionStore) (map[string][]models.CustomerMetrics, error) {
+	result := make(map[string][]models.CustomerMetrics, len(CustomerMetricDimensions))
+	for _, dim := range CustomerMetricDimensions {
+		metrics, err := dimensionCustomerMetrics(ctx, store, dim)
+		if err != nil {
+			return nil, err
+		}
+		result[dim] = metrics
+	}
+	return result, nil
+}
+
+// dimensionCustomerMetrics computes the customer metrics of every value of
+// one dimension.
+func dimensionCustomerMetrics(ctx context.Context, store *TransactionStore, dim string) ([]models.CustomerMetrics, error) {
+	metrics, err := customerMetricsByCode(ctx, store, dim)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dictionary codes may exist for values that no longer occur.
+	metrics = slices.DeleteFunc(metrics, func(m models.CustomerMetrics) bool { return m.Transactions == 0 })
+	if dim == DimensionMonth {
+		slices.SortFunc(metrics, func(a, b models.CustomerMetrics) int { return cmp.Compare(a.Value, b.Value) })
+	} else {
+		slices.SortFunc(metrics, func(a, b models.CustomerMetrics) int {
+			if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+				return c
+			}
+			if c := cmp.Compare(a.Value, b.Value); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Country, b.Country)
+		})
+	}
+	return metrics, nil
+}
+
+// customerMetricsByCode computes the customer metrics of one dimension
+// indexed by code, including codes without sales.
+func customerMetricsByCode(ctx context.Context, store *TransactionStore, dim string) ([]models.CustomerMetrics, error) {
+	anonymous, hasAnonymous := store.UserDict.Lookup("")
+	codes, labels := store.dimensionCodes(dim)
+
+	metrics := make([]models.CustomerMetrics, len(labels))
+	for code, label := range labels {
+		metrics[code] = models.CustomerMetrics{Dimension: dim, Value: label}
+		if dim == DimensionRegion {
+			metrics[code].Country = store.CountryDict.Value(store.RegionCountries[code])
+		}
+	}
+
+	keys := make([]uint64, 0, store.Len())
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		code := codes(i)
+		m := &metrics[code]
+		m.Revenue += store.Totals[i]
+		m.Transactions++
+		m.Units += int(store.Quantities[i])
+		if user := store.Users[i]; !hasAnonymous || user != anonymous {
+			keys = append(keys, uint64(code)<<32|uint64(user))
+		}
+	}
+
+	slices.Sort(keys)
+	for start := 0; start < len(keys); {
+		end := start + 1
+		for end < len(keys) && keys[end] == keys[start] {
+			end++
+		}
+		m := &metrics[keys[start]>>32]
+		m.Customers++
+		if end-start > 1 {
+			m.RepeatCustomers++
+		}
+		start = end
+	}
+
+	for i := range metrics {
+		m := &metrics[i]
+		if m.Transactions > 0 {
+			m.AverageOrderValue = m.Revenue / float64(m.Transactions)
+			m.UnitsPerTransaction = float64(m.Units) / float64(m.Transactions)
+		}
+		if m.Customers > 0 {
+			m.RepeatPurchaseRate = float64(m.RepeatCustomers) / float64(m.Customers) * 100
+		}
+	}
+
+	return metrics, nil
+}
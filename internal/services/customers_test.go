@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func TestAnalytics_CustomerMetrics(t *testing.T) {
+	jan := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Date: jan, UserID: "U1", Country: "USA", Region: "Texas", Category: "Toys", TotalPrice: 100, Quantity: 2},
+		{Date: feb, UserID: "U1", Country: "USA", Region: "Ohio", Category: "Toys", TotalPrice: 50, Quantity: 1},
+		{Date: feb, UserID: "U2", Country: "USA", Region: "Ohio", Category: "Books", TotalPrice: 30, Quantity: 3},
+		{Date: feb, Country: "USA", Region: "Ohio", Category: "Books", TotalPrice: 20, Quantity: 2},
+		{Date: jan, UserID: "U3", Country: "Canada", Region: "Quebec", Category: "Books", TotalPrice: 10, Quantity: 1},
+	})
+	ctx := context.Background()
+
+	countries, err := a.CustomerMetrics(ctx, DimensionCountry)
+	if err != nil {
+		t.Fatalf("CustomerMetrics() error = %v", err)
+	}
+	if len(countries) != 2 {
+		t.Fatalf("expected 2 countries, got %+v", countries)
+	}
+	usa := countries[0]
+	want := models.CustomerMetrics{
+		Dimension: DimensionCountry, Value: "USA", Revenue: 200, Transactions: 4, Units: 8,
+		Customers: 2, RepeatCustomers: 1, AverageOrderValue: 50, UnitsPerTransaction: 2, RepeatPurchaseRate: 50,
+	}
+	if usa != want {
+		t.Errorf("USA = %+v, want %+v", usa, want)
+	}
+
+	// U1 buys once in each month, so neither month has a repeat customer
+	months, err := a.CustomerMetrics(ctx, DimensionMonth)
+	if err != nil {
+		t.Fatalf("CustomerMetrics(month) error = %v", err)
+	}
+	if len(months) != 2 || months[0].Value != "2023-01" || months[1].Value != "2023-02" {
+		t.Fatalf("expected January then February, got %+v", months)
+	}
+	if months[1].Customers != 2 || months[1].RepeatCustomers != 0 || months[1].Transactions != 3 {
+		t.Errorf("February = %+v", months[1])
+	}
+
+	regions, err := a.CustomerMetrics(ctx, DimensionRegion)
+	if err != nil {
+		t.Fatalf("CustomerMetrics(region) error = %v", err)
+	}
+	if regions[0].Value != "Ohio" || regions[0].Customers != 2 {
+		t.Errorf("expected Ohio first with 2 customers, got %+v", regions[0])
+	}
+
+	if _, err := a.CustomerMetrics(ctx, "product"); err == nil {
+		t.Error("expected error for unsupported dimension")
+	}
+}
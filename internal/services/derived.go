@@ -0,0 +1,33 @@
+package services
+
+import "sync"
+
+// derivedCache memoizes a value computed from one data set and recomputes it
+// once the data is replaced.
+type derivedCache[T any] struct {
+	mu     sync.Mutex
+	source *PrecomputedData
+	value  T
+}
+
+func (c *derivedCache[T]) get(source *PrecomputedData, compute func() (T, error)) (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.source == source {
+		return c.value, nil
+	}
+	value, err := compute()
+	if err != nil {
+		return value, err
+	}
+	c.source, c.value = source, value
+	return value, nil
+}
+
+// current returns the data set derived values are computed from.
+func (a *Analytics) current() *PrecomputedData {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.precomputed
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/tdigest"
+)
+
+const (
+	DistributionTotalPrice = "total_price"
+	DistributionQuantity   = "quantity"
+	DistributionPrice      = "price"
+)
+
+const (
+	DefaultHistogramBins = 20
+	MaxHistogramBins     = 100
+)
+
+var (
+	DistributionMetrics    = []string{DistributionTotalPrice, DistributionQuantity, DistributionPrice}
+	DistributionDimensions = []string{DimensionCountry, DimensionCategory}
+)
+
+var ErrUnknownValue = errors.New("no sales for this value")
+
+// distributionOverall is the dimension key of the digest over all rows.
+const distributionOverall = ""
+
+// newDistributions returns empty digest maps indexed as metric -> dimension
+// -> value, with the overall digest under an empty dimension and value.
+func newDistributions() map[string]map[string]map[string]*tdigest.Digest {
+	byMetric := make(map[string]map[string]map[string]*tdigest.Digest, len(DistributionMetrics))
+	for _, metric := range DistributionMetrics {
+		byDim := make(map[string]map[string]*tdigest.Digest, len(DistributionDimensions)+1)
+		byDim[distributionOverall] = make(map[string]*tdigest.Digest)
+		for _, dim := range DistributionDimensions {
+			byDim[dim] = make(map[string]*tdigest.Digest)
+		}
+		byMetric[metric] = byDim
+	}
+	return byMetric
+}
+
+func distributionValue(tx models.Transaction, metric string) float64 {
+	switch metric {
+	case DistributionQuantity:
+		return float64(tx.Quantity)
+	case DistributionPrice:
+		return tx.Price
+	default:
+		return tx.TotalPrice
+	}
+}
+
+func addDistributions(digests map[string]map[string]map[string]*tdigest.Digest, tx models.Transaction) {
+	for metric, byDim := range digests {
+		x := distributionValue(tx, metric)
+		for dim, values := range byDim {
+			value := dimensionValue(tx, dim)
+			d := values[value]
+			if d == nil {
+				d = tdigest.New(tdigest.DefaultCompression)
+				values[value] = d
+			}
+			d.Add(x)
+		}
+	}
+}
+
+func (a *Analytics) mergeDistributions(local, global map[string]map[string]map[string]*tdigest.Digest) {
+	for metric, byDim := range local {
+		for dim, values := range byDim {
+			for value, d := range values {
+				if target := global[metric][dim][value]; target != nil {
+					target.Merge(d)
+				} else {
+					global[metric][dim][value] = d
+				}
+			}
+		}
+	}
+}
+
+// compressDistributions flushes every digest so readers never mutate them.
+func compressDistributions(digests map[string]map[string]map[string]*tdigest.Digest) {
+	for _, byDim := range digests {
+		for _, values := range byDim {
+			for _, d := range values {
+				d.Compress()
+			}
+		}
+	}
+}
+
+func validateDistribution(metric, dimension string) error {
+	if !slices.Contains(DistributionMetrics, metric) {
+		return fmt.Errorf("metric must be one of: %s", strings.Join(DistributionMetrics, ", "))
+	}
+	if dimension != distributionOverall && !slices.Contains(DistributionDimensions, dimension) {
+		return fmt.Errorf("dimension must be one of: %s", strings.Join(DistributionDimensions, ", "))
+	}
+	return nil
+}
+
+// Distribution summarises one transaction field with its quantiles and an
+// equal-width histogram of bins between the smallest and largest value,
+// either over all transactions or, with a dimension, for one of its values.
+// It returns ErrUnknownValue when the value never occurs.
+func (a *Analytics) Distribution(metric, dimension, value string, bins int) (*models.Distribution, error) {
+	if err := validateDistribution(metric, dimension); err != nil {
+		return nil, err
+	}
+	if dimension != distributionOverall && value == "" {
+		return nil, fmt.Errorf("a %s is required", dimension)
+	}
+	if bins < 1 || bins > MaxHistogramBins {
+		return nil, fmt.Errorf("bins must be between 1 and %d", MaxHistogramBins)
+	}
+
+	d := a.current().Distributions[metric][dimension][value]
+	if d == nil {
+		if dimension != distributionOverall {
+			return nil, ErrUnknownValue
+		}
+		return &models.Distribution{Metric: metric, Histogram: []models.HistogramBin{}}, nil
+	}
+
+	result := summarise(d)
+	result.Metric, result.Dimension, result.Value = metric, dimension, value
+	result.Histogram = histogram(d, bins)
+	return &result, nil
+}
+
+// DistributionBreakdown summarises a field for every value of a dimension,
+// most transactions first, without histograms.
+func (a *Analytics) DistributionBreakdown(metric, dimension string) ([]models.Distribution, error) {
+	if err := validateDistribution(metric, dimension); err != nil {
+		return nil, err
+	}
+	if dimension == distributionOverall {
+		return nil, fmt.Errorf("dimension must be one of: %s", strings.Join(DistributionDimensions, ", "))
+	}
+
+	digests := a.current().Distributions[metric][dimension]
+	result := make([]models.Distribution, 0, len(digests))
+	for value, d := range digests {
+		summary := summarise(d)
+		summary.Metric, summary.Dimension, summary.Value = metric, dimension, value
+		result = append(result, summary)
+	}
+	slices.SortFunc(result, func(a, b models.Distribution) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+	return result, nil
+}
+
+func summarise(d *tdigest.Digest) models.Distribution {
+	return models.Distribution{
+		Count: int(d.Count()),
+		Min:   d.Min,
+		Max:   d.Max,
+		Mean:  d.Mean(),
+		P50:   d.Quantile(0.5),
+		P90:   d.Quantile(0.9),
+		P99:   d.Quantile(0.99),
+	}
+}
+
+func histogram(d *tdigest.Digest, bins int) []models.HistogramBin {
+	if d.Max == d.Min {
+		return []models.HistogramBin{{From: d.Min, To: d.Max, Count: int(d.Count())}}
+	}
+	width := (d.Max - d.Min) / float64(bins)
+	result := make([]models.HistogramBin, bins)
+	prev := 0.0
+	for i := range result {
+		from, to := d.Min+float64(i)*width, d.Min+float64(i+1)*width
+		if i == bins-1 {
+			to = d.Max
+		}
+		cdf := d.CDF(to)
+		result[i] = models.HistogramBin{From: from, To: to, Count: int(math.Round(cdf*d.Count())) - int(math.Round(prev*d.Count()))}
+		prev = cdf
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"abt-dashboard/internal/models"
+)
+
+func distributionTestAnalytics() *Analytics {
+	a := NewAnalytics()
+	data := make([]models.Transaction, 0, 100)
+	for i := 1; i <= 100; i++ {
+		country := "USA"
+		if i > 80 {
+			country = "Spain"
+		}
+		data = append(data, models.Transaction{Country: country, Category: "Toys", Price: float64(i), Quantity: 1 + i%2, TotalPrice: float64(i * (1 + i%2))})
+	}
+	a.SetData(data)
+	return a
+}
+
+func TestAnalytics_Distribution(t *testing.T) {
+	a := distributionTestAnalytics()
+
+	d, err := a.Distribution(DistributionPrice, "", "", 10)
+	if err != nil {
+		t.Fatalf("Distribution() error = %v", err)
+	}
+	if d.Count != 100 || d.Min != 1 || d.Max != 100 || d.Mean != 50.5 {
+		t.Errorf("unexpected summary %+v", d)
+	}
+	if d.P50 < 49 || d.P50 > 52 || d.P90 < 89 || d.P90 > 92 || d.P99 < 98 || d.P99 > 100 {
+		t.Errorf("quantiles p50=%v p90=%v p99=%v are off", d.P50, d.P90, d.P99)
+	}
+
+	if len(d.Histogram) != 10 {
+		t.Fatalf("expected 10 bins, got %d", len(d.Histogram))
+	}
+	total := 0
+	for _, bin := range d.Histogram {
+		total += bin.Count
+		if bin.Count < 8 || bin.Count > 12 {
+			t.Errorf("uniform prices should spread evenly, got %+v", bin)
+		}
+	}
+	if total != 100 || d.Histogram[9].To != 100 {
+		t.Errorf("bins should cover all 100 values up to the maximum, got %d up to %v", total, d.Histogram[9].To)
+	}
+
+	spain, err := a.Distribution(DistributionPrice, DimensionCountry, "Spain", 5)
+	if err != nil {
+		t.Fatalf("Distribution(Spain) error = %v", err)
+	}
+	if spain.Count != 20 || spain.Min != 81 || spain.Value != "Spain" {
+		t.Errorf("unexpected Spain summary %+v", spain)
+	}
+
+	if _, err := a.Distribution(DistributionPrice, DimensionCountry, "France", 5); !errors.Is(err, ErrUnknownValue) {
+		t.Errorf("expected ErrUnknownValue, got %v", err)
+	}
+	for _, args := range [][3]string{{"margin", "", ""}, {DistributionPrice, DimensionRegion, "x"}, {DistributionPrice, DimensionCountry, ""}} {
+		if _, err := a.Distribution(args[0], args[1], args[2], 5); err == nil {
+			t.Errorf("Distribution(%v) should fail", args)
+		}
+	}
+}
+
+func TestAnalytics_DistributionBreakdown(t *testing.T) {
+	a := distributionTestAnalytics()
+
+	breakdown, err := a.DistributionBreakdown(DistributionQuantity, DimensionCountry)
+	if err != nil {
+		t.Fatalf("DistributionBreakdown() error = %v", err)
+	}
+	if len(breakdown) != 2 || breakdown[0].Value != "USA" || breakdown[0].Count != 80 {
+		t.Fatalf("expected USA first with 80 transactions, got %+v", breakdown)
+	}
+	if usa := breakdown[0]; usa.Min != 1 || usa.Max != 2 || usa.Mean != 1.5 || usa.Histogram != nil {
+		t.Errorf("unexpected USA summary %+v", usa)
+	}
+}
+
+func TestAnalytics_Distribution_Empty(t *testing.T) {
+	a := NewAnalytics()
+	d, err := a.Distribution(DistributionTotalPrice, "", "", DefaultHistogramBins)
+	if err != nil || d.Count != 0 {
+		t.Errorf("expected an empty distribution, got %+v, %v", d, err)
+	}
+}
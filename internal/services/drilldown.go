@@ -0,0 +1,159 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"abt-dashboard/internal/models"
+)
+
+// LevelAll is the root of the geographic hierarchy, above the countries.
+const LevelAll = "all"
+
+// DrilldownLevels are the levels of the hierarchy from the top down. A
+// region is only meaningful within its country, since the same region name
+// can occur in several countries.
+var DrilldownLevels = []string{DimensionCountry, DimensionRegion, DimensionProduct}
+
+var ErrUnknownPath = errors.New("no sales under this path")
+
+// ParseDrillPath splits a path such as "Germany/Bavaria" into its segments.
+// A product segment is its product_id, or its name when it has none.
+// Segments are path-escaped, so names containing a slash stay intact. An
+// empty path is the root.
+func ParseDrillPath(path string) ([]string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, nil
+	}
+	segments := strings.Split(path, "/")
+	if len(segments) > len(DrilldownLevels) {
+		return nil, fmt.Errorf("path can be at most %d levels deep (%s)", len(DrilldownLevels), strings.Join(DrilldownLevels, "/"))
+	}
+	for i, s := range segments {
+		name, err := url.PathUnescape(s)
+		if err != nil || name == "" {
+			return nil, fmt.Errorf("invalid path segment %q", s)
+		}
+		segments[i] = name
+	}
+	return segments, nil
+}
+
+// DrillPath is the inverse of ParseDrillPath.
+func DrillPath(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = url.PathEscape(s)
+	}
+	return strings.Join(escaped, "/")
+}
+
+// Drilldown totals the sales under a path of the geographic hierarchy and
+// breaks them down by the next level, biggest revenue first. With a metric,
+// the name of a derived metric, the node and its children also report it.
+// It returns ErrUnknownPath when nothing was sold under the path and
+// ErrUnknownMetric for a metric that is not registered.
+func (a *Analytics) Drilldown(ctx context.Context, path []string, metric string) (*models.DrilldownNode, error) {
+	if len(path) > len(DrilldownLevels) {
+		return nil, fmt.Errorf("path can be at most %d levels deep", len(DrilldownLevels))
+	}
+	var derived *DerivedMetric
+	if metric != "" {
+		var ok bool
+		if derived, ok = a.DerivedMetric(metric); !ok {
+			return nil, ErrUnknownMetric
+		}
+	}
+	cube := a.current().Cube
+
+	// Each path segment slices the cube on its level.
+	levels := []int{cubeCountry, cubeRegion, cubeProduct}
+	var filters [cubeDims][]uint32
+	for i, name := range path {
+		codes, err := cube.codes(levels[i], []string{name})
+		if err != nil {
+			return nil, err
+		}
+		if len(codes) == 0 {
+			return nil, ErrUnknownPath
+		}
+		filters[levels[i]] = codes
+	}
+
+	node := &models.DrilldownNode{
+		Level:    LevelAll,
+		Path:     DrillPath(path...),
+		Children: make([]models.DrilldownChild, 0),
+	}
+	if len(path) > 0 {
+		last := levels[len(path)-1]
+		node.Level = DrilldownLevels[len(path)-1]
+		node.Name = cube.label(last, filters[last][0])
+	}
+
+	var groupBy []int
+	if len(path) < len(DrilldownLevels) {
+		node.ChildLevel = DrilldownLevels[len(path)]
+		groupBy = []int{levels[len(path)]}
+	}
+	groups, err := cube.rollUp(ctx, groupBy, filters)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range groups {
+		addTotals(&node.PeriodTotals, t)
+	}
+	if len(path) > 0 && node.Orders == 0 {
+		return nil, ErrUnknownPath
+	}
+
+	var values map[cubeKey]float64
+	if derived != nil {
+		total, err := cube.metricValues(ctx, derived, nil, filters)
+		if err != nil {
+			return nil, err
+		}
+		node.MetricValue = metricValue(total, cubeKey{})
+		if groupBy != nil {
+			if values, err = cube.metricValues(ctx, derived, groupBy, filters); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if groupBy == nil {
+		return node, nil
+	}
+
+	for key, t := range groups {
+		code := key[groupBy[0]]
+		name, segment := cube.label(groupBy[0], code), cube.label(groupBy[0], code)
+		if groupBy[0] == cubeProduct {
+			segment = cube.store.productRef(code)
+		}
+		child := models.DrilldownChild{
+			Name:         name,
+			Path:         DrillPath(append(slices.Clip(path), segment)...),
+			PeriodTotals: t,
+		}
+		if node.Revenue != 0 {
+			child.Share = t.Revenue / node.Revenue * 100
+		}
+		if derived != nil {
+			child.MetricValue = metricValue(values, key)
+		}
+		node.Children = append(node.Children, child)
+	}
+	slices.SortFunc(node.Children, func(a, b models.DrilldownChild) int {
+		if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return node, nil
+}
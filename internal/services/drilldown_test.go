@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"abt-dashboard/internal/models"
+)
+
+func drilldownTestAnalytics() *Analytics {
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Country: "Germany", Region: "North", ProductName: "Kite", Quantity: 1, TotalPrice: 30},
+		{Country: "Germany", Region: "North", ProductName: "Atlas", Quantity: 2, TotalPrice: 10},
+		{Country: "Germany", Region: "Bavaria", ProductName: "Kite", Quantity: 1, TotalPrice: 60},
+		// The same region name in another country must stay separate
+		{Country: "Spain", Region: "North", ProductName: "A/B Lamp", Quantity: 1, TotalPrice: 500},
+	})
+	return a
+}
+
+func TestAnalytics_Drilldown(t *testing.T) {
+	a := drilldownTestAnalytics()
+	ctx := context.Background()
+
+	root, err := a.Drilldown(ctx, nil, "")
+	if err != nil {
+		t.Fatalf("Drilldown(root) error = %v", err)
+	}
+	if root.Level != LevelAll || root.ChildLevel != DimensionCountry || root.Revenue != 600 || len(root.Children) != 2 {
+		t.Fatalf("unexpected root %+v", root)
+	}
+	if c := root.Children[0]; c.Name != "Spain" || c.Path != "Spain" || c.Share < 83 || c.Share > 84 {
+		t.Errorf("expected Spain first with a 83%% share, got %+v", c)
+	}
+
+	germanyNorth, err := a.Drilldown(ctx, []string{"Germany", "North"}, "")
+	if err != nil {
+		t.Fatalf("Drilldown(Germany/North) error = %v", err)
+	}
+	if germanyNorth.Level != DimensionRegion || germanyNorth.Revenue != 40 || germanyNorth.Orders != 2 || germanyNorth.Units != 3 {
+		t.Errorf("Germany/North should not include Spain's North, got %+v", germanyNorth)
+	}
+	if germanyNorth.Children[0].Name != "Kite" || germanyNorth.Children[0].Path != "Germany/North/Kite" {
+		t.Errorf("unexpected children %+v", germanyNorth.Children)
+	}
+
+	spainNorth, err := a.Drilldown(ctx, []string{"Spain", "North"}, "")
+	if err != nil {
+		t.Fatalf("Drilldown(Spain/North) error = %v", err)
+	}
+	lamp := spainNorth.Children[0]
+	if lamp.Path != "Spain/North/A%2FB%20Lamp" {
+		t.Errorf("product path should be escaped, got %q", lamp.Path)
+	}
+	path, err := ParseDrillPath(lamp.Path)
+	if err != nil || !slices.Equal(path, []string{"Spain", "North", "A/B Lamp"}) {
+		t.Fatalf("ParseDrillPath(%q) = %v, %v", lamp.Path, path, err)
+	}
+	leaf, err := a.Drilldown(ctx, path, "")
+	if err != nil {
+		t.Fatalf("Drilldown(product) error = %v", err)
+	}
+	if leaf.Level != DimensionProduct || leaf.ChildLevel != "" || len(leaf.Children) != 0 || leaf.Revenue != 500 {
+		t.Errorf("unexpected product leaf %+v", leaf)
+	}
+
+	for _, path := range [][]string{{"France"}, {"Spain", "Bavaria"}, {"Germany", "North", "A/B Lamp"}} {
+		if _, err := a.Drilldown(ctx, path, ""); !errors.Is(err, ErrUnknownPath) {
+			t.Errorf("Drilldown(%v) error = %v, want ErrUnknownPath", path, err)
+		}
+	}
+}
+
+func TestParseDrillPath(t *testing.T) {
+	if path, err := ParseDrillPath("/Germany/Bavaria/"); err != nil || !slices.Equal(path, []string{"Germany", "Bavaria"}) {
+		t.Errorf("ParseDrillPath() = %v, %v", path, err)
+	}
+	if path, err := ParseDrillPath(""); err != nil || path != nil {
+		t.Errorf("empty path should be the root, got %v, %v", path, err)
+	}
+	for _, bad := range []string{"a/b/c/d", "Germany//Kite", "%zz"} {
+		if _, err := ParseDrillPath(bad); err == nil {
+			t.Errorf("ParseDrillPath(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package forecast projects evenly spaced series forward. Series with at
+// least two full seasons use additive Holt-Winters exponential smoothing;
+// shorter series fall back to an ordinary least squares linear trend.
+package forecast
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	MethodHoltWinters = "holt-winters"
+	MethodLinear      = "linear"
+)
+
+// Confidence is the coverage of the reported prediction intervals.
+const Confidence = 0.95
+
+// z is the two-sided standard normal quantile for Confidence.
+const z = 1.959964
+
+// MinPoints is the shortest history any method can project from.
+const MinPoints = 3
+
+var ErrInsufficientData = errors.New("forecast: not enough history")
+
+type Point struct {
+	Value float64
+	Lower float64
+	Upper float64
+}
+
+type Result struct {
+	Method string
+	Points []Point
+	// Alpha, Beta and Gamma are the fitted smoothing parameters and are zero
+	// for the linear method.
+	Alpha float64
+	Beta  float64
+	Gamma float64
+}
+
+// Forecast projects series horizon steps ahead using Holt-Winters when the
+// series covers at least two seasons of the given length, and a linear trend
+// otherwise.
+func Forecast(series []float64, horizon, season int) (Result, error) {
+	if season > 1 && len(series) >= 2*season {
+		return HoltWinters(series, horizon, season)
+	}
+	return LinearTrend(series, horizon)
+}
+
+// smoothingGrid is searched exhaustively for each parameter. It is coarse but
+// the series are short and the fit is cheap, so the search stays instant.
+var smoothingGrid = []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+
+// HoltWinters fits additive Holt-Winters smoothing, picking the parameters
+// that minimise the one-step-ahead squared error, and projects horizon steps.
+func HoltWinters(series []float64, horizon, season int) (Result, error) {
+	if season < 2 || len(series) < 2*season {
+		return Result{}, ErrInsufficientData
+	}
+
+	best := math.Inf(1)
+	var alpha, beta, gamma float64
+	for _, a := range smoothingGrid {
+		for _, b := range smoothingGrid {
+			for _, g := range smoothingGrid {
+				if sse := fitHoltWinters(series, season, a, b, g).sse; sse < best {
+					best, alpha, beta, gamma = sse, a, b, g
+				}
+			}
+		}
+	}
+
+	fit := fitHoltWinters(series, season, alpha, beta, gamma)
+	sigma2 := fit.sse / float64(len(series)-season)
+	n := len(series)
+
+	points := make([]Point, horizon)
+	for h := 1; h <= horizon; h++ {
+		value := fit.level + float64(h)*fit.trend + fit.seasonal[n-season+(h-1)%season]
+
+		// Variance of the h-step error for the additive model (Hyndman et
+		// al., 2008): sigma² · (1 + Σ c_j²) over the preceding steps.
+		variance := 1.0
+		for j := 1; j < h; j++ {
+			c := alpha * (1 + float64(j)*beta)
+			if j%season == 0 {
+				c += gamma * (1 - alpha)
+			}
+			variance += c * c
+		}
+		margin := z * math.Sqrt(sigma2*variance)
+		points[h-1] = Point{Value: value, Lower: value - margin, Upper: value + margin}
+	}
+
+	return Result{Method: MethodHoltWinters, Points: points, Alpha: alpha, Beta: beta, Gamma: gamma}, nil
+}
+
+type holtWintersFit struct {
+	level    float64
+	trend    float64
+	seasonal []float64
+	sse      float64
+}
+
+// fitHoltWinters runs the smoothing recursions over the series. The means of
+// the first two seasons initialise the trend, and the first season, detrended
+// around its midpoint, initialises the level and seasonal indices.
+func fitHoltWinters(series []float64, season int, alpha, beta, gamma float64) holtWintersFit {
+	first, second := mean(series[:season]), mean(series[season:2*season])
+	trend := (second - first) / float64(season)
+	mid := float64(season-1) / 2
+	level := first + trend*mid
+
+	seasonal := make([]float64, len(series))
+	for i := range season {
+		seasonal[i] = series[i] - (first + trend*(float64(i)-mid))
+	}
+
+	sse := 0.0
+	for t := season; t < len(series); t++ {
+		predicted := level + trend + seasonal[t-season]
+		err := series[t] - predicted
+		sse += err * err
+
+		prevLevel := level
+		level = alpha*(series[t]-seasonal[t-season]) + (1-alpha)*(level+trend)
+		trend = beta*(level-prevLevel) + (1-beta)*trend
+		seasonal[t] = gamma*(series[t]-level) + (1-gamma)*seasonal[t-season]
+	}
+
+	return holtWintersFit{level: level, trend: trend, seasonal: seasonal, sse: sse}
+}
+
+// LinearTrend fits an ordinary least squares line through the series and
+// projects it horizon steps with prediction intervals that widen with the
+// distance from the observed range.
+func LinearTrend(series []float64, horizon int) (Result, error) {
+	n := len(series)
+	if n < MinPoints {
+		return Result{}, ErrInsufficientData
+	}
+
+	xMean := float64(n-1) / 2
+	yMean := mean(series)
+	var sxx, sxy float64
+	for i, y := range series {
+		dx := float64(i) - xMean
+		sxx += dx * dx
+		sxy += dx * (y - yMean)
+	}
+	slope := sxy / sxx
+	intercept := yMean - slope*xMean
+
+	var sse float64
+	for i, y := range series {
+		r := y - (intercept + slope*float64(i))
+		sse += r * r
+	}
+	sigma := math.Sqrt(sse / float64(n-2))
+
+	points := make([]Point, horizon)
+	for h := range horizon {
+		x := float64(n + h)
+		value := intercept + slope*x
+		margin := z * sigma * math.Sqrt(1+1/float64(n)+(x-xMean)*(x-xMean)/sxx)
+		points[h] = Point{Value: value, Lower: value - margin, Upper: value + margin}
+	}
+
+	return Result{Method: MethodLinear, Points: points}, nil
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,88 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func seasonalSeries(seasons int) []float64 {
+	pattern := []float64{10, 12, 15, 20, 25, 30, 28, 24, 18, 14, 12, 30}
+	series := make([]float64, 0, seasons*len(pattern))
+	for i := range seasons * len(pattern) {
+		series = append(series, pattern[i%len(pattern)]+float64(i)*5/12)
+	}
+	return series
+}
+
+func TestForecast_PicksMethodByHistory(t *testing.T) {
+	result, err := Forecast(seasonalSeries(3), 6, 12)
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	if result.Method != MethodHoltWinters {
+		t.Errorf("method = %q, want %q with three seasons", result.Method, MethodHoltWinters)
+	}
+
+	result, err = Forecast(seasonalSeries(1), 6, 12)
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	if result.Method != MethodLinear {
+		t.Errorf("method = %q, want %q with one season", result.Method, MethodLinear)
+	}
+}
+
+func TestHoltWinters_FollowsSeasonAndTrend(t *testing.T) {
+	series := seasonalSeries(4)
+	result, err := HoltWinters(series, 12, 12)
+	if err != nil {
+		t.Fatalf("HoltWinters() error = %v", err)
+	}
+	if len(result.Points) != 12 {
+		t.Fatalf("expected 12 points, got %d", len(result.Points))
+	}
+
+	// A noiseless trend-plus-season series continues exactly: each month is
+	// five above the same month last year.
+	for i, p := range result.Points {
+		want := series[len(series)-12+i] + 5
+		if math.Abs(p.Value-want) > 1.5 {
+			t.Errorf("point %d = %.2f, want about %.2f", i, p.Value, want)
+		}
+		if p.Lower > p.Value || p.Upper < p.Value {
+			t.Errorf("point %d interval [%.2f, %.2f] does not contain %.2f", i, p.Lower, p.Upper, p.Value)
+		}
+	}
+}
+
+func TestLinearTrend(t *testing.T) {
+	series := []float64{10, 12, 13, 16, 18, 19}
+	result, err := LinearTrend(series, 3)
+	if err != nil {
+		t.Fatalf("LinearTrend() error = %v", err)
+	}
+
+	// Least squares through the series gives y = 9.952 + 1.886x.
+	if math.Abs(result.Points[0].Value-(9.952+1.886*6)) > 0.01 {
+		t.Errorf("first point = %.3f, want about 21.27", result.Points[0].Value)
+	}
+
+	prevWidth := 0.0
+	for i, p := range result.Points {
+		width := p.Upper - p.Lower
+		if width <= prevWidth {
+			t.Errorf("interval %d width %.3f should widen beyond %.3f", i, width, prevWidth)
+		}
+		prevWidth = width
+	}
+}
+
+func TestForecast_InsufficientData(t *testing.T) {
+	if _, err := Forecast([]float64{1, 2}, 3, 12); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("expected ErrInsufficientData, got %v", err)
+	}
+	if _, err := HoltWinters(seasonalSeries(1), 3, 12); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("expected ErrInsufficientData for one season, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/forecast"
+)
+
+// MaxForecastHorizon bounds how many months ahead a forecast may reach.
+const MaxForecastHorizon = 24
+
+// monthsPerYear is the seasonal period of the monthly series.
+const monthsPerYear = 12
+
+// Forecast projects the chronological monthly series of metric horizon months
+// past the last month with sales, optionally restricted to one country.
+// Projected values and interval bounds are clamped at zero since none of the
+// metrics can be negative.
+func (a *Analytics) Forecast(ctx context.Context, metric string, horizon int, country string) (*models.Forecast, error) {
+	if !slices.Contains(SeriesMetrics, metric) {
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+	if horizon < 1 || horizon > MaxForecastHorizon {
+		return nil, fmt.Errorf("horizon must be between 1 and %d months", MaxForecastHorizon)
+	}
+
+	var history []models.TimeSeriesPoint
+	if country == "" {
+		a.mu.RLock()
+		history = rollupDaily(a.precomputed.DailySales, GranularityMonth, metric)
+		a.mu.RUnlock()
+	} else {
+		var err error
+		if history, err = countryMonthlySeries(ctx, a.Cube(), country, metric); err != nil {
+			return nil, err
+		}
+	}
+
+	values := make([]float64, len(history))
+	for i, p := range history {
+		values[i] = p.Value
+	}
+
+	result, err := forecast.Forecast(values, horizon, monthsPerYear)
+	if errors.Is(err, forecast.ErrInsufficientData) {
+		return nil, fmt.Errorf("at least %d months of sales are needed to forecast, found %d", forecast.MinPoints, len(history))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	last, _ := time.Parse(time.DateOnly, history[len(history)-1].Start)
+	points := make([]models.ForecastPoint, len(result.Points))
+	for i, p := range result.Points {
+		start := last.AddDate(0, i+1, 0)
+		points[i] = models.ForecastPoint{
+			Period: periodLabel(start, GranularityMonth),
+			Start:  start.Format(time.DateOnly),
+			Value:  max(p.Value, 0),
+			Lower:  max(p.Lower, 0),
+			Upper:  max(p.Upper, 0),
+		}
+	}
+
+	return &models.Forecast{
+		Metric:     metric,
+		Country:    country,
+		Method:     result.Method,
+		Confidence: forecast.Confidence,
+		History:    history,
+		Points:     points,
+	}, nil
+}
+
+// countryMonthlySeries rolls the monthly series of metric for one country up
+// from the cube, from its first month with sales through its last.
+func countryMonthlySeries(ctx context.Context, cube *Cube, country, metric string) ([]models.TimeSeriesPoint, error) {
+	codes, err := cube.codes(cubeCountry, []string{country})
+	if err != nil {
+		return nil, err
+	}
+	var filters [cubeDims][]uint32
+	filters[cubeCountry] = codes
+	months := map[cubeKey]models.PeriodTotals{}
+	if len(codes) > 0 {
+		if months, err = cube.rollUp(ctx, []int{cubeMonth}, filters); err != nil {
+			return nil, err
+		}
+	}
+	if len(months) == 0 {
+		return []models.TimeSeriesPoint{}, nil
+	}
+
+	first, last := uint32(math.MaxUint32), uint32(0)
+	for key := range months {
+		first, last = min(first, key[cubeMonth]), max(last, key[cubeMonth])
+	}
+	points := make([]models.TimeSeriesPoint, 0, last-first+1)
+	for month := first; month <= last; month++ {
+		var key cubeKey
+		key[cubeMonth] = month
+		start := time.Date(int(month/12), time.Month(month%12+1), 1, 0, 0, 0, 0, time.UTC)
+		points = append(points, models.TimeSeriesPoint{
+			Period: periodLabel(start, GranularityMonth),
+			Start:  start.Format(time.DateOnly),
+			Value:  periodMetric(months[key], metric),
+		})
+	}
+	return points, nil
+}
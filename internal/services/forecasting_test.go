@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/forecast"
+)
+
+func TestAnalytics_Forecast(t *testing.T) {
+	a := NewAnalytics()
+	var txs []models.Transaction
+	for month := range 30 {
+		date := time.Date(2021, time.Month(month+1), 10, 0, 0, 0, 0, time.UTC)
+		txs = append(txs,
+			models.Transaction{Date: date, Country: "USA", TotalPrice: 100 + float64(month%12)*10, Quantity: 1},
+			models.Transaction{Date: date, Country: "Canada", TotalPrice: 50, Quantity: 2},
+		)
+	}
+	a.SetData(txs)
+
+	result, err := a.Forecast(context.Background(), MetricRevenue, 6, "")
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	if result.Method != forecast.MethodHoltWinters {
+		t.Errorf("method = %q, want %q", result.Method, forecast.MethodHoltWinters)
+	}
+	if len(result.History) != 30 || len(result.Points) != 6 {
+		t.Fatalf("expected 30 history and 6 forecast points, got %d and %d", len(result.History), len(result.Points))
+	}
+	if result.Points[0].Period != "2023-07" || result.Points[5].Period != "2023-12" {
+		t.Errorf("forecast periods = %s..%s, want 2023-07..2023-12", result.Points[0].Period, result.Points[5].Period)
+	}
+	for _, p := range result.Points {
+		if p.Lower < 0 || p.Lower > p.Value || p.Upper < p.Value {
+			t.Errorf("invalid interval %+v", p)
+		}
+	}
+
+	canada, err := a.Forecast(context.Background(), MetricUnits, 3, "Canada")
+	if err != nil {
+		t.Fatalf("Forecast(Canada) error = %v", err)
+	}
+	if h := canada.History; len(h) != 30 || h[0].Start != "2021-01-01" || h[29].Period != "2023-06" || h[0].Value != 2 {
+		t.Errorf("Canada history = %+v, want 30 months of 2 units from 2021-01", h)
+	}
+	for _, p := range canada.Points {
+		if p.Value < 1.99 || p.Value > 2.01 {
+			t.Errorf("Canada sells a flat 2 units a month, forecast %+v", p)
+		}
+	}
+}
+
+func TestAnalytics_Forecast_Errors(t *testing.T) {
+	a := timeSeriesTestAnalytics()
+	ctx := context.Background()
+
+	if _, err := a.Forecast(ctx, "profit", 6, ""); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+	if _, err := a.Forecast(ctx, MetricRevenue, MaxForecastHorizon+1, ""); err == nil {
+		t.Error("expected error for horizon beyond the maximum")
+	}
+	if _, err := a.Forecast(ctx, MetricRevenue, 6, "Atlantis"); err == nil {
+		t.Error("expected error for a country without history")
+	}
+}
@@ -0,0 +1,188 @@
+// Package geo maps country names as they appear in sales data to ISO-3166
+// countries. The mapping table is embedded and lists for each country its
+// codes, English name, common alternative names and an approximate centroid
+// for placing it on a map. A second table holds simplified outlines of the
+// countries big enough to be drawn as shapes on a world map.
+package geo
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Country is an ISO-3166 country.
+type Country struct {
+	// Code is the ISO-3166 alpha-2 code, Alpha3 the alpha-3 code.
+	Code   string
+	Alpha3 string
+	Name   string
+	// Lat and Lon locate the country's centroid in degrees.
+	Lat float64
+	Lon float64
+}
+
+// Point is a position in degrees.
+type Point struct {
+	Lon float64
+	Lat float64
+}
+
+//go:embed countries.csv
+var countriesCSV string
+
+//go:embed outlines.csv
+var outlinesCSV string
+
+var (
+	countries []Country
+	byKey     map[string]int
+	outlines  map[string][][]Point
+)
+
+func init() {
+	var err error
+	countries, byKey, err = parseCountries(countriesCSV)
+	if err != nil {
+		panic("geo: " + err.Error())
+	}
+	outlines, err = parseOutlines(outlinesCSV, countries)
+	if err != nil {
+		panic("geo: outlines: " + err.Error())
+	}
+}
+
+// parseCountries reads the mapping table and indexes every code, name and
+// alternative name of a country under its normalized form.
+func parseCountries(data string) ([]Country, map[string]int, error) {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) < 2 {
+		return nil, nil, fmt.Errorf("country table is empty")
+	}
+
+	list := make([]Country, 0, len(records)-1)
+	index := make(map[string]int, 4*len(records))
+	for line, rec := range records[1:] {
+		if len(rec) != 6 {
+			return nil, nil, fmt.Errorf("line %d: expected 6 fields, got %d", line+2, len(rec))
+		}
+		lat, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: latitude: %w", line+2, err)
+		}
+		lon, err := strconv.ParseFloat(rec[4], 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: longitude: %w", line+2, err)
+		}
+		c := Country{Code: rec[0], Alpha3: rec[1], Name: rec[2], Lat: lat, Lon: lon}
+
+		keys := []string{c.Code, c.Alpha3, c.Name}
+		if rec[5] != "" {
+			keys = append(keys, strings.Split(rec[5], "|")...)
+		}
+		for _, key := range keys {
+			k := Normalize(key)
+			if prev, ok := index[k]; ok && prev != len(list) {
+				return nil, nil, fmt.Errorf("line %d: %q already names %s", line+2, key, list[prev].Name)
+			}
+			index[k] = len(list)
+		}
+		list = append(list, c)
+	}
+	return list, index, nil
+}
+
+// parseOutlines reads the outline table: one ring per line, keyed by alpha-2
+// code, as "lon lat" points separated by semicolons. A country made of
+// several islands has a line per island.
+func parseOutlines(data string, list []Country) (map[string][][]Point, error) {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(list))
+	for _, c := range list {
+		known[c.Code] = true
+	}
+
+	result := make(map[string][][]Point)
+	for line, rec := range records[min(len(records), 1):] {
+		if len(rec) != 2 {
+			return nil, fmt.Errorf("line %d: expected 2 fields, got %d", line+2, len(rec))
+		}
+		if !known[rec[0]] {
+			return nil, fmt.Errorf("line %d: unknown country code %q", line+2, rec[0])
+		}
+		pairs := strings.Split(rec[1], ";")
+		if len(pairs) < 3 {
+			return nil, fmt.Errorf("line %d: a ring needs at least 3 points", line+2)
+		}
+		ring := make([]Point, len(pairs))
+		for i, pair := range pairs {
+			lon, lat, ok := strings.Cut(pair, " ")
+			if !ok {
+				return nil, fmt.Errorf("line %d: point %q is not \"lon lat\"", line+2, pair)
+			}
+			if ring[i].Lon, err = strconv.ParseFloat(lon, 64); err != nil {
+				return nil, fmt.Errorf("line %d: longitude: %w", line+2, err)
+			}
+			if ring[i].Lat, err = strconv.ParseFloat(lat, 64); err != nil {
+				return nil, fmt.Errorf("line %d: latitude: %w", line+2, err)
+			}
+			if ring[i].Lon < -180 || ring[i].Lon > 180 || ring[i].Lat < -90 || ring[i].Lat > 90 {
+				return nil, fmt.Errorf("line %d: point %q out of range", line+2, pair)
+			}
+		}
+		result[rec[0]] = append(result[rec[0]], ring)
+	}
+	return result, nil
+}
+
+// Lookup finds the country a name refers to: its English name, one of its
+// common alternative names or its alpha-2 or alpha-3 code. Case,
+// punctuation and a leading "the" are ignored.
+func Lookup(name string) (Country, bool) {
+	i, ok := byKey[Normalize(name)]
+	if !ok {
+		return Country{}, false
+	}
+	return countries[i], true
+}
+
+// Countries returns every country of the table, ordered by alpha-2 code.
+func Countries() []Country {
+	return append([]Country(nil), countries...)
+}
+
+// Outline returns the simplified borders of a country, by alpha-2 code, as
+// closed rings of points. Countries too small to draw have none. The rings
+// are shared and must not be modified.
+func Outline(code string) [][]Point {
+	return outlines[code]
+}
+
+// Normalize returns the form names are compared in: lowercased, without
+// punctuation or a leading "the", and with runs of spaces folded.
+func Normalize(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-':
+			space = true
+		}
+	}
+	return strings.TrimPrefix(b.String(), "the ")
+}
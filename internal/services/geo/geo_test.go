@@ -0,0 +1,106 @@
+package geo
+
+import (
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"Germany", "DE"},
+		{"germany", "DE"},
+		{"  GERMANY ", "DE"},
+		{"DEU", "DE"},
+		{"de", "DE"},
+		{"Deutschland", "DE"},
+		{"USA", "US"},
+		{"United States of America", "US"},
+		{"U.S.A.", "US"},
+		{"UK", "GB"},
+		{"The Netherlands", "NL"},
+		{"Côte d'Ivoire", "CI"},
+		{"Cote dIvoire", "CI"},
+		{"Guinea Bissau", "GW"},
+		{"Turkey", "TR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := Lookup(tt.name)
+			if !ok {
+				t.Fatalf("Lookup(%q) found nothing", tt.name)
+			}
+			if c.Code != tt.code {
+				t.Errorf("Lookup(%q) = %s, want %s", tt.name, c.Code, tt.code)
+			}
+		})
+	}
+
+	for _, name := range []string{"", "Atlantis", "Germany East"} {
+		if c, ok := Lookup(name); ok {
+			t.Errorf("Lookup(%q) = %s, want no match", name, c.Code)
+		}
+	}
+}
+
+func TestCountries(t *testing.T) {
+	list := Countries()
+	if len(list) < 190 {
+		t.Fatalf("expected the whole world, got %d countries", len(list))
+	}
+	for i, c := range list {
+		if len(c.Code) != 2 || len(c.Alpha3) != 3 || c.Name == "" {
+			t.Errorf("malformed country %+v", c)
+		}
+		if c.Lat < -90 || c.Lat > 90 || c.Lon < -180 || c.Lon > 180 {
+			t.Errorf("%s centroid out of range: %v, %v", c.Code, c.Lat, c.Lon)
+		}
+		if i > 0 && list[i-1].Code >= c.Code {
+			t.Errorf("countries not ordered by code at %s", c.Code)
+		}
+	}
+
+	// The result is a copy.
+	list[0].Name = "changed"
+	if Countries()[0].Name == "changed" {
+		t.Error("Countries() should not expose the table")
+	}
+}
+
+func TestParseCountries_DuplicateName(t *testing.T) {
+	data := "code,alpha3,name,lat,lon,aliases\nCG,COG,Republic of the Congo,-1,15,Congo\nCD,COD,Democratic Republic of the Congo,-2.5,23.5,Congo\n"
+	if _, _, err := parseCountries(data); err == nil {
+		t.Error("expected an error for a name shared by two countries")
+	}
+}
+
+func TestOutline(t *testing.T) {
+	us := Outline("US")
+	if len(us) < 2 {
+		t.Fatalf("US should have the mainland and Alaska, got %d rings", len(us))
+	}
+	for _, ring := range us {
+		if len(ring) < 3 {
+			t.Errorf("ring with %d points", len(ring))
+		}
+	}
+	if rings := Outline("MC"); rings != nil {
+		t.Errorf("Monaco is too small to outline, got %d rings", len(rings))
+	}
+}
+
+func TestParseOutlines_Errors(t *testing.T) {
+	list := []Country{{Code: "FR"}}
+	for _, data := range []string{
+		"code,ring\nXX,0 0;1 0;1 1\n",
+		"code,ring\nFR,0 0;1 0\n",
+		"code,ring\nFR,0 0;1 0;1\n",
+		"code,ring\nFR,0 0;1 0;200 1\n",
+	} {
+		if _, err := parseOutlines(data, list); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+const (
+	DimensionCountry  = "country"
+	DimensionRegion   = "region"
+	DimensionCategory = "category"
+	DimensionProduct  = "product"
+
+	// Brands and subcategories come from the product catalog.
+	DimensionBrand       = "brand"
+	DimensionSubcategory = "subcategory"
+)
+
+var GrowthDimensions = []string{DimensionCountry, DimensionRegion, DimensionCategory, DimensionProduct}
+
+func newDimensionMonthly() map[string]map[string]map[string]models.PeriodTotals {
+	byDim := make(map[string]map[string]map[string]models.PeriodTotals, len(GrowthDimensions))
+	for _, dim := range GrowthDimensions {
+		byDim[dim] = make(map[string]map[string]models.PeriodTotals)
+	}
+	return byDim
+}
+
+// dimensionValue is the value tx is totalled under. Products and regions are
+// keyed by identity; Growth resolves their display names.
+func dimensionValue(tx models.Transaction, dimension string) string {
+	switch dimension {
+	case DimensionCountry:
+		return tx.Country
+	case DimensionRegion:
+		return regionKey(tx.Country, tx.Region)
+	case DimensionCategory:
+		return tx.Category
+	case DimensionProduct:
+		return productKey(tx.ProductID, tx.ProductName)
+	default:
+		return ""
+	}
+}
+
+func addDimensionMonthly(values map[string]map[string]models.PeriodTotals, value, month string, tx models.Transaction) {
+	months := values[value]
+	if months == nil {
+		months = make(map[string]models.PeriodTotals)
+		values[value] = months
+	}
+	totals := months[month]
+	totals.Revenue += tx.TotalPrice
+	totals.Orders++
+	totals.Units += tx.Quantity
+	months[month] = totals
+}
+
+func (a *Analytics) mergeDimensionMonthly(local, global map[string]map[string]map[string]models.PeriodTotals) {
+	for dim, values := range local {
+		for value, months := range values {
+			target := global[dim][value]
+			if target == nil {
+				global[dim][value] = months
+				continue
+			}
+			for month, totals := range months {
+				merged := target[month]
+				merged.Revenue += totals.Revenue
+				merged.Orders += totals.Orders
+				merged.Units += totals.Units
+				target[month] = merged
+			}
+		}
+	}
+}
+
+// Growth compares each value of a dimension in the given month (YYYY-MM)
+// against the previous month and the same month a year earlier. metric is
+// one of SeriesMetrics or a derived metric; a derived metric that is
+// undefined in a month counts as zero there, as in its time series. An empty
+// period selects the latest month with sales. Results are ordered by the
+// current period's metric, largest first.
+func (a *Analytics) Growth(ctx context.Context, dimension, period, metric string) ([]models.GrowthMetric, error) {
+	if !slices.Contains(GrowthDimensions, dimension) {
+		return nil, fmt.Errorf("unknown dimension %q", dimension)
+	}
+	derived, _ := a.DerivedMetric(metric)
+	if derived == nil && !slices.Contains(SeriesMetrics, metric) {
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+
+	a.mu.RLock()
+	totals := a.precomputed.DimensionMonthly[dimension]
+	store := a.precomputed.Store
+	latest := ""
+	if n := len(a.precomputed.DailySales); n > 0 {
+		latest = a.precomputed.DailySales[n-1].Date[:len("2006-01")]
+	}
+	a.mu.RUnlock()
+
+	if period == "" {
+		period = latest
+	}
+	if period == "" {
+		return []models.GrowthMetric{}, nil
+	}
+	current, err := time.Parse("2006-01", period)
+	if err != nil {
+		return nil, fmt.Errorf("period must be formatted as YYYY-MM, got %q", period)
+	}
+	prevMonth := current.AddDate(0, -1, 0).Format("2006-01")
+	prevYear := current.AddDate(-1, 0, 0).Format("2006-01")
+	periods := []string{period, prevMonth, prevYear}
+
+	// values holds the metric of each value in the compared months, keyed
+	// like DimensionMonthly.
+	var values map[string]map[string]float64
+	if derived != nil {
+		if values, err = derivedMonthly(ctx, store, derived, dimension, periods); err != nil {
+			return nil, err
+		}
+	} else {
+		values = make(map[string]map[string]float64, len(totals))
+		for value, months := range totals {
+			for _, month := range periods {
+				if t, ok := months[month]; ok {
+					if values[value] == nil {
+						values[value] = make(map[string]float64, len(periods))
+					}
+					values[value][month] = periodMetric(t, metric)
+				}
+			}
+		}
+	}
+
+	result := make([]models.GrowthMetric, 0, len(values))
+	for value, months := range values {
+		var productID, country string
+		switch dimension {
+		case DimensionProduct:
+			if code, ok := store.ProductDict.Lookup(value); ok {
+				value, productID = store.ProductNames[code], store.ProductIDs[code]
+			}
+		case DimensionRegion:
+			if code, ok := store.RegionDict.Lookup(value); ok {
+				value, country = store.RegionNames[code], store.CountryDict.Value(store.RegionCountries[code])
+			}
+		}
+		g := models.GrowthMetric{
+			Dimension:     dimension,
+			Value:         value,
+			ProductID:     productID,
+			Country:       country,
+			Period:        period,
+			Current:       months[period],
+			PreviousMonth: months[prevMonth],
+			PreviousYear:  months[prevYear],
+		}
+		g.MoMChange = g.Current - g.PreviousMonth
+		g.MoMPercent = percentChange(g.Current, g.PreviousMonth)
+		g.YoYChange = g.Current - g.PreviousYear
+		g.YoYPercent = percentChange(g.Current, g.PreviousYear)
+		result = append(result, g)
+	}
+
+	slices.SortFunc(result, func(a, b models.GrowthMetric) int {
+		if c := cmp.Compare(b.Current, a.Current); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Value, b.Value); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Country, b.Country); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+	return result, nil
+}
+
+// derivedMonthly evaluates a derived metric for every value of a dimension
+// in each of the given months (YYYY-MM), keyed like DimensionMonthly.
+func derivedMonthly(ctx context.Context, store *TransactionStore, m *DerivedMetric, dimension string, months []string) (map[string]map[string]float64, error) {
+	byIndex := make(map[int32]string, len(months))
+	for _, month := range months {
+		t, err := time.Parse("2006-01", month)
+		if err != nil {
+			return nil, err
+		}
+		byIndex[monthIndex(dayNumber(t))] = month
+	}
+
+	type key struct {
+		code  uint32
+		month string
+	}
+	column, dict := store.dimensionDict(dimension)
+	values, err := metricBy(ctx, store, m,
+		func(i int) bool {
+			_, ok := byIndex[monthIndex(store.Dates[i])]
+			return ok
+		},
+		func(i int) key { return key{column[i], byIndex[monthIndex(store.Dates[i])]} })
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]float64)
+	for k, v := range values {
+		value := dict.Value(k.code)
+		if result[value] == nil {
+			result[value] = make(map[string]float64, len(months))
+		}
+		result[value][k.month] = v
+	}
+	return result, nil
+}
+
+func periodMetric(totals models.PeriodTotals, metric string) float64 {
+	switch metric {
+	case MetricOrders:
+		return float64(totals.Orders)
+	case MetricUnits:
+		return float64(totals.Units)
+	default:
+		return totals.Revenue
+	}
+}
+
+// percentChange is undefined against an empty base period, reported as nil.
+func percentChange(current, base float64) *float64 {
+	if base == 0 {
+		return nil
+	}
+	pct := (current - base) / base * 100
+	return &pct
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func growthTestAnalytics() *Analytics {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Date: day(2022, 6, 10), Country: "USA", Region: "Texas", Category: "Toys", ProductName: "Kite", TotalPrice: 100, Quantity: 1},
+		{Date: day(2023, 5, 3), Country: "USA", Region: "Texas", Category: "Toys", ProductName: "Kite", TotalPrice: 200, Quantity: 2},
+		{Date: day(2023, 6, 1), Country: "USA", Region: "Texas", Category: "Toys", ProductName: "Kite", TotalPrice: 150, Quantity: 1},
+		{Date: day(2023, 6, 20), Country: "USA", Region: "Ohio", Category: "Books", ProductName: "Atlas", TotalPrice: 50, Quantity: 5},
+		{Date: day(2023, 6, 21), Country: "Canada", Region: "Ontario", Category: "Books", ProductName: "Atlas", TotalPrice: 80, Quantity: 1},
+	})
+	return a
+}
+
+func TestAnalytics_Growth_Country(t *testing.T) {
+	a := growthTestAnalytics()
+
+	result, err := a.Growth(context.Background(), DimensionCountry, "", MetricRevenue)
+	if err != nil {
+		t.Fatalf("Growth() error = %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("expected 2 countries, got %d", len(result))
+	}
+
+	usa := result[0]
+	if usa.Value != "USA" || usa.Period != "2023-06" {
+		t.Fatalf("expected USA for latest period first, got %+v", usa)
+	}
+	if usa.Current != 200 || usa.PreviousMonth != 200 || usa.PreviousYear != 100 {
+		t.Errorf("unexpected totals: %+v", usa)
+	}
+	if usa.MoMChange != 0 || usa.MoMPercent == nil || *usa.MoMPercent != 0 {
+		t.Errorf("unexpected MoM: %v %v", usa.MoMChange, usa.MoMPercent)
+	}
+	if usa.YoYChange != 100 || usa.YoYPercent == nil || *usa.YoYPercent != 100 {
+		t.Errorf("unexpected YoY: %v %v", usa.YoYChange, usa.YoYPercent)
+	}
+
+	canada := result[1]
+	if canada.MoMPercent != nil || canada.YoYPercent != nil {
+		t.Error("percent change against an empty base should be nil")
+	}
+}
+
+func TestAnalytics_Growth_DimensionsAndMetrics(t *testing.T) {
+	a := growthTestAnalytics()
+
+	tests := []struct {
+		dimension string
+		period    string
+		metric    string
+		wantValue string
+		wantCur   float64
+	}{
+		{DimensionRegion, "2023-06", MetricRevenue, "Texas", 150},
+		{DimensionCategory, "2023-06", MetricUnits, "Books", 6},
+		{DimensionProduct, "2023-05", MetricOrders, "Kite", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dimension, func(t *testing.T) {
+			result, err := a.Growth(context.Background(), tt.dimension, tt.period, tt.metric)
+			if err != nil {
+				t.Fatalf("Growth() error = %v", err)
+			}
+			if len(result) == 0 || result[0].Value != tt.wantValue || result[0].Current != tt.wantCur {
+				t.Errorf("top entry = %+v, want %s with %v", result, tt.wantValue, tt.wantCur)
+			}
+		})
+	}
+}
+
+func TestAnalytics_Growth_InvalidOptions(t *testing.T) {
+	a := growthTestAnalytics()
+
+	if _, err := a.Growth(context.Background(), "planet", "", MetricRevenue); err == nil {
+		t.Error("expected error for unknown dimension")
+	}
+	if _, err := a.Growth(context.Background(), DimensionCountry, "June", MetricRevenue); err == nil {
+		t.Error("expected error for malformed period")
+	}
+	if _, err := a.Growth(context.Background(), DimensionCountry, "", "margin"); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+}
+
+func TestAnalytics_Growth_LoadedFromCSV(t *testing.T) {
+	csv := `transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock,added_date
+T001,2023-01-15,U001,USA,California,P001,Laptop,Electronics,10,1,10,50,2023-01-01
+T002,2023-02-16,U002,USA,California,P001,Laptop,Electronics,10,2,20,50,2023-01-01`
+
+	f := createTempCSV(t, csv)
+	defer os.Remove(f)
+
+	a := NewAnalytics()
+	if err := a.LoadFromCSV(context.Background(), f); err != nil {
+		t.Fatalf("LoadFromCSV() error = %v", err)
+	}
+
+	result, err := a.Growth(context.Background(), DimensionProduct, "", MetricRevenue)
+	if err != nil {
+		t.Fatalf("Growth() error = %v", err)
+	}
+	if len(result) != 1 || result[0].MoMChange != 10 {
+		t.Errorf("expected Laptop MoM change of 10, got %+v", result)
+	}
+}
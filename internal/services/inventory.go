@@ -0,0 +1,171 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+const (
+	// velocityWindowDays is the trailing window, ending on the last day of
+	// data, over which sales velocity is measured.
+	velocityWindowDays = 30
+	// Products with less cover than lowStockDays risk stocking out before a
+	// typical reorder arrives; more than overstockDays ties up capital.
+	lowStockDays  = 14
+	overstockDays = 180
+)
+
+const (
+	StockStatusOut       = "out_of_stock"
+	StockStatusLow       = "low"
+	StockStatusHealthy   = "healthy"
+	StockStatusOverstock = "overstock"
+)
+
+var StockStatuses = []string{StockStatusOut, StockStatusLow, StockStatusHealthy, StockStatusOverstock}
+
+// Inventory returns the stock position of every product: the latest stock
+// reading by transaction date, units sold per day over the trailing window,
+// and the days of cover that leaves. Products with stock but no recent sales
+// count as overstocked. Items are ordered by days of cover, most urgent
+// first, and computed once per data set.
+func (a *Analytics) Inventory(ctx context.Context) ([]models.InventoryItem, error) {
+	precomputed := a.current()
+	return a.inventory.get(precomputed, func() ([]models.InventoryItem, error) {
+		return computeInventory(ctx, precomputed.Store)
+	})
+}
+
+// InventoryByStatus filters the inventory to one stock status.
+func (a *Analytics) InventoryByStatus(ctx context.Context, status string) ([]models.InventoryItem, error) {
+	if status != "" && !slices.Contains(StockStatuses, status) {
+		return nil, fmt.Errorf("unknown stock status %q", status)
+	}
+	items, err := a.Inventory(ctx)
+	if err != nil || status == "" {
+		return items, err
+	}
+	return slices.DeleteFunc(slices.Clone(items), func(item models.InventoryItem) bool {
+		return item.Status != status
+	}), nil
+}
+
+// stockReading is the latest stock level reported for a product and the
+// category of the row it came from.
+type stockReading struct {
+	day      int32
+	stock    int32
+	category uint32
+	seen     bool
+}
+
+// latestStock finds the most recent stock reading of every product among the
+// rows accepted by match, or all rows when match is nil, indexed by product
+// code. Several readings on the same day keep the lowest, since stock only
+// falls as the day's sales go through; this keeps the result independent of
+// the order rows are processed in.
+func latestStock(ctx context.Context, store *TransactionStore, match func(i int) bool) ([]stockReading, error) {
+	readings := make([]stockReading, len(store.ProductNames))
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if match != nil && !match(i) {
+			continue
+		}
+		r := &readings[store.Products[i]]
+		day, stock := store.Dates[i], store.Stocks[i]
+		if !r.seen || day > r.day || (day == r.day && stock < r.stock) {
+			*r = stockReading{day: day, stock: stock, category: store.Categories[i], seen: true}
+		}
+	}
+	return readings, nil
+}
+
+func computeInventory(ctx context.Context, store *TransactionStore) ([]models.InventoryItem, error) {
+	result := make([]models.InventoryItem, 0)
+	if store.Len() == 0 {
+		return result, nil
+	}
+
+	readings, err := latestStock(ctx, store, nil)
+	if err != nil {
+		return nil, err
+	}
+	sold := make([]int, len(readings))
+	lastDay := slices.Max(store.Dates)
+	windowStart := lastDay - velocityWindowDays + 1
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if store.Dates[i] >= windowStart {
+			sold[store.Products[i]] += int(store.Quantities[i])
+		}
+	}
+
+	for code, r := range readings {
+		if !r.seen {
+			continue
+		}
+		item := models.InventoryItem{
+			ProductID:   store.ProductIDs[code],
+			ProductName: store.ProductNames[code],
+			Category:    store.CategoryDict.Value(r.category),
+			Stock:       int(r.stock),
+			StockDate:   dayTime(r.day).Format(time.DateOnly),
+			UnitsSold:   sold[code],
+			Velocity:    float64(sold[code]) / velocityWindowDays,
+		}
+		if item.Velocity > 0 {
+			cover := float64(max(item.Stock, 0)) / item.Velocity
+			item.DaysOfCover = &cover
+		}
+		item.Status = stockStatus(item)
+		result = append(result, item)
+	}
+
+	slices.SortFunc(result, func(a, b models.InventoryItem) int {
+		if c := cmp.Compare(coverOrInf(a), coverOrInf(b)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.ProductName, b.ProductName); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+	return result, nil
+}
+
+func stockStatus(item models.InventoryItem) string {
+	switch {
+	case item.Stock <= 0:
+		return StockStatusOut
+	case item.DaysOfCover == nil || *item.DaysOfCover > overstockDays:
+		return StockStatusOverstock
+	case *item.DaysOfCover < lowStockDays:
+		return StockStatusLow
+	default:
+		return StockStatusHealthy
+	}
+}
+
+func coverOrInf(item models.InventoryItem) float64 {
+	if item.Stock <= 0 {
+		return 0
+	}
+	if item.DaysOfCover == nil {
+		return math.Inf(1)
+	}
+	return *item.DaysOfCover
+}
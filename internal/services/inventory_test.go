@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func inventoryTestAnalytics() *Analytics {
+	end := time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC)
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		// Kite: 60 units in the window, 10 left → 5 days of cover
+		{Date: end, ProductName: "Kite", Category: "Toys", Quantity: 30, Stock: 10},
+		{Date: end, ProductName: "Kite", Category: "Toys", Quantity: 30, Stock: 40},
+		{Date: end.AddDate(0, 0, -100), ProductName: "Kite", Category: "Toys", Quantity: 1, Stock: 500},
+		// Atlas: 3 units in the window, 30 left → 300 days of cover
+		{Date: end.AddDate(0, 0, -5), ProductName: "Atlas", Category: "Books", Quantity: 3, Stock: 30},
+		// Lamp: 30 units in the window, 30 left → 30 days of cover
+		{Date: end.AddDate(0, 0, -29), ProductName: "Lamp", Category: "Home", Quantity: 30, Stock: 30},
+		// Drone: sold out
+		{Date: end.AddDate(0, 0, -2), ProductName: "Drone", Category: "Toys", Quantity: 1, Stock: 0},
+		// Vase: nothing sold recently
+		{Date: end.AddDate(0, 0, -60), ProductName: "Vase", Category: "Home", Quantity: 1, Stock: 4},
+	})
+	return a
+}
+
+func TestAnalytics_Inventory(t *testing.T) {
+	a := inventoryTestAnalytics()
+
+	items, err := a.Inventory(context.Background())
+	if err != nil {
+		t.Fatalf("Inventory() error = %v", err)
+	}
+
+	want := []struct {
+		name   string
+		stock  int
+		status string
+	}{
+		{"Drone", 0, StockStatusOut},
+		{"Kite", 10, StockStatusLow},
+		{"Lamp", 30, StockStatusHealthy},
+		{"Atlas", 30, StockStatusOverstock},
+		{"Vase", 4, StockStatusOverstock},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d items, got %+v", len(want), items)
+	}
+	for i, w := range want {
+		if items[i].ProductName != w.name || items[i].Stock != w.stock || items[i].Status != w.status {
+			t.Errorf("item %d = %+v, want %s with %d in stock, %s", i, items[i], w.name, w.stock, w.status)
+		}
+	}
+
+	kite := items[1]
+	if kite.UnitsSold != 60 || kite.Velocity != 2 || kite.DaysOfCover == nil || *kite.DaysOfCover != 5 {
+		t.Errorf("Kite = %+v, want 60 sold at 2/day with 5 days of cover", kite)
+	}
+	if items[4].DaysOfCover != nil {
+		t.Errorf("Vase has no recent sales and should have no days of cover, got %v", *items[4].DaysOfCover)
+	}
+}
+
+func TestAnalytics_InventoryByStatus(t *testing.T) {
+	a := inventoryTestAnalytics()
+
+	items, err := a.InventoryByStatus(context.Background(), StockStatusOverstock)
+	if err != nil {
+		t.Fatalf("InventoryByStatus() error = %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("expected 2 overstocked products, got %+v", items)
+	}
+
+	if _, err := a.InventoryByStatus(context.Background(), "backordered"); err == nil {
+		t.Error("expected error for unknown status")
+	}
+}
+
+func TestAnalytics_TopProducts_LatestStock(t *testing.T) {
+	a := inventoryTestAnalytics()
+
+	for _, p := range a.TopProducts(10) {
+		if p.ProductName == "Kite" && (p.StockQuantity != 10 || p.StockDate != "2023-06-30") {
+			t.Errorf("Kite stock = %d on %s, want the lowest reading of the latest day (10 on 2023-06-30)", p.StockQuantity, p.StockDate)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+const (
+	// Launch performance is measured over the first 30 and 90 days after a
+	// product is added to the catalog, the launch day included.
+	launchWindowShort = 30
+	launchWindowLong  = 90
+
+	// MaxLaunchLookbackDays bounds how far back a launch still counts as
+	// recent.
+	MaxLaunchLookbackDays = 3650
+)
+
+// Launches returns the launch performance of every product with a known
+// catalog addition date: how long it took to sell and what it earned in its
+// first 30 and 90 days. A product's launch is the earliest addition date
+// recorded for it, and sales dated before it are ignored. Launches are
+// ordered newest first and computed once per data set.
+func (a *Analytics) Launches(ctx context.Context) ([]models.ProductLaunch, error) {
+	precomputed := a.current()
+	return a.launches.get(precomputed, func() ([]models.ProductLaunch, error) {
+		return computeLaunches(ctx, precomputed.Store)
+	})
+}
+
+// RecentLaunches ranks the products added within the last days of data by
+// their first-30-day revenue, biggest first.
+func (a *Analytics) RecentLaunches(ctx context.Context, days int) ([]models.ProductLaunch, error) {
+	if days < 1 || days > MaxLaunchLookbackDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxLaunchLookbackDays)
+	}
+	launches, err := a.Launches(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.ProductLaunch, 0)
+	for _, l := range launches {
+		// Launches are newest first, so the first one past the lookback
+		// ends the scan.
+		if l.DaysLive > days {
+			break
+		}
+		result = append(result, l)
+	}
+	slices.SortStableFunc(result, func(a, b models.ProductLaunch) int {
+		return cmp.Compare(b.Revenue30, a.Revenue30)
+	})
+	return result, nil
+}
+
+func computeLaunches(ctx context.Context, store *TransactionStore) ([]models.ProductLaunch, error) {
+	result := make([]models.ProductLaunch, 0)
+	if store.Len() == 0 {
+		return result, nil
+	}
+
+	// First pass: each product's launch day.
+	launches := make([]models.ProductLaunch, len(store.ProductNames))
+	added := make([]int32, len(launches))
+	for i := range added {
+		added[i] = math.MaxInt32
+	}
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if day := store.Added[i]; day != noDate {
+			product := store.Products[i]
+			added[product] = min(added[product], day)
+			launches[product].Category = store.CategoryDict.Value(store.Categories[i])
+		}
+	}
+
+	// Second pass: first sale and windowed totals since the launch.
+	firstSale := make([]int32, len(added))
+	for i := range firstSale {
+		firstSale[i] = math.MaxInt32
+	}
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		product := store.Products[i]
+		launch, day := added[product], store.Dates[i]
+		if launch == math.MaxInt32 || day < launch {
+			continue
+		}
+		l := &launches[product]
+		firstSale[product] = min(firstSale[product], day)
+		if age := day - launch; age < launchWindowLong {
+			l.Revenue90 += store.Totals[i]
+			l.Units90 += int(store.Quantities[i])
+			if age < launchWindowShort {
+				l.Revenue30 += store.Totals[i]
+				l.Units30 += int(store.Quantities[i])
+			}
+		}
+	}
+
+	lastDay := slices.Max(store.Dates)
+	for product, launch := range added {
+		if launch == math.MaxInt32 {
+			continue
+		}
+		l := launches[product]
+		l.ProductID = store.ProductIDs[product]
+		l.ProductName = store.ProductNames[product]
+		l.AddedDate = dayTime(launch).Format(time.DateOnly)
+		l.DaysLive = max(int(lastDay-launch)+1, 0)
+		if first := firstSale[product]; first != math.MaxInt32 {
+			days := int(first - launch)
+			l.FirstSaleDate = dayTime(first).Format(time.DateOnly)
+			l.DaysToFirstSale = &days
+		}
+		result = append(result, l)
+	}
+
+	slices.SortFunc(result, func(a, b models.ProductLaunch) int {
+		if c := cmp.Compare(b.AddedDate, a.AddedDate); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.ProductName, b.ProductName); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func launchTestAnalytics() *Analytics {
+	day := func(m time.Month, d int) time.Time { return time.Date(2023, m, d, 0, 0, 0, 0, time.UTC) }
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		// Kite: added Jan 1, first sold Jan 11, then once in each window
+		{Date: day(1, 11), ProductName: "Kite", Category: "Toys", Quantity: 2, TotalPrice: 20, AddedDate: day(1, 1)},
+		{Date: day(2, 15), ProductName: "Kite", Category: "Toys", Quantity: 1, TotalPrice: 10, AddedDate: day(1, 5)},
+		{Date: day(5, 1), ProductName: "Kite", Category: "Toys", Quantity: 1, TotalPrice: 10, AddedDate: day(1, 1)},
+		// Atlas: added Apr 20 but a stray sale predates it
+		{Date: day(4, 1), ProductName: "Atlas", Category: "Books", Quantity: 1, TotalPrice: 99, AddedDate: day(4, 20)},
+		{Date: day(4, 20), ProductName: "Atlas", Category: "Books", Quantity: 3, TotalPrice: 45, AddedDate: day(4, 20)},
+		// Lamp: added late April, not sold since
+		{Date: day(4, 10), ProductName: "Lamp", Category: "Home", Quantity: 1, TotalPrice: 5, AddedDate: day(4, 25)},
+		// Vase: no addition date
+		{Date: day(3, 1), ProductName: "Vase", Category: "Home", Quantity: 1, TotalPrice: 5},
+	})
+	return a
+}
+
+func TestAnalytics_Launches(t *testing.T) {
+	a := launchTestAnalytics()
+
+	launches, err := a.Launches(context.Background())
+	if err != nil {
+		t.Fatalf("Launches() error = %v", err)
+	}
+
+	names := make([]string, len(launches))
+	for i, l := range launches {
+		names[i] = l.ProductName
+	}
+	if len(launches) != 3 || names[0] != "Lamp" || names[1] != "Atlas" || names[2] != "Kite" {
+		t.Fatalf("expected Lamp, Atlas, Kite newest first, got %v", names)
+	}
+
+	kite := launches[2]
+	if kite.AddedDate != "2023-01-01" || kite.FirstSaleDate != "2023-01-11" || kite.DaysToFirstSale == nil || *kite.DaysToFirstSale != 10 {
+		t.Errorf("Kite launch = %+v, want first sale 10 days after 2023-01-01", kite)
+	}
+	if kite.Revenue30 != 20 || kite.Units30 != 2 || kite.Revenue90 != 30 || kite.Units90 != 3 {
+		t.Errorf("Kite windows = %+v, want 20/2 in 30 days and 30/3 in 90 days", kite)
+	}
+	if kite.DaysLive != 121 {
+		t.Errorf("Kite DaysLive = %d, want 121", kite.DaysLive)
+	}
+
+	atlas := launches[1]
+	if atlas.Revenue90 != 45 || *atlas.DaysToFirstSale != 0 {
+		t.Errorf("Atlas = %+v, sales before the launch should be ignored", atlas)
+	}
+
+	lamp := launches[0]
+	if lamp.DaysToFirstSale != nil || lamp.FirstSaleDate != "" || lamp.Category != "Home" {
+		t.Errorf("Lamp = %+v, want an unsold Home launch", lamp)
+	}
+}
+
+func TestAnalytics_RecentLaunches(t *testing.T) {
+	a := launchTestAnalytics()
+
+	launches, err := a.RecentLaunches(context.Background(), 30)
+	if err != nil {
+		t.Fatalf("RecentLaunches() error = %v", err)
+	}
+	if len(launches) != 2 || launches[0].ProductName != "Atlas" || launches[1].ProductName != "Lamp" {
+		t.Errorf("expected Atlas then Lamp, got %+v", launches)
+	}
+
+	if _, err := a.RecentLaunches(context.Background(), 0); err == nil {
+		t.Error("expected error for a zero lookback")
+	}
+}
+
+func TestParseTransactionFast_AddedDate(t *testing.T) {
+	record := []string{"T1", "2023-01-15", "U1", "USA", "Texas", "P1", "Kite", "Toys", "10", "1", "10", "5", "2022-12-01"}
+
+	tx, err := parseTransactionFast(record)
+	if err != nil {
+		t.Fatalf("parseTransactionFast() error = %v", err)
+	}
+	if want := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC); !tx.AddedDate.Equal(want) {
+		t.Errorf("AddedDate = %v, want %v", tx.AddedDate, want)
+	}
+
+	record[12] = ""
+	if tx, err := parseTransactionFast(record); err != nil || !tx.AddedDate.IsZero() {
+		t.Errorf("empty added date should parse as zero, got %v, %v", tx.AddedDate, err)
+	}
+
+	// A malformed added date must not drop the sale.
+	record[12] = "soon"
+	if tx, err := parseTransactionFast(record); err != nil || !tx.AddedDate.IsZero() || tx.TotalPrice != 10 {
+		t.Errorf("malformed added date should parse as zero, got %+v, %v", tx, err)
+	}
+}
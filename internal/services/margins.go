@@ -0,0 +1,93 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"abt-dashboard/internal/models"
+)
+
+var MarginDimensions = []string{DimensionCountry, DimensionCategory, DimensionBrand, DimensionSubcategory}
+
+// Margins returns the gross margin of every value of a dimension, biggest
+// revenue first. A row costs its quantity times the catalog's unit cost;
+// rows of products missing from the catalog count towards revenue only, so
+// margins cover the costed revenue. Sales without a catalog entry have an
+// empty brand and subcategory. Results are computed once per data set.
+func (a *Analytics) Margins(ctx context.Context, dimension string) ([]models.Margin, error) {
+	if !slices.Contains(MarginDimensions, dimension) {
+		return nil, fmt.Errorf("unknown dimension %q", dimension)
+	}
+
+	precomputed := a.current()
+	all, err := a.margins.get(precomputed, func() (map[string][]models.Margin, error) {
+		result := make(map[string][]models.Margin, len(MarginDimensions))
+		for _, dim := range MarginDimensions {
+			margins, err := computeMargins(ctx, precomputed.Store, dim)
+			if err != nil {
+				return nil, err
+			}
+			result[dim] = margins
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all[dimension], nil
+}
+
+// MissingCatalog lists the products sold without a catalog entry, biggest
+// revenue first.
+func (a *Analytics) MissingCatalog() []models.MissingCatalogEntry {
+	return a.current().MissingCatalog
+}
+
+func computeMargins(ctx context.Context, store *TransactionStore, dim string) ([]models.Margin, error) {
+	column, labels := store.dimensionColumn(dim)
+	margins := make([]models.Margin, len(labels))
+	for code, label := range labels {
+		margins[code] = models.Margin{Dimension: dim, Value: label}
+	}
+
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		m := &margins[column[i]]
+		m.Revenue += store.Totals[i]
+		m.Transactions++
+		if cost := store.Costs[i]; math.IsNaN(cost) {
+			m.UncostedRevenue += store.Totals[i]
+		} else {
+			m.CostedRevenue += store.Totals[i]
+			m.Cost += cost * float64(store.Quantities[i])
+		}
+	}
+
+	result := make([]models.Margin, 0, len(margins))
+	for _, m := range margins {
+		// Dictionary codes may exist for values that no longer occur.
+		if m.Transactions == 0 {
+			continue
+		}
+		m.GrossMargin = m.CostedRevenue - m.Cost
+		if m.CostedRevenue != 0 {
+			pct := m.GrossMargin / m.CostedRevenue * 100
+			m.MarginPercent = &pct
+		}
+		result = append(result, m)
+	}
+	slices.SortFunc(result, func(a, b models.Margin) int {
+		if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+	return result, nil
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/metricexpr"
+	"abt-dashboard/internal/services/sqlquery"
+)
+
+// DerivedMetric is a metric defined by an expression over aggregates of the
+// transaction columns, such as "sum(total_price) / sum(quantity)", counting
+// only the rows matching an optional WHERE condition, as in
+// "count() where total_price > 1000". The columns are those of SQLTable.
+type DerivedMetric struct {
+	Name string
+	Expr string
+
+	metric *sqlquery.Metric
+}
+
+// metricDimensionColumns maps the dimensions a derived metric can be grouped
+// by to their SQL columns. Products and regions are grouped by identity
+// instead; see EvaluateMetric.
+var metricDimensionColumns = map[string]string{
+	DimensionMonth:    "month",
+	DimensionCountry:  "country",
+	DimensionCategory: "category",
+}
+
+// ParseDerivedMetric parses a metric definition with metricexpr.Parse and
+// checks its columns and types against SQLTable. Mistakes in the expression
+// come back as *sqlquery.Error.
+func ParseDerivedMetric(name, expr string) (*DerivedMetric, error) {
+	def, err := metricexpr.Parse(name, expr)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(SQLColumns, name) {
+		return nil, fmt.Errorf("metric name %q is already a column", name)
+	}
+	// Columns resolve the same on any store, so an empty one is enough to
+	// check the expression.
+	c := &sqlCompiler{store: NewTransactionStore()}
+	if _, err := c.metric(def.Metric); err != nil {
+		return nil, err
+	}
+	return &DerivedMetric{Name: def.Name, Expr: def.Expr, metric: def.Metric}, nil
+}
+
+// metric compiles a metric definition into an aggregation.
+func (c *sqlCompiler) metric(m *sqlquery.Metric) (*sqlAggregation, error) {
+	agg := &sqlAggregation{}
+	if m.Where != nil {
+		where, err := c.compile(m.Where)
+		if err != nil {
+			return nil, err
+		}
+		if where.kind != sqlBool {
+			return nil, sqlquery.Errorf(m.Where, "WHERE needs a condition, got %s", sqlKindNames[where.kind])
+		}
+		agg.filter = where.cond
+	}
+	value, err := c.metricExpr(m.Expr, agg)
+	if err != nil {
+		return nil, err
+	}
+	agg.finish = func(results []any) any { return sqlNumberValue(value(results)) }
+	return agg, nil
+}
+
+// metricExpr compiles arithmetic over aggregates, appending each aggregate
+// to agg. Aggregates without a value, such as the average of no rows, make
+// the whole metric undefined. The shape of the expression was checked by
+// metricexpr.Parse.
+func (c *sqlCompiler) metricExpr(n sqlquery.Node, agg *sqlAggregation) (func(results []any) float64, error) {
+	switch n := n.(type) {
+	case *sqlquery.Number:
+		v := n.Value
+		return func([]any) float64 { return v }, nil
+	case *sqlquery.Unary:
+		if n.Op != "-" {
+			break
+		}
+		x, err := c.metricExpr(n.X, agg)
+		if err != nil {
+			return nil, err
+		}
+		return func(r []any) float64 { return -x(r) }, nil
+	case *sqlquery.Binary:
+		if !slices.Contains([]string{"+", "-", "*", "/"}, n.Op) {
+			break
+		}
+		l, err := c.metricExpr(n.Left, agg)
+		if err != nil {
+			return nil, err
+		}
+		r, err := c.metricExpr(n.Right, agg)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "+":
+			return func(v []any) float64 { return l(v) + r(v) }, nil
+		case "-":
+			return func(v []any) float64 { return l(v) - r(v) }, nil
+		case "*":
+			return func(v []any) float64 { return l(v) * r(v) }, nil
+		default:
+			return func(v []any) float64 { return l(v) / r(v) }, nil
+		}
+	case *sqlquery.Call:
+		a, err := c.aggregate(n)
+		if err != nil {
+			return nil, err
+		}
+		if a.arg.kind == sqlDate && (n.Func == "MIN" || n.Func == "MAX") {
+			return nil, sqlquery.Errorf(n, "%s of a date is not a number", n.Func)
+		}
+		k := len(agg.aggs)
+		agg.aggs = append(agg.aggs, a)
+		return func(results []any) float64 {
+			if v, ok := results[k].(float64); ok {
+				return v
+			}
+			return math.NaN()
+		}, nil
+	}
+	return nil, sqlquery.Errorf(n, "metrics combine aggregates and numbers with + - * /")
+}
+
+// RegisterDerivedMetric adds a metric defined by an expression. Its name
+// must not be taken by another metric.
+func (a *Analytics) RegisterDerivedMetric(m *DerivedMetric) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if slices.ContainsFunc(a.aggregators, func(r Aggregator) bool { return r.Name() == m.Name }) ||
+		slices.ContainsFunc(a.derivedMetrics, func(d *DerivedMetric) bool { return d.Name == m.Name }) {
+		return fmt.Errorf("metric %q is already registered", m.Name)
+	}
+	a.derivedMetrics = append(a.derivedMetrics, m)
+	return nil
+}
+
+// DerivedMetricNames lists the metrics defined by expressions in
+// registration order.
+func (a *Analytics) DerivedMetricNames() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, len(a.derivedMetrics))
+	for i, m := range a.derivedMetrics {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// DerivedMetric returns the registered derived metric with the given name.
+func (a *Analytics) DerivedMetric(name string) (*DerivedMetric, bool) {
+	m := a.derivedMetricsByName()[name]
+	return m, m != nil
+}
+
+// derivedMetricsByName returns the registered derived metrics keyed by name.
+func (a *Analytics) derivedMetricsByName() map[string]*DerivedMetric {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	metrics := make(map[string]*DerivedMetric, len(a.derivedMetrics))
+	for _, m := range a.derivedMetrics {
+		metrics[m.Name] = m
+	}
+	return metrics
+}
+
+// EvaluateMetric computes a derived metric over the rows matching q, once
+// per value of groupBy in value order, or as a single group with an empty
+// name when groupBy is empty. Values are nil where the metric is undefined,
+// such as a ratio over no rows. Products and regions are grouped by identity
+// and named by their display name, so products sharing a name stay apart and
+// are told apart by their product_id, and regions by their country.
+func (a *Analytics) EvaluateMetric(ctx context.Context, name string, q Query, groupBy string) ([]models.MetricGroup, error) {
+	metrics := a.derivedMetricsByName()
+	m := metrics[name]
+	if m == nil {
+		if slices.Contains(a.MetricNames(), name) {
+			return nil, fmt.Errorf("metric %q is computed during ingestion and cannot be filtered or grouped", name)
+		}
+		return nil, ErrUnknownMetric
+	}
+
+	store := a.current().Store
+	c := &sqlCompiler{store: store, metrics: metrics}
+	var groups []sqlExpr
+	var identify func(g *models.MetricGroup, value string)
+	switch column, ok := metricDimensionColumns[groupBy]; {
+	case groupBy == "":
+	case groupBy == DimensionProduct:
+		byCode := func(labels []string) sqlExpr {
+			return sqlExpr{kind: sqlText, code: func(i int) uint32 { return store.Products[i] }, labels: labels}
+		}
+		groups = append(groups, byCode(store.ProductNames), byCode(store.ProductIDs))
+		identify = func(g *models.MetricGroup, id string) { g.ProductID = id }
+	case groupBy == DimensionRegion:
+		countries := make([]string, len(store.RegionCountries))
+		for code, country := range store.RegionCountries {
+			countries[code] = store.CountryDict.Value(country)
+		}
+		byCode := func(labels []string) sqlExpr {
+			return sqlExpr{kind: sqlText, code: func(i int) uint32 { return store.Regions[i] }, labels: labels}
+		}
+		groups = append(groups, byCode(store.RegionNames), byCode(countries))
+		identify = func(g *models.MetricGroup, country string) { g.Country = country }
+	case ok:
+		e, err := c.column(&sqlquery.Column{Name: column})
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, e)
+	default:
+		return nil, fmt.Errorf("unknown dimension %q, must be one of: %s", groupBy, strings.Join(CubeDimensions, ", "))
+	}
+	match, ok := q.matcher(store)
+	if !ok {
+		match = func(int) bool { return false }
+	}
+	return evaluateMetric(ctx, c, m, match, groups, identify)
+}
+
+// evaluateMetric computes a derived metric per group of rows. The first
+// group expression names the groups; a second one, for products and
+// regions, tells groups sharing a name apart and is passed to identify.
+func evaluateMetric(ctx context.Context, c *sqlCompiler, m *DerivedMetric, match func(i int) bool, groups []sqlExpr, identify func(g *models.MetricGroup, value string)) ([]models.MetricGroup, error) {
+	agg, err := c.metric(m.metric)
+	if err != nil {
+		return nil, err
+	}
+	outputs := []sqlOutput{{name: m.Name, agg: agg, group: -1}}
+	for k := range groups {
+		outputs = append(outputs, sqlOutput{group: k})
+	}
+	rows, err := sqlGroupRows(ctx, c.store, match, groups, outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.MetricGroup, len(rows))
+	for k, row := range rows {
+		if len(groups) > 0 {
+			result[k].Group = row[1].(string)
+		}
+		if len(groups) > 1 {
+			identify(&result[k], row[2].(string))
+		}
+		if v, ok := row[0].(float64); ok {
+			result[k].Value = &v
+		}
+	}
+	return result, nil
+}
+
+// metricBy evaluates a derived metric over the store rows accepted by match,
+// or all rows when match is nil, once per key. Keys where the metric is
+// undefined are left out.
+func metricBy[K comparable](ctx context.Context, store *TransactionStore, m *DerivedMetric, match func(i int) bool, key func(i int) K) (map[K]float64, error) {
+	groups, err := newMetricGroups[K](store, m)
+	if err != nil {
+		return nil, err
+	}
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if match != nil && !match(i) {
+			continue
+		}
+		groups.add(key(i), i)
+	}
+	return groups.values(), nil
+}
+
+// metricGroups accumulates a derived metric per key, for callers that pick
+// the rows themselves.
+type metricGroups[K comparable] struct {
+	agg  *sqlAggregation
+	accs map[K][]sqlAccumulator
+}
+
+func newMetricGroups[K comparable](store *TransactionStore, m *DerivedMetric) (*metricGroups[K], error) {
+	agg, err := (&sqlCompiler{store: store}).metric(m.metric)
+	if err != nil {
+		return nil, err
+	}
+	return &metricGroups[K]{agg: agg, accs: make(map[K][]sqlAccumulator)}, nil
+}
+
+// add adds store row i to the group k.
+func (g *metricGroups[K]) add(k K, i int) {
+	acc, ok := g.accs[k]
+	if !ok {
+		acc = g.agg.newAccumulators()
+		g.accs[k] = acc
+	}
+	g.agg.add(acc, i)
+}
+
+// values returns the metric of every group where it is defined.
+func (g *metricGroups[K]) values() map[K]float64 {
+	values := make(map[K]float64, len(g.accs))
+	for k, acc := range g.accs {
+		if v, ok := g.agg.result(acc).(float64); ok {
+			values[k] = v
+		}
+	}
+	return values
+}
+
+// metricValue returns the value of a derived metric for a key as reported
+// in results: nil where the metric is undefined.
+func metricValue[K comparable](values map[K]float64, key K) *float64 {
+	if v, ok := values[key]; ok {
+		return &v
+	}
+	return nil
+}
+
+// metricSeries computes a derived metric per period between the first and
+// last day with sales. Periods without a value report zero, as the built-in
+// series do.
+func (a *Analytics) metricSeries(ctx context.Context, m *DerivedMetric, granularity string) ([]models.TimeSeriesPoint, error) {
+	data := a.current()
+	if len(data.DailySales) == 0 {
+		return []models.TimeSeriesPoint{}, nil
+	}
+
+	// Number the periods of the days with sales so rows can be grouped by
+	// a dictionary-coded period column.
+	codes := make(map[int32]uint32, len(data.DailySales))
+	var starts []time.Time
+	for _, ds := range data.DailySales {
+		day, err := time.Parse(time.DateOnly, ds.Date)
+		if err != nil {
+			continue
+		}
+		start := periodStart(day, granularity)
+		if len(starts) == 0 || !starts[len(starts)-1].Equal(start) {
+			starts = append(starts, start)
+		}
+		codes[dayNumber(day)] = uint32(len(starts) - 1)
+	}
+	labels := make([]string, len(starts))
+	for k, start := range starts {
+		labels[k] = start.Format(time.DateOnly)
+	}
+	store := data.Store
+	period := sqlExpr{kind: sqlText, code: func(i int) uint32 { return codes[store.Dates[i]] }, labels: labels}
+
+	c := &sqlCompiler{store: store, metrics: a.derivedMetricsByName()}
+	groups, err := evaluateMetric(ctx, c, m, func(int) bool { return true }, []sqlExpr{period}, nil)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]float64, len(groups))
+	for _, g := range groups {
+		if g.Value != nil {
+			values[g.Group] = *g.Value
+		}
+	}
+
+	points := make([]models.TimeSeriesPoint, 0)
+	end := starts[len(starts)-1]
+	for t := starts[0]; !t.After(end); t = nextPeriod(t, granularity) {
+		start := t.Format(time.DateOnly)
+		points = append(points, models.TimeSeriesPoint{
+			Period: periodLabel(t, granularity),
+			Start:  start,
+			Value:  values[start],
+		})
+	}
+	return points, nil
+}
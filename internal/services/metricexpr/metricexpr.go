@@ -0,0 +1,73 @@
+// Package metricexpr parses derived metric definitions: a name and an
+// expression over aggregates of the transaction columns, such as
+// "sum(total_price) / sum(quantity)", counting only the rows matching an
+// optional WHERE condition, as in "count() where total_price > 1000". It
+// checks the name and the shape of the expression; whether the columns
+// exist and have the right types is up to the table the metric is compiled
+// against.
+package metricexpr
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"abt-dashboard/internal/services/sqlquery"
+)
+
+// Definition is a parsed metric definition.
+type Definition struct {
+	Name   string
+	Expr   string
+	Metric *sqlquery.Metric
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// CheckName returns an error unless name is usable as a metric name:
+// lowercase letters, digits, '-' or '_', starting with a letter or digit.
+func CheckName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("metric name %q must be lowercase letters, digits, '-' or '_'", name)
+	}
+	return nil
+}
+
+// Parse parses a metric definition. Mistakes in the expression come back as
+// *sqlquery.Error.
+func Parse(name, expr string) (*Definition, error) {
+	if err := CheckName(name); err != nil {
+		return nil, err
+	}
+	m, err := sqlquery.ParseMetric(expr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExpr(m.Expr); err != nil {
+		return nil, err
+	}
+	return &Definition{Name: name, Expr: expr, Metric: m}, nil
+}
+
+// checkExpr checks that n only combines aggregates and numbers with
+// + - * /.
+func checkExpr(n sqlquery.Node) error {
+	switch n := n.(type) {
+	case *sqlquery.Number, *sqlquery.Call:
+		return nil
+	case *sqlquery.Unary:
+		if n.Op == "-" {
+			return checkExpr(n.X)
+		}
+	case *sqlquery.Binary:
+		if slices.Contains([]string{"+", "-", "*", "/"}, n.Op) {
+			if err := checkExpr(n.Left); err != nil {
+				return err
+			}
+			return checkExpr(n.Right)
+		}
+	case *sqlquery.Column:
+		return sqlquery.Errorf(n, "column %s must be inside an aggregate such as sum(%s)", n.Name, n.Name)
+	}
+	return sqlquery.Errorf(n, "metrics combine aggregates and numbers with + - * /")
+}
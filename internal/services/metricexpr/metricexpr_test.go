@@ -0,0 +1,50 @@
+package metricexpr
+
+import (
+	"errors"
+	"testing"
+
+	"abt-dashboard/internal/services/sqlquery"
+)
+
+func TestParse(t *testing.T) {
+	d, err := Parse("big_orders", "count() where total_price > 1000")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if d.Name != "big_orders" || d.Metric.Where == nil || d.Metric.Expr.String() != "COUNT(*)" {
+		t.Errorf("Parse() = %+v", d)
+	}
+
+	if _, err := Parse("avg_unit_price", "-sum(total_price) / (sum(quantity) + 1)"); err != nil {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"Bad Name", "count()"},
+		{"_hidden", "count()"},
+		{"unit_price", "total_price / quantity"},
+		{"compare", "sum(price) > 3"},
+		{"negated", "not count()"},
+		{"unknown_function", "median(price)"},
+		{"unfinished", "sum(total_price) / sum(quantity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" = "+tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.name, tt.expr)
+			if err == nil {
+				t.Fatalf("Parse(%q, %q) should fail", tt.name, tt.expr)
+			}
+			var qerr *sqlquery.Error
+			if CheckName(tt.name) == nil && !errors.As(err, &qerr) {
+				t.Errorf("expected a *sqlquery.Error, got %v", err)
+			}
+		})
+	}
+}
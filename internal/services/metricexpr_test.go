@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/sqlquery"
+)
+
+func derivedMetricTestAnalytics(t *testing.T) *Analytics {
+	t.Helper()
+	a := sqlTestAnalytics()
+	for name, expr := range map[string]string{
+		"avg_unit_price": "sum(total_price) / sum(quantity)",
+		"big_orders":     "count() where total_price > 15",
+	} {
+		m, err := ParseDerivedMetric(name, expr)
+		if err != nil {
+			t.Fatalf("ParseDerivedMetric(%s) error = %v", name, err)
+		}
+		if err := a.RegisterDerivedMetric(m); err != nil {
+			t.Fatalf("RegisterDerivedMetric(%s) error = %v", name, err)
+		}
+	}
+	return a
+}
+
+func metricGroupValues(groups []models.MetricGroup) map[string]any {
+	values := make(map[string]any, len(groups))
+	for _, g := range groups {
+		if g.Value == nil {
+			values[g.Group] = nil
+		} else {
+			values[g.Group] = *g.Value
+		}
+	}
+	return values
+}
+
+func TestAnalytics_EvaluateMetric(t *testing.T) {
+	a := derivedMetricTestAnalytics(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		metric  string
+		query   Query
+		groupBy string
+		want    map[string]any
+	}{
+		{"total", "avg_unit_price", Query{}, "", map[string]any{"": 125.0 / 9}},
+		{"filtered", "avg_unit_price", Query{Country: "Spain"}, "", map[string]any{"": 9.0}},
+		{"grouped", "avg_unit_price", Query{}, DimensionCountry, map[string]any{"Germany": 20.0, "Spain": 9.0}},
+		{"where clause", "big_orders", Query{}, DimensionCategory, map[string]any{"Home": 1.0, "Toys": 2.0}},
+		{"no rows", "avg_unit_price", Query{Category: "Garden"}, "", map[string]any{"": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := a.EvaluateMetric(ctx, tt.metric, tt.query, tt.groupBy)
+			if err != nil {
+				t.Fatalf("EvaluateMetric() error = %v", err)
+			}
+			got := metricGroupValues(groups)
+			if len(got) != len(tt.want) {
+				t.Fatalf("groups = %v, want %v", got, tt.want)
+			}
+			for group, want := range tt.want {
+				if got[group] != want {
+					t.Errorf("group %q = %v, want %v", group, got[group], want)
+				}
+			}
+		})
+	}
+
+	if _, err := a.EvaluateMetric(ctx, "nope", Query{}, ""); !errors.Is(err, ErrUnknownMetric) {
+		t.Errorf("expected ErrUnknownMetric, got %v", err)
+	}
+	if _, err := a.EvaluateMetric(ctx, "big_orders", Query{}, "color"); err == nil {
+		t.Error("expected an error for an unknown dimension")
+	}
+
+	if err := a.RegisterAggregator(WeekendShare()); err != nil {
+		t.Fatalf("RegisterAggregator() error = %v", err)
+	}
+	if _, err := a.EvaluateMetric(ctx, "weekend_share", Query{Country: "Spain"}, ""); err == nil || errors.Is(err, ErrUnknownMetric) {
+		t.Errorf("expected an error filtering an ingestion metric, got %v", err)
+	}
+}
+
+func TestAnalytics_EvaluateMetric_SharedProductName(t *testing.T) {
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{ProductID: "P1", ProductName: "Chair", Category: "Home", TotalPrice: 40},
+		{ProductID: "P2", ProductName: "Chair", Category: "Office", TotalPrice: 60},
+		{ProductID: "P2", ProductName: "Chair", Category: "Office", TotalPrice: 20},
+	})
+	m, err := ParseDerivedMetric("revenue_sum", "sum(total_price)")
+	if err != nil {
+		t.Fatalf("ParseDerivedMetric() error = %v", err)
+	}
+	if err := a.RegisterDerivedMetric(m); err != nil {
+		t.Fatalf("RegisterDerivedMetric() error = %v", err)
+	}
+
+	groups, err := a.EvaluateMetric(context.Background(), "revenue_sum", Query{}, DimensionProduct)
+	if err != nil {
+		t.Fatalf("EvaluateMetric() error = %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected two Chair groups, got %+v", groups)
+	}
+	for i, want := range []struct {
+		id    string
+		value float64
+	}{{"P1", 40}, {"P2", 80}} {
+		g := groups[i]
+		if g.Group != "Chair" || g.ProductID != want.id || g.Value == nil || *g.Value != want.value {
+			t.Errorf("group %d = %+v, want Chair %s with %v", i, g, want.id, want.value)
+		}
+	}
+}
+
+func TestAnalytics_EvaluateMetric_SharedRegionName(t *testing.T) {
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Country: "India", Region: "Punjab", TotalPrice: 40},
+		{Country: "Pakistan", Region: "Punjab", TotalPrice: 60},
+	})
+	m, err := ParseDerivedMetric("revenue_sum", "sum(total_price)")
+	if err != nil {
+		t.Fatalf("ParseDerivedMetric() error = %v", err)
+	}
+	if err := a.RegisterDerivedMetric(m); err != nil {
+		t.Fatalf("RegisterDerivedMetric() error = %v", err)
+	}
+
+	groups, err := a.EvaluateMetric(context.Background(), "revenue_sum", Query{}, DimensionRegion)
+	if err != nil {
+		t.Fatalf("EvaluateMetric() error = %v", err)
+	}
+	if len(groups) != 2 || groups[0].Country != "India" || *groups[0].Value != 40 || groups[1].Country != "Pakistan" || *groups[1].Value != 60 {
+		t.Errorf("groups = %+v, want Punjab in India and in Pakistan", groups)
+	}
+}
+
+func TestAnalytics_DerivedMetricEverywhere(t *testing.T) {
+	a := derivedMetricTestAnalytics(t)
+	ctx := context.Background()
+
+	value, err := a.Metric(ctx, "big_orders")
+	if err != nil {
+		t.Fatalf("Metric() error = %v", err)
+	}
+	if value != 3.0 {
+		t.Errorf("big_orders = %v, want 3", value)
+	}
+	if got := strings.Join(a.MetricNames(), ","); got != "avg_unit_price,big_orders" && got != "big_orders,avg_unit_price" {
+		t.Errorf("MetricNames() = %v", got)
+	}
+
+	points, err := a.TimeSeries(ctx, "month", "avg_unit_price")
+	if err != nil {
+		t.Fatalf("TimeSeries() error = %v", err)
+	}
+	if len(points) != 2 || points[0].Value != 10 || points[1].Value != 95.0/6 {
+		t.Errorf("TimeSeries() = %+v", points)
+	}
+
+	result, err := a.SQL(ctx, "SELECT country, avg_unit_price, big_orders FROM transactions GROUP BY country ORDER BY country", 100)
+	if err != nil {
+		t.Fatalf("SQL() error = %v", err)
+	}
+	want := [][]any{{"Germany", 20.0, 2.0}, {"Spain", 9.0, 1.0}}
+	if len(result.Rows) != len(want) {
+		t.Fatalf("rows = %v, want %v", result.Rows, want)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if result.Rows[i][j] != want[i][j] {
+				t.Errorf("row %d column %d = %v, want %v", i, j, result.Rows[i][j], want[i][j])
+			}
+		}
+	}
+
+	if _, err := a.SQL(ctx, "SELECT country FROM transactions WHERE big_orders > 1", 100); err == nil {
+		t.Error("expected an error using a metric outside an aggregate position")
+	}
+}
+
+func TestAnalytics_DerivedMetricBreakdowns(t *testing.T) {
+	a := derivedMetricTestAnalytics(t)
+	ctx := context.Background()
+	value := func(v *float64) any {
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+
+	products, err := a.RankedProducts(ctx, "avg_unit_price", 2)
+	if err != nil {
+		t.Fatalf("RankedProducts() error = %v", err)
+	}
+	if len(products) != 2 || products[0].ProductName != "Lamp" || value(products[0].MetricValue) != 50.0 ||
+		products[1].ProductName != "Kite" || value(products[1].MetricValue) != 10.0 {
+		t.Errorf("RankedProducts(avg_unit_price) = %+v, want Lamp 50, Kite 10", products)
+	}
+	regions, err := a.RankedRegions(ctx, "avg_unit_price", 3)
+	if err != nil {
+		t.Fatalf("RankedRegions() error = %v", err)
+	}
+	if len(regions) != 3 || regions[0].Region != "Berlin" || regions[1].Region != "Bavaria" || regions[2].Region != "North" ||
+		value(regions[2].MetricValue) != 9.0 {
+		t.Errorf("RankedRegions(avg_unit_price) = %+v, want Berlin, Bavaria, North 9", regions)
+	}
+	if _, err := a.RankedRegions(ctx, "margin", 3); err == nil || !strings.Contains(err.Error(), "big_orders") {
+		t.Errorf("RankedRegions(margin) error = %v, want one listing the derived metrics", err)
+	}
+
+	growth, err := a.Growth(ctx, DimensionCountry, "2023-02", "avg_unit_price")
+	if err != nil {
+		t.Fatalf("Growth() error = %v", err)
+	}
+	if len(growth) != 2 || growth[0].Value != "Germany" || growth[0].Current != 50 || growth[0].PreviousMonth != 10 ||
+		growth[1].Value != "Spain" || growth[1].Current != 9 || growth[1].MoMPercent != nil {
+		t.Errorf("Growth(avg_unit_price) = %+v", growth)
+	}
+
+	big, _ := a.DerivedMetric("big_orders")
+	rows, err := a.Cube().Query(ctx, CubeQuery{GroupBy: []string{DimensionCategory}, Filters: map[string][]string{DimensionMonth: {"2023-02"}}, Metric: big})
+	if err != nil {
+		t.Fatalf("Cube().Query() error = %v", err)
+	}
+	if len(rows) != 2 || value(rows[0].MetricValue) != 1.0 || value(rows[1].MetricValue) != 1.0 {
+		t.Errorf("Cube().Query(big_orders) = %+v, want one big order per category", rows)
+	}
+
+	node, err := a.Drilldown(ctx, nil, "avg_unit_price")
+	if err != nil {
+		t.Fatalf("Drilldown() error = %v", err)
+	}
+	if value(node.MetricValue) != 125.0/9 || len(node.Children) != 2 ||
+		value(node.Children[0].MetricValue) != 20.0 || value(node.Children[1].MetricValue) != 9.0 {
+		t.Errorf("Drilldown(avg_unit_price) = %+v", node)
+	}
+	if _, err := a.Drilldown(ctx, nil, "margin"); !errors.Is(err, ErrUnknownMetric) {
+		t.Errorf("Drilldown(margin) error = %v, want ErrUnknownMetric", err)
+	}
+
+	table, err := a.CountryRevenueMetric(ctx, a.CountryRevenue(), "avg_unit_price")
+	if err != nil {
+		t.Fatalf("CountryRevenueMetric() error = %v", err)
+	}
+	for _, r := range table {
+		want := map[string]float64{"Kite": 10, "Lamp": 50, "Vase": 5}[r.ProductName]
+		if value(r.MetricValue) != want {
+			t.Errorf("CountryRevenueMetric() %s/%s = %v, want %v", r.Country, r.ProductName, value(r.MetricValue), want)
+		}
+	}
+}
+
+func TestAnalytics_CompareDerivedMetrics(t *testing.T) {
+	a := derivedMetricTestAnalytics(t)
+	current := Query{From: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC)}
+	previous := Query{From: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)}
+
+	comparison, err := a.Compare(context.Background(), current, previous, 10)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	metrics := make(map[string]models.MetricComparison)
+	for _, m := range comparison.Metrics {
+		metrics[m.Name] = m
+	}
+	big := metrics["big_orders"]
+	if big.Current == nil || *big.Current != 2 || big.Previous == nil || *big.Previous != 1 || big.Percent == nil || *big.Percent != 100 {
+		t.Errorf("big_orders comparison = %+v, want 2 vs 1, +100%%", big)
+	}
+	avg := metrics["avg_unit_price"]
+	if avg.Current == nil || *avg.Current != 95.0/6 || avg.Previous == nil || *avg.Previous != 10 {
+		t.Errorf("avg_unit_price comparison = %+v, want 95/6 vs 10", avg)
+	}
+}
+
+func TestParseDerivedMetric_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"Bad Name", "count()"},
+		{"price", "count()"},
+		{"unit_price", "total_price / quantity"},
+		{"unknown", "sum(colour)"},
+		{"text_sum", "sum(country)"},
+		{"first_day", "min(date)"},
+		{"condition", "count() where total_price"},
+		{"compare", "sum(price) > 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" = "+tt.expr, func(t *testing.T) {
+			if _, err := ParseDerivedMetric(tt.name, tt.expr); err == nil {
+				t.Errorf("ParseDerivedMetric(%q, %q) should fail", tt.name, tt.expr)
+			}
+		})
+	}
+
+	_, err := ParseDerivedMetric("ratio", "sum(total_price) / sum(quantity")
+	var qerr *sqlquery.Error
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected a *sqlquery.Error, got %v", err)
+	}
+}
+
+func TestAnalytics_RegisterDerivedMetric(t *testing.T) {
+	a := NewAnalytics()
+	if err := a.RegisterAggregator(WeekendShare()); err != nil {
+		t.Fatalf("RegisterAggregator() error = %v", err)
+	}
+	clash, err := ParseDerivedMetric("weekend_share", "count()")
+	if err != nil {
+		t.Fatalf("ParseDerivedMetric() error = %v", err)
+	}
+	if err := a.RegisterDerivedMetric(clash); err == nil {
+		t.Error("expected an error for a name taken by an aggregator")
+	}
+
+	orders, _ := ParseDerivedMetric("orders", "count()")
+	if err := a.RegisterDerivedMetric(orders); err != nil {
+		t.Fatalf("RegisterDerivedMetric() error = %v", err)
+	}
+	if err := a.RegisterDerivedMetric(orders); err == nil {
+		t.Error("expected an error registering a name twice")
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/metricexpr"
+)
+
+// Aggregator computes a custom metric next to the built-in aggregates. It
+// follows the same path through ingestion: every batch observes its rows
+// into a fresh aggregator from New, which is then merged into the running
+// one, and the running one is finalized once the data set is complete.
+type Aggregator interface {
+	// Name identifies the metric and is served as /api/metrics/{name}.
+	Name() string
+	// New returns an empty aggregator of the same kind.
+	New() Aggregator
+	// Observe adds one transaction.
+	Observe(tx models.Transaction)
+	// Merge adds everything other has observed. other always comes from
+	// New on the same aggregator.
+	Merge(other Aggregator)
+	// Finalize returns the metric value, which must encode as JSON.
+	Finalize() any
+}
+
+var ErrUnknownMetric = errors.New("no metric with this name")
+
+// RegisterAggregator adds a custom metric. Register aggregators at startup,
+// before data is loaded: a metric only covers data sets loaded after it was
+// registered.
+func (a *Analytics) RegisterAggregator(agg Aggregator) error {
+	name := agg.Name()
+	if err := metricexpr.CheckName(name); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if slices.ContainsFunc(a.aggregators, func(r Aggregator) bool { return r.Name() == name }) ||
+		slices.ContainsFunc(a.derivedMetrics, func(d *DerivedMetric) bool { return d.Name == name }) {
+		return fmt.Errorf("metric %q is already registered", name)
+	}
+	a.aggregators = append(a.aggregators, agg)
+	return nil
+}
+
+// MetricNames lists the registered custom metrics, aggregators first, each
+// in registration order.
+func (a *Analytics) MetricNames() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, 0, len(a.aggregators)+len(a.derivedMetrics))
+	for _, agg := range a.aggregators {
+		names = append(names, agg.Name())
+	}
+	for _, m := range a.derivedMetrics {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// Metric returns the value of a custom metric over the current data set.
+// Derived metrics are evaluated on demand, to a float64 or nil.
+func (a *Analytics) Metric(ctx context.Context, name string) (any, error) {
+	if slices.Contains(a.DerivedMetricNames(), name) {
+		groups, err := a.EvaluateMetric(ctx, name, Query{}, "")
+		if err != nil {
+			return nil, err
+		}
+		if v := groups[0].Value; v != nil {
+			return *v, nil
+		}
+		return nil, nil
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	i := slices.IndexFunc(a.aggregators, func(r Aggregator) bool { return r.Name() == name })
+	if i < 0 {
+		return nil, ErrUnknownMetric
+	}
+	if value, ok := a.precomputed.metrics[name]; ok {
+		return value, nil
+	}
+	// Registered after the data was loaded: nothing has been observed yet.
+	return a.aggregators[i].New().Finalize(), nil
+}
+
+// newAggregators returns an empty aggregator for every registered metric.
+func (a *Analytics) newAggregators() []Aggregator {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	aggs := make([]Aggregator, len(a.aggregators))
+	for i, agg := range a.aggregators {
+		aggs[i] = agg.New()
+	}
+	return aggs
+}
+
+func finalizeMetrics(aggs []Aggregator) map[string]any {
+	metrics := make(map[string]any, len(aggs))
+	for _, agg := range aggs {
+		metrics[agg.Name()] = agg.Finalize()
+	}
+	return metrics
+}
+
+// replayMetrics computes the custom metrics from the stored rows, batch by
+// batch as ingestion would. Finalized values are not cached, so data loaded
+// from the cache gets its metrics this way.
+func (a *Analytics) replayMetrics(ctx context.Context, store *TransactionStore) (map[string]any, error) {
+	global := a.newAggregators()
+	if len(global) == 0 {
+		return map[string]any{}, nil
+	}
+	for start := 0; start < store.Len(); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		local := a.newAggregators()
+		for i := start; i < min(start+batchSize, store.Len()); i++ {
+			tx := store.Row(i)
+			for _, agg := range local {
+				agg.Observe(tx)
+			}
+		}
+		for k, agg := range global {
+			agg.Merge(local[k])
+		}
+	}
+	return finalizeMetrics(global), nil
+}
+
+// NewRatioAggregator builds a metric dividing the sum of numerator over all
+// transactions by the sum of denominator.
+func NewRatioAggregator(name string, numerator, denominator func(tx models.Transaction) float64) Aggregator {
+	return &ratioAggregator{name: name, numerator: numerator, denominator: denominator}
+}
+
+type ratioAggregator struct {
+	name                   string
+	numerator, denominator func(tx models.Transaction) float64
+	num, den               float64
+}
+
+func (r *ratioAggregator) Name() string { return r.name }
+
+func (r *ratioAggregator) New() Aggregator {
+	return &ratioAggregator{name: r.name, numerator: r.numerator, denominator: r.denominator}
+}
+
+func (r *ratioAggregator) Observe(tx models.Transaction) {
+	r.num += r.numerator(tx)
+	r.den += r.denominator(tx)
+}
+
+func (r *ratioAggregator) Merge(other Aggregator) {
+	o := other.(*ratioAggregator)
+	r.num += o.num
+	r.den += o.den
+}
+
+func (r *ratioAggregator) Finalize() any {
+	m := models.RatioMetric{Numerator: r.num, Denominator: r.den}
+	if r.den != 0 {
+		m.Ratio = r.num / r.den
+	}
+	return m
+}
+
+// WeekendShare is the share of revenue taken on Saturdays and Sundays.
+func WeekendShare() Aggregator {
+	return NewRatioAggregator("weekend_share",
+		func(tx models.Transaction) float64 {
+			if day := tx.Date.Weekday(); day == time.Saturday || day == time.Sunday {
+				return tx.TotalPrice
+			}
+			return 0
+		},
+		func(tx models.Transaction) float64 { return tx.TotalPrice },
+	)
+}
+
+// DiscountRate is the share of list value, price times quantity, that was
+// not charged.
+func DiscountRate() Aggregator {
+	return NewRatioAggregator("discount_rate",
+		func(tx models.Transaction) float64 { return tx.Price*float64(tx.Quantity) - tx.TotalPrice },
+		func(tx models.Transaction) float64 { return tx.Price * float64(tx.Quantity) },
+	)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+// countAggregator counts transactions, to check every row is observed once.
+type countAggregator struct{ n int }
+
+func (c *countAggregator) Name() string                  { return "count" }
+func (c *countAggregator) New() Aggregator               { return &countAggregator{} }
+func (c *countAggregator) Observe(tx models.Transaction) { c.n++ }
+func (c *countAggregator) Merge(other Aggregator)        { c.n += other.(*countAggregator).n }
+func (c *countAggregator) Finalize() any                 { return c.n }
+
+func TestAnalytics_Metric(t *testing.T) {
+	saturday := time.Date(2023, 1, 14, 0, 0, 0, 0, time.UTC)
+	monday := time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC)
+
+	a := NewAnalytics()
+	for _, agg := range []Aggregator{WeekendShare(), DiscountRate(), &countAggregator{}} {
+		if err := a.RegisterAggregator(agg); err != nil {
+			t.Fatalf("RegisterAggregator(%s) error = %v", agg.Name(), err)
+		}
+	}
+	a.SetData([]models.Transaction{
+		{Date: saturday, Price: 10, Quantity: 3, TotalPrice: 30},
+		{Date: monday, Price: 25, Quantity: 4, TotalPrice: 90},
+	})
+
+	weekend, err := a.Metric(context.Background(), "weekend_share")
+	if err != nil {
+		t.Fatalf("Metric() error = %v", err)
+	}
+	if got := weekend.(models.RatioMetric); got.Numerator != 30 || got.Denominator != 120 || got.Ratio != 0.25 {
+		t.Errorf("weekend_share = %+v", got)
+	}
+
+	discount, _ := a.Metric(context.Background(), "discount_rate")
+	if got := discount.(models.RatioMetric); math.Abs(got.Ratio-10.0/130) > 1e-9 {
+		t.Errorf("discount_rate = %+v, want ratio %v", got, 10.0/130)
+	}
+
+	if count, _ := a.Metric(context.Background(), "count"); count != 2 {
+		t.Errorf("count = %v, want 2", count)
+	}
+
+	if _, err := a.Metric(context.Background(), "nope"); !errors.Is(err, ErrUnknownMetric) {
+		t.Errorf("expected ErrUnknownMetric, got %v", err)
+	}
+
+	if got := a.MetricNames(); len(got) != 3 || got[0] != "weekend_share" || got[2] != "count" {
+		t.Errorf("MetricNames() = %v", got)
+	}
+}
+
+func TestAnalytics_RegisterAggregator(t *testing.T) {
+	a := NewAnalytics()
+	if err := a.RegisterAggregator(WeekendShare()); err != nil {
+		t.Fatalf("RegisterAggregator() error = %v", err)
+	}
+	if err := a.RegisterAggregator(WeekendShare()); err == nil {
+		t.Error("expected an error registering a name twice")
+	}
+	if err := a.RegisterAggregator(NewRatioAggregator("Bad Name", nil, nil)); err == nil {
+		t.Error("expected an error for a name that is not URL safe")
+	}
+
+	// Registered after the data was loaded, the metric reports nothing.
+	a.SetData([]models.Transaction{{Date: time.Now(), TotalPrice: 10}})
+	if err := a.RegisterAggregator(&countAggregator{}); err != nil {
+		t.Fatalf("RegisterAggregator() error = %v", err)
+	}
+	if count, err := a.Metric(context.Background(), "count"); err != nil || count != 0 {
+		t.Errorf("expected an empty count, got %v, %v", count, err)
+	}
+}
+
+func TestAnalytics_Metric_Ingestion(t *testing.T) {
+	csv := `transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock,added_date
+T001,2023-01-14,U001,USA,California,P001,Laptop,Electronics,10,1,10,50,2023-01-01
+T002,2023-02-16,U002,Canada,Ontario,P002,Mouse,Electronics,12,2,20,50,2023-01-01`
+
+	f := createTempCSV(t, csv)
+	defer os.Remove(f)
+
+	a := NewAnalytics()
+	a.RegisterAggregator(WeekendShare())
+	if err := a.LoadFromCSV(context.Background(), f); err != nil {
+		t.Fatalf("LoadFromCSV() error = %v", err)
+	}
+	defer os.Remove(a.getCacheFilename(f))
+
+	if m, _ := a.Metric(context.Background(), "weekend_share"); m.(models.RatioMetric).Numerator != 10 {
+		t.Errorf("weekend_share after ingestion = %+v", m)
+	}
+
+	// A second load comes from the cache, which does not hold the metrics;
+	// they are replayed from the stored rows, including new registrations.
+	cached := NewAnalytics()
+	cached.RegisterAggregator(WeekendShare())
+	cached.RegisterAggregator(DiscountRate())
+	if err := cached.LoadFromCSV(context.Background(), f); err != nil {
+		t.Fatalf("LoadFromCSV() from cache error = %v", err)
+	}
+	if m, _ := cached.Metric(context.Background(), "weekend_share"); m.(models.RatioMetric).Numerator != 10 {
+		t.Errorf("weekend_share after cache load = %+v", m)
+	}
+	if m, _ := cached.Metric(context.Background(), "discount_rate"); m.(models.RatioMetric).Numerator != 4 {
+		t.Errorf("discount_rate after cache load = %+v", m)
+	}
+}
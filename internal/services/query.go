@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+	"golang.org/x/sync/errgroup"
+)
+
+// ctxCheckInterval is how many rows a scan processes between context checks.
+const ctxCheckInterval = 1 << 16
+
+// Query restricts an aggregation to an inclusive date range and optional
+// dimension filters. A zero date leaves that side of the range open.
+type Query struct {
+	From     time.Time
+	To       time.Time
+	Country  string
+	Region   string
+	Category string
+}
+
+func (q Query) dateRange() models.DateRange {
+	r := models.DateRange{}
+	if !q.From.IsZero() {
+		r.From = q.From.Format(time.DateOnly)
+	}
+	if !q.To.IsZero() {
+		r.To = q.To.Format(time.DateOnly)
+	}
+	return r
+}
+
+// matcher compiles the query into a row predicate over the store. It reports
+// false when a filter names a value that never occurs, so nothing can match.
+func (q Query) matcher(store *TransactionStore) (func(i int) bool, bool) {
+	from, to := int32(-1<<31), int32(1<<31-1)
+	if !q.From.IsZero() {
+		from = dayNumber(q.From)
+	}
+	if !q.To.IsZero() {
+		to = dayNumber(q.To)
+	}
+
+	// A region name may stand for several regions, one per country.
+	type columnFilter struct {
+		column []uint32
+		codes  []uint32
+	}
+	var filters []columnFilter
+	for _, f := range []struct {
+		value     string
+		dimension string
+	}{
+		{q.Country, DimensionCountry},
+		{q.Region, DimensionRegion},
+		{q.Category, DimensionCategory},
+	} {
+		if f.value == "" {
+			continue
+		}
+		codes := store.dimensionLookup(f.dimension, f.value)
+		if len(codes) == 0 {
+			return nil, false
+		}
+		column, _ := store.dimensionColumn(f.dimension)
+		filters = append(filters, columnFilter{column: column, codes: codes})
+	}
+
+	return func(i int) bool {
+		if d := store.Dates[i]; d < from || d > to {
+			return false
+		}
+		for _, f := range filters {
+			if !slices.Contains(f.codes, f.column[i]) {
+				return false
+			}
+		}
+		return true
+	}, true
+}
+
+// Snapshot aggregates the stored transactions matching q into the same shape
+// as the precomputed data, so range queries reuse the ingestion aggregation.
+func (a *Analytics) Snapshot(ctx context.Context, q Query) (*PrecomputedData, error) {
+	return a.snapshot(ctx, q, false)
+}
+
+// snapshot aggregates the transactions matching q. A ranked snapshot holds
+// only the ranked views and daily sales, skipping the dimension series,
+// distributions and custom aggregators of a full one.
+func (a *Analytics) snapshot(ctx context.Context, q Query, ranked bool) (*PrecomputedData, error) {
+	a.mu.RLock()
+	store := a.precomputed.Store
+	a.mu.RUnlock()
+
+	groups := newAggregationGroups(a.newAggregators())
+	count := int64(0)
+
+	match, ok := q.matcher(store)
+	if !ok {
+		match = func(int) bool { return false }
+	}
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if !match(i) {
+			continue
+		}
+		if ranked {
+			addDailySale(groups.daily, dayTime(store.Dates[i]).Format(time.DateOnly), store.Totals[i], int(store.Quantities[i]))
+		} else {
+			a.aggregateTransaction(store.Row(i), groups)
+		}
+		count++
+	}
+
+	var snapshot *PrecomputedData
+	var err error
+	if ranked {
+		snapshot, err = rankedViews(ctx, store, match)
+		if err == nil {
+			snapshot.DailySales = a.sortDailySales(groups.daily)
+		}
+	} else {
+		snapshot, err = a.buildPrecomputed(ctx, groups, store, match)
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Store = nil
+	snapshot.RecordCount = count
+	return snapshot, nil
+}
+
+// Compare aggregates two queries side by side from ranked snapshots, which
+// hold every view it reads. The top rows of the current query are paired
+// with their values in the previous one, and the monthly series are aligned
+// by position so month one of each range lines up.
+func (a *Analytics) Compare(ctx context.Context, current, previous Query, limit int) (*models.Comparison, error) {
+	if current.From.IsZero() || current.To.IsZero() || current.To.Before(current.From) {
+		return nil, fmt.Errorf("current range needs a start on or before its end")
+	}
+	if previous.From.IsZero() || previous.To.IsZero() || previous.To.Before(previous.From) {
+		return nil, fmt.Errorf("comparison range needs a start on or before its end")
+	}
+
+	var cur, prev *PrecomputedData
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		cur, err = a.snapshot(gctx, current, true)
+		return err
+	})
+	g.Go(func() (err error) {
+		prev, err = a.snapshot(gctx, previous, true)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	metrics, err := a.compareMetrics(ctx, current, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Comparison{
+		Current:        current.dateRange(),
+		Previous:       previous.dateRange(),
+		CountryRevenue: compareCountryRevenue(cur.CountryRevenue, prev.CountryRevenue, limit),
+		TopProducts:    compareProducts(cur.TopProducts, prev.TopProducts, limit),
+		MonthlySales: compareSeries(
+			rollupBetween(cur.DailySales, current.From, current.To, GranularityMonth, MetricRevenue),
+			rollupBetween(prev.DailySales, previous.From, previous.To, GranularityMonth, MetricRevenue),
+		),
+		TopRegions: compareRegions(cur.TopRegions, prev.TopRegions, limit),
+		Metrics:    metrics,
+	}, nil
+}
+
+// compareMetrics evaluates every derived metric over both queries.
+func (a *Analytics) compareMetrics(ctx context.Context, current, previous Query) ([]models.MetricComparison, error) {
+	store := a.current().Store
+	metrics := a.derivedMetricsByName()
+	c := &sqlCompiler{store: store, metrics: metrics}
+	value := func(m *DerivedMetric, q Query) (*float64, error) {
+		match, ok := q.matcher(store)
+		if !ok {
+			match = func(int) bool { return false }
+		}
+		groups, err := evaluateMetric(ctx, c, m, match, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		return groups[0].Value, nil
+	}
+
+	result := make([]models.MetricComparison, 0, len(metrics))
+	for _, name := range a.DerivedMetricNames() {
+		mc := models.MetricComparison{Name: name}
+		var err error
+		if mc.Current, err = value(metrics[name], current); err != nil {
+			return nil, err
+		}
+		if mc.Previous, err = value(metrics[name], previous); err != nil {
+			return nil, err
+		}
+		if mc.Current != nil && mc.Previous != nil {
+			mc.Percent = percentChange(*mc.Current, *mc.Previous)
+		}
+		result = append(result, mc)
+	}
+	return result, nil
+}
+
+func newDelta(current, previous float64) models.Delta {
+	return models.Delta{
+		Current:  current,
+		Previous: previous,
+		Change:   current - previous,
+		Percent:  percentChange(current, previous),
+	}
+}
+
+func compareCountryRevenue(cur, prev []models.CountryRevenue, limit int) []models.CountryRevenueComparison {
+	previous := make(map[string]models.CountryRevenue, len(prev))
+	for _, row := range prev {
+		previous[row.Country+"|"+productKey(row.ProductID, row.ProductName)+"|"+row.Category] = row
+	}
+
+	result := make([]models.CountryRevenueComparison, 0, min(limit, len(cur)))
+	for _, row := range cur[:min(limit, len(cur))] {
+		p := previous[row.Country+"|"+productKey(row.ProductID, row.ProductName)+"|"+row.Category]
+		result = append(result, models.CountryRevenueComparison{
+			Country:      row.Country,
+			ProductID:    row.ProductID,
+			ProductName:  row.ProductName,
+			Category:     row.Category,
+			Revenue:      newDelta(row.TotalRevenue, p.TotalRevenue),
+			Transactions: newDelta(float64(row.Transactions), float64(p.Transactions)),
+		})
+	}
+	return result
+}
+
+func compareProducts(cur, prev []models.ProductFrequency, limit int) []models.ProductComparison {
+	previous := make(map[string]int, len(prev))
+	for _, p := range prev {
+		previous[productKey(p.ProductID, p.ProductName)] = p.Frequency
+	}
+
+	result := make([]models.ProductComparison, 0, min(limit, len(cur)))
+	for _, p := range cur[:min(limit, len(cur))] {
+		result = append(result, models.ProductComparison{
+			ProductID:   p.ProductID,
+			ProductName: p.ProductName,
+			Category:    p.Category,
+			Frequency:   newDelta(float64(p.Frequency), float64(previous[productKey(p.ProductID, p.ProductName)])),
+		})
+	}
+	return result
+}
+
+func compareRegions(cur, prev []models.RegionRevenue, limit int) []models.RegionComparison {
+	previous := make(map[string]models.RegionRevenue, len(prev))
+	for _, r := range prev {
+		previous[regionKey(r.Country, r.Region)] = r
+	}
+
+	result := make([]models.RegionComparison, 0, min(limit, len(cur)))
+	for _, r := range cur[:min(limit, len(cur))] {
+		p := previous[regionKey(r.Country, r.Region)]
+		result = append(result, models.RegionComparison{
+			Country:   r.Country,
+			Region:    r.Region,
+			Revenue:   newDelta(r.Revenue, p.Revenue),
+			ItemsSold: newDelta(float64(r.ItemsSold), float64(p.ItemsSold)),
+		})
+	}
+	return result
+}
+
+func compareSeries(cur, prev []models.TimeSeriesPoint) []models.PeriodComparison {
+	result := make([]models.PeriodComparison, max(len(cur), len(prev)))
+	for i := range result {
+		var c, p float64
+		if i < len(cur) {
+			result[i].CurrentPeriod = cur[i].Period
+			c = cur[i].Value
+		}
+		if i < len(prev) {
+			result[i].PreviousPeriod = prev[i].Period
+			p = prev[i].Value
+		}
+		result[i].Revenue = newDelta(c, p)
+	}
+	return result
+}
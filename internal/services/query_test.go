@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func comparisonTestAnalytics() *Analytics {
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Date: date(2022, 7, 10), Country: "USA", Region: "Texas", ProductName: "Kite", Category: "Toys", Quantity: 1, TotalPrice: 100},
+		{Date: date(2022, 9, 1), Country: "Canada", Region: "Ontario", ProductName: "Atlas", Category: "Books", Quantity: 2, TotalPrice: 40},
+		{Date: date(2023, 7, 4), Country: "USA", Region: "Texas", ProductName: "Kite", Category: "Toys", Quantity: 2, TotalPrice: 250},
+		{Date: date(2023, 8, 20), Country: "USA", Region: "Ohio", ProductName: "Kite", Category: "Toys", Quantity: 1, TotalPrice: 125},
+		{Date: date(2023, 10, 1), Country: "USA", Region: "Ohio", ProductName: "Kite", Category: "Toys", Quantity: 9, TotalPrice: 900},
+	})
+	return a
+}
+
+func TestAnalytics_Snapshot(t *testing.T) {
+	a := comparisonTestAnalytics()
+
+	tests := []struct {
+		name        string
+		query       Query
+		wantRecords int64
+		wantRevenue float64
+	}{
+		{"unbounded", Query{}, 5, 1415},
+		{"date range", Query{From: date(2023, 7, 1), To: date(2023, 9, 30)}, 2, 375},
+		{"open start", Query{To: date(2022, 12, 31)}, 2, 140},
+		{"country filter", Query{Country: "Canada"}, 1, 40},
+		{"region and range", Query{From: date(2023, 1, 1), Region: "Ohio"}, 2, 1025},
+		{"unknown value", Query{Category: "Garden"}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap, err := a.Snapshot(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("Snapshot() error = %v", err)
+			}
+			if snap.RecordCount != tt.wantRecords {
+				t.Errorf("RecordCount = %d, want %d", snap.RecordCount, tt.wantRecords)
+			}
+			revenue := 0.0
+			for _, r := range snap.TopRegions {
+				revenue += r.Revenue
+			}
+			if revenue != tt.wantRevenue {
+				t.Errorf("revenue = %v, want %v", revenue, tt.wantRevenue)
+			}
+		})
+	}
+}
+
+func TestAnalytics_Snapshot_Cancelled(t *testing.T) {
+	a := comparisonTestAnalytics()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := a.Snapshot(ctx, Query{}); err == nil {
+		t.Error("expected error from cancelled context")
+	}
+}
+
+func TestAnalytics_Snapshot_Ranked(t *testing.T) {
+	a := comparisonTestAnalytics()
+	q := Query{From: date(2023, 7, 1), To: date(2023, 9, 30)}
+
+	full, err := a.Snapshot(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	ranked, err := a.snapshot(context.Background(), q, true)
+	if err != nil {
+		t.Fatalf("snapshot() error = %v", err)
+	}
+	if !reflect.DeepEqual(ranked.CountryRevenue, full.CountryRevenue) || !reflect.DeepEqual(ranked.TopProducts, full.TopProducts) ||
+		!reflect.DeepEqual(ranked.TopRegions, full.TopRegions) || !reflect.DeepEqual(ranked.DailySales, full.DailySales) {
+		t.Errorf("ranked snapshot differs from the full one:\n%+v\n%+v", ranked, full)
+	}
+	if ranked.RecordCount != 2 || ranked.DimensionMonthly != nil || ranked.Distributions != nil {
+		t.Errorf("expected a ranked snapshot of 2 rows without series or distributions, got %+v", ranked)
+	}
+}
+
+func TestAnalytics_Compare(t *testing.T) {
+	a := comparisonTestAnalytics()
+
+	c, err := a.Compare(context.Background(),
+		Query{From: date(2023, 7, 1), To: date(2023, 9, 30)},
+		Query{From: date(2022, 7, 1), To: date(2022, 9, 30)},
+		10)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	if c.Current.From != "2023-07-01" || c.Previous.To != "2022-09-30" {
+		t.Errorf("unexpected ranges: %+v %+v", c.Current, c.Previous)
+	}
+
+	if len(c.CountryRevenue) != 1 {
+		t.Fatalf("expected one current country row, got %+v", c.CountryRevenue)
+	}
+	usa := c.CountryRevenue[0].Revenue
+	if usa.Current != 375 || usa.Previous != 100 || usa.Change != 275 || usa.Percent == nil || *usa.Percent != 275 {
+		t.Errorf("unexpected USA revenue delta: %+v", usa)
+	}
+
+	if len(c.MonthlySales) != 3 {
+		t.Fatalf("expected 3 aligned months, got %d", len(c.MonthlySales))
+	}
+	sep := c.MonthlySales[2]
+	if sep.CurrentPeriod != "2023-09" || sep.PreviousPeriod != "2022-09" || sep.Revenue.Current != 0 || sep.Revenue.Previous != 40 {
+		t.Errorf("unexpected September comparison: %+v", sep)
+	}
+
+	if len(c.TopRegions) != 2 || c.TopRegions[0].Region != "Texas" || c.TopRegions[0].Revenue.Previous != 100 {
+		t.Errorf("unexpected regions: %+v", c.TopRegions)
+	}
+	if len(c.TopProducts) != 1 || c.TopProducts[0].Frequency.Current != 2 || c.TopProducts[0].Frequency.Previous != 1 {
+		t.Errorf("unexpected products: %+v", c.TopProducts)
+	}
+}
+
+func TestAnalytics_Compare_InvalidRanges(t *testing.T) {
+	a := comparisonTestAnalytics()
+	valid := Query{From: date(2023, 1, 1), To: date(2023, 3, 31)}
+
+	if _, err := a.Compare(context.Background(), Query{}, valid, 10); err == nil {
+		t.Error("expected error for open current range")
+	}
+	if _, err := a.Compare(context.Background(), valid, Query{From: date(2022, 3, 1), To: date(2022, 1, 1)}, 10); err == nil {
+		t.Error("expected error for inverted comparison range")
+	}
+}
+
+func TestAnalytics_Snapshot_AfterCacheLoad(t *testing.T) {
+	csv := `transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock,added_date
+T001,2023-01-15,U001,USA,California,P001,Laptop,Electronics,10,1,10,50,2023-01-01
+T002,2023-02-16,U002,Canada,Ontario,P002,Mouse,Electronics,10,2,20,50,2023-01-01`
+
+	f := createTempCSV(t, csv)
+	defer os.Remove(f)
+
+	if err := NewAnalytics().LoadFromCSV(context.Background(), f); err != nil {
+		t.Fatalf("LoadFromCSV() error = %v", err)
+	}
+	defer os.Remove(NewAnalytics().getCacheFilename(f))
+
+	// A second load is served from the cache written by the first
+	a := NewAnalytics()
+	if err := a.LoadFromCSV(context.Background(), f); err != nil {
+		t.Fatalf("LoadFromCSV() from cache error = %v", err)
+	}
+
+	snap, err := a.Snapshot(context.Background(), Query{Country: "Canada"})
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snap.RecordCount != 1 {
+		t.Errorf("expected filter to work on cached store, got %d records", snap.RecordCount)
+	}
+
+	dist, err := a.Distribution(DistributionTotalPrice, DimensionCountry, "Canada", 1)
+	if err != nil || dist.Count != 1 || dist.Max != 20 {
+		t.Errorf("expected digests to survive the cache, got %+v, %v", dist, err)
+	}
+
+	rows, err := a.Cube().Query(context.Background(), CubeQuery{GroupBy: []string{DimensionCountry}, Filters: map[string][]string{DimensionMonth: {"2023-02"}}})
+	if err != nil || len(rows) != 1 || rows[0].Values[0] != "Canada" || rows[0].Revenue != 20 {
+		t.Errorf("expected the cube to survive the cache, got %+v, %v", rows, err)
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// WatchCSV polls the loaded CSV file every interval and reloads it when its
+// modification time changes, until ctx is cancelled. Each reload gets at most
+// timeout to finish; a failed reload keeps serving the previous data.
+func (a *Analytics) WatchCSV(ctx context.Context, interval, timeout time.Duration) {
+	path := a.csvPath
+	lastMod := a.csvModTime
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			a.logger.Warn("stat csv for reload", "filename", path, "error", err)
+			continue
+		}
+		if info.ModTime().Equal(lastMod) {
+			continue
+		}
+
+		a.logger.Info("csv changed, reloading", "filename", path, "modified", info.ModTime())
+		loadCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = a.LoadFromCSV(loadCtx, path)
+		cancel()
+		if err != nil {
+			a.logger.Error("reload csv", "filename", path, "error", err)
+		}
+		// A failed reload is retried on the next tick; a successful one
+		// records the modification time it saw.
+		lastMod = a.csvModTime
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+// Segments follow the common RFM grid over the recency score and the
+// combined frequency-monetary score.
+const (
+	SegmentChampions          = "champions"
+	SegmentLoyal              = "loyal_customers"
+	SegmentPotentialLoyalists = "potential_loyalists"
+	SegmentNewCustomers       = "new_customers"
+	SegmentPromising          = "promising"
+	SegmentNeedAttention      = "need_attention"
+	SegmentAboutToSleep       = "about_to_sleep"
+	SegmentAtRisk             = "at_risk"
+	SegmentCantLoseThem       = "cant_lose_them"
+	SegmentHibernating        = "hibernating"
+	SegmentLost               = "lost"
+)
+
+// Segments lists every segment from most to least valuable.
+var Segments = []string{
+	SegmentChampions, SegmentLoyal, SegmentPotentialLoyalists, SegmentNewCustomers,
+	SegmentPromising, SegmentNeedAttention, SegmentAboutToSleep, SegmentAtRisk,
+	SegmentCantLoseThem, SegmentHibernating, SegmentLost,
+}
+
+var SegmentNames = map[string]string{
+	SegmentChampions:          "Champions",
+	SegmentLoyal:              "Loyal Customers",
+	SegmentPotentialLoyalists: "Potential Loyalists",
+	SegmentNewCustomers:       "New Customers",
+	SegmentPromising:          "Promising",
+	SegmentNeedAttention:      "Need Attention",
+	SegmentAboutToSleep:       "About to Sleep",
+	SegmentAtRisk:             "At Risk",
+	SegmentCantLoseThem:       "Can't Lose Them",
+	SegmentHibernating:        "Hibernating",
+	SegmentLost:               "Lost",
+}
+
+// rfmBuckets is the number of score buckets per dimension (quintiles).
+const rfmBuckets = 5
+
+// Customers returns the RFM profile of every customer with a user ID, ordered
+// by spend, largest first. Recency is measured from the day after the last
+// transaction in the data; a customer's country is that of their most recent
+// purchase. Profiles are computed once per data set.
+func (a *Analytics) Customers(ctx context.Context) ([]models.CustomerRFM, error) {
+	precomputed := a.current()
+	return a.customers.get(precomputed, func() ([]models.CustomerRFM, error) {
+		return scoreCustomers(ctx, precomputed.Store)
+	})
+}
+
+func scoreCustomers(ctx context.Context, store *TransactionStore) ([]models.CustomerRFM, error) {
+	type profile struct {
+		last      int32
+		country   uint32
+		frequency int
+		monetary  float64
+		seen      bool
+	}
+	profiles := make([]profile, len(store.UserDict.Values))
+	lastDay := int32(math.MinInt32)
+
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		p := &profiles[store.Users[i]]
+		day := store.Dates[i]
+		if !p.seen || day >= p.last {
+			p.last, p.country = day, store.Countries[i]
+		}
+		p.seen = true
+		p.frequency++
+		p.monetary += store.Totals[i]
+		lastDay = max(lastDay, day)
+	}
+
+	customers := make([]models.CustomerRFM, 0, len(profiles))
+	for code, p := range profiles {
+		user := store.UserDict.Value(uint32(code))
+		if !p.seen || user == "" {
+			continue
+		}
+		customers = append(customers, models.CustomerRFM{
+			UserID:      user,
+			Country:     store.CountryDict.Value(p.country),
+			LastOrder:   dayTime(p.last).Format(time.DateOnly),
+			RecencyDays: int(lastDay-p.last) + 1,
+			Frequency:   p.frequency,
+			Monetary:    p.monetary,
+		})
+	}
+
+	// Fewer days since the last order is better, so recency ranks inverted.
+	assignScores(customers, func(c models.CustomerRFM) float64 { return -float64(c.RecencyDays) }, func(c *models.CustomerRFM, s int) { c.R = s })
+	assignScores(customers, func(c models.CustomerRFM) float64 { return float64(c.Frequency) }, func(c *models.CustomerRFM, s int) { c.F = s })
+	assignScores(customers, func(c models.CustomerRFM) float64 { return c.Monetary }, func(c *models.CustomerRFM, s int) { c.M = s })
+	for i := range customers {
+		customers[i].Segment = segmentFor(customers[i].R, (customers[i].F+customers[i].M+1)/2)
+	}
+
+	slices.SortFunc(customers, func(a, b models.CustomerRFM) int {
+		if c := cmp.Compare(b.Monetary, a.Monetary); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return customers, nil
+}
+
+// assignScores ranks customers by value and scores them 1 to rfmBuckets by
+// quantile, higher values scoring higher. Tied values share the score of the
+// first of them so equal customers never land in different buckets.
+func assignScores(customers []models.CustomerRFM, value func(models.CustomerRFM) float64, set func(*models.CustomerRFM, int)) {
+	order := make([]int, len(customers))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Compare(value(customers[a]), value(customers[b]))
+	})
+
+	score := 0
+	for rank, idx := range order {
+		if rank == 0 || value(customers[idx]) != value(customers[order[rank-1]]) {
+			score = rank*rfmBuckets/len(order) + 1
+		}
+		set(&customers[idx], score)
+	}
+}
+
+func segmentFor(r, fm int) string {
+	switch {
+	case r == 5 && fm >= 4:
+		return SegmentChampions
+	case r >= 3 && fm >= 4:
+		return SegmentLoyal
+	case r >= 4 && fm >= 2:
+		return SegmentPotentialLoyalists
+	case r == 5:
+		return SegmentNewCustomers
+	case r == 4:
+		return SegmentPromising
+	case r == 3 && fm == 3:
+		return SegmentNeedAttention
+	case r == 3:
+		return SegmentAboutToSleep
+	case fm == 5:
+		return SegmentCantLoseThem
+	case fm >= 3:
+		return SegmentAtRisk
+	case r == 1 && fm == 1:
+		return SegmentLost
+	default:
+		return SegmentHibernating
+	}
+}
+
+// CustomerSegments summarises how many customers fall in each segment and
+// what share of revenue they bring, overall and per country. An optional
+// country restricts the breakdown to that country.
+func (a *Analytics) CustomerSegments(ctx context.Context, country string) (*models.CustomerSegments, error) {
+	customers, err := a.Customers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CustomerSegments{Countries: make([]models.SegmentBreakdown, 0)}
+	overall := newSegmentTally("")
+	byCountry := make(map[string]*segmentTally)
+	var latest string
+	for _, c := range customers {
+		latest = max(latest, c.LastOrder)
+		if country != "" && c.Country != country {
+			continue
+		}
+		overall.add(c)
+		if byCountry[c.Country] == nil {
+			byCountry[c.Country] = newSegmentTally(c.Country)
+		}
+		byCountry[c.Country].add(c)
+	}
+
+	if latest != "" {
+		last, _ := time.Parse(time.DateOnly, latest)
+		result.ReferenceDate = last.AddDate(0, 0, 1).Format(time.DateOnly)
+	}
+	result.Overall = overall.breakdown()
+	for _, t := range byCountry {
+		result.Countries = append(result.Countries, t.breakdown())
+	}
+	slices.SortFunc(result.Countries, func(a, b models.SegmentBreakdown) int {
+		if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Country, b.Country)
+	})
+	return result, nil
+}
+
+// SegmentCustomers returns the customers of one segment, optionally limited
+// to a country, ordered by spend.
+func (a *Analytics) SegmentCustomers(ctx context.Context, segment, country string) ([]models.CustomerRFM, error) {
+	if _, ok := SegmentNames[segment]; !ok {
+		return nil, fmt.Errorf("unknown segment %q", segment)
+	}
+	customers, err := a.Customers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.CustomerRFM, 0)
+	for _, c := range customers {
+		if c.Segment == segment && (country == "" || c.Country == country) {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+type segmentTally struct {
+	country   string
+	customers int
+	revenue   float64
+	segments  map[string]*models.SegmentSummary
+}
+
+func newSegmentTally(country string) *segmentTally {
+	return &segmentTally{country: country, segments: make(map[string]*models.SegmentSummary)}
+}
+
+func (t *segmentTally) add(c models.CustomerRFM) {
+	t.customers++
+	t.revenue += c.Monetary
+	s := t.segments[c.Segment]
+	if s == nil {
+		s = &models.SegmentSummary{Segment: c.Segment, Name: SegmentNames[c.Segment]}
+		t.segments[c.Segment] = s
+	}
+	s.Customers++
+	s.Revenue += c.Monetary
+}
+
+// breakdown lists the segments in their canonical order, including empty
+// ones so every country reports the same rows.
+func (t *segmentTally) breakdown() models.SegmentBreakdown {
+	b := models.SegmentBreakdown{
+		Country:   t.country,
+		Customers: t.customers,
+		Revenue:   t.revenue,
+		Segments:  make([]models.SegmentSummary, 0, len(Segments)),
+	}
+	for _, id := range Segments {
+		s := models.SegmentSummary{Segment: id, Name: SegmentNames[id]}
+		if tallied := t.segments[id]; tallied != nil {
+			s = *tallied
+		}
+		if t.revenue > 0 {
+			s.RevenueShare = s.Revenue / t.revenue * 100
+		}
+		b.Segments = append(b.Segments, s)
+	}
+	return b
+}
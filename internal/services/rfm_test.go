@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+// rfmTestAnalytics builds ten customers where U0 is the most recent, most
+// frequent and biggest spender and U9 the least of all three.
+func rfmTestAnalytics() *Analytics {
+	end := time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC)
+	var txs []models.Transaction
+	for u := range 10 {
+		country := "USA"
+		if u%2 == 1 {
+			country = "Canada"
+		}
+		for n := range 10 - u {
+			txs = append(txs, models.Transaction{
+				Date:       end.AddDate(0, 0, -10*u-n),
+				UserID:     fmt.Sprintf("U%d", u),
+				Country:    country,
+				TotalPrice: float64(100 - 10*u),
+			})
+		}
+	}
+	// Transactions without a user are ignored
+	txs = append(txs, models.Transaction{Date: end, Country: "USA", TotalPrice: 1000})
+
+	a := NewAnalytics()
+	a.SetData(txs)
+	return a
+}
+
+func TestAnalytics_Customers(t *testing.T) {
+	a := rfmTestAnalytics()
+
+	customers, err := a.Customers(context.Background())
+	if err != nil {
+		t.Fatalf("Customers() error = %v", err)
+	}
+	if len(customers) != 10 {
+		t.Fatalf("expected 10 customers, got %d", len(customers))
+	}
+
+	best, worst := customers[0], customers[9]
+	if best.UserID != "U0" || best.R != 5 || best.F != 5 || best.M != 5 || best.Segment != SegmentChampions {
+		t.Errorf("best customer = %+v, want U0 scored 555 as a champion", best)
+	}
+	if best.RecencyDays != 1 || best.Frequency != 10 || best.Monetary != 1000 {
+		t.Errorf("best customer raw values = %+v", best)
+	}
+	if worst.UserID != "U9" || worst.R != 1 || worst.F != 1 || worst.M != 1 || worst.Segment != SegmentLost {
+		t.Errorf("worst customer = %+v, want U9 scored 111 as lost", worst)
+	}
+}
+
+func TestAssignScores_TiesShareAScore(t *testing.T) {
+	customers := []models.CustomerRFM{{Frequency: 1}, {Frequency: 1}, {Frequency: 1}, {Frequency: 2}, {Frequency: 9}}
+	assignScores(customers, func(c models.CustomerRFM) float64 { return float64(c.Frequency) }, func(c *models.CustomerRFM, s int) { c.F = s })
+
+	want := []int{1, 1, 1, 4, 5}
+	for i, c := range customers {
+		if c.F != want[i] {
+			t.Errorf("customer %d (frequency %d) scored %d, want %d", i, c.Frequency, c.F, want[i])
+		}
+	}
+}
+
+func TestSegmentFor(t *testing.T) {
+	tests := []struct {
+		r, fm int
+		want  string
+	}{
+		{5, 5, SegmentChampions},
+		{3, 4, SegmentLoyal},
+		{4, 2, SegmentPotentialLoyalists},
+		{5, 1, SegmentNewCustomers},
+		{4, 1, SegmentPromising},
+		{3, 3, SegmentNeedAttention},
+		{3, 1, SegmentAboutToSleep},
+		{2, 4, SegmentAtRisk},
+		{1, 5, SegmentCantLoseThem},
+		{2, 2, SegmentHibernating},
+		{1, 1, SegmentLost},
+	}
+	for _, tt := range tests {
+		if got := segmentFor(tt.r, tt.fm); got != tt.want {
+			t.Errorf("segmentFor(%d, %d) = %q, want %q", tt.r, tt.fm, got, tt.want)
+		}
+	}
+}
+
+func TestAnalytics_CustomerSegments(t *testing.T) {
+	a := rfmTestAnalytics()
+
+	segments, err := a.CustomerSegments(context.Background(), "")
+	if err != nil {
+		t.Fatalf("CustomerSegments() error = %v", err)
+	}
+	if segments.ReferenceDate != "2023-07-01" {
+		t.Errorf("reference date = %q, want 2023-07-01", segments.ReferenceDate)
+	}
+	if segments.Overall.Customers != 10 || len(segments.Overall.Segments) != len(Segments) {
+		t.Errorf("overall = %+v", segments.Overall)
+	}
+
+	share, customers := 0.0, 0
+	for _, s := range segments.Overall.Segments {
+		share += s.RevenueShare
+		customers += s.Customers
+	}
+	if customers != 10 || share < 99.999 || share > 100.001 {
+		t.Errorf("segments should cover every customer and all revenue, got %d customers and %.3f%%", customers, share)
+	}
+
+	if len(segments.Countries) != 2 || segments.Countries[0].Country != "USA" || segments.Countries[0].Customers != 5 {
+		t.Errorf("expected USA first with 5 customers, got %+v", segments.Countries)
+	}
+
+	canada, err := a.CustomerSegments(context.Background(), "Canada")
+	if err != nil {
+		t.Fatalf("CustomerSegments(Canada) error = %v", err)
+	}
+	if canada.Overall.Customers != 5 || len(canada.Countries) != 1 {
+		t.Errorf("expected only Canada's 5 customers, got %+v", canada)
+	}
+}
+
+func TestAnalytics_SegmentCustomers(t *testing.T) {
+	a := rfmTestAnalytics()
+
+	champions, err := a.SegmentCustomers(context.Background(), SegmentChampions, "")
+	if err != nil {
+		t.Fatalf("SegmentCustomers() error = %v", err)
+	}
+	if len(champions) == 0 || champions[0].UserID != "U0" {
+		t.Errorf("expected U0 among champions, got %+v", champions)
+	}
+
+	if _, err := a.SegmentCustomers(context.Background(), "vip", ""); err == nil {
+		t.Error("expected error for unknown segment")
+	}
+}
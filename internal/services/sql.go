@@ -0,0 +1,897 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/sqlquery"
+)
+
+// SQLTable is the one table SQL queries can read.
+const SQLTable = "transactions"
+
+// SQLColumns are the columns of SQLTable: the CSV fields plus month, the
+// transaction date as YYYY-MM.
+var SQLColumns = []string{
+	"transaction_date", "month", "user_id", "country", "region", "product_id",
+	"product_name", "category", "price", "quantity", "total_price", "stock", "added_date",
+}
+
+type sqlKind int
+
+const (
+	sqlNumber sqlKind = iota
+	sqlText
+	sqlDate
+	sqlBool
+)
+
+var sqlKindNames = map[sqlKind]string{sqlNumber: "a number", sqlText: "text", sqlDate: "a date", sqlBool: "a condition"}
+
+// sqlExpr is an expression compiled against the store. Only the accessor for
+// its kind is set. Text is always dictionary coded, so grouping, equality
+// and LIKE work on codes rather than strings.
+type sqlExpr struct {
+	kind   sqlKind
+	num    func(i int) float64
+	day    func(i int) int32
+	code   func(i int) uint32
+	labels []string
+	cond   func(i int) bool
+	// constant marks literals, which the compiler folds into comparisons.
+	constant bool
+}
+
+// key returns a value that is equal for rows with equal values of e.
+func (e sqlExpr) key(i int) uint64 {
+	switch e.kind {
+	case sqlNumber:
+		return math.Float64bits(e.num(i))
+	case sqlText:
+		return uint64(e.code(i))
+	case sqlDate:
+		return uint64(uint32(e.day(i)))
+	default:
+		if e.cond(i) {
+			return 1
+		}
+		return 0
+	}
+}
+
+// value returns the row's value as it appears in results: float64, string,
+// bool, or nil for missing dates and undefined arithmetic.
+func (e sqlExpr) value(i int) any {
+	switch e.kind {
+	case sqlNumber:
+		return sqlNumberValue(e.num(i))
+	case sqlText:
+		return e.labels[e.code(i)]
+	case sqlDate:
+		return sqlDateValue(e.day(i))
+	default:
+		return e.cond(i)
+	}
+}
+
+func sqlNumberValue(v float64) any {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return v
+}
+
+func sqlDateValue(day int32) any {
+	if day == noDate {
+		return nil
+	}
+	return dayTime(day).Format(time.DateOnly)
+}
+
+// repeatedLabels is a text column whose labels may repeat, as product names
+// do across products and region names across countries. Equal labels share
+// the code of the first, so rows compare and group by the value they show.
+func repeatedLabels(column []uint32, labels []string) sqlExpr {
+	first := make(map[string]uint32, len(labels))
+	canonical := make([]uint32, len(labels))
+	for code, label := range labels {
+		if _, ok := first[label]; !ok {
+			first[label] = uint32(code)
+		}
+		canonical[code] = first[label]
+	}
+	return sqlExpr{kind: sqlText, code: func(i int) uint32 { return canonical[column[i]] }, labels: labels}
+}
+
+// sqlCompiler turns syntax trees into expressions over one store.
+type sqlCompiler struct {
+	store *TransactionStore
+	// metrics are the derived metrics queries may select by name.
+	metrics map[string]*DerivedMetric
+}
+
+func (c *sqlCompiler) column(n *sqlquery.Column) (sqlExpr, error) {
+	s := c.store
+	dict := func(column []uint32, d *Dictionary) sqlExpr {
+		return sqlExpr{kind: sqlText, code: func(i int) uint32 { return column[i] }, labels: d.Values}
+	}
+	switch n.Name {
+	case "transaction_date":
+		return sqlExpr{kind: sqlDate, day: func(i int) int32 { return s.Dates[i] }}, nil
+	case "added_date":
+		return sqlExpr{kind: sqlDate, day: func(i int) int32 { return s.Added[i] }}, nil
+	case "month":
+		code, labels := s.dimensionCodes(DimensionMonth)
+		return sqlExpr{kind: sqlText, code: code, labels: labels}, nil
+	case "user_id":
+		return dict(s.Users, &s.UserDict), nil
+	case "country":
+		return dict(s.Countries, &s.CountryDict), nil
+	case "region":
+		return repeatedLabels(s.Regions, s.RegionNames), nil
+	case "product_id":
+		return repeatedLabels(s.Products, s.ProductIDs), nil
+	case "product_name":
+		return repeatedLabels(s.Products, s.ProductNames), nil
+	case "category":
+		return dict(s.Categories, &s.CategoryDict), nil
+	case "price":
+		return sqlExpr{kind: sqlNumber, num: func(i int) float64 { return s.Prices[i] }}, nil
+	case "quantity":
+		return sqlExpr{kind: sqlNumber, num: func(i int) float64 { return float64(s.Quantities[i]) }}, nil
+	case "total_price":
+		return sqlExpr{kind: sqlNumber, num: func(i int) float64 { return s.Totals[i] }}, nil
+	case "stock":
+		return sqlExpr{kind: sqlNumber, num: func(i int) float64 { return float64(s.Stocks[i]) }}, nil
+	default:
+		if c.metrics[n.Name] != nil {
+			return sqlExpr{}, sqlquery.Errorf(n, "metric %s is an aggregate and is not allowed here", n.Name)
+		}
+		return sqlExpr{}, sqlquery.Errorf(n, "unknown column %q, must be one of: %s", n.Name, strings.Join(SQLColumns, ", "))
+	}
+}
+
+// compile compiles a row-level expression; aggregates are not allowed.
+func (c *sqlCompiler) compile(n sqlquery.Node) (sqlExpr, error) {
+	switch n := n.(type) {
+	case *sqlquery.Column:
+		return c.column(n)
+	case *sqlquery.Number:
+		v := n.Value
+		return sqlExpr{kind: sqlNumber, num: func(int) float64 { return v }, constant: true}, nil
+	case *sqlquery.Text:
+		return sqlExpr{kind: sqlText, code: func(int) uint32 { return 0 }, labels: []string{n.Value}, constant: true}, nil
+	case *sqlquery.Unary:
+		x, err := c.compile(n.X)
+		if err != nil {
+			return sqlExpr{}, err
+		}
+		if n.Op == "NOT" {
+			if x.kind != sqlBool {
+				return sqlExpr{}, sqlquery.Errorf(n, "NOT needs a condition, got %s", sqlKindNames[x.kind])
+			}
+			return sqlExpr{kind: sqlBool, cond: func(i int) bool { return !x.cond(i) }}, nil
+		}
+		if x.kind != sqlNumber {
+			return sqlExpr{}, sqlquery.Errorf(n, "minus needs a number, got %s", sqlKindNames[x.kind])
+		}
+		return sqlExpr{kind: sqlNumber, num: func(i int) float64 { return -x.num(i) }, constant: x.constant}, nil
+	case *sqlquery.Binary:
+		return c.binary(n)
+	case *sqlquery.Between:
+		low := &sqlquery.Binary{Op: ">=", Left: n.X, Right: n.Low}
+		high := &sqlquery.Binary{Op: "<=", Left: n.X, Right: n.High}
+		var in sqlquery.Node = &sqlquery.Binary{Op: "AND", Left: low, Right: high}
+		if n.Not {
+			in = &sqlquery.Unary{Op: "NOT", X: in}
+		}
+		e, err := c.compile(in)
+		if err != nil {
+			// Point at BETWEEN rather than the synthesized nodes.
+			if qerr, ok := err.(*sqlquery.Error); ok && qerr.Token == "" {
+				return sqlExpr{}, sqlquery.Errorf(n, "%s", qerr.Msg)
+			}
+		}
+		return e, err
+	case *sqlquery.In:
+		return c.in(n)
+	case *sqlquery.Like:
+		return c.like(n)
+	case *sqlquery.Call:
+		return sqlExpr{}, sqlquery.Errorf(n, "aggregate %s is not allowed here", n.Func)
+	default:
+		return sqlExpr{}, sqlquery.Errorf(n, "unsupported expression")
+	}
+}
+
+func (c *sqlCompiler) binary(n *sqlquery.Binary) (sqlExpr, error) {
+	l, err := c.compile(n.Left)
+	if err != nil {
+		return sqlExpr{}, err
+	}
+	r, err := c.compile(n.Right)
+	if err != nil {
+		return sqlExpr{}, err
+	}
+
+	switch n.Op {
+	case "AND", "OR":
+		if l.kind != sqlBool || r.kind != sqlBool {
+			return sqlExpr{}, sqlquery.Errorf(n, "%s needs conditions on both sides", n.Op)
+		}
+		if n.Op == "AND" {
+			return sqlExpr{kind: sqlBool, cond: func(i int) bool { return l.cond(i) && r.cond(i) }}, nil
+		}
+		return sqlExpr{kind: sqlBool, cond: func(i int) bool { return l.cond(i) || r.cond(i) }}, nil
+	case "+", "-", "*", "/":
+		if l.kind != sqlNumber || r.kind != sqlNumber {
+			return sqlExpr{}, sqlquery.Errorf(n, "%s needs numbers on both sides", n.Op)
+		}
+		e := sqlExpr{kind: sqlNumber, constant: l.constant && r.constant}
+		switch n.Op {
+		case "+":
+			e.num = func(i int) float64 { return l.num(i) + r.num(i) }
+		case "-":
+			e.num = func(i int) float64 { return l.num(i) - r.num(i) }
+		case "*":
+			e.num = func(i int) float64 { return l.num(i) * r.num(i) }
+		default:
+			// Division by zero yields NaN or ±Inf, which results show as null.
+			e.num = func(i int) float64 { return l.num(i) / r.num(i) }
+		}
+		return e, nil
+	}
+
+	// Comparisons. Dates compare with date columns or 'YYYY-MM-DD' text.
+	if l.kind == sqlDate && r.kind == sqlText && r.constant {
+		if r, err = sqlDateLiteral(n.Right, r); err != nil {
+			return sqlExpr{}, err
+		}
+	} else if r.kind == sqlDate && l.kind == sqlText && l.constant {
+		if l, err = sqlDateLiteral(n.Left, l); err != nil {
+			return sqlExpr{}, err
+		}
+	}
+	if l.kind != r.kind || l.kind == sqlBool {
+		return sqlExpr{}, sqlquery.Errorf(n, "cannot compare %s with %s", sqlKindNames[l.kind], sqlKindNames[r.kind])
+	}
+
+	var order func(i int) int
+	switch l.kind {
+	case sqlNumber:
+		order = func(i int) int { return cmp.Compare(l.num(i), r.num(i)) }
+	case sqlDate:
+		order = func(i int) int {
+			a, b := l.day(i), r.day(i)
+			if a == noDate || b == noDate {
+				// Missing dates match no comparison; 2 fails every test below.
+				return 2
+			}
+			return cmp.Compare(a, b)
+		}
+	default:
+		// Equality against a literal compares dictionary codes.
+		if (n.Op == "=" || n.Op == "!=") && (l.constant || r.constant) {
+			if l.constant {
+				l, r = r, l
+			}
+			code, found := uint32(0), false
+			if i := slices.Index(l.labels, r.labels[0]); i >= 0 {
+				code, found = uint32(i), true
+			}
+			want := n.Op == "="
+			return sqlExpr{kind: sqlBool, cond: func(i int) bool { return (found && l.code(i) == code) == want }}, nil
+		}
+		order = func(i int) int { return strings.Compare(l.labels[l.code(i)], r.labels[r.code(i)]) }
+	}
+
+	var test func(o int) bool
+	switch n.Op {
+	case "=":
+		test = func(o int) bool { return o == 0 }
+	case "!=":
+		test = func(o int) bool { return o == -1 || o == 1 }
+	case "<":
+		test = func(o int) bool { return o == -1 }
+	case "<=":
+		test = func(o int) bool { return o == -1 || o == 0 }
+	case ">":
+		test = func(o int) bool { return o == 1 }
+	default:
+		test = func(o int) bool { return o == 0 || o == 1 }
+	}
+	return sqlExpr{kind: sqlBool, cond: func(i int) bool { return test(order(i)) }}, nil
+}
+
+func sqlDateLiteral(n sqlquery.Node, e sqlExpr) (sqlExpr, error) {
+	t, err := time.Parse(time.DateOnly, e.labels[0])
+	if err != nil {
+		return sqlExpr{}, sqlquery.Errorf(n, "dates must be written as 'YYYY-MM-DD'")
+	}
+	day := dayNumber(t)
+	return sqlExpr{kind: sqlDate, day: func(int) int32 { return day }, constant: true}, nil
+}
+
+func (c *sqlCompiler) in(n *sqlquery.In) (sqlExpr, error) {
+	x, err := c.compile(n.X)
+	if err != nil {
+		return sqlExpr{}, err
+	}
+	items := make([]sqlExpr, len(n.List))
+	for k, node := range n.List {
+		item, err := c.compile(node)
+		if err != nil {
+			return sqlExpr{}, err
+		}
+		if !item.constant {
+			return sqlExpr{}, sqlquery.Errorf(node, "IN lists take literal values")
+		}
+		if x.kind == sqlDate && item.kind == sqlText {
+			if item, err = sqlDateLiteral(node, item); err != nil {
+				return sqlExpr{}, err
+			}
+		}
+		if item.kind != x.kind {
+			return sqlExpr{}, sqlquery.Errorf(node, "cannot compare %s with %s", sqlKindNames[x.kind], sqlKindNames[item.kind])
+		}
+		items[k] = item
+	}
+
+	var member func(i int) bool
+	switch x.kind {
+	case sqlText:
+		codes := make(map[uint32]bool, len(items))
+		for _, item := range items {
+			if code := slices.Index(x.labels, item.labels[0]); code >= 0 {
+				codes[uint32(code)] = true
+			}
+		}
+		member = func(i int) bool { return codes[x.code(i)] }
+	case sqlNumber:
+		values := make(map[float64]bool, len(items))
+		for _, item := range items {
+			values[item.num(0)] = true
+		}
+		member = func(i int) bool { return values[x.num(i)] }
+	case sqlDate:
+		days := make(map[int32]bool, len(items))
+		for _, item := range items {
+			days[item.day(0)] = true
+		}
+		member = func(i int) bool { return days[x.day(i)] }
+	default:
+		return sqlExpr{}, sqlquery.Errorf(n, "IN needs a value, got %s", sqlKindNames[x.kind])
+	}
+	if n.Not {
+		return sqlExpr{kind: sqlBool, cond: func(i int) bool { return !member(i) }}, nil
+	}
+	return sqlExpr{kind: sqlBool, cond: member}, nil
+}
+
+func (c *sqlCompiler) like(n *sqlquery.Like) (sqlExpr, error) {
+	x, err := c.compile(n.X)
+	if err != nil {
+		return sqlExpr{}, err
+	}
+	if x.kind != sqlText {
+		return sqlExpr{}, sqlquery.Errorf(n, "LIKE needs text, got %s", sqlKindNames[x.kind])
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^(?s:")
+	for _, r := range n.Pattern.Value {
+		switch r {
+		case '%':
+			pattern.WriteString(".*")
+		case '_':
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	pattern.WriteString(")$")
+	re := regexp.MustCompile(pattern.String())
+
+	// Match every distinct value once instead of every row.
+	matches := make([]bool, len(x.labels))
+	for code, label := range x.labels {
+		matches[code] = re.MatchString(label) != n.Not
+	}
+	return sqlExpr{kind: sqlBool, cond: func(i int) bool { return matches[x.code(i)] }}, nil
+}
+
+// aggregated reports whether n holds an aggregate or a derived metric.
+func (c *sqlCompiler) aggregated(n sqlquery.Node) bool {
+	switch n := n.(type) {
+	case *sqlquery.Call:
+		return true
+	case *sqlquery.Column:
+		return c.metrics[n.Name] != nil
+	case *sqlquery.Unary:
+		return c.aggregated(n.X)
+	case *sqlquery.Binary:
+		return c.aggregated(n.Left) || c.aggregated(n.Right)
+	case *sqlquery.Between:
+		return c.aggregated(n.X) || c.aggregated(n.Low) || c.aggregated(n.High)
+	case *sqlquery.In:
+		return c.aggregated(n.X) || slices.ContainsFunc(n.List, c.aggregated)
+	case *sqlquery.Like:
+		return c.aggregated(n.X)
+	default:
+		return false
+	}
+}
+
+// sqlAggregate accumulates one aggregate over the rows of a group.
+type sqlAggregate struct {
+	fn       string
+	arg      sqlExpr
+	distinct bool
+}
+
+type sqlAccumulator struct {
+	n        int
+	sum      float64
+	min, max float64
+	seen     map[uint64]struct{}
+}
+
+func (agg *sqlAggregate) add(acc *sqlAccumulator, i int) {
+	if agg.arg.kind == sqlDate && agg.arg.day(i) == noDate {
+		return
+	}
+	var v float64
+	switch agg.arg.kind {
+	case sqlNumber:
+		v = agg.arg.num(i)
+	case sqlDate:
+		v = float64(agg.arg.day(i))
+	}
+	if agg.distinct {
+		if acc.seen == nil {
+			acc.seen = make(map[uint64]struct{})
+		}
+		acc.seen[agg.arg.key(i)] = struct{}{}
+		return
+	}
+	if acc.n == 0 || v < acc.min {
+		acc.min = v
+	}
+	if acc.n == 0 || v > acc.max {
+		acc.max = v
+	}
+	acc.n++
+	acc.sum += v
+}
+
+func (agg *sqlAggregate) result(acc *sqlAccumulator) any {
+	switch {
+	case agg.distinct:
+		return float64(len(acc.seen))
+	case agg.fn == "COUNT":
+		return float64(acc.n)
+	case agg.fn == "SUM":
+		return acc.sum
+	case acc.n == 0:
+		return nil
+	case agg.fn == "AVG":
+		return acc.sum / float64(acc.n)
+	}
+	v := acc.min
+	if agg.fn == "MAX" {
+		v = acc.max
+	}
+	if agg.arg.kind == sqlDate {
+		return sqlDateValue(int32(v))
+	}
+	return v
+}
+
+func (c *sqlCompiler) aggregate(n *sqlquery.Call) (*sqlAggregate, error) {
+	agg := &sqlAggregate{fn: n.Func, distinct: n.Distinct}
+	if n.Arg == nil {
+		// COUNT(*) counts rows; a constant argument does the same.
+		agg.arg = sqlExpr{kind: sqlNumber, num: func(int) float64 { return 1 }}
+		return agg, nil
+	}
+	if c.aggregated(n.Arg) {
+		return nil, sqlquery.Errorf(n.Arg, "aggregates cannot be nested")
+	}
+	arg, err := c.compile(n.Arg)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case n.Func == "COUNT":
+	case (n.Func == "SUM" || n.Func == "AVG") && arg.kind != sqlNumber:
+		return nil, sqlquery.Errorf(n, "%s needs a number, got %s", n.Func, sqlKindNames[arg.kind])
+	case (n.Func == "MIN" || n.Func == "MAX") && arg.kind != sqlNumber && arg.kind != sqlDate:
+		return nil, sqlquery.Errorf(n, "%s needs a number or date, got %s", n.Func, sqlKindNames[arg.kind])
+	}
+	agg.arg = arg
+	return agg, nil
+}
+
+// sqlOutput is one result column, visible or only sorted on.
+type sqlOutput struct {
+	name string
+	node sqlquery.Node
+	// Exactly one of row, group and agg is set: row for plain queries, group
+	// for a GROUP BY expression (by index), agg for an aggregate or metric.
+	row   *sqlExpr
+	group int
+	agg   *sqlAggregation
+}
+
+// sqlAggregation computes one value per group from one or more aggregates.
+type sqlAggregation struct {
+	aggs []*sqlAggregate
+	// filter, when set, limits the rows the aggregates see.
+	filter func(i int) bool
+	finish func(results []any) any
+}
+
+func (agg *sqlAggregation) add(accs []sqlAccumulator, i int) {
+	if agg.filter != nil && !agg.filter(i) {
+		return
+	}
+	for k, a := range agg.aggs {
+		a.add(&accs[k], i)
+	}
+}
+
+func (agg *sqlAggregation) result(accs []sqlAccumulator) any {
+	results := make([]any, len(agg.aggs))
+	for k, a := range agg.aggs {
+		results[k] = a.result(&accs[k])
+	}
+	return agg.finish(results)
+}
+
+func (agg *sqlAggregation) newAccumulators() []sqlAccumulator {
+	return make([]sqlAccumulator, len(agg.aggs))
+}
+
+type sqlOrder struct {
+	column int
+	desc   bool
+}
+
+// SQL runs a SELECT over the transactions table. Mistakes in the query come
+// back as *sqlquery.Error. At most maxRows rows are returned; a LIMIT above
+// that is an error, and without LIMIT the result is cut at maxRows and
+// marked truncated. The scan stops when ctx is done.
+func (a *Analytics) SQL(ctx context.Context, query string, maxRows int) (*models.SQLResult, error) {
+	q, err := sqlquery.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	if q.Table.Name != SQLTable {
+		return nil, sqlquery.Errorf(q.Table, "unknown table %q, the only table is %s", q.Table.Name, SQLTable)
+	}
+	limit := maxRows
+	if q.Limit != nil {
+		// Compare before converting: a huge LIMIT does not fit an int.
+		if q.Limit.Value > float64(maxRows) {
+			return nil, sqlquery.Errorf(q.Limit, "LIMIT can be at most %d", maxRows)
+		}
+		limit = int(q.Limit.Value)
+	}
+
+	store := a.current().Store
+	c := &sqlCompiler{store: store, metrics: a.derivedMetricsByName()}
+
+	match := func(int) bool { return true }
+	if q.Where != nil {
+		where, err := c.compile(q.Where)
+		if err != nil {
+			return nil, err
+		}
+		if where.kind != sqlBool {
+			return nil, sqlquery.Errorf(q.Where, "WHERE needs a condition, got %s", sqlKindNames[where.kind])
+		}
+		match = where.cond
+	}
+
+	grouped := len(q.GroupBy) > 0 || slices.ContainsFunc(q.Select, func(s sqlquery.SelectItem) bool { return c.aggregated(s.Expr) })
+	if q.Star && grouped {
+		return nil, &sqlquery.Error{Pos: q.StarPos, Token: "*", Msg: "SELECT * cannot be grouped"}
+	}
+
+	groupBy := make([]sqlExpr, len(q.GroupBy))
+	for k, n := range q.GroupBy {
+		if c.aggregated(n) {
+			return nil, sqlquery.Errorf(n, "aggregates are not allowed in GROUP BY")
+		}
+		if groupBy[k], err = c.compile(n); err != nil {
+			return nil, err
+		}
+	}
+
+	// output compiles a select or ORDER BY expression for the query's mode.
+	output := func(name string, n sqlquery.Node) (sqlOutput, error) {
+		out := sqlOutput{name: name, node: n, group: -1}
+		if !grouped {
+			e, err := c.compile(n)
+			out.row = &e
+			return out, err
+		}
+		if call, ok := n.(*sqlquery.Call); ok {
+			agg, err := c.aggregate(call)
+			out.agg = &sqlAggregation{aggs: []*sqlAggregate{agg}, finish: func(results []any) any { return results[0] }}
+			return out, err
+		}
+		if col, ok := n.(*sqlquery.Column); ok && c.metrics[col.Name] != nil {
+			agg, err := c.metric(c.metrics[col.Name].metric)
+			out.agg = agg
+			return out, err
+		}
+		if c.aggregated(n) {
+			return out, sqlquery.Errorf(n, "aggregates cannot be combined in expressions")
+		}
+		out.group = slices.IndexFunc(q.GroupBy, func(g sqlquery.Node) bool { return g.String() == n.String() })
+		if out.group < 0 {
+			return out, sqlquery.Errorf(n, "%s must appear in GROUP BY or inside an aggregate", n.String())
+		}
+		return out, nil
+	}
+
+	var outputs []sqlOutput
+	if q.Star {
+		for _, name := range SQLColumns {
+			out, err := output(name, &sqlquery.Column{Name: name})
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, out)
+		}
+	}
+	for _, item := range q.Select {
+		out, err := output(item.Name(), item.Expr)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	}
+	visible := len(outputs)
+
+	// ORDER BY names a position, an alias or a selected expression; anything
+	// else is computed as a hidden column.
+	orders := make([]sqlOrder, len(q.OrderBy))
+	for k, item := range q.OrderBy {
+		column := -1
+		switch n := item.Expr.(type) {
+		case *sqlquery.Number:
+			if n.Value != math.Trunc(n.Value) || n.Value < 1 || n.Value > float64(visible) {
+				return nil, sqlquery.Errorf(n, "ORDER BY position must be between 1 and %d", visible)
+			}
+			column = int(n.Value) - 1
+		case *sqlquery.Column:
+			column = slices.IndexFunc(outputs[:visible], func(o sqlOutput) bool { return strings.EqualFold(o.name, n.Name) })
+		}
+		if column < 0 {
+			column = slices.IndexFunc(outputs[:visible], func(o sqlOutput) bool { return o.node.String() == item.Expr.String() })
+		}
+		if column < 0 {
+			out, err := output(item.Expr.String(), item.Expr)
+			if err != nil {
+				return nil, err
+			}
+			column = len(outputs)
+			outputs = append(outputs, out)
+		}
+		orders[k] = sqlOrder{column: column, desc: item.Desc}
+	}
+
+	var rows [][]any
+	if grouped {
+		rows, err = sqlGroupRows(ctx, store, match, groupBy, outputs)
+	} else {
+		rows, err = sqlPlainRows(ctx, store, match, outputs, orders, limit+1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(orders) > 0 {
+		slices.SortStableFunc(rows, func(x, y []any) int {
+			for _, o := range orders {
+				c := compareSQLValues(x[o.column], y[o.column])
+				if o.desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+	}
+
+	result := &models.SQLResult{Columns: make([]string, visible), Rows: make([][]any, 0, min(len(rows), limit))}
+	for k, out := range outputs[:visible] {
+		result.Columns[k] = out.name
+	}
+	for _, row := range rows[:min(len(rows), limit)] {
+		result.Rows = append(result.Rows, row[:visible])
+	}
+	result.Truncated = q.Limit == nil && len(rows) > limit
+	return result, nil
+}
+
+// sqlPlainRows evaluates an ungrouped query. Without an order it stops after
+// want rows; with one it keeps only the want first rows in order.
+func sqlPlainRows(ctx context.Context, store *TransactionStore, match func(i int) bool, outputs []sqlOutput, orders []sqlOrder, want int) ([][]any, error) {
+	var picked []int
+	if len(orders) == 0 {
+		for i := range store.Len() {
+			if i%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			if len(picked) == want {
+				break
+			}
+			if match(i) {
+				picked = append(picked, i)
+			}
+		}
+	} else {
+		// topN keeps the greatest rows, so rank rows that sort first highest;
+		// ties keep row order.
+		compare := func(x, y int) int {
+			for _, o := range orders {
+				e := outputs[o.column].row
+				c := compareSQLValues(e.value(x), e.value(y))
+				if o.desc {
+					c = -c
+				}
+				if c != 0 {
+					return -c
+				}
+			}
+			return cmp.Compare(y, x)
+		}
+		candidates := make([]int, 0)
+		for i := range store.Len() {
+			if i%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if len(candidates) > 0 {
+					picked = topN(append(picked, candidates...), want, compare)
+					candidates = candidates[:0]
+				}
+			}
+			if match(i) {
+				candidates = append(candidates, i)
+			}
+		}
+		picked = topN(append(picked, candidates...), want, compare)
+	}
+
+	rows := make([][]any, len(picked))
+	for k, i := range picked {
+		row := make([]any, len(outputs))
+		for j, out := range outputs {
+			row[j] = out.row.value(i)
+		}
+		rows[k] = row
+	}
+	return rows, nil
+}
+
+// sqlGroupRows evaluates a grouped query. Without GROUP BY every row falls
+// in one group, which is reported even when no row matches.
+func sqlGroupRows(ctx context.Context, store *TransactionStore, match func(i int) bool, groupBy []sqlExpr, outputs []sqlOutput) ([][]any, error) {
+	type group struct {
+		row int
+		// accs holds the accumulators of each aggregated output.
+		accs [][]sqlAccumulator
+	}
+	newGroup := func(row int) *group {
+		g := &group{row: row, accs: make([][]sqlAccumulator, len(outputs))}
+		for k, out := range outputs {
+			if out.agg != nil {
+				g.accs[k] = out.agg.newAccumulators()
+			}
+		}
+		return g
+	}
+	groups := make(map[string]*group)
+	order := make([]*group, 0)
+	key := make([]byte, 8*len(groupBy))
+
+	for i := range store.Len() {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if !match(i) {
+			continue
+		}
+		for k, e := range groupBy {
+			binary.LittleEndian.PutUint64(key[8*k:], e.key(i))
+		}
+		g, ok := groups[string(key)]
+		if !ok {
+			g = newGroup(i)
+			groups[string(key)] = g
+			order = append(order, g)
+		}
+		for k, out := range outputs {
+			if out.agg != nil {
+				out.agg.add(g.accs[k], i)
+			}
+		}
+	}
+	if len(groupBy) == 0 && len(order) == 0 {
+		order = append(order, newGroup(-1))
+	}
+
+	rows := make([][]any, len(order))
+	for k, g := range order {
+		row := make([]any, len(outputs))
+		for j, out := range outputs {
+			if out.agg != nil {
+				row[j] = out.agg.result(g.accs[j])
+			} else {
+				row[j] = groupBy[out.group].value(g.row)
+			}
+		}
+		rows[k] = row
+	}
+	// Groups come out in order of first appearance unless ORDER BY says
+	// otherwise; sort by the grouped values so results do not depend on
+	// how rows were loaded.
+	slices.SortStableFunc(rows, func(x, y []any) int {
+		for j, out := range outputs {
+			if out.agg == nil {
+				if c := compareSQLValues(x[j], y[j]); c != 0 {
+					return c
+				}
+			}
+		}
+		return 0
+	})
+	return rows, nil
+}
+
+// compareSQLValues orders result values, with nulls first.
+func compareSQLValues(x, y any) int {
+	switch {
+	case x == nil && y == nil:
+		return 0
+	case x == nil:
+		return -1
+	case y == nil:
+		return 1
+	}
+	switch x := x.(type) {
+	case float64:
+		return cmp.Compare(x, y.(float64))
+	case string:
+		return strings.Compare(x, y.(string))
+	case bool:
+		switch {
+		case x == y.(bool):
+			return 0
+		case !x:
+			return -1
+		default:
+			return 1
+		}
+	default:
+		panic(fmt.Sprintf("unexpected SQL value %T", x))
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+	"abt-dashboard/internal/services/sqlquery"
+)
+
+func sqlTestAnalytics() *Analytics {
+	jan := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	added := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Date: jan, UserID: "u1", Country: "Germany", Region: "Bavaria", ProductName: "Kite", Category: "Toys", Price: 10, Quantity: 1, TotalPrice: 10, Stock: 5, AddedDate: added},
+		{Date: jan, UserID: "u2", Country: "Germany", Region: "Bavaria", ProductName: "Kite", Category: "Toys", Price: 10, Quantity: 2, TotalPrice: 20, Stock: 4},
+		{Date: feb, UserID: "u1", Country: "Germany", Region: "Berlin", ProductName: "Lamp", Category: "Home", Price: 50, Quantity: 1, TotalPrice: 50, Stock: 9},
+		{Date: feb, UserID: "u3", Country: "Spain", Region: "North", ProductName: "Kite", Category: "Toys", Price: 10, Quantity: 4, TotalPrice: 40, Stock: 3},
+		{Date: feb, UserID: "u3", Country: "Spain", Region: "North", ProductName: "Vase", Category: "Home", Price: 5, Quantity: 1, TotalPrice: 5, Stock: 0},
+	})
+	return a
+}
+
+func TestAnalytics_SQL(t *testing.T) {
+	a := sqlTestAnalytics()
+
+	tests := []struct {
+		name    string
+		query   string
+		columns []string
+		rows    [][]any
+	}{
+		{
+			name:    "filter and order",
+			query:   "SELECT product_name, total_price FROM transactions WHERE country = 'Germany' AND quantity >= 1 ORDER BY total_price DESC",
+			columns: []string{"product_name", "total_price"},
+			rows:    [][]any{{"Lamp", 50.0}, {"Kite", 20.0}, {"Kite", 10.0}},
+		},
+		{
+			name:    "group by with aggregates",
+			query:   "select country, sum(total_price) as revenue, count(*), count(distinct user_id), avg(quantity) from transactions group by country order by revenue desc",
+			columns: []string{"country", "revenue", "COUNT(*)", "COUNT(DISTINCT user_id)", "AVG(quantity)"},
+			rows:    [][]any{{"Germany", 80.0, 3.0, 2.0, 4.0 / 3}, {"Spain", 45.0, 2.0, 1.0, 2.5}},
+		},
+		{
+			name:    "order by position and limit",
+			query:   "SELECT month, category, SUM(quantity) FROM transactions GROUP BY month, category ORDER BY 3 DESC LIMIT 2",
+			columns: []string{"month", "category", "SUM(quantity)"},
+			rows:    [][]any{{"2023-02", "Toys", 4.0}, {"2023-01", "Toys", 3.0}},
+		},
+		{
+			name:    "order by an aggregate that is not selected",
+			query:   "SELECT region FROM transactions GROUP BY region ORDER BY SUM(total_price)",
+			columns: []string{"region"},
+			rows:    [][]any{{"Bavaria"}, {"North"}, {"Berlin"}},
+		},
+		{
+			name:    "in, like and between",
+			query:   "SELECT user_id FROM transactions WHERE product_name LIKE 'K%' OR (category IN ('Home') AND price BETWEEN 1 AND 10) ORDER BY user_id",
+			columns: []string{"user_id"},
+			rows:    [][]any{{"u1"}, {"u2"}, {"u3"}, {"u3"}},
+		},
+		{
+			name:    "dates",
+			query:   "SELECT transaction_date, added_date FROM transactions WHERE transaction_date < '2023-02-01' ORDER BY added_date",
+			columns: []string{"transaction_date", "added_date"},
+			rows:    [][]any{{"2023-01-10", nil}, {"2023-01-10", "2022-12-01"}},
+		},
+		{
+			name:    "global aggregate over no rows",
+			query:   "SELECT COUNT(*), SUM(total_price), MAX(transaction_date) FROM transactions WHERE country = 'France'",
+			columns: []string{"COUNT(*)", "SUM(total_price)", "MAX(transaction_date)"},
+			rows:    [][]any{{0.0, 0.0, nil}},
+		},
+		{
+			name:    "arithmetic and division by zero",
+			query:   "SELECT total_price / stock AS cover FROM transactions WHERE product_name = 'Vase'",
+			columns: []string{"cover"},
+			rows:    [][]any{{nil}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := a.SQL(context.Background(), tt.query, 100)
+			if err != nil {
+				t.Fatalf("SQL() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Columns, tt.columns) {
+				t.Errorf("columns = %v, want %v", result.Columns, tt.columns)
+			}
+			if !reflect.DeepEqual(result.Rows, tt.rows) {
+				t.Errorf("rows = %v, want %v", result.Rows, tt.rows)
+			}
+			if result.Truncated {
+				t.Error("result should not be truncated")
+			}
+		})
+	}
+}
+
+func TestAnalytics_SQLProducts(t *testing.T) {
+	day := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Date: day, ProductID: "P2", ProductName: "Cable", TotalPrice: 30},
+		{Date: day, ProductID: "P3", ProductName: "Cable", TotalPrice: 20},
+		{Date: day, ProductName: "Sticker", TotalPrice: 1},
+		{Date: day, ProductName: "Poster", TotalPrice: 2},
+	})
+
+	for query, want := range map[string][][]any{
+		// Products sharing a name group by what they show.
+		"SELECT product_name, SUM(total_price) FROM transactions WHERE product_name = 'Cable' GROUP BY product_name": {{"Cable", 50.0}},
+		"SELECT product_id FROM transactions WHERE product_name IN ('Cable') ORDER BY product_id":                    {{"P2"}, {"P3"}},
+		"SELECT COUNT(*) FROM transactions WHERE product_id = ''":                                                    {{2.0}},
+	} {
+		result, err := a.SQL(context.Background(), query, 100)
+		if err != nil {
+			t.Fatalf("%s: error = %v", query, err)
+		}
+		if !reflect.DeepEqual(result.Rows, want) {
+			t.Errorf("%s: rows = %v, want %v", query, result.Rows, want)
+		}
+	}
+}
+
+func TestAnalytics_SQLRowLimit(t *testing.T) {
+	a := sqlTestAnalytics()
+
+	result, err := a.SQL(context.Background(), "SELECT * FROM transactions", 2)
+	if err != nil {
+		t.Fatalf("SQL() error = %v", err)
+	}
+	if len(result.Rows) != 2 || !result.Truncated {
+		t.Errorf("expected 2 rows marked truncated, got %d rows, truncated %t", len(result.Rows), result.Truncated)
+	}
+	if !reflect.DeepEqual(result.Columns, SQLColumns) {
+		t.Errorf("SELECT * columns = %v", result.Columns)
+	}
+
+	result, err = a.SQL(context.Background(), "SELECT country FROM transactions LIMIT 2", 2)
+	if err != nil {
+		t.Fatalf("SQL() error = %v", err)
+	}
+	if result.Truncated {
+		t.Error("a result cut by LIMIT should not be marked truncated")
+	}
+
+	if _, err := a.SQL(context.Background(), "SELECT country FROM transactions LIMIT 3", 2); err == nil {
+		t.Error("expected an error for a LIMIT above the row limit")
+	}
+}
+
+func TestAnalytics_SQLErrors(t *testing.T) {
+	a := sqlTestAnalytics()
+
+	tests := []struct {
+		query string
+		token string
+		msg   string
+	}{
+		{"SELECT country FROM sales", "sales", "unknown table"},
+		{"SELECT colour FROM transactions", "colour", "unknown column"},
+		{"SELECT country FROM transactions WHERE price = 'ten'", "=", "cannot compare"},
+		{"SELECT country FROM transactions WHERE price", "price", "WHERE needs a condition"},
+		{"SELECT country, SUM(price) FROM transactions", "country", "must appear in GROUP BY"},
+		{"SELECT SUM(country) FROM transactions", "SUM", "needs a number"},
+		{"SELECT SUM(price) * 2 FROM transactions", "*", "cannot be combined"},
+		{"SELECT country FROM transactions WHERE SUM(price) > 1", "SUM", "not allowed here"},
+		{"SELECT * FROM transactions GROUP BY country", "*", "cannot be grouped"},
+		{"SELECT country FROM transactions ORDER BY 2", "2", "ORDER BY position"},
+		{"SELECT country FROM transactions ORDER BY 100000000000000000000", "100000000000000000000", "ORDER BY position"},
+		{"SELECT country FROM transactions LIMIT 9223372036854775807", "9223372036854775807", "LIMIT can be at most"},
+		{"SELECT country FROM transactions WHERE transaction_date > '2023-13-01'", "2023-13-01", "dates must be written"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := a.SQL(context.Background(), tt.query, 100)
+			var qerr *sqlquery.Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("expected a *sqlquery.Error, got %v", err)
+			}
+			if qerr.Token != tt.token || !strings.Contains(qerr.Msg, tt.msg) {
+				t.Errorf("error = %v, want %q near %q", qerr, tt.msg, tt.token)
+			}
+			// Strings are reported unquoted but positioned at their quote.
+			if got := strings.TrimPrefix(tt.query[qerr.Pos:], "'"); !strings.HasPrefix(strings.ToUpper(got), strings.ToUpper(tt.token)) {
+				t.Errorf("position %d points at %q, want %q", qerr.Pos, got, tt.token)
+			}
+		})
+	}
+}
+
+func TestAnalytics_SQLCancelled(t *testing.T) {
+	a := sqlTestAnalytics()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := a.SQL(ctx, "SELECT country FROM transactions", 100); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package sqlquery parses a restricted SELECT dialect: one table, WHERE,
+// GROUP BY, ORDER BY and LIMIT, with SUM, COUNT, AVG, MIN, MAX and
+// COUNT(DISTINCT ...) aggregates, and metric definitions built from the
+// same expressions. It only builds the syntax tree; evaluating it is up to
+// the caller. Every node and error carries the byte offset of the token it
+// came from so mistakes can be pointed out in the query text.
+package sqlquery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error reports a problem at a token of the query.
+type Error struct {
+	// Pos is the byte offset of the offending token.
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s at end of query", e.Msg)
+	}
+	return fmt.Sprintf("%s at position %d near %q", e.Msg, e.Pos+1, e.Token)
+}
+
+// Pointer renders the query line holding the error with a caret under the
+// offending token.
+func (e *Error) Pointer(query string) string {
+	pos := min(e.Pos, len(query))
+	start := strings.LastIndexByte(query[:pos], '\n') + 1
+	end := strings.IndexByte(query[pos:], '\n')
+	if end < 0 {
+		end = len(query)
+	} else {
+		end += pos
+	}
+	return query[start:end] + "\n" + strings.Repeat(" ", len([]rune(query[start:pos]))) + "^"
+}
+
+// Errorf builds an Error at a node, for callers reporting problems found
+// while evaluating the tree.
+func Errorf(n Node, format string, args ...any) *Error {
+	return &Error{Pos: n.Pos(), Token: n.token(), Msg: fmt.Sprintf(format, args...)}
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	// text is the token as written; for strings it is the unquoted value.
+	text string
+	pos  int
+	end  int
+}
+
+// is reports whether the token is the given keyword or symbol. Keywords
+// match regardless of case.
+func (t token) is(s string) bool {
+	switch t.kind {
+	case tokIdent:
+		return strings.EqualFold(t.text, s)
+	case tokSymbol:
+		return t.text == s
+	default:
+		return false
+	}
+}
+
+var keywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true,
+	"ORDER": true, "ASC": true, "DESC": true, "LIMIT": true, "AS": true,
+	"AND": true, "OR": true, "NOT": true, "IN": true, "LIKE": true,
+	"BETWEEN": true, "DISTINCT": true,
+}
+
+func isKeyword(t token) bool {
+	return t.kind == tokIdent && keywords[strings.ToUpper(t.text)]
+}
+
+func lex(query string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			// Comment to the end of the line.
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case isLetter(c):
+			start := i
+			for i < len(query) && (isLetter(query[i]) || isDigit(query[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: query[start:i], pos: start, end: i})
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			start := i
+			for i < len(query) && (isDigit(query[i]) || query[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: query[start:i], pos: start, end: i})
+		case c == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(query) {
+					return nil, &Error{Pos: start, Token: query[start:], Msg: "unterminated string"}
+				}
+				if query[i] == '\'' {
+					// A doubled quote stands for one quote.
+					if i+1 < len(query) && query[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(query[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start, end: i})
+		default:
+			start := i
+			if two := query[i:min(i+2, len(query))]; two == "<=" || two == ">=" || two == "<>" || two == "!=" {
+				i += 2
+			} else if strings.IndexByte("(),*=<>+-/;", c) >= 0 {
+				i++
+			} else {
+				return nil, &Error{Pos: start, Token: string(c), Msg: "unexpected character"}
+			}
+			tokens = append(tokens, token{kind: tokSymbol, text: query[start:i], pos: start, end: i})
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(query), end: len(query)}), nil
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
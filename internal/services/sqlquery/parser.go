@@ -0,0 +1,604 @@
+package sqlquery
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Aggregate functions. COUNT also takes * and DISTINCT.
+var Aggregates = []string{"COUNT", "SUM", "AVG", "MIN", "MAX"}
+
+// Node is an expression of the syntax tree.
+type Node interface {
+	// Pos is the byte offset of the token the node starts at.
+	Pos() int
+	// String renders the node canonically: identifiers in lower case,
+	// keywords and functions in upper case. Equal expressions render the
+	// same regardless of how they were written.
+	String() string
+	token() string
+}
+
+type at struct {
+	pos int
+	tok string
+}
+
+func (a at) Pos() int      { return a.pos }
+func (a at) token() string { return a.tok }
+
+func atToken(t token) at {
+	if t.kind == tokEOF {
+		return at{pos: t.pos}
+	}
+	return at{pos: t.pos, tok: t.text}
+}
+
+type Column struct {
+	at
+	Name string
+}
+
+func (c *Column) String() string { return c.Name }
+
+type Number struct {
+	at
+	Value float64
+}
+
+func (n *Number) String() string { return n.tok }
+
+type Text struct {
+	at
+	Value string
+}
+
+func (t *Text) String() string { return "'" + strings.ReplaceAll(t.Value, "'", "''") + "'" }
+
+// Unary is NOT or a minus sign.
+type Unary struct {
+	at
+	Op string
+	X  Node
+}
+
+func (u *Unary) String() string {
+	if u.Op == "NOT" {
+		return "NOT " + operand(u.X)
+	}
+	return u.Op + operand(u.X)
+}
+
+// Binary covers arithmetic, comparisons, AND and OR. Comparison operators
+// are normalized so <> reads as !=.
+type Binary struct {
+	at
+	Op          string
+	Left, Right Node
+}
+
+func (b *Binary) String() string {
+	return operand(b.Left) + " " + b.Op + " " + operand(b.Right)
+}
+
+type In struct {
+	at
+	X    Node
+	List []Node
+	Not  bool
+}
+
+func (n *In) String() string {
+	items := make([]string, len(n.List))
+	for i, item := range n.List {
+		items[i] = item.String()
+	}
+	return operand(n.X) + not(n.Not) + " IN (" + strings.Join(items, ", ") + ")"
+}
+
+type Between struct {
+	at
+	X, Low, High Node
+	Not          bool
+}
+
+func (b *Between) String() string {
+	return operand(b.X) + not(b.Not) + " BETWEEN " + operand(b.Low) + " AND " + operand(b.High)
+}
+
+// Like matches text against a pattern where % stands for any run of
+// characters and _ for exactly one.
+type Like struct {
+	at
+	X       Node
+	Pattern *Text
+	Not     bool
+}
+
+func (l *Like) String() string {
+	return operand(l.X) + not(l.Not) + " LIKE " + l.Pattern.String()
+}
+
+// Call is an aggregate. Arg is nil for COUNT(*).
+type Call struct {
+	at
+	Func     string
+	Arg      Node
+	Distinct bool
+}
+
+func (c *Call) String() string {
+	switch {
+	case c.Arg == nil:
+		return c.Func + "(*)"
+	case c.Distinct:
+		return c.Func + "(DISTINCT " + c.Arg.String() + ")"
+	default:
+		return c.Func + "(" + c.Arg.String() + ")"
+	}
+}
+
+// operand parenthesizes compound expressions inside another one so the
+// rendering keeps the tree's grouping.
+func operand(n Node) string {
+	switch n.(type) {
+	case *Binary, *In, *Between, *Like:
+		return "(" + n.String() + ")"
+	default:
+		return n.String()
+	}
+}
+
+func not(negated bool) string {
+	if negated {
+		return " NOT"
+	}
+	return ""
+}
+
+type SelectItem struct {
+	Expr  Node
+	Alias string
+}
+
+// Name is the column heading of the item: its alias, or the expression.
+func (s SelectItem) Name() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Expr.String()
+}
+
+type OrderItem struct {
+	Expr Node
+	Desc bool
+}
+
+type Query struct {
+	// Star selects every column; Select is then empty.
+	Star    bool
+	StarPos int
+	Select  []SelectItem
+	Table   *Column
+	Where   Node
+	GroupBy []Node
+	OrderBy []OrderItem
+	// Limit is nil when the query has no LIMIT clause.
+	Limit *Number
+}
+
+// Parse parses one SELECT statement. A trailing semicolon is allowed.
+func Parse(query string) (*Query, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	return p.query()
+}
+
+// Metric is a metric definition: an expression over aggregates, counting
+// only the rows matching Where when it is set.
+type Metric struct {
+	Expr  Node
+	Where Node
+}
+
+// ParseMetric parses a metric definition such as
+// "sum(total_price) / sum(quantity)" or "count() where total_price > 1000".
+func ParseMetric(def string) (*Metric, error) {
+	tokens, err := lex(def)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	m := &Metric{}
+	if m.Expr, err = p.expr(); err != nil {
+		return nil, err
+	}
+	if p.accept("WHERE") {
+		if m.Where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return m, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given keyword or symbol.
+func (p *parser) accept(s string) bool {
+	if p.peek().is(s) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.errorf("expected %s", s)
+	}
+	return nil
+}
+
+// errorf reports a problem at the next token.
+func (p *parser) errorf(format string, args ...any) *Error {
+	t := p.peek()
+	e := &Error{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+	if t.kind != tokEOF {
+		e.Token = t.text
+	}
+	return e
+}
+
+func (p *parser) query() (*Query, error) {
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	q := &Query{}
+
+	if t := p.peek(); t.is("*") {
+		p.next()
+		q.Star, q.StarPos = true, t.pos
+	} else {
+		for {
+			expr, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			item := SelectItem{Expr: expr}
+			if p.accept("AS") {
+				if t := p.peek(); t.kind != tokIdent || isKeyword(t) {
+					return nil, p.errorf("expected a column alias")
+				}
+				item.Alias = p.next().text
+			} else if t := p.peek(); t.kind == tokIdent && !isKeyword(t) {
+				item.Alias = p.next().text
+			}
+			q.Select = append(q.Select, item)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokIdent || isKeyword(t) {
+		return nil, p.errorf("expected a table name")
+	}
+	p.next()
+	q.Table = &Column{at: atToken(t), Name: strings.ToLower(t.text)}
+
+	if p.accept("WHERE") {
+		where, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		q.Where = where
+	}
+
+	if p.accept("GROUP") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			expr, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			q.GroupBy = append(q.GroupBy, expr)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			expr, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			item := OrderItem{Expr: expr}
+			if p.accept("DESC") {
+				item.Desc = true
+			} else {
+				p.accept("ASC")
+			}
+			q.OrderBy = append(q.OrderBy, item)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("LIMIT") {
+		t := p.peek()
+		n, err := strconv.Atoi(t.text)
+		if t.kind != tokNumber || err != nil || n < 0 {
+			return nil, p.errorf("LIMIT must be a whole number")
+		}
+		p.next()
+		q.Limit = &Number{at: atToken(t), Value: float64(n)}
+	}
+
+	p.accept(";")
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return q, nil
+}
+
+// Expressions, loosest binding first: OR, AND, NOT, comparisons, + and -,
+// * and /, unary minus.
+
+func (p *parser) expr() (Node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("OR") {
+		t := p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{at: atToken(t), Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("AND") {
+		t := p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{at: atToken(t), Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (Node, error) {
+	if t := p.peek(); t.is("NOT") {
+		p.next()
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{at: atToken(t), Op: "NOT", X: x}, nil
+	}
+	return p.comparison()
+}
+
+var comparisons = map[string]string{"=": "=", "!=": "!=", "<>": "!=", "<": "<", "<=": "<=", ">": ">", ">=": ">="}
+
+func (p *parser) comparison() (Node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if op, ok := comparisons[t.text]; ok && t.kind == tokSymbol {
+		p.next()
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{at: atToken(t), Op: op, Left: left, Right: right}, nil
+	}
+
+	negated := false
+	if t.is("NOT") {
+		p.next()
+		negated = true
+		t = p.peek()
+	}
+	switch {
+	case t.is("IN"):
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		n := &In{at: atToken(t), X: left, Not: negated}
+		for {
+			item, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			n.List = append(n.List, item)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case t.is("BETWEEN"):
+		p.next()
+		low, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		return &Between{at: atToken(t), X: left, Low: low, High: high, Not: negated}, nil
+	case t.is("LIKE"):
+		p.next()
+		pt := p.peek()
+		if pt.kind != tokString {
+			return nil, p.errorf("LIKE needs a quoted pattern")
+		}
+		p.next()
+		return &Like{at: atToken(t), X: left, Pattern: &Text{at: atToken(pt), Value: pt.text}, Not: negated}, nil
+	case negated:
+		return nil, p.errorf("expected IN, BETWEEN or LIKE after NOT")
+	}
+	return left, nil
+}
+
+func (p *parser) additive() (Node, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("+") || p.peek().is("-") {
+		t := p.next()
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{at: atToken(t), Op: t.text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) multiplicative() (Node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("*") || p.peek().is("/") {
+		t := p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{at: atToken(t), Op: t.text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Node, error) {
+	if t := p.peek(); t.is("-") {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{at: atToken(t), Op: "-", X: x}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Node, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number")
+		}
+		p.next()
+		return &Number{at: atToken(t), Value: v}, nil
+	case t.kind == tokString:
+		p.next()
+		return &Text{at: atToken(t), Value: t.text}, nil
+	case t.is("("):
+		p.next()
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case t.kind == tokIdent && !isKeyword(t):
+		p.next()
+		if !p.peek().is("(") {
+			return &Column{at: atToken(t), Name: strings.ToLower(t.text)}, nil
+		}
+		return p.call(t)
+	case t.kind == tokEOF:
+		return nil, p.errorf("unexpected end of query")
+	default:
+		return nil, p.errorf("expected an expression")
+	}
+}
+
+func (p *parser) call(name token) (Node, error) {
+	fn := strings.ToUpper(name.text)
+	if !slices.Contains(Aggregates, fn) {
+		p.i--
+		return nil, p.errorf("unknown function %s, must be one of: %s", fn, strings.Join(Aggregates, ", "))
+	}
+	p.next() // (
+
+	c := &Call{at: atToken(name), Func: fn}
+	switch {
+	case fn == "COUNT" && (p.accept("*") || p.peek().is(")")):
+	case fn == "COUNT" && p.accept("DISTINCT"):
+		c.Distinct = true
+		fallthrough
+	default:
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		c.Arg = arg
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
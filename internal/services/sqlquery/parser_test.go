@@ -0,0 +1,166 @@
+package sqlquery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`select Country, sum(total_price) as Revenue, count(distinct user_id)
+		from Transactions
+		where price >= 10 and not (category <> 'Toys' or region in ('North', 'South'))
+			and product_name not like 'K_te%' and quantity between 1 and 2 * 3 -- comment
+		group by country
+		order by revenue desc, 1
+		limit 10;`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	var names []string
+	for _, item := range q.Select {
+		names = append(names, item.Name())
+	}
+	if got := strings.Join(names, "|"); got != "country|Revenue|COUNT(DISTINCT user_id)" {
+		t.Errorf("select names = %q", got)
+	}
+	if q.Table.Name != "transactions" {
+		t.Errorf("table = %q", q.Table.Name)
+	}
+	wantWhere := "(((price >= 10) AND NOT ((category != 'Toys') OR (region IN ('North', 'South')))) AND (product_name NOT LIKE 'K_te%')) AND (quantity BETWEEN 1 AND (2 * 3))"
+	if got := q.Where.String(); got != wantWhere {
+		t.Errorf("where = %q\n want %q", got, wantWhere)
+	}
+	if len(q.GroupBy) != 1 || q.GroupBy[0].String() != "country" {
+		t.Errorf("group by = %v", q.GroupBy)
+	}
+	if len(q.OrderBy) != 2 || !q.OrderBy[0].Desc || q.OrderBy[1].Desc || q.OrderBy[1].Expr.String() != "1" {
+		t.Errorf("order by = %+v", q.OrderBy)
+	}
+	if q.Limit == nil || q.Limit.Value != 10 {
+		t.Errorf("limit = %v", q.Limit)
+	}
+}
+
+func TestParse_Precedence(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"a + b * c", "a + (b * c)"},
+		{"(a + b) * c", "(a + b) * c"},
+		{"a - b - c", "(a - b) - c"},
+		{"-a * b", "-a * b"},
+		{"a = 1 OR b = 2 AND c = 3", "(a = 1) OR ((b = 2) AND (c = 3))"},
+		{"(a = 1 OR b = 2) AND c = 3", "((a = 1) OR (b = 2)) AND (c = 3)"},
+		{"NOT a = 1 AND b = 2", "NOT (a = 1) AND (b = 2)"},
+		{"a = 'it''s'", "a = 'it''s'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := Parse("SELECT x FROM t WHERE " + tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := q.Where.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			// The canonical form parses back to itself.
+			again, err := Parse("SELECT x FROM t WHERE " + q.Where.String())
+			if err != nil {
+				t.Fatalf("reparse error = %v", err)
+			}
+			if again.Where.String() != tt.want {
+				t.Errorf("reparsed String() = %q", again.Where.String())
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		token string
+	}{
+		{"SELEC x FROM t", 0, "SELEC"},
+		{"SELECT FROM t", 7, "FROM"},
+		{"SELECT x y z FROM t", 11, "z"},
+		{"SELECT x FROM t WHERE", 21, ""},
+		{"SELECT x FROM t WHERE a = ", 26, ""},
+		{"SELECT median(x) FROM t", 7, "median"},
+		{"SELECT x FROM t WHERE a LIKE b", 29, "b"},
+		{"SELECT x FROM t LIMIT 1.5", 22, "1.5"},
+		{"SELECT x FROM t WHERE a = 'open", 26, "'open"},
+		{"SELECT x FROM t WHERE a # 1", 24, "#"},
+		{"SELECT x FROM t ORDER x", 22, "x"},
+		{"SELECT x FROM t; SELECT", 17, "SELECT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			var qerr *Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("expected an *Error, got %v", err)
+			}
+			if qerr.Pos != tt.pos || qerr.Token != tt.token {
+				t.Errorf("error at %d near %q, want %d near %q (%v)", qerr.Pos, qerr.Token, tt.pos, tt.token, qerr)
+			}
+		})
+	}
+}
+
+func TestError_Pointer(t *testing.T) {
+	query := "SELECT x\nFROM t\nWHERE a ! 1"
+	_, err := Parse(query)
+	var qerr *Error
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected an *Error, got %v", err)
+	}
+	if got, want := qerr.Pointer(query), "WHERE a ! 1\n        ^"; got != want {
+		t.Errorf("Pointer() = %q, want %q", got, want)
+	}
+	if got := qerr.Error(); got != `unexpected character at position 25 near "!"` {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	tests := []struct {
+		def   string
+		expr  string
+		where string
+	}{
+		{"sum(total_price) / sum(quantity)", "SUM(total_price) / SUM(quantity)", ""},
+		{"count() where total_price > 1000", "COUNT(*)", "total_price > 1000"},
+		{"avg(price) WHERE category IN ('Toys')", "AVG(price)", "category IN ('Toys')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.def, func(t *testing.T) {
+			m, err := ParseMetric(tt.def)
+			if err != nil {
+				t.Fatalf("ParseMetric() error = %v", err)
+			}
+			if got := m.Expr.String(); got != tt.expr {
+				t.Errorf("expr = %q, want %q", got, tt.expr)
+			}
+			where := ""
+			if m.Where != nil {
+				where = m.Where.String()
+			}
+			if where != tt.where {
+				t.Errorf("where = %q, want %q", where, tt.where)
+			}
+		})
+	}
+
+	for _, def := range []string{"", "sum(price) where", "sum(price) limit 3", "sum(price"} {
+		if _, err := ParseMetric(def); err == nil {
+			t.Errorf("ParseMetric(%q) should fail", def)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+const secondsPerDay = 24 * 60 * 60
+
+// noDate marks a missing optional date in a day column.
+const noDate = math.MinInt32
+
+// Dictionary interns the distinct values of a string column so rows can store
+// compact codes instead of repeated strings.
+type Dictionary struct {
+	Values []string
+	index  map[string]uint32
+}
+
+func (d *Dictionary) Code(value string) uint32 {
+	if d.index == nil {
+		d.reindex()
+	}
+	if code, ok := d.index[value]; ok {
+		return code
+	}
+	code := uint32(len(d.Values))
+	d.Values = append(d.Values, value)
+	d.index[value] = code
+	return code
+}
+
+// Lookup returns the code of an existing value without interning it.
+func (d *Dictionary) Lookup(value string) (uint32, bool) {
+	code, ok := d.index[value]
+	return code, ok
+}
+
+func (d *Dictionary) Value(code uint32) string {
+	return d.Values[code]
+}
+
+func (d *Dictionary) reindex() {
+	d.index = make(map[string]uint32, len(d.Values))
+	for i, v := range d.Values {
+		d.index[v] = uint32(i)
+	}
+}
+
+// TransactionStore keeps every ingested transaction in columnar form so
+// queries over arbitrary date ranges and filters can be answered without
+// re-reading the CSV. Dates are stored as days since the Unix epoch.
+type TransactionStore struct {
+	Dates      []int32
+	Countries  []uint32
+	Regions    []uint32
+	Products   []uint32
+	Categories []uint32
+	Prices     []float64
+	Quantities []int32
+	Totals     []float64
+	Stocks     []int32
+	Users      []uint32
+	// Added is the product's catalog addition day, or noDate when the row
+	// did not carry one.
+	Added []int32
+	// Costs is the product catalog's unit cost, NaN when the product is not
+	// in the catalog; brands and subcategories are then empty.
+	Costs         []float64
+	Brands        []uint32
+	Subcategories []uint32
+
+	// ProductIDs, ProductNames and ProductNameDays are indexed by product
+	// code. A product is identified by its product_id, or by its name when
+	// its rows carry no ID, in which case ProductIDs holds "". Its display
+	// name is the name on its most recent row, sold on ProductNameDays.
+	ProductIDs      []string
+	ProductNames    []string
+	ProductNameDays []int32
+
+	// RegionNames and RegionCountries are indexed by region code. Region
+	// names are only unique within a country, so a region is identified by
+	// its country and name; RegionCountries holds the country's code.
+	RegionNames     []string
+	RegionCountries []uint32
+
+	CountryDict     Dictionary
+	RegionDict      Dictionary
+	ProductDict     Dictionary
+	CategoryDict    Dictionary
+	UserDict        Dictionary
+	BrandDict       Dictionary
+	SubcategoryDict Dictionary
+}
+
+func NewTransactionStore() *TransactionStore {
+	return &TransactionStore{}
+}
+
+func (s *TransactionStore) Len() int {
+	return len(s.Dates)
+}
+
+func (s *TransactionStore) Append(tx models.Transaction) {
+	day := dayNumber(tx.Date)
+	s.Dates = append(s.Dates, day)
+	country := s.CountryDict.Code(tx.Country)
+	s.Countries = append(s.Countries, country)
+	s.Regions = append(s.Regions, s.appendRegion(tx, country))
+	s.Products = append(s.Products, s.appendProduct(tx, day))
+	s.Categories = append(s.Categories, s.CategoryDict.Code(tx.Category))
+	s.Prices = append(s.Prices, tx.Price)
+	s.Quantities = append(s.Quantities, int32(tx.Quantity))
+	s.Totals = append(s.Totals, tx.TotalPrice)
+	s.Stocks = append(s.Stocks, int32(tx.Stock))
+	s.Users = append(s.Users, s.UserDict.Code(tx.UserID))
+	added := int32(noDate)
+	if !tx.AddedDate.IsZero() {
+		added = dayNumber(tx.AddedDate)
+	}
+	s.Added = append(s.Added, added)
+	cost := math.NaN()
+	if tx.Cataloged {
+		cost = tx.UnitCost
+	}
+	s.Costs = append(s.Costs, cost)
+	s.Brands = append(s.Brands, s.BrandDict.Code(tx.Brand))
+	s.Subcategories = append(s.Subcategories, s.SubcategoryDict.Code(tx.Subcategory))
+}
+
+// appendProduct returns the code of the product of tx, keeping its display
+// name current. Ties on a day go to the greater name, so the result does not
+// depend on the order rows are appended in.
+func (s *TransactionStore) appendProduct(tx models.Transaction, day int32) uint32 {
+	code := s.ProductDict.Code(productKey(tx.ProductID, tx.ProductName))
+	if int(code) == len(s.ProductIDs) {
+		s.ProductIDs = append(s.ProductIDs, tx.ProductID)
+		s.ProductNames = append(s.ProductNames, tx.ProductName)
+		s.ProductNameDays = append(s.ProductNameDays, day)
+		return code
+	}
+	if last := s.ProductNameDays[code]; day > last || (day == last && tx.ProductName > s.ProductNames[code]) {
+		s.ProductNames[code] = tx.ProductName
+		s.ProductNameDays[code] = day
+	}
+	return code
+}
+
+// appendRegion returns the code of the region of tx within its country.
+func (s *TransactionStore) appendRegion(tx models.Transaction, country uint32) uint32 {
+	code := s.RegionDict.Code(regionKey(tx.Country, tx.Region))
+	if int(code) == len(s.RegionNames) {
+		s.RegionNames = append(s.RegionNames, tx.Region)
+		s.RegionCountries = append(s.RegionCountries, country)
+	}
+	return code
+}
+
+// regionKey identifies a region: its country and its name, which other
+// countries may also use.
+func regionKey(country, region string) string {
+	return country + "\x00" + region
+}
+
+// productKey identifies a product: its product_id, or its name for rows
+// without one. The prefix keeps names from colliding with IDs.
+func productKey(id, name string) string {
+	if id != "" {
+		return id
+	}
+	return "\x00" + name
+}
+
+// productRef is what links and paths use to point at a product: its ID, or
+// its name when it has none.
+func (s *TransactionStore) productRef(code uint32) string {
+	if id := s.ProductIDs[code]; id != "" {
+		return id
+	}
+	return s.ProductNames[code]
+}
+
+// Row materializes the transaction at index i.
+func (s *TransactionStore) Row(i int) models.Transaction {
+	var added time.Time
+	if s.Added[i] != noDate {
+		added = dayTime(s.Added[i])
+	}
+	cost := s.Costs[i]
+	if math.IsNaN(cost) {
+		cost = 0
+	}
+	return models.Transaction{
+		Date:        dayTime(s.Dates[i]),
+		Country:     s.CountryDict.Value(s.Countries[i]),
+		Region:      s.RegionNames[s.Regions[i]],
+		ProductID:   s.ProductIDs[s.Products[i]],
+		ProductName: s.ProductNames[s.Products[i]],
+		Category:    s.CategoryDict.Value(s.Categories[i]),
+		Price:       s.Prices[i],
+		Quantity:    int(s.Quantities[i]),
+		TotalPrice:  s.Totals[i],
+		Stock:       int(s.Stocks[i]),
+		UserID:      s.UserDict.Value(s.Users[i]),
+		AddedDate:   added,
+		Cataloged:   !math.IsNaN(s.Costs[i]),
+		UnitCost:    cost,
+		Brand:       s.BrandDict.Value(s.Brands[i]),
+		Subcategory: s.SubcategoryDict.Value(s.Subcategories[i]),
+	}
+}
+
+// dimensionColumn returns the code column backing a dimension and the labels
+// its codes index. Product labels are display names, which several products
+// may share, and region labels are names, which several countries may share.
+func (s *TransactionStore) dimensionColumn(dimension string) ([]uint32, []string) {
+	switch dimension {
+	case DimensionProduct:
+		return s.Products, s.ProductNames
+	case DimensionRegion:
+		return s.Regions, s.RegionNames
+	}
+	column, dict := s.dimensionDict(dimension)
+	return column, dict.Values
+}
+
+func (s *TransactionStore) dimensionDict(dimension string) ([]uint32, *Dictionary) {
+	switch dimension {
+	case DimensionRegion:
+		return s.Regions, &s.RegionDict
+	case DimensionCategory:
+		return s.Categories, &s.CategoryDict
+	case DimensionProduct:
+		return s.Products, &s.ProductDict
+	case DimensionBrand:
+		return s.Brands, &s.BrandDict
+	case DimensionSubcategory:
+		return s.Subcategories, &s.SubcategoryDict
+	default:
+		return s.Countries, &s.CountryDict
+	}
+}
+
+// dimensionLookup returns the codes of a dimension value. A product is
+// matched by its product_id or, failing that, by its display name, and a
+// region by its name; either may match several codes.
+func (s *TransactionStore) dimensionLookup(dimension, value string) []uint32 {
+	if dimension != DimensionRegion {
+		_, dict := s.dimensionDict(dimension)
+		if code, ok := dict.Lookup(value); ok {
+			return []uint32{code}
+		}
+		if dimension != DimensionProduct {
+			return nil
+		}
+	}
+	_, labels := s.dimensionColumn(dimension)
+	var codes []uint32
+	for code, name := range labels {
+		if name == value {
+			codes = append(codes, uint32(code))
+		}
+	}
+	return codes
+}
+
+// dimensionCodes returns a per-row code for a dimension and the labels the
+// codes index. Months are numbered from the earliest month in the store.
+func (s *TransactionStore) dimensionCodes(dimension string) (func(i int) uint32, []string) {
+	if dimension != DimensionMonth {
+		column, labels := s.dimensionColumn(dimension)
+		return func(i int) uint32 { return column[i] }, labels
+	}
+
+	if s.Len() == 0 {
+		return func(int) uint32 { return 0 }, nil
+	}
+	first := monthIndex(slices.Min(s.Dates))
+	last := monthIndex(slices.Max(s.Dates))
+	labels := make([]string, last-first+1)
+	for i := range labels {
+		labels[i] = monthLabel(first + int32(i))
+	}
+	return func(i int) uint32 { return uint32(monthIndex(s.Dates[i]) - first) }, labels
+}
+
+// reindex rebuilds the dictionary lookups, which are not part of the cache.
+func (s *TransactionStore) reindex() {
+	s.CountryDict.reindex()
+	s.RegionDict.reindex()
+	s.ProductDict.reindex()
+	s.CategoryDict.reindex()
+	s.UserDict.reindex()
+	s.BrandDict.reindex()
+	s.SubcategoryDict.reindex()
+}
+
+func dayNumber(t time.Time) int32 {
+	return int32(t.Unix() / secondsPerDay)
+}
+
+func dayTime(day int32) time.Time {
+	return time.Unix(int64(day)*secondsPerDay, 0).UTC()
+}
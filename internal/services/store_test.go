@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func TestTransactionStore_RoundTrip(t *testing.T) {
+	store := NewTransactionStore()
+	txs := []models.Transaction{
+		{Date: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), Country: "USA", Region: "Texas", ProductName: "Kite", Category: "Toys", Price: 10, Quantity: 2, TotalPrice: 20, Stock: 7, UserID: "U1", AddedDate: time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{Date: time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), Country: "USA", Region: "Ohio", ProductName: "Atlas", Category: "Books", Price: 5, Quantity: 1, TotalPrice: 5, Stock: 3, UserID: "U2"},
+	}
+	for _, tx := range txs {
+		store.Append(tx)
+	}
+
+	if store.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", store.Len())
+	}
+	for i, want := range txs {
+		if got := store.Row(i); got != want {
+			t.Errorf("Row(%d) = %+v, want %+v", i, got, want)
+		}
+	}
+	if len(store.CountryDict.Values) != 1 {
+		t.Errorf("expected repeated country to be interned once, got %v", store.CountryDict.Values)
+	}
+}
+
+func TestDictionary_ReindexAfterDecode(t *testing.T) {
+	// Dictionaries decoded from the cache arrive without their lookup index
+	d := Dictionary{Values: []string{"a", "b"}}
+	if _, ok := d.Lookup("b"); ok {
+		t.Fatal("lookup should fail before reindex")
+	}
+	d.reindex()
+	if code, ok := d.Lookup("b"); !ok || code != 1 {
+		t.Errorf("Lookup(b) = %d, %v, want 1, true", code, ok)
+	}
+	if code := d.Code("c"); code != 2 {
+		t.Errorf("Code(c) = %d, want 2", code)
+	}
+}
+
+func TestTransactionStore_ProductIdentity(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 0, 0, 0, 0, time.UTC) }
+	store := NewTransactionStore()
+	for _, tx := range []models.Transaction{
+		{Date: day(20), ProductID: "P1", ProductName: "Laptop Pro"},
+		{Date: day(10), ProductID: "P1", ProductName: "Laptop"},
+		{Date: day(10), ProductID: "P2", ProductName: "Cable"},
+		{Date: day(11), ProductID: "P3", ProductName: "Cable"},
+		{Date: day(12), ProductName: "Sticker"},
+	} {
+		store.Append(tx)
+	}
+
+	if len(store.ProductNames) != 4 {
+		t.Fatalf("expected 4 products, got %v", store.ProductNames)
+	}
+	// The rename merges into P1 and keeps the latest name, whatever the
+	// order rows arrive in.
+	if row := store.Row(1); row.ProductID != "P1" || row.ProductName != "Laptop Pro" || store.Products[0] != store.Products[1] {
+		t.Errorf("renamed row = %+v, want P1 named Laptop Pro", row)
+	}
+	if store.Products[2] == store.Products[3] {
+		t.Error("products sharing a name should stay apart")
+	}
+	if codes := store.dimensionLookup(DimensionProduct, "Cable"); len(codes) != 2 {
+		t.Errorf("lookup by shared name = %v, want both products", codes)
+	}
+	if codes := store.dimensionLookup(DimensionProduct, "P3"); len(codes) != 1 || codes[0] != store.Products[3] {
+		t.Errorf("lookup by ID = %v, want P3", codes)
+	}
+	sticker := store.Products[4]
+	if store.ProductIDs[sticker] != "" || store.productRef(sticker) != "Sticker" {
+		t.Errorf("a product without an ID should be referred to by name, got %q", store.productRef(sticker))
+	}
+}
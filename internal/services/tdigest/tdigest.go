@@ -0,0 +1,196 @@
+// Package tdigest implements the merging t-digest of Dunning and Ertl, a
+// compact sketch of a distribution that answers quantile and rank queries
+// with small relative error at the tails. Digests built over separate parts
+// of a data set merge into a digest of the whole, so they can be built in
+// parallel and combined.
+package tdigest
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// DefaultCompression keeps roughly a hundred centroids, which bounds the
+// quantile error to well under a percent of rank.
+const DefaultCompression = 100
+
+// Centroid summarises Weight points by their mean.
+type Centroid struct {
+	Mean   float64
+	Weight float64
+}
+
+// Digest sketches a stream of values. Fields are exported so digests can be
+// serialised; use the methods to read them. A Digest is not safe for
+// concurrent use until Compress has been called, after which the query
+// methods only read.
+type Digest struct {
+	Compression float64
+	// Centroids are ordered by mean.
+	Centroids []Centroid
+	Total     float64
+	Min       float64
+	Max       float64
+
+	buffer []Centroid
+}
+
+func New(compression float64) *Digest {
+	return &Digest{Compression: compression, Min: math.Inf(1), Max: math.Inf(-1)}
+}
+
+// Add records one value.
+func (d *Digest) Add(x float64) {
+	d.add(Centroid{Mean: x, Weight: 1}, x, x)
+}
+
+// Merge folds the values of other into d, leaving other unchanged.
+func (d *Digest) Merge(other *Digest) {
+	for _, c := range other.buffer {
+		d.add(c, other.Min, other.Max)
+	}
+	for _, c := range other.Centroids {
+		d.add(c, other.Min, other.Max)
+	}
+}
+
+func (d *Digest) add(c Centroid, lo, hi float64) {
+	d.Min = min(d.Min, lo)
+	d.Max = max(d.Max, hi)
+	d.buffer = append(d.buffer, c)
+	if len(d.buffer) >= int(5*d.Compression) {
+		d.Compress()
+	}
+}
+
+// Count returns the number of values recorded.
+func (d *Digest) Count() float64 {
+	d.Compress()
+	return d.Total
+}
+
+// Mean returns the mean of the recorded values, which the centroids
+// preserve exactly.
+func (d *Digest) Mean() float64 {
+	d.Compress()
+	if d.Total == 0 {
+		return math.NaN()
+	}
+	sum := 0.0
+	for _, c := range d.Centroids {
+		sum += c.Mean * c.Weight
+	}
+	return sum / d.Total
+}
+
+// Compress merges buffered values into the centroids. Neighbouring
+// centroids are combined as long as the result spans at most one unit of
+// the arcsine scale function, which keeps centroids small near the tails.
+func (d *Digest) Compress() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	all := append(d.Centroids, d.buffer...)
+	d.buffer = nil
+	slices.SortFunc(all, func(a, b Centroid) int { return cmp.Compare(a.Mean, b.Mean) })
+
+	total := 0.0
+	for _, c := range all {
+		total += c.Weight
+	}
+
+	normalizer := d.Compression / (2 * math.Pi)
+	limit := func(soFar float64) float64 {
+		k := normalizer*math.Asin(2*soFar/total-1) + 1
+		if k/normalizer >= math.Pi/2 {
+			return total
+		}
+		return total * (math.Sin(k/normalizer) + 1) / 2
+	}
+
+	merged := make([]Centroid, 0, int(d.Compression))
+	current := all[0]
+	soFar := 0.0
+	wLimit := limit(soFar)
+	for _, next := range all[1:] {
+		if soFar+current.Weight+next.Weight <= wLimit {
+			weight := current.Weight + next.Weight
+			current.Mean += (next.Mean - current.Mean) * next.Weight / weight
+			current.Weight = weight
+			continue
+		}
+		soFar += current.Weight
+		merged = append(merged, current)
+		wLimit = limit(soFar)
+		current = next
+	}
+	d.Centroids = append(merged, current)
+	d.Total = total
+}
+
+// Quantile returns the estimated value at rank q in [0, 1], interpolating
+// between centroid means and the recorded extremes. It returns NaN for an
+// empty digest.
+func (d *Digest) Quantile(q float64) float64 {
+	d.Compress()
+	cs := d.Centroids
+	switch {
+	case d.Total == 0:
+		return math.NaN()
+	case q <= 0:
+		return d.Min
+	case q >= 1:
+		return d.Max
+	case len(cs) == 1:
+		return cs[0].Mean
+	}
+
+	index := q * d.Total
+	// Each centroid's weight is centred on its mean; below the first centre
+	// and above the last the values spread out to the extremes.
+	if first := cs[0]; index < first.Weight/2 {
+		return d.Min + (first.Mean-d.Min)*index/(first.Weight/2)
+	}
+	cum := cs[0].Weight / 2
+	for i := range len(cs) - 1 {
+		dw := (cs[i].Weight + cs[i+1].Weight) / 2
+		if index < cum+dw {
+			return cs[i].Mean + (cs[i+1].Mean-cs[i].Mean)*(index-cum)/dw
+		}
+		cum += dw
+	}
+	last := cs[len(cs)-1]
+	return last.Mean + (d.Max-last.Mean)*min((index-cum)/(last.Weight/2), 1)
+}
+
+// CDF returns the estimated fraction of values at or below x. It returns
+// NaN for an empty digest.
+func (d *Digest) CDF(x float64) float64 {
+	d.Compress()
+	cs := d.Centroids
+	switch {
+	case d.Total == 0:
+		return math.NaN()
+	case x < d.Min:
+		return 0
+	case x >= d.Max:
+		return 1
+	case len(cs) == 1:
+		return (x - d.Min) / (d.Max - d.Min)
+	}
+
+	if first := cs[0]; x < first.Mean {
+		return first.Weight / 2 * (x - d.Min) / (first.Mean - d.Min) / d.Total
+	}
+	cum := cs[0].Weight / 2
+	for i := range len(cs) - 1 {
+		dw := (cs[i].Weight + cs[i+1].Weight) / 2
+		if x < cs[i+1].Mean {
+			return (cum + dw*(x-cs[i].Mean)/(cs[i+1].Mean-cs[i].Mean)) / d.Total
+		}
+		cum += dw
+	}
+	last := cs[len(cs)-1]
+	return (cum + last.Weight/2*(x-last.Mean)/(d.Max-last.Mean)) / d.Total
+}
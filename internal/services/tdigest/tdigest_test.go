@@ -0,0 +1,99 @@
+package tdigest
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestDigest_SmallExact(t *testing.T) {
+	d := New(DefaultCompression)
+	for _, x := range []float64{4, 1, 3, 2} {
+		d.Add(x)
+	}
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0, 1},
+		{0.5, 2.5},
+		{1, 4},
+	}
+	for _, tt := range tests {
+		if got := d.Quantile(tt.q); got != tt.want {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := d.CDF(2.5); got != 0.5 {
+		t.Errorf("CDF(2.5) = %v, want 0.5", got)
+	}
+	if d.Count() != 4 || d.Mean() != 2.5 {
+		t.Errorf("Count() = %v, Mean() = %v, want 4 and 2.5", d.Count(), d.Mean())
+	}
+}
+
+func TestDigest_Empty(t *testing.T) {
+	d := New(DefaultCompression)
+	if !math.IsNaN(d.Quantile(0.5)) || !math.IsNaN(d.CDF(1)) || !math.IsNaN(d.Mean()) {
+		t.Error("an empty digest should answer NaN")
+	}
+}
+
+func TestDigest_Accuracy(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	values := make([]float64, 100000)
+	d := New(DefaultCompression)
+	for i := range values {
+		// Skewed like order values: most small, a long tail of big ones
+		values[i] = math.Exp(rng.NormFloat64())
+		d.Add(values[i])
+	}
+	slices.Sort(values)
+
+	if n := len(d.Centroids); n > 2*DefaultCompression {
+		t.Errorf("expected at most %d centroids, got %d", 2*DefaultCompression, n)
+	}
+	for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99, 0.999} {
+		got := d.Quantile(q)
+		// Compare in rank space, where the t-digest error is bounded
+		rank, _ := slices.BinarySearch(values, got)
+		if err := math.Abs(float64(rank)/float64(len(values)) - q); err > 0.01*math.Min(1, 4*q*(1-q)+0.05) {
+			t.Errorf("Quantile(%v) = %v sits at rank %.4f", q, got, float64(rank)/float64(len(values)))
+		}
+		if cdf := d.CDF(values[int(q*float64(len(values)))]); math.Abs(cdf-q) > 0.01 {
+			t.Errorf("CDF at the true %v quantile = %v", q, cdf)
+		}
+	}
+}
+
+func TestDigest_Merge(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	whole := New(DefaultCompression)
+	parts := []*Digest{New(DefaultCompression), New(DefaultCompression), New(DefaultCompression)}
+	for i := range 30000 {
+		x := rng.Float64() * 1000
+		whole.Add(x)
+		parts[i%len(parts)].Add(x)
+	}
+
+	merged := New(DefaultCompression)
+	for _, p := range parts {
+		merged.Merge(p)
+	}
+
+	if merged.Count() != whole.Count() || merged.Min != whole.Min || merged.Max != whole.Max {
+		t.Fatalf("merged digest covers %v values in [%v, %v], want %v in [%v, %v]",
+			merged.Count(), merged.Min, merged.Max, whole.Count(), whole.Min, whole.Max)
+	}
+	if math.Abs(merged.Mean()-whole.Mean()) > 1e-9*whole.Mean() {
+		t.Errorf("Mean() = %v, want %v", merged.Mean(), whole.Mean())
+	}
+	for _, q := range []float64{0.01, 0.5, 0.99} {
+		// Uniform on [0, 1000), so the quantile is 1000q
+		if got := merged.Quantile(q); math.Abs(got-1000*q) > 10 {
+			t.Errorf("merged Quantile(%v) = %v, want about %v", q, got, 1000*q)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+const (
+	GranularityDay     = "day"
+	GranularityWeek    = "week"
+	GranularityMonth   = "month"
+	GranularityQuarter = "quarter"
+	GranularityYear    = "year"
+)
+
+const (
+	MetricRevenue = "revenue"
+	MetricOrders  = "orders"
+	MetricUnits   = "units"
+)
+
+var (
+	Granularities = []string{GranularityDay, GranularityWeek, GranularityMonth, GranularityQuarter, GranularityYear}
+	SeriesMetrics = []string{MetricRevenue, MetricOrders, MetricUnits}
+)
+
+// TimeSeries rolls the daily aggregates up to the given granularity and
+// returns one point per period in chronological order. Periods without sales
+// between the first and last sale are included with a zero value. metric is
+// one of SeriesMetrics or a derived metric, which is evaluated per period.
+func (a *Analytics) TimeSeries(ctx context.Context, granularity, metric string) ([]models.TimeSeriesPoint, error) {
+	if m := a.derivedMetricsByName()[metric]; m != nil {
+		if !slices.Contains(Granularities, granularity) {
+			return nil, fmt.Errorf("unknown granularity %q", granularity)
+		}
+		return a.metricSeries(ctx, m, granularity)
+	}
+	if err := validateSeriesOptions(granularity, metric); err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	daily := a.precomputed.DailySales
+	a.mu.RUnlock()
+
+	return rollupDaily(daily, granularity, metric), nil
+}
+
+func validateSeriesOptions(granularity, metric string) error {
+	if !slices.Contains(Granularities, granularity) {
+		return fmt.Errorf("unknown granularity %q", granularity)
+	}
+	if !slices.Contains(SeriesMetrics, metric) {
+		return fmt.Errorf("unknown metric %q", metric)
+	}
+	return nil
+}
+
+func rollupDaily(daily []models.DailySales, granularity, metric string) []models.TimeSeriesPoint {
+	if len(daily) == 0 {
+		return []models.TimeSeriesPoint{}
+	}
+
+	first, _ := time.Parse(time.DateOnly, daily[0].Date)
+	last, _ := time.Parse(time.DateOnly, daily[len(daily)-1].Date)
+	return rollupBetween(daily, first, last, granularity, metric)
+}
+
+// rollupBetween buckets the daily aggregates into periods and returns every
+// period from the one containing first through the one containing last.
+func rollupBetween(daily []models.DailySales, first, last time.Time, granularity, metric string) []models.TimeSeriesPoint {
+	totals := make(map[time.Time]float64)
+	for _, ds := range daily {
+		day, err := time.Parse(time.DateOnly, ds.Date)
+		if err != nil {
+			continue
+		}
+		totals[periodStart(day, granularity)] += dailyMetric(ds, metric)
+	}
+
+	points := make([]models.TimeSeriesPoint, 0)
+	end := periodStart(last, granularity)
+	for t := periodStart(first, granularity); !t.After(end); t = nextPeriod(t, granularity) {
+		points = append(points, models.TimeSeriesPoint{
+			Period: periodLabel(t, granularity),
+			Start:  t.Format(time.DateOnly),
+			Value:  totals[t],
+		})
+	}
+	return points
+}
+
+func dailyMetric(ds models.DailySales, metric string) float64 {
+	switch metric {
+	case MetricOrders:
+		return float64(ds.Orders)
+	case MetricUnits:
+		return float64(ds.Units)
+	default:
+		return ds.Revenue
+	}
+}
+
+// periodStart truncates t to the first day of its period. Weeks start on
+// Monday to line up with ISO week numbers.
+func periodStart(t time.Time, granularity string) time.Time {
+	y, m, d := t.Date()
+	switch granularity {
+	case GranularityWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC)
+	case GranularityMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case GranularityQuarter:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case GranularityYear:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func nextPeriod(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityWeek:
+		return t.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return t.AddDate(0, 1, 0)
+	case GranularityQuarter:
+		return t.AddDate(0, 3, 0)
+	case GranularityYear:
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+func periodLabel(t time.Time, granularity string) string {
+	switch granularity {
+	case GranularityWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case GranularityMonth:
+		return t.Format("2006-01")
+	case GranularityQuarter:
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case GranularityYear:
+		return t.Format("2006")
+	default:
+		return t.Format(time.DateOnly)
+	}
+}
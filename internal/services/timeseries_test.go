@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func timeSeriesTestAnalytics() *Analytics {
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		{Date: time.Date(2023, 3, 5, 0, 0, 0, 0, time.UTC), TotalPrice: 300, Quantity: 3},
+		{Date: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), TotalPrice: 100, Quantity: 1},
+		{Date: time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC), TotalPrice: 50, Quantity: 2},
+		{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), TotalPrice: 10, Quantity: 1},
+	})
+	return a
+}
+
+func TestAnalytics_TimeSeries_MonthlyGapFilled(t *testing.T) {
+	a := timeSeriesTestAnalytics()
+
+	points, err := a.TimeSeries(context.Background(), GranularityMonth, MetricRevenue)
+	if err != nil {
+		t.Fatalf("TimeSeries() error = %v", err)
+	}
+
+	// 2023-01 through 2024-01 inclusive
+	if len(points) != 13 {
+		t.Fatalf("expected 13 monthly points, got %d", len(points))
+	}
+	if points[0].Period != "2023-01" || points[0].Value != 150 {
+		t.Errorf("first point = %+v, want 2023-01 with 150", points[0])
+	}
+	if points[1].Period != "2023-02" || points[1].Value != 0 {
+		t.Errorf("gap month = %+v, want 2023-02 with 0", points[1])
+	}
+	if points[12].Period != "2024-01" || points[12].Start != "2024-01-01" {
+		t.Errorf("last point = %+v, want 2024-01 starting 2024-01-01", points[12])
+	}
+}
+
+func TestAnalytics_TimeSeries_Granularities(t *testing.T) {
+	a := timeSeriesTestAnalytics()
+
+	tests := []struct {
+		granularity string
+		metric      string
+		wantLen     int
+		wantFirst   models.TimeSeriesPoint
+	}{
+		{GranularityDay, MetricOrders, 353, models.TimeSeriesPoint{Period: "2023-01-15", Start: "2023-01-15", Value: 1}},
+		{GranularityWeek, MetricUnits, 52, models.TimeSeriesPoint{Period: "2023-W02", Start: "2023-01-09", Value: 1}},
+		{GranularityQuarter, MetricRevenue, 5, models.TimeSeriesPoint{Period: "2023-Q1", Start: "2023-01-01", Value: 450}},
+		{GranularityYear, MetricOrders, 2, models.TimeSeriesPoint{Period: "2023", Start: "2023-01-01", Value: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.granularity, func(t *testing.T) {
+			points, err := a.TimeSeries(context.Background(), tt.granularity, tt.metric)
+			if err != nil {
+				t.Fatalf("TimeSeries() error = %v", err)
+			}
+			if len(points) != tt.wantLen {
+				t.Errorf("expected %d points, got %d", tt.wantLen, len(points))
+			}
+			if points[0] != tt.wantFirst {
+				t.Errorf("first point = %+v, want %+v", points[0], tt.wantFirst)
+			}
+			for i := 1; i < len(points); i++ {
+				if points[i].Start <= points[i-1].Start {
+					t.Fatalf("points not chronological at %d: %s after %s", i, points[i].Start, points[i-1].Start)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalytics_TimeSeries_InvalidOptions(t *testing.T) {
+	a := timeSeriesTestAnalytics()
+
+	if _, err := a.TimeSeries(context.Background(), "hourly", MetricRevenue); err == nil {
+		t.Error("expected error for unknown granularity")
+	}
+	if _, err := a.TimeSeries(context.Background(), GranularityMonth, "profit"); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+}
+
+func TestAnalytics_TimeSeries_Empty(t *testing.T) {
+	points, err := NewAnalytics().TimeSeries(context.Background(), GranularityMonth, MetricRevenue)
+	if err != nil {
+		t.Fatalf("TimeSeries() error = %v", err)
+	}
+	if points == nil || len(points) != 0 {
+		t.Errorf("expected empty non-nil slice, got %v", points)
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"abt-dashboard/internal/models"
+)
+
+const (
+	RankByRevenue   = "revenue"
+	RankByOrders    = "orders"
+	RankByUnits     = "units"
+	RankByCustomers = "customers"
+)
+
+var RankMetrics = []string{RankByRevenue, RankByOrders, RankByUnits, RankByCustomers}
+
+// validateRanking checks a top-N request. rankBy is one of RankMetrics or a
+// derived metric, which is returned.
+func (a *Analytics) validateRanking(rankBy string, limit int) (*DerivedMetric, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	if slices.Contains(RankMetrics, rankBy) {
+		return nil, nil
+	}
+	if m, ok := a.DerivedMetric(rankBy); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("rank_by must be one of: %s", strings.Join(append(slices.Clone(RankMetrics), a.DerivedMetricNames()...), ", "))
+}
+
+// rankValue orders items by a derived metric, those where it is undefined
+// last.
+func rankValue(v *float64) float64 {
+	if v == nil {
+		return math.Inf(-1)
+	}
+	return *v
+}
+
+// RankedProducts returns the limit best products by revenue, orders, units,
+// distinct customers or a derived metric, with the four built-in metrics
+// filled in and MetricValue set when ranking by a derived one.
+func (a *Analytics) RankedProducts(ctx context.Context, rankBy string, limit int) ([]models.ProductFrequency, error) {
+	derived, err := a.validateRanking(rankBy, limit)
+	if err != nil {
+		return nil, err
+	}
+	precomputed := a.current()
+	store, cube := precomputed.Store, precomputed.Cube
+
+	var customers map[uint32]int
+	if rankBy == RankByCustomers {
+		customers, err = a.productCustomers.get(precomputed, func() (map[uint32]int, error) {
+			return cube.customers(ctx, cubeProduct, nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var values map[uint32]float64
+	if derived != nil {
+		values, err = metricBy(ctx, store, derived, nil, func(i int) uint32 { return store.Products[i] })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	top, customers, err := rankCodes(ctx, cube, cubeProduct, rankBy, limit, customers, values, func(a, b uint32) int {
+		if c := cmp.Compare(store.ProductNames[b], store.ProductNames[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(store.ProductIDs[b], store.ProductIDs[a])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := a.productsByCode.get(precomputed, func() ([]models.ProductFrequency, error) {
+		return indexProducts(store, precomputed.TopProducts), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.ProductFrequency, len(top))
+	for i, code := range top {
+		t := cube.marginals[cubeProduct][code]
+		result[i] = products[code]
+		result[i].ProductID = store.ProductIDs[code]
+		result[i].ProductName = store.ProductNames[code]
+		result[i].Frequency = t.Orders
+		result[i].Revenue = t.Revenue
+		result[i].Units = t.Units
+		result[i].Customers = customers[code]
+		if derived != nil {
+			result[i].MetricValue = metricValue(values, code)
+		}
+	}
+	return result, nil
+}
+
+// RankedRegions returns the limit best regions by revenue, orders, units,
+// distinct customers or a derived metric, with the four built-in metrics
+// filled in and MetricValue set when ranking by a derived one.
+func (a *Analytics) RankedRegions(ctx context.Context, rankBy string, limit int) ([]models.RegionRevenue, error) {
+	derived, err := a.validateRanking(rankBy, limit)
+	if err != nil {
+		return nil, err
+	}
+	precomputed := a.current()
+	store, cube := precomputed.Store, precomputed.Cube
+
+	var customers map[uint32]int
+	if rankBy == RankByCustomers {
+		customers, err = a.regionCustomers.get(precomputed, func() (map[uint32]int, error) {
+			return cube.customers(ctx, cubeRegion, nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var values map[uint32]float64
+	if derived != nil {
+		values, err = metricBy(ctx, store, derived, nil, func(i int) uint32 { return store.Regions[i] })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	country := func(code uint32) string { return store.CountryDict.Value(store.RegionCountries[code]) }
+	top, customers, err := rankCodes(ctx, cube, cubeRegion, rankBy, limit, customers, values, func(a, b uint32) int {
+		if c := cmp.Compare(store.RegionNames[b], store.RegionNames[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(country(b), country(a))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.RegionRevenue, len(top))
+	for i, code := range top {
+		t := cube.marginals[cubeRegion][code]
+		result[i] = models.RegionRevenue{
+			Country:   country(code),
+			Region:    store.RegionNames[code],
+			Revenue:   t.Revenue,
+			ItemsSold: t.Units,
+			Orders:    t.Orders,
+			Customers: customers[code],
+		}
+		if derived != nil {
+			result[i].MetricValue = metricValue(values, code)
+		}
+	}
+	return result, nil
+}
+
+// rankCodes returns the limit best values of cube dimension d, ties broken
+// by tie, with the distinct customers of each. Revenue, orders and units
+// come from the cube's roll-up of the dimension. Ranking by customers takes
+// the counts of every value; otherwise only the values returned are
+// counted, from the rows of their cells.
+func rankCodes(ctx context.Context, cube *Cube, d int, rankBy string, limit int, customers map[uint32]int, values map[uint32]float64, tie func(a, b uint32) int) ([]uint32, map[uint32]int, error) {
+	marginals := cube.marginals[d]
+	value := func(code uint32) float64 {
+		switch rankBy {
+		case RankByRevenue:
+			return marginals[code].Revenue
+		case RankByOrders:
+			return float64(marginals[code].Orders)
+		case RankByUnits:
+			return float64(marginals[code].Units)
+		case RankByCustomers:
+			return float64(customers[code])
+		default:
+			return rankValue(metricValue(values, code))
+		}
+	}
+	top := topN(slices.Collect(maps.Keys(marginals)), limit, func(a, b uint32) int {
+		if c := cmp.Compare(value(a), value(b)); c != 0 {
+			return c
+		}
+		return tie(a, b)
+	})
+	if rankBy == RankByCustomers {
+		return top, customers, nil
+	}
+	customers, err := cube.customers(ctx, d, top)
+	return top, customers, err
+}
+
+// indexProducts indexes the category and stock recorded at ingestion by
+// product code.
+func indexProducts(store *TransactionStore, products []models.ProductFrequency) []models.ProductFrequency {
+	byCode := make([]models.ProductFrequency, len(store.ProductNames))
+	for _, p := range products {
+		if code, ok := store.ProductDict.Lookup(productKey(p.ProductID, p.ProductName)); ok {
+			byCode[code] = p
+		}
+	}
+	return byCode
+}
+
+// topN returns the n greatest items by compare, greatest first, keeping only
+// n items in memory instead of sorting the whole slice.
+func topN[T any](items []T, n int, compare func(a, b T) int) []T {
+	top := make([]T, 0, min(n, len(items))+1)
+	for _, item := range items {
+		if len(top) == n && compare(item, top[n-1]) <= 0 {
+			continue
+		}
+		i, _ := slices.BinarySearchFunc(top, item, func(e, target T) int { return compare(target, e) })
+		top = slices.Insert(top, i, item)
+		if len(top) > n {
+			top = top[:n]
+		}
+	}
+	return top
+}
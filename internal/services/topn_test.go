@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+func topNTestAnalytics() *Analytics {
+	day := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	a := NewAnalytics()
+	a.SetData([]models.Transaction{
+		// Piano: one big sale
+		{Date: day, UserID: "U1", Region: "North", ProductName: "Piano", Category: "Music", Quantity: 1, TotalPrice: 5000, Stock: 3},
+		// Pick: many small orders from one customer
+		{Date: day, UserID: "U2", Region: "South", ProductName: "Pick", Category: "Music", Quantity: 10, TotalPrice: 5, Stock: 900},
+		{Date: day, UserID: "U2", Region: "South", ProductName: "Pick", Category: "Music", Quantity: 10, TotalPrice: 5, Stock: 880},
+		{Date: day, UserID: "U2", Region: "South", ProductName: "Pick", Category: "Music", Quantity: 10, TotalPrice: 5, Stock: 860},
+		// String: bought by three customers
+		{Date: day, UserID: "U3", Region: "East", ProductName: "String", Category: "Music", Quantity: 1, TotalPrice: 8, Stock: 50},
+		{Date: day, UserID: "U4", Region: "East", ProductName: "String", Category: "Music", Quantity: 1, TotalPrice: 8, Stock: 49},
+		{Date: day, UserID: "U5", Region: "East", ProductName: "String", Category: "Music", Quantity: 2, TotalPrice: 16, Stock: 48},
+	})
+	return a
+}
+
+func TestAnalytics_RankedProducts(t *testing.T) {
+	a := topNTestAnalytics()
+
+	tests := []struct {
+		rankBy string
+		want   []string
+	}{
+		{RankByRevenue, []string{"Piano", "String", "Pick"}},
+		{RankByOrders, []string{"Pick", "String", "Piano"}},
+		{RankByUnits, []string{"Pick", "String", "Piano"}},
+		{RankByCustomers, []string{"String", "Piano", "Pick"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rankBy, func(t *testing.T) {
+			products, err := a.RankedProducts(context.Background(), tt.rankBy, 10)
+			if err != nil {
+				t.Fatalf("RankedProducts() error = %v", err)
+			}
+			if len(products) != len(tt.want) {
+				t.Fatalf("expected %d products, got %+v", len(tt.want), products)
+			}
+			for i, name := range tt.want {
+				if products[i].ProductName != name {
+					t.Errorf("product %d = %s, want %s", i, products[i].ProductName, name)
+				}
+			}
+		})
+	}
+
+	products, err := a.RankedProducts(context.Background(), RankByCustomers, 1)
+	if err != nil {
+		t.Fatalf("RankedProducts() error = %v", err)
+	}
+	if len(products) != 1 {
+		t.Fatalf("expected limit to cap results at 1, got %d", len(products))
+	}
+	s := products[0]
+	if s.Category != "Music" || s.Frequency != 3 || s.Units != 4 || s.Revenue != 32 || s.Customers != 3 || s.StockQuantity != 48 {
+		t.Errorf("String = %+v, want every metric and the latest stock filled in", s)
+	}
+}
+
+func TestAnalytics_RankedRegions(t *testing.T) {
+	a := topNTestAnalytics()
+
+	regions, err := a.RankedRegions(context.Background(), RankByUnits, 2)
+	if err != nil {
+		t.Fatalf("RankedRegions() error = %v", err)
+	}
+	if len(regions) != 2 || regions[0].Region != "South" || regions[1].Region != "East" {
+		t.Fatalf("expected South then East by units, got %+v", regions)
+	}
+	if south := regions[0]; south.ItemsSold != 30 || south.Orders != 3 || south.Customers != 1 || south.Revenue != 15 {
+		t.Errorf("South = %+v, want 30 units from 3 orders by 1 customer", south)
+	}
+}
+
+func TestAnalytics_RankedProducts_Validation(t *testing.T) {
+	a := topNTestAnalytics()
+
+	if _, err := a.RankedProducts(context.Background(), "margin", 10); err == nil {
+		t.Error("expected an error for an unknown rank metric")
+	}
+	if _, err := a.RankedRegions(context.Background(), RankByRevenue, 0); err == nil {
+		t.Error("expected an error for a zero limit")
+	}
+}
+
+func TestTopN(t *testing.T) {
+	got := topN([]int{3, 9, 1, 9, 4, 7}, 3, func(a, b int) int { return a - b })
+	want := []int{9, 9, 7}
+	if len(got) != len(want) {
+		t.Fatalf("topN() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("topN() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"abt-dashboard/internal/models"
+)
+
+var (
+	ErrUnknownView = errors.New("no view with this name")
+	ErrInvalidView = errors.New("invalid view")
+	// ErrViewConflict means the view was saved or deleted since the version
+	// the caller last read.
+	ErrViewConflict = errors.New("view has changed since it was read")
+)
+
+var viewNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ViewStore keeps saved views in a JSON file. Every change rewrites the file,
+// which is fine for the handful of views a team keeps.
+//
+// Saves use optimistic concurrency: a save carries the version it was based
+// on, zero for a new view, and fails with ErrViewConflict when the stored
+// view has moved on.
+type ViewStore struct {
+	mu    sync.Mutex
+	path  string
+	views map[string]models.SavedView
+}
+
+// OpenViewStore loads the views saved in path. A missing file is an empty
+// store; it is created on the first save.
+func OpenViewStore(path string) (*ViewStore, error) {
+	s := &ViewStore{path: path, views: make(map[string]models.SavedView)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var views []models.SavedView
+	if err := json.Unmarshal(data, &views); err != nil {
+		return nil, fmt.Errorf("read views from %s: %w", path, err)
+	}
+	for _, v := range views {
+		s.views[v.Name] = v
+	}
+	return s, nil
+}
+
+// List returns the saved views by name.
+func (s *ViewStore) List() []models.SavedView {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted()
+}
+
+// Get returns the view saved under name, or ErrUnknownView.
+func (s *ViewStore) Get(name string) (models.SavedView, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.views[name]
+	if !ok {
+		return models.SavedView{}, ErrUnknownView
+	}
+	return v, nil
+}
+
+// Save creates or replaces a view. v.Version must be the version of the
+// stored view, or zero when there is none. The saved view, with its new
+// version, is returned.
+func (s *ViewStore) Save(v models.SavedView) (models.SavedView, error) {
+	if err := validateView(v); err != nil {
+		return models.SavedView{}, fmt.Errorf("%w: %v", ErrInvalidView, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, existed := s.views[v.Name]
+	if v.Version != previous.Version {
+		return models.SavedView{}, ErrViewConflict
+	}
+	v.Version++
+	v.UpdatedAt = time.Now().UTC()
+	s.views[v.Name] = v
+	if err := s.persist(); err != nil {
+		if existed {
+			s.views[v.Name] = previous
+		} else {
+			delete(s.views, v.Name)
+		}
+		return models.SavedView{}, err
+	}
+	return v, nil
+}
+
+// Delete removes a view. A non-zero version must match the stored view.
+func (s *ViewStore) Delete(name string, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.views[name]
+	if !ok {
+		return ErrUnknownView
+	}
+	if version != 0 && version != v.Version {
+		return ErrViewConflict
+	}
+	delete(s.views, name)
+	if err := s.persist(); err != nil {
+		s.views[name] = v
+		return err
+	}
+	return nil
+}
+
+func (s *ViewStore) sorted() []models.SavedView {
+	views := make([]models.SavedView, 0, len(s.views))
+	for _, v := range s.views {
+		views = append(views, v)
+	}
+	slices.SortFunc(views, func(a, b models.SavedView) int { return cmp.Compare(a.Name, b.Name) })
+	return views
+}
+
+// persist writes the views to a temporary file and renames it over the store
+// so a crash never leaves a half-written file behind.
+func (s *ViewStore) persist() error {
+	data, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func validateView(v models.SavedView) error {
+	if !viewNamePattern.MatchString(v.Name) {
+		return fmt.Errorf("view name %q must be lowercase letters, digits, '-' or '_'", v.Name)
+	}
+	if v.Version < 0 {
+		return fmt.Errorf("version cannot be negative")
+	}
+	if err := validateViewRange("", v.From, v.To); err != nil {
+		return err
+	}
+	return validateViewRange("compare_", v.CompareFrom, v.CompareTo)
+}
+
+func validateViewRange(prefix, from, to string) error {
+	start, err := parseViewDate(prefix+"from", from)
+	if err != nil {
+		return err
+	}
+	end, err := parseViewDate(prefix+"to", to)
+	if err != nil {
+		return err
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return fmt.Errorf("%sto must not be before %sfrom", prefix, prefix)
+	}
+	return nil
+}
+
+func parseViewDate(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date in YYYY-MM-DD format, got %q", name, value)
+	}
+	return t, nil
+}
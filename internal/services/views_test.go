@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"abt-dashboard/internal/models"
+)
+
+func TestViewStore_SaveAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+	store, err := OpenViewStore(path)
+	if err != nil {
+		t.Fatalf("OpenViewStore() error = %v", err)
+	}
+	if got := store.List(); len(got) != 0 {
+		t.Fatalf("a missing file should be an empty store, got %v", got)
+	}
+
+	saved, err := store.Save(models.SavedView{Name: "emea-q3", From: "2023-07-01", To: "2023-09-30", Country: "Germany"})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if saved.Version != 1 || saved.UpdatedAt.IsZero() {
+		t.Errorf("a new view should be at version 1, got %+v", saved)
+	}
+	if _, err := store.Save(models.SavedView{Name: "apac", Category: "Toys"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reopened, err := OpenViewStore(path)
+	if err != nil {
+		t.Fatalf("OpenViewStore() error = %v", err)
+	}
+	views := reopened.List()
+	if len(views) != 2 || views[0].Name != "apac" || views[1].Name != "emea-q3" {
+		t.Fatalf("List() = %+v, want apac and emea-q3 by name", views)
+	}
+	if got, _ := reopened.Get("emea-q3"); got.Country != "Germany" || got.To != "2023-09-30" || got.Version != 1 {
+		t.Errorf("Get() = %+v", got)
+	}
+	if _, err := reopened.Get("nope"); !errors.Is(err, ErrUnknownView) {
+		t.Errorf("expected ErrUnknownView, got %v", err)
+	}
+
+	// No temporary files are left next to the store.
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the store file, got %d entries", len(entries))
+	}
+}
+
+func TestViewStore_OptimisticConcurrency(t *testing.T) {
+	store, err := OpenViewStore(filepath.Join(t.TempDir(), "views.json"))
+	if err != nil {
+		t.Fatalf("OpenViewStore() error = %v", err)
+	}
+	v1, err := store.Save(models.SavedView{Name: "emea", Country: "Germany"})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Two editors read version 1; the second save is stale.
+	a, b := v1, v1
+	a.Country = "France"
+	if _, err := store.Save(a); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	b.Country = "Spain"
+	if _, err := store.Save(b); !errors.Is(err, ErrViewConflict) {
+		t.Errorf("expected ErrViewConflict for a stale save, got %v", err)
+	}
+	if _, err := store.Save(models.SavedView{Name: "emea"}); !errors.Is(err, ErrViewConflict) {
+		t.Errorf("expected ErrViewConflict creating a view that exists, got %v", err)
+	}
+	if got, _ := store.Get("emea"); got.Country != "France" || got.Version != 2 {
+		t.Errorf("Get() = %+v, want France at version 2", got)
+	}
+
+	if err := store.Delete("emea", 1); !errors.Is(err, ErrViewConflict) {
+		t.Errorf("expected ErrViewConflict for a stale delete, got %v", err)
+	}
+	if err := store.Delete("emea", 2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete("emea", 0); !errors.Is(err, ErrUnknownView) {
+		t.Errorf("expected ErrUnknownView, got %v", err)
+	}
+}
+
+func TestViewStore_Validation(t *testing.T) {
+	store, err := OpenViewStore(filepath.Join(t.TempDir(), "views.json"))
+	if err != nil {
+		t.Fatalf("OpenViewStore() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		view models.SavedView
+	}{
+		{"name with spaces", models.SavedView{Name: "EMEA Q3"}},
+		{"empty name", models.SavedView{}},
+		{"bad date", models.SavedView{Name: "v", From: "07/01/2023"}},
+		{"reversed range", models.SavedView{Name: "v", From: "2023-09-30", To: "2023-07-01"}},
+		{"reversed comparison", models.SavedView{Name: "v", CompareFrom: "2022-09-30", CompareTo: "2022-07-01"}},
+		{"negative version", models.SavedView{Name: "v", Version: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.Save(tt.view); !errors.Is(err, ErrInvalidView) {
+				t.Errorf("expected ErrInvalidView, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenViewStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenViewStore(path); err == nil {
+		t.Error("expected an error for a corrupt store")
+	}
+}
@@ -42,14 +42,14 @@ func Base(title string, description string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</title><script type=\"module\" src=\"https://cdn.jsdelivr.net/gh/starfederation/datastar@main/bundles/datastar.js\"></script><script src=\"https://cdn.jsdelivr.net/npm/chart.js@4.4.7/dist/chart.umd.js\"></script><style>\n\t\t\t:root { \n\t\t\t\t--primary:#3b82f6; --secondary:#64748b; --success:#22c55e; --danger:#ef4444; \n\t\t\t\t--warning:#f59e0b; --info:#8b5cf6; --background:#f8fafc; --surface:#ffffff; \n\t\t\t\t--text-primary:#1e293b; --text-secondary:#64748b; --border:#e2e8f0; \n\t\t\t\t--shadow:0 4px 6px -1px rgb(0 0 0 / .1),0 2px 4px -2px rgb(0 0 0 / .1); \n\t\t\t\t--border-radius:12px; --transition:all 0.3s ease;\n\t\t\t\t--header-height: 140px;\n\t\t\t\t--card-padding: 28px;\n\t\t\t\t--grid-gap: 24px;\n\t\t\t}\n\t\t\t\n\t\t\t* {\n\t\t\t\tbox-sizing: border-box;\n\t\t\t}\n\t\t\t\n\t\t\tbody {\n\t\t\t\tfont-family: system-ui, -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;\n\t\t\t\tmargin: 0;\n\t\t\t\tpadding: 16px;\n\t\t\t\tbackground: var(--background);\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t\tline-height: 1.6;\n\t\t\t\toverflow-x: hidden;\n\t\t\t}\n\t\t\t\n\t\t\t.header {\n\t\t\t\tbackground: linear-gradient(135deg, var(--primary), var(--info));\n\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\tpadding: 32px 20px;\n\t\t\t\ttext-align: center;\n\t\t\t\tcolor: #fff;\n\t\t\t\tbox-shadow: var(--shadow);\n\t\t\t\tmargin-bottom: var(--grid-gap);\n\t\t\t}\n\t\t\t\n\t\t\t.header h1 {\n\t\t\t\tmargin: 0 0 8px 0;\n\t\t\t\tfont-size: clamp(1.5rem, 4vw, 2.5rem);\n\t\t\t\tfont-weight: 700;\n\t\t\t}\n\t\t\t\n\t\t\t.header p {\n\t\t\t\tmargin: 0;\n\t\t\t\tfont-size: clamp(0.9rem, 2vw, 1.1rem);\n\t\t\t\topacity: 0.9;\n\t\t\t}\n\t\t\t\n\t\t\t.grid {\n\t\t\t\tdisplay: grid;\n\t\t\t\tgrid-template-columns: repeat(auto-fit, minmax(320px, 1fr));\n\t\t\t\tgap: var(--grid-gap);\n\t\t\t\tmargin: var(--grid-gap) 0;\n\t\t\t}\n\t\t\t\n\t\t\t.card {\n\t\t\t\tbackground: var(--surface);\n\t\t\t\tborder: 1px solid var(--border);\n\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\tpadding: var(--card-padding);\n\t\t\t\tbox-shadow: var(--shadow);\n\t\t\t\ttransition: var(--transition);\n\t\t\t\toverflow: hidden;\n\t\t\t}\n\t\t\t\n\t\t\t.card:hover {\n\t\t\t\ttransform: translateY(-2px);\n\t\t\t\tbox-shadow: 0 8px 25px -5px rgb(0 0 0 / .1);\n\t\t\t}\n\t\t\t\n\t\t\t.card h3 {\n\t\t\t\tmargin: 0 0 20px 0;\n\t\t\t\tfont-size: clamp(1rem, 2.5vw, 1.25rem);\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t\tfont-weight: 600;\n\t\t\t}\n\t\t\t\n\t\t\t.chart {\n\t\t\t\theight: 350px;\n\t\t\t\tposition: relative;\n\t\t\t\tmargin: 16px 0;\n\t\t\t}\n\t\t\t\n\t\t\t.table-container {\n\t\t\t\toverflow-x: auto;\n\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\tbox-shadow: var(--shadow);\n\t\t\t}\n\t\t\t\n\t\t\t.modern-table {\n\t\t\t\twidth: 100%;\n\t\t\t\tmin-width: 600px;\n\t\t\t\tborder-collapse: collapse;\n\t\t\t\tfont-size: 14px;\n\t\t\t\tbackground: white;\n\t\t\t}\n\t\t\t\n\t\t\t.modern-table th {\n\t\t\t\tbackground: linear-gradient(135deg, #f8fafc, #f1f5f9);\n\t\t\t\tpadding: 12px 16px;\n\t\t\t\ttext-align: left;\n\t\t\t\tfont-weight: 600;\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t\tborder-bottom: 2px solid var(--border);\n\t\t\t\twhite-space: nowrap;\n\t\t\t\tfont-size: 13px;\n\t\t\t}\n\t\t\t\n\t\t\t.modern-table td {\n\t\t\t\tpadding: 12px 16px;\n\t\t\t\tborder-bottom: 1px solid var(--border);\n\t\t\t\ttransition: var(--transition);\n\t\t\t\tfont-size: 13px;\n\t\t\t}\n\t\t\t\n\t\t\t.modern-table tr:hover td {\n\t\t\t\tbackground-color: #f8fafc;\n\t\t\t}\n\t\t\t\n\t\t\t.category-badge {\n\t\t\t\tbackground: #f1f5f9;\n\t\t\t\tpadding: 4px 8px;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tfont-size: 11px;\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t\twhite-space: nowrap;\n\t\t\t\tdisplay: inline-block;\n\t\t\t}\n\t\t\t\n\t\t\t.loading {\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tjustify-content: center;\n\t\t\t\tmin-height: 200px;\n\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\tfont-size: 14px;\n\t\t\t}\n\t\t\t\n\t\t\t.loading::after {\n\t\t\t\tcontent: \"\";\n\t\t\t\twidth: 20px;\n\t\t\t\theight: 20px;\n\t\t\t\tborder: 2px solid var(--primary);\n\t\t\t\tborder-top: transparent;\n\t\t\t\tborder-radius: 50%;\n\t\t\t\tmargin-left: 10px;\n\t\t\t\tanimation: spin 1s linear infinite;\n\t\t\t}\n\t\t\t\n\t\t\t@keyframes spin {\n\t\t\t\tto { transform: rotate(360deg); }\n\t\t\t}\n\t\t\t\n\t\t\t/* Mobile optimizations */\n\t\t\t@media (max-width: 768px) {\n\t\t\t\tbody {\n\t\t\t\t\tpadding: 12px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.header {\n\t\t\t\t\tpadding: 24px 16px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.grid {\n\t\t\t\t\tgrid-template-columns: 1fr;\n\t\t\t\t\tgap: 20px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.card {\n\t\t\t\t\tpadding: 20px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.chart {\n\t\t\t\t\theight: 280px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.modern-table th,\n\t\t\t\t.modern-table td {\n\t\t\t\t\tpadding: 10px 12px;\n\t\t\t\t\tfont-size: 12px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.category-badge {\n\t\t\t\t\tfont-size: 10px;\n\t\t\t\t\tpadding: 3px 6px;\n\t\t\t\t}\n\t\t\t}\n\t\t\t\n\t\t\t/* Small mobile optimizations */\n\t\t\t@media (max-width: 480px) {\n\t\t\t\tbody {\n\t\t\t\t\tpadding: 8px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.header {\n\t\t\t\t\tpadding: 20px 12px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.card {\n\t\t\t\t\tpadding: 16px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.chart {\n\t\t\t\t\theight: 250px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.modern-table {\n\t\t\t\t\tmin-width: 500px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.modern-table th,\n\t\t\t\t.modern-table td {\n\t\t\t\t\tpadding: 8px 10px;\n\t\t\t\t\tfont-size: 11px;\n\t\t\t\t}\n\t\t\t}\n\t\t\t\n\t\t\t/* Large screen optimizations */\n\t\t\t@media (min-width: 1200px) {\n\t\t\t\tbody {\n\t\t\t\t\tpadding: 24px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.grid {\n\t\t\t\t\tgrid-template-columns: repeat(auto-fit, minmax(450px, 1fr));\n\t\t\t\t\tgap: 32px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.card {\n\t\t\t\t\tpadding: 32px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.chart {\n\t\t\t\t\theight: 400px;\n\t\t\t\t}\n\t\t\t}\n\t\t\t\n\t\t\t/* Ultra-wide screen optimizations */\n\t\t\t@media (min-width: 1600px) {\n\t\t\t\t.grid {\n\t\t\t\t\tgrid-template-columns: repeat(auto-fit, minmax(500px, 1fr));\n\t\t\t\t\tmax-width: 1400px;\n\t\t\t\t\tmargin: var(--grid-gap) auto;\n\t\t\t\t}\n\t\t\t}\n\t\t\t\n\t\t\t/* Print styles */\n\t\t\t@media print {\n\t\t\t\tbody {\n\t\t\t\t\tbackground: white;\n\t\t\t\t\tpadding: 0;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.card {\n\t\t\t\t\tbreak-inside: avoid;\n\t\t\t\t\tbox-shadow: none;\n\t\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\t\tmargin-bottom: 20px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.chart {\n\t\t\t\t\theight: 300px;\n\t\t\t\t}\n\t\t\t}\n\t\t</style></head><body data-signals='{\"refreshInterval\": 30000, \"autoRefresh\": true}'><div class=\"header\"><h1>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</title><script type=\"module\" src=\"https://cdn.jsdelivr.net/gh/starfederation/datastar@main/bundles/datastar.js\"></script><script src=\"https://cdn.jsdelivr.net/npm/chart.js@4.4.7/dist/chart.umd.js\"></script><style>\n\t\t\t:root { \n\t\t\t\t--primary:#3b82f6; --secondary:#64748b; --success:#22c55e; --danger:#ef4444; \n\t\t\t\t--warning:#f59e0b; --info:#8b5cf6; --background:#f8fafc; --surface:#ffffff; \n\t\t\t\t--text-primary:#1e293b; --text-secondary:#64748b; --border:#e2e8f0; \n\t\t\t\t--shadow:0 4px 6px -1px rgb(0 0 0 / .1),0 2px 4px -2px rgb(0 0 0 / .1); \n\t\t\t\t--border-radius:12px; --transition:all 0.3s ease;\n\t\t\t\t--header-height: 140px;\n\t\t\t\t--card-padding: 28px;\n\t\t\t\t--grid-gap: 24px;\n\t\t\t}\n\t\t\t\n\t\t\t* {\n\t\t\t\tbox-sizing: border-box;\n\t\t\t}\n\t\t\t\n\t\t\tbody {\n\t\t\t\tfont-family: system-ui, -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;\n\t\t\t\tmargin: 0;\n\t\t\t\tpadding: 16px;\n\t\t\t\tbackground: var(--background);\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t\tline-height: 1.6;\n\t\t\t\toverflow-x: hidden;\n\t\t\t}\n\t\t\t\n\t\t\t.header {\n\t\t\t\tbackground: linear-gradient(135deg, var(--primary), var(--info));\n\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\tpadding: 32px 20px;\n\t\t\t\ttext-align: center;\n\t\t\t\tcolor: #fff;\n\t\t\t\tbox-shadow: var(--shadow);\n\t\t\t\tmargin-bottom: var(--grid-gap);\n\t\t\t}\n\t\t\t\n\t\t\t.header h1 {\n\t\t\t\tmargin: 0 0 8px 0;\n\t\t\t\tfont-size: clamp(1.5rem, 4vw, 2.5rem);\n\t\t\t\tfont-weight: 700;\n\t\t\t}\n\t\t\t\n\t\t\t.header p {\n\t\t\t\tmargin: 0;\n\t\t\t\tfont-size: clamp(0.9rem, 2vw, 1.1rem);\n\t\t\t\topacity: 0.9;\n\t\t\t}\n\t\t\t\n\t\t\t.grid {\n\t\t\t\tdisplay: grid;\n\t\t\t\tgrid-template-columns: repeat(auto-fit, minmax(320px, 1fr));\n\t\t\t\tgap: var(--grid-gap);\n\t\t\t\tmargin: var(--grid-gap) 0;\n\t\t\t}\n\t\t\t\n\t\t\t.card {\n\t\t\t\tbackground: var(--surface);\n\t\t\t\tborder: 1px solid var(--border);\n\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\tpadding: var(--card-padding);\n\t\t\t\tbox-shadow: var(--shadow);\n\t\t\t\ttransition: var(--transition);\n\t\t\t\toverflow: hidden;\n\t\t\t}\n\t\t\t\n\t\t\t.card:hover {\n\t\t\t\ttransform: translateY(-2px);\n\t\t\t\tbox-shadow: 0 8px 25px -5px rgb(0 0 0 / .1);\n\t\t\t}\n\t\t\t\n\t\t\t.card h3 {\n\t\t\t\tmargin: 0 0 20px 0;\n\t\t\t\tfont-size: clamp(1rem, 2.5vw, 1.25rem);\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t\tfont-weight: 600;\n\t\t\t}\n\t\t\t\n\t\t\t.chart {\n\t\t\t\theight: 350px;\n\t\t\t\tposition: relative;\n\t\t\t\tmargin: 16px 0;\n\t\t\t}\n\t\t\t\n\t\t\t.table-container {\n\t\t\t\toverflow-x: auto;\n\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\tbox-shadow: var(--shadow);\n\t\t\t}\n\t\t\t\n\t\t\t.modern-table {\n\t\t\t\twidth: 100%;\n\t\t\t\tmin-width: 600px;\n\t\t\t\tborder-collapse: collapse;\n\t\t\t\tfont-size: 14px;\n\t\t\t\tbackground: white;\n\t\t\t}\n\t\t\t\n\t\t\t.modern-table th {\n\t\t\t\tbackground: linear-gradient(135deg, #f8fafc, #f1f5f9);\n\t\t\t\tpadding: 12px 16px;\n\t\t\t\ttext-align: left;\n\t\t\t\tfont-weight: 600;\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t\tborder-bottom: 2px solid var(--border);\n\t\t\t\twhite-space: nowrap;\n\t\t\t\tfont-size: 13px;\n\t\t\t}\n\t\t\t\n\t\t\t.modern-table td {\n\t\t\t\tpadding: 12px 16px;\n\t\t\t\tborder-bottom: 1px solid var(--border);\n\t\t\t\ttransition: var(--transition);\n\t\t\t\tfont-size: 13px;\n\t\t\t}\n\t\t\t\n\t\t\t.modern-table tr:hover td {\n\t\t\t\tbackground-color: #f8fafc;\n\t\t\t}\n\t\t\t\n\t\t\t.category-badge {\n\t\t\t\tbackground: #f1f5f9;\n\t\t\t\tpadding: 4px 8px;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tfont-size: 11px;\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t\twhite-space: nowrap;\n\t\t\t\tdisplay: inline-block;\n\t\t\t}\n\t\t\t\n\t\t\t.delta-badge {\n\t\t\t\tpadding: 2px 6px;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tfont-size: 10px;\n\t\t\t\tfont-weight: 600;\n\t\t\t\twhite-space: nowrap;\n\t\t\t\tdisplay: inline-block;\n\t\t\t\tbackground: #f1f5f9;\n\t\t\t\tcolor: var(--text-secondary);\n\t\t\t}\n\t\t\t\n\t\t\t.delta-badge.up {\n\t\t\t\tbackground: #dcfce7;\n\t\t\t\tcolor: #166534;\n\t\t\t}\n\t\t\t\n\t\t\t.delta-badge.down {\n\t\t\t\tbackground: #fee2e2;\n\t\t\t\tcolor: #991b1b;\n\t\t\t}\n\t\t\t\n\t\t\t.card-controls {\n\t\t\t\tdisplay: flex;\n\t\t\t\tgap: 8px;\n\t\t\t\tflex-wrap: wrap;\n\t\t\t\tmargin-bottom: 12px;\n\t\t\t}\n\t\t\t\n\t\t\t.card-controls select,\n\t\t\t.card-controls input {\n\t\t\t\tpadding: 6px 10px;\n\t\t\t\tborder: 1px solid var(--border);\n\t\t\t\tborder-radius: 6px;\n\t\t\t\tbackground: var(--surface);\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t\tfont-size: 13px;\n\t\t\t}\n\t\t\t\n\t\t\t.btn {\n\t\t\t\tpadding: 6px 14px;\n\t\t\t\tborder: none;\n\t\t\t\tborder-radius: 6px;\n\t\t\t\tbackground: var(--primary);\n\t\t\t\tcolor: #fff;\n\t\t\t\tfont-size: 13px;\n\t\t\t\tfont-weight: 600;\n\t\t\t\tcursor: pointer;\n\t\t\t\ttransition: var(--transition);\n\t\t\t}\n\t\t\t\n\t\t\t.btn.secondary {\n\t\t\t\tbackground: #f1f5f9;\n\t\t\t\tcolor: var(--text-primary);\n\t\t\t}\n\t\t\t\n\t\t\t.compare-status {\n\t\t\t\tfont-size: 13px;\n\t\t\t\tcolor: var(--text-secondary);\n\t\t\t}\n\t\t\t\n\t\t\t.compare-status.error {\n\t\t\t\tcolor: var(--danger);\n\t\t\t}\n\t\t\t\n\t\t\t.anomaly-alert {\n\t\t\t\tmargin-bottom: 12px;\n\t\t\t\tpadding: 8px 12px;\n\t\t\t\tborder-radius: 8px;\n\t\t\t\tbackground: #fef3c7;\n\t\t\t\tcolor: #92400e;\n\t\t\t\tfont-size: 13px;\n\t\t\t\tfont-weight: 600;\n\t\t\t}\n\t\t\t\n\t\t\t.anomaly-drop td:first-child {\n\t\t\t\tborder-left: 3px solid var(--danger);\n\t\t\t}\n\t\t\t\n\t\t\t.anomaly-spike td:first-child {\n\t\t\t\tborder-left: 3px solid var(--warning);\n\t\t\t}\n\t\t\t\n\t\t\t.anomaly-new {\n\t\t\t\tbackground: #fffbeb;\n\t\t\t}\n\t\t\t\n\t\t\t.cohort-heatmap .heat-cell {\n\t\t\t\ttext-align: center;\n\t\t\t\tfont-variant-numeric: tabular-nums;\n\t\t\t}\n\t\t\t\n\t\t\t.drillable {\n\t\t\t\tcursor: pointer;\n\t\t\t}\n\t\t\t\n\t\t\t.country-map {\n\t\t\t\tdisplay: block;\n\t\t\t\twidth: 100%;\n\t\t\t\theight: auto;\n\t\t\t}\n\t\t\t\n\t\t\t.country-map .map-ocean {\n\t\t\t\tfill: var(--background);\n\t\t\t}\n\t\t\t\n\t\t\t.country-map .map-equator {\n\t\t\t\tstroke: var(--border);\n\t\t\t\tstroke-dasharray: 4 4;\n\t\t\t}\n\t\t\t\n\t\t\t.map-country {\n\t\t\t\tfill: var(--primary);\n\t\t\t\tstroke: var(--surface);\n\t\t\t\tstroke-width: 0.5;\n\t\t\t\tstroke-linejoin: round;\n\t\t\t}\n\t\t\t\n\t\t\t.map-country.empty {\n\t\t\t\tfill: var(--border);\n\t\t\t}\n\t\t\t\n\t\t\t.map-country.selected {\n\t\t\t\tfill: var(--warning);\n\t\t\t\tfill-opacity: 1;\n\t\t\t\tstroke: var(--text-primary);\n\t\t\t\tstroke-width: 2;\n\t\t\t}\n\t\t\t\n\t\t\t.drilldown {\n\t\t\t\tmargin-bottom: 16px;\n\t\t\t\tpadding: 12px;\n\t\t\t\tborder-radius: 8px;\n\t\t\t\tbackground: #f8fafc;\n\t\t\t}\n\t\t\t\n\t\t\t.drilldown-header {\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tjustify-content: space-between;\n\t\t\t\tmargin-bottom: 8px;\n\t\t\t}\n\t\t\t\n\t\t\t.breadcrumb a {\n\t\t\t\tcolor: var(--primary);\n\t\t\t\ttext-decoration: none;\n\t\t\t\tfont-weight: 600;\n\t\t\t}\n\t\t\t\n\t\t\t.drilldown-totals {\n\t\t\t\tmargin-bottom: 8px;\n\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\tfont-size: 13px;\n\t\t\t}\n\t\t\t\n\t\t\t.stock-out_of_stock td:first-child {\n\t\t\t\tborder-left: 3px solid var(--danger);\n\t\t\t}\n\t\t\t\n\t\t\t.stock-low td:first-child {\n\t\t\t\tborder-left: 3px solid var(--warning);\n\t\t\t}\n\t\t\t\n\t\t\t.stock-badge {\n\t\t\t\tpadding: 2px 8px;\n\t\t\t\tborder-radius: 10px;\n\t\t\t\tbackground: #fee2e2;\n\t\t\t\tcolor: #991b1b;\n\t\t\t\tfont-size: 12px;\n\t\t\t\tfont-weight: 600;\n\t\t\t}\n\t\t\t\n\t\t\t.table-note {\n\t\t\t\tmargin-top: 8px;\n\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\tfont-size: 13px;\n\t\t\t}\n\t\t\t\n\t\t\t.quantiles {\n\t\t\t\tdisplay: flex;\n\t\t\t\tflex-wrap: wrap;\n\t\t\t\tgap: 16px;\n\t\t\t\tfont-size: 14px;\n\t\t\t}\n\t\t\t\n\t\t\t.quantiles small {\n\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\ttext-transform: uppercase;\n\t\t\t}\n\t\t\t\n\t\t\t.abc-class {\n\t\t\t\tdisplay: inline-block;\n\t\t\t\twidth: 20px;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tcolor: #fff;\n\t\t\t\tfont-weight: 700;\n\t\t\t\ttext-align: center;\n\t\t\t}\n\t\t\t\n\t\t\t.abc-A {\n\t\t\t\tbackground: var(--success);\n\t\t\t}\n\t\t\t\n\t\t\t.abc-B {\n\t\t\t\tbackground: var(--warning);\n\t\t\t}\n\t\t\t\n\t\t\t.abc-C {\n\t\t\t\tbackground: #94a3b8;\n\t\t\t}\n\t\t\t\n\t\t\t.empty-state {\n\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\tfont-size: 14px;\n\t\t\t}\n\t\t\t\n\t\t\t.loading {\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tjustify-content: center;\n\t\t\t\tmin-height: 200px;\n\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\tfont-size: 14px;\n\t\t\t}\n\t\t\t\n\t\t\t.loading::after {\n\t\t\t\tcontent: \"\";\n\t\t\t\twidth: 20px;\n\t\t\t\theight: 20px;\n\t\t\t\tborder: 2px solid var(--primary);\n\t\t\t\tborder-top: transparent;\n\t\t\t\tborder-radius: 50%;\n\t\t\t\tmargin-left: 10px;\n\t\t\t\tanimation: spin 1s linear infinite;\n\t\t\t}\n\t\t\t\n\t\t\t@keyframes spin {\n\t\t\t\tto { transform: rotate(360deg); }\n\t\t\t}\n\t\t\t\n\t\t\t/* Mobile optimizations */\n\t\t\t@media (max-width: 768px) {\n\t\t\t\tbody {\n\t\t\t\t\tpadding: 12px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.header {\n\t\t\t\t\tpadding: 24px 16px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.grid {\n\t\t\t\t\tgrid-template-columns: 1fr;\n\t\t\t\t\tgap: 20px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.card {\n\t\t\t\t\tpadding: 20px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.chart {\n\t\t\t\t\theight: 280px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.modern-table th,\n\t\t\t\t.modern-table td {\n\t\t\t\t\tpadding: 10px 12px;\n\t\t\t\t\tfont-size: 12px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.category-badge {\n\t\t\t\t\tfont-size: 10px;\n\t\t\t\t\tpadding: 3px 6px;\n\t\t\t\t}\n\t\t\t}\n\t\t\t\n\t\t\t/* Small mobile optimizations */\n\t\t\t@media (max-width: 480px) {\n\t\t\t\tbody {\n\t\t\t\t\tpadding: 8px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.header {\n\t\t\t\t\tpadding: 20px 12px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.card {\n\t\t\t\t\tpadding: 16px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.chart {\n\t\t\t\t\theight: 250px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.modern-table {\n\t\t\t\t\tmin-width: 500px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.modern-table th,\n\t\t\t\t.modern-table td {\n\t\t\t\t\tpadding: 8px 10px;\n\t\t\t\t\tfont-size: 11px;\n\t\t\t\t}\n\t\t\t}\n\t\t\t\n\t\t\t/* Large screen optimizations */\n\t\t\t@media (min-width: 1200px) {\n\t\t\t\tbody {\n\t\t\t\t\tpadding: 24px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.grid {\n\t\t\t\t\tgrid-template-columns: repeat(auto-fit, minmax(450px, 1fr));\n\t\t\t\t\tgap: 32px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.card {\n\t\t\t\t\tpadding: 32px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.chart {\n\t\t\t\t\theight: 400px;\n\t\t\t\t}\n\t\t\t}\n\t\t\t\n\t\t\t/* Ultra-wide screen optimizations */\n\t\t\t@media (min-width: 1600px) {\n\t\t\t\t.grid {\n\t\t\t\t\tgrid-template-columns: repeat(auto-fit, minmax(500px, 1fr));\n\t\t\t\t\tmax-width: 1400px;\n\t\t\t\t\tmargin: var(--grid-gap) auto;\n\t\t\t\t}\n\t\t\t}\n\t\t\t\n\t\t\t/* Print styles */\n\t\t\t@media print {\n\t\t\t\tbody {\n\t\t\t\t\tbackground: white;\n\t\t\t\t\tpadding: 0;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.card {\n\t\t\t\t\tbreak-inside: avoid;\n\t\t\t\t\tbox-shadow: none;\n\t\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\t\tmargin-bottom: 20px;\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t.chart {\n\t\t\t\t\theight: 300px;\n\t\t\t\t}\n\t\t\t}\n\t\t</style></head><body data-signals='{\"refreshInterval\": 30000, \"autoRefresh\": true}'><div class=\"header\"><h1>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var3 string
 		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(title)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/base.templ`, Line: 280, Col: 15}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/base.templ`, Line: 495, Col: 15}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 		if templ_7745c5c3_Err != nil {
@@ -62,7 +62,7 @@ func Base(title string, description string) templ.Component {
 		var templ_7745c5c3_Var4 string
 		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(description)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/base.templ`, Line: 281, Col: 20}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/base.templ`, Line: 496, Col: 20}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
 		if templ_7745c5c3_Err != nil {
@@ -76,7 +76,7 @@ func Base(title string, description string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<script>\n\t\t\tlet charts = {};\n\t\t\t\n\t\t\tconst chartConfig = {\n\t\t\t\tresponsive: true,\n\t\t\t\tmaintainAspectRatio: false,\n\t\t\t\tplugins: { \n\t\t\t\t\tlegend: { position: 'bottom', labels: { usePointStyle: true } },\n\t\t\t\t\ttooltip: { \n\t\t\t\t\t\tbackgroundColor: 'rgba(0,0,0,0.9)', \n\t\t\t\t\t\ttitleColor: '#fff', \n\t\t\t\t\t\tbodyColor: '#fff',\n\t\t\t\t\t\tborderColor: 'rgba(255,255,255,0.1)',\n\t\t\t\t\t\tborderWidth: 1\n\t\t\t\t\t}\n\t\t\t\t},\n\t\t\t\tanimations: { \n\t\t\t\t\ttension: { duration: 1000, easing: 'easeInOutBack' },\n\t\t\t\t\ty: { duration: 500, easing: 'easeOutQuart' }\n\t\t\t\t}\n\t\t\t};\n\n\t\t\tconst createChart = (canvas, config) => {\n\t\t\t\tif (charts[canvas.id]) {\n\t\t\t\t\tcharts[canvas.id].destroy();\n\t\t\t\t}\n\t\t\t\tconst ctx = canvas.getContext('2d');\n\t\t\t\tcharts[canvas.id] = new Chart(ctx, {\n\t\t\t\t\t...config,\n\t\t\t\t\toptions: { ...chartConfig, ...(config.options || {}) }\n\t\t\t\t});\n\t\t\t};\n\n\t\t\twindow.initProductsChart = (data) => {\n\t\t\t\tconsole.log('🚀 Initializing products chart with data:', data);\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst canvas = document.getElementById('products-chart');\n\t\t\t\t\tif (canvas && data && Array.isArray(data)) {\n\t\t\t\t\t\tcreateChart(canvas, {\n\t\t\t\t\t\t\ttype: 'bar',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: data.map(p => p.product_name.slice(0, 20) + (p.product_name.length > 20 ? '...' : '')),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: 'Transaction Count',\n\t\t\t\t\t\t\t\t\tdata: data.map(p => p.frequency),\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(59, 130, 246, 0.8)',\n\t\t\t\t\t\t\t\t\tborderColor: 'rgb(59, 130, 246)',\n\t\t\t\t\t\t\t\t\tborderWidth: 2,\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}, {\n\t\t\t\t\t\t\t\t\tlabel: 'Stock Quantity',\n\t\t\t\t\t\t\t\t\tdata: data.map(p => p.stock_quantity),\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(34, 197, 94, 0.8)',\n\t\t\t\t\t\t\t\t\tborderColor: 'rgb(34, 197, 94)',\n\t\t\t\t\t\t\t\t\tborderWidth: 2,\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t} else {\n\t\t\t\t\t\tconsole.error('Products chart: Canvas not found or invalid data', {canvas, data});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\twindow.initMonthlyChart = (data) => {\n\t\t\t\tconsole.log('📊 Initializing monthly chart with data:', data);\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst canvas = document.getElementById('monthly-chart');\n\t\t\t\t\tif (canvas && data && Array.isArray(data)) {\n\t\t\t\t\t\tcreateChart(canvas, {\n\t\t\t\t\t\t\ttype: 'line',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: data.map(m => m.month),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: 'Sales Volume ($)',\n\t\t\t\t\t\t\t\t\tdata: data.map(m => m.volume),\n\t\t\t\t\t\t\t\t\tborderColor: 'rgb(139, 92, 246)',\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(139, 92, 246, 0.2)',\n\t\t\t\t\t\t\t\t\tfill: true,\n\t\t\t\t\t\t\t\t\ttension: 0.4,\n\t\t\t\t\t\t\t\t\tborderWidth: 3,\n\t\t\t\t\t\t\t\t\tpointBackgroundColor: 'rgb(139, 92, 246)',\n\t\t\t\t\t\t\t\t\tpointBorderColor: '#fff',\n\t\t\t\t\t\t\t\t\tpointBorderWidth: 2,\n\t\t\t\t\t\t\t\t\tpointRadius: 6\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t} else {\n\t\t\t\t\t\tconsole.error('Monthly chart: Canvas not found or invalid data', {canvas, data});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\twindow.initRegionsChart = (data) => {\n\t\t\t\tconsole.log('🌍 Initializing regions chart with data:', data);\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst canvas = document.getElementById('regions-chart');\n\t\t\t\t\tif (canvas && data && Array.isArray(data)) {\n\t\t\t\t\t\tcreateChart(canvas, {\n\t\t\t\t\t\t\ttype: 'bar',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: data.map(r => r.region),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: 'Total Revenue ($)',\n\t\t\t\t\t\t\t\t\tdata: data.map(r => r.total_revenue),\n\t\t\t\t\t\t\t\t\tbackgroundColor: data.map((_, i) => \n\t\t\t\t\t\t\t\t\t\t`hsla(${(i * 360 / data.length)}, 70%, 60%, 0.8)`\n\t\t\t\t\t\t\t\t\t),\n\t\t\t\t\t\t\t\t\tborderColor: data.map((_, i) => \n\t\t\t\t\t\t\t\t\t\t`hsla(${(i * 360 / data.length)}, 70%, 50%, 1)`\n\t\t\t\t\t\t\t\t\t),\n\t\t\t\t\t\t\t\t\tborderWidth: 2,\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t},\n\t\t\t\t\t\t\toptions: {\n\t\t\t\t\t\t\t\tindexAxis: 'y',\n\t\t\t\t\t\t\t\tscales: {\n\t\t\t\t\t\t\t\t\tx: { \n\t\t\t\t\t\t\t\t\t\tbeginAtZero: true,\n\t\t\t\t\t\t\t\t\t\tticks: { callback: value => '$' + value.toLocaleString() }\n\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t} else {\n\t\t\t\t\t\tconsole.error('Regions chart: Canvas not found or invalid data', {canvas, data});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\tif (typeof EventSource !== 'undefined') {\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconsole.log('Dashboard initialized with Datastar SSE support');\n\t\t\t\t}, 1000);\n\t\t\t}\n\t\t</script></body></html>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<script>\n\t\t\tlet charts = {};\n\t\t\t\n\t\t\tconst chartConfig = {\n\t\t\t\tresponsive: true,\n\t\t\t\tmaintainAspectRatio: false,\n\t\t\t\tplugins: { \n\t\t\t\t\tlegend: { position: 'bottom', labels: { usePointStyle: true } },\n\t\t\t\t\ttooltip: { \n\t\t\t\t\t\tbackgroundColor: 'rgba(0,0,0,0.9)', \n\t\t\t\t\t\ttitleColor: '#fff', \n\t\t\t\t\t\tbodyColor: '#fff',\n\t\t\t\t\t\tborderColor: 'rgba(255,255,255,0.1)',\n\t\t\t\t\t\tborderWidth: 1\n\t\t\t\t\t}\n\t\t\t\t},\n\t\t\t\tanimations: { \n\t\t\t\t\ttension: { duration: 1000, easing: 'easeInOutBack' },\n\t\t\t\t\ty: { duration: 500, easing: 'easeOutQuart' }\n\t\t\t\t}\n\t\t\t};\n\n\t\t\tconst createChart = (canvas, config) => {\n\t\t\t\tif (charts[canvas.id]) {\n\t\t\t\t\tcharts[canvas.id].destroy();\n\t\t\t\t}\n\t\t\t\tconst ctx = canvas.getContext('2d');\n\t\t\t\tcharts[canvas.id] = new Chart(ctx, {\n\t\t\t\t\t...config,\n\t\t\t\t\toptions: { ...chartConfig, ...(config.options || {}) }\n\t\t\t\t});\n\t\t\t};\n\n\t\t\t// rankMetrics maps a top-N rank_by value to the field holding it in\n\t\t\t// the product and region rows and its chart label. Any other value\n\t\t\t// is a derived metric, reported as metric_value.\n\t\t\tconst rankMetrics = {\n\t\t\t\trevenue: { product: 'revenue', region: 'total_revenue', label: 'Revenue ($)' },\n\t\t\t\torders: { product: 'frequency', region: 'orders', label: 'Transaction Count' },\n\t\t\t\tunits: { product: 'units', region: 'items_sold', label: 'Units Sold' },\n\t\t\t\tcustomers: { product: 'customers', region: 'customers', label: 'Customers' }\n\t\t\t};\n\t\t\tconst rankMetric = (rankBy, fallback) =>\n\t\t\t\trankMetrics[rankBy] || (rankBy ? { product: 'metric_value', region: 'metric_value', label: rankBy } : rankMetrics[fallback]);\n\n\t\t\t// Region names repeat across countries, so charts name both.\n\t\t\tconst regionLabel = r => r.country ? r.region + ', ' + r.country : r.region;\n\n\t\t\twindow.initProductsChart = (data, rankBy) => {\n\t\t\t\tconsole.log('🚀 Initializing products chart with data:', data);\n\t\t\t\tconst metric = rankMetric(rankBy, 'orders');\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst canvas = document.getElementById('products-chart');\n\t\t\t\t\tif (canvas && data && Array.isArray(data)) {\n\t\t\t\t\t\tcreateChart(canvas, {\n\t\t\t\t\t\t\ttype: 'bar',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: data.map(p => p.product_name.slice(0, 20) + (p.product_name.length > 20 ? '...' : '')),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: metric.label,\n\t\t\t\t\t\t\t\t\tdata: data.map(p => p[metric.product] || 0),\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(59, 130, 246, 0.8)',\n\t\t\t\t\t\t\t\t\tborderColor: 'rgb(59, 130, 246)',\n\t\t\t\t\t\t\t\t\tborderWidth: 2,\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}, {\n\t\t\t\t\t\t\t\t\tlabel: 'Stock Quantity',\n\t\t\t\t\t\t\t\t\tdata: data.map(p => p.stock_quantity),\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(34, 197, 94, 0.8)',\n\t\t\t\t\t\t\t\t\tborderColor: 'rgb(34, 197, 94)',\n\t\t\t\t\t\t\t\t\tborderWidth: 2,\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t} else {\n\t\t\t\t\t\tconsole.error('Products chart: Canvas not found or invalid data', {canvas, data});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\twindow.initMonthlyChart = (data) => {\n\t\t\t\tconsole.log('📊 Initializing monthly chart with data:', data);\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst canvas = document.getElementById('monthly-chart');\n\t\t\t\t\tif (canvas && data && Array.isArray(data)) {\n\t\t\t\t\t\tcreateChart(canvas, {\n\t\t\t\t\t\t\ttype: 'line',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: data.map(m => m.month),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: 'Sales Volume ($)',\n\t\t\t\t\t\t\t\t\tdata: data.map(m => m.volume),\n\t\t\t\t\t\t\t\t\tborderColor: 'rgb(139, 92, 246)',\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(139, 92, 246, 0.2)',\n\t\t\t\t\t\t\t\t\tfill: true,\n\t\t\t\t\t\t\t\t\ttension: 0.4,\n\t\t\t\t\t\t\t\t\tborderWidth: 3,\n\t\t\t\t\t\t\t\t\tpointBackgroundColor: 'rgb(139, 92, 246)',\n\t\t\t\t\t\t\t\t\tpointBorderColor: '#fff',\n\t\t\t\t\t\t\t\t\tpointBorderWidth: 2,\n\t\t\t\t\t\t\t\t\tpointRadius: 6\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t} else {\n\t\t\t\t\t\tconsole.error('Monthly chart: Canvas not found or invalid data', {canvas, data});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\tconst seriesLabels = {\n\t\t\t\trevenue: 'Revenue ($)',\n\t\t\t\torders: 'Orders',\n\t\t\t\tunits: 'Units Sold'\n\t\t\t};\n\n\t\t\twindow.initTimeSeriesChart = (series) => {\n\t\t\t\tconsole.log('📊 Initializing time series chart with data:', series);\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst canvas = document.getElementById('monthly-chart');\n\t\t\t\t\tconst points = series && series.points;\n\t\t\t\t\tif (canvas && Array.isArray(points)) {\n\t\t\t\t\t\t// The forecast continues from the last actual point as a\n\t\t\t\t\t\t// dashed line inside a shaded confidence band.\n\t\t\t\t\t\tconst forecast = Array.isArray(series.forecast) && points.length ? series.forecast : [];\n\t\t\t\t\t\tconst lastActual = points.length - 1;\n\t\t\t\t\t\tconst pad = (values) => points.map((_, i) => i === lastActual ? points[i].value : null).concat(values);\n\t\t\t\t\t\tconst forecastSets = forecast.length ? [{\n\t\t\t\t\t\t\tlabel: 'Forecast upper',\n\t\t\t\t\t\t\tdata: pad(forecast.map(p => p.upper)),\n\t\t\t\t\t\t\tborderColor: 'transparent',\n\t\t\t\t\t\t\tbackgroundColor: 'rgba(139, 92, 246, 0.1)',\n\t\t\t\t\t\t\tpointRadius: 0,\n\t\t\t\t\t\t\tfill: '+1'\n\t\t\t\t\t\t}, {\n\t\t\t\t\t\t\tlabel: 'Forecast lower',\n\t\t\t\t\t\t\tdata: pad(forecast.map(p => p.lower)),\n\t\t\t\t\t\t\tborderColor: 'transparent',\n\t\t\t\t\t\t\tpointRadius: 0,\n\t\t\t\t\t\t\tfill: false\n\t\t\t\t\t\t}, {\n\t\t\t\t\t\t\tlabel: `Forecast (${series.forecastMethod})`,\n\t\t\t\t\t\t\tdata: pad(forecast.map(p => p.value)),\n\t\t\t\t\t\t\tborderColor: 'rgb(139, 92, 246)',\n\t\t\t\t\t\t\tborderDash: [6, 4],\n\t\t\t\t\t\t\tborderWidth: 2,\n\t\t\t\t\t\t\tpointRadius: 0,\n\t\t\t\t\t\t\ttension: 0.3,\n\t\t\t\t\t\t\tfill: false\n\t\t\t\t\t\t}] : [];\n\t\t\t\t\t\tcreateChart(canvas, {\n\t\t\t\t\t\t\ttype: 'line',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: points.map(p => p.period).concat(forecast.map(p => p.period)),\n\t\t\t\t\t\t\t\tdatasets: [...forecastSets, {\n\t\t\t\t\t\t\t\t\tlabel: seriesLabels[series.metric] || series.metric,\n\t\t\t\t\t\t\t\t\tdata: points.map(p => p.value),\n\t\t\t\t\t\t\t\t\tborderColor: 'rgb(139, 92, 246)',\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(139, 92, 246, 0.2)',\n\t\t\t\t\t\t\t\t\tfill: true,\n\t\t\t\t\t\t\t\t\ttension: 0.3,\n\t\t\t\t\t\t\t\t\tborderWidth: 3,\n\t\t\t\t\t\t\t\t\tpointBackgroundColor: 'rgb(139, 92, 246)',\n\t\t\t\t\t\t\t\t\tpointBorderColor: '#fff',\n\t\t\t\t\t\t\t\t\tpointBorderWidth: 2,\n\t\t\t\t\t\t\t\t\tpointRadius: points.length > 60 ? 0 : 4\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t},\n\t\t\t\t\t\t\toptions: {\n\t\t\t\t\t\t\t\tplugins: {\n\t\t\t\t\t\t\t\t\t...chartConfig.plugins,\n\t\t\t\t\t\t\t\t\tlegend: {\n\t\t\t\t\t\t\t\t\t\t...chartConfig.plugins.legend,\n\t\t\t\t\t\t\t\t\t\tlabels: {\n\t\t\t\t\t\t\t\t\t\t\t...chartConfig.plugins.legend.labels,\n\t\t\t\t\t\t\t\t\t\t\tfilter: (item) => item.text !== 'Forecast upper' && item.text !== 'Forecast lower'\n\t\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t} else {\n\t\t\t\t\t\tconsole.error('Time series chart: Canvas not found or invalid data', {canvas, series});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\twindow.initComparisonCharts = (c) => {\n\t\t\t\tconsole.log('🔀 Initializing comparison charts with data:', c);\n\t\t\t\tconst current = `${c.current.from} – ${c.current.to}`;\n\t\t\t\tconst previous = `${c.previous.from} – ${c.previous.to}`;\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst products = document.getElementById('products-chart');\n\t\t\t\t\tif (products && Array.isArray(c.top_products)) {\n\t\t\t\t\t\tcreateChart(products, {\n\t\t\t\t\t\t\ttype: 'bar',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: c.top_products.map(p => p.product_name.slice(0, 20) + (p.product_name.length > 20 ? '...' : '')),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: current,\n\t\t\t\t\t\t\t\t\tdata: c.top_products.map(p => p.frequency.current),\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(59, 130, 246, 0.8)',\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}, {\n\t\t\t\t\t\t\t\t\tlabel: previous,\n\t\t\t\t\t\t\t\t\tdata: c.top_products.map(p => p.frequency.previous),\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(100, 116, 139, 0.5)',\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t}\n\n\t\t\t\t\tconst monthly = document.getElementById('monthly-chart');\n\t\t\t\t\tif (monthly && Array.isArray(c.monthly_sales)) {\n\t\t\t\t\t\tcreateChart(monthly, {\n\t\t\t\t\t\t\ttype: 'line',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: c.monthly_sales.map(m => m.current_period || m.previous_period),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: current,\n\t\t\t\t\t\t\t\t\tdata: c.monthly_sales.map(m => m.revenue.current),\n\t\t\t\t\t\t\t\t\tborderColor: 'rgb(139, 92, 246)',\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(139, 92, 246, 0.2)',\n\t\t\t\t\t\t\t\t\tfill: true,\n\t\t\t\t\t\t\t\t\ttension: 0.3,\n\t\t\t\t\t\t\t\t\tborderWidth: 3\n\t\t\t\t\t\t\t\t}, {\n\t\t\t\t\t\t\t\t\tlabel: previous,\n\t\t\t\t\t\t\t\t\tdata: c.monthly_sales.map(m => m.revenue.previous),\n\t\t\t\t\t\t\t\t\tborderColor: 'rgb(100, 116, 139)',\n\t\t\t\t\t\t\t\t\tborderDash: [6, 4],\n\t\t\t\t\t\t\t\t\ttension: 0.3,\n\t\t\t\t\t\t\t\t\tborderWidth: 2\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t},\n\t\t\t\t\t\t\toptions: {\n\t\t\t\t\t\t\t\tplugins: {\n\t\t\t\t\t\t\t\t\ttooltip: {\n\t\t\t\t\t\t\t\t\t\tcallbacks: {\n\t\t\t\t\t\t\t\t\t\t\ttitle: items => {\n\t\t\t\t\t\t\t\t\t\t\t\tconst m = c.monthly_sales[items[0].dataIndex];\n\t\t\t\t\t\t\t\t\t\t\t\treturn `${m.current_period || '–'} vs ${m.previous_period || '–'}`;\n\t\t\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t}\n\n\t\t\t\t\tconst regions = document.getElementById('regions-chart');\n\t\t\t\t\tif (regions && Array.isArray(c.top_regions)) {\n\t\t\t\t\t\tcreateChart(regions, {\n\t\t\t\t\t\t\ttype: 'bar',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: c.top_regions.map(regionLabel),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: current,\n\t\t\t\t\t\t\t\t\tdata: c.top_regions.map(r => r.revenue.current),\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(34, 197, 94, 0.8)',\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}, {\n\t\t\t\t\t\t\t\t\tlabel: previous,\n\t\t\t\t\t\t\t\t\tdata: c.top_regions.map(r => r.revenue.previous),\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(100, 116, 139, 0.5)',\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t},\n\t\t\t\t\t\t\toptions: {\n\t\t\t\t\t\t\t\tindexAxis: 'y',\n\t\t\t\t\t\t\t\tscales: {\n\t\t\t\t\t\t\t\t\tx: {\n\t\t\t\t\t\t\t\t\t\tbeginAtZero: true,\n\t\t\t\t\t\t\t\t\t\tticks: { callback: value => (metric.region === 'total_revenue' ? '$' : '') + value.toLocaleString() }\n\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\twindow.initRegionsChart = (data, rankBy) => {\n\t\t\t\tconsole.log('🌍 Initializing regions chart with data:', data);\n\t\t\t\tconst metric = rankMetric(rankBy, 'revenue');\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst canvas = document.getElementById('regions-chart');\n\t\t\t\t\tif (canvas && data && Array.isArray(data)) {\n\t\t\t\t\t\tcreateChart(canvas, {\n\t\t\t\t\t\t\ttype: 'bar',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: data.map(regionLabel),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: metric.label,\n\t\t\t\t\t\t\t\t\tdata: data.map(r => r[metric.region] || 0),\n\t\t\t\t\t\t\t\t\tbackgroundColor: data.map((_, i) => \n\t\t\t\t\t\t\t\t\t\t`hsla(${(i * 360 / data.length)}, 70%, 60%, 0.8)`\n\t\t\t\t\t\t\t\t\t),\n\t\t\t\t\t\t\t\t\tborderColor: data.map((_, i) => \n\t\t\t\t\t\t\t\t\t\t`hsla(${(i * 360 / data.length)}, 70%, 50%, 1)`\n\t\t\t\t\t\t\t\t\t),\n\t\t\t\t\t\t\t\t\tborderWidth: 2,\n\t\t\t\t\t\t\t\t\tborderRadius: 4\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t},\n\t\t\t\t\t\t\toptions: {\n\t\t\t\t\t\t\t\tindexAxis: 'y',\n\t\t\t\t\t\t\t\tscales: {\n\t\t\t\t\t\t\t\t\tx: { \n\t\t\t\t\t\t\t\t\t\tbeginAtZero: true,\n\t\t\t\t\t\t\t\t\t\tticks: { callback: value => '$' + value.toLocaleString() }\n\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t} else {\n\t\t\t\t\t\tconsole.error('Regions chart: Canvas not found or invalid data', {canvas, data});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\twindow.initDistributionChart = (data) => {\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst canvas = document.getElementById('distribution-chart');\n\t\t\t\t\tif (canvas && data && Array.isArray(data.histogram)) {\n\t\t\t\t\t\tconst digits = data.metric === 'quantity' ? 0 : 2;\n\t\t\t\t\t\tcreateChart(canvas, {\n\t\t\t\t\t\t\ttype: 'bar',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: data.histogram.map(b => `${b.from.toFixed(digits)}–${b.to.toFixed(digits)}`),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\tlabel: 'Transactions',\n\t\t\t\t\t\t\t\t\tdata: data.histogram.map(b => b.count),\n\t\t\t\t\t\t\t\t\tbackgroundColor: 'rgba(59, 130, 246, 0.6)',\n\t\t\t\t\t\t\t\t\tborderColor: 'rgba(59, 130, 246, 1)',\n\t\t\t\t\t\t\t\t\tborderWidth: 1,\n\t\t\t\t\t\t\t\t\tbarPercentage: 1,\n\t\t\t\t\t\t\t\t\tcategoryPercentage: 1\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t},\n\t\t\t\t\t\t\toptions: {\n\t\t\t\t\t\t\t\tscales: {\n\t\t\t\t\t\t\t\t\ty: { beginAtZero: true }\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t} else {\n\t\t\t\t\t\tconsole.error('Distribution chart: Canvas not found or invalid data', {canvas, data});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\twindow.initParetoChart = (data) => {\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconst canvas = document.getElementById('pareto-chart');\n\t\t\t\t\tif (canvas && data && Array.isArray(data)) {\n\t\t\t\t\t\tcreateChart(canvas, {\n\t\t\t\t\t\t\ttype: 'bar',\n\t\t\t\t\t\t\tdata: {\n\t\t\t\t\t\t\t\tlabels: data.map(p => p.product_share.toFixed(0) + '%'),\n\t\t\t\t\t\t\t\tdatasets: [{\n\t\t\t\t\t\t\t\t\ttype: 'line',\n\t\t\t\t\t\t\t\t\tlabel: 'Cumulative revenue (%)',\n\t\t\t\t\t\t\t\t\tdata: data.map(p => p.cumulative_share),\n\t\t\t\t\t\t\t\t\tborderColor: 'rgba(239, 68, 68, 1)',\n\t\t\t\t\t\t\t\t\tpointRadius: 0,\n\t\t\t\t\t\t\t\t\tyAxisID: 'cumulative'\n\t\t\t\t\t\t\t\t}, {\n\t\t\t\t\t\t\t\t\tlabel: 'Revenue share (%)',\n\t\t\t\t\t\t\t\t\tdata: data.map(p => p.revenue_share),\n\t\t\t\t\t\t\t\t\tbackgroundColor: data.map(p => p.cumulative_share - p.revenue_share < 80\n\t\t\t\t\t\t\t\t\t\t? 'rgba(16, 185, 129, 0.7)'\n\t\t\t\t\t\t\t\t\t\t: p.cumulative_share - p.revenue_share < 95\n\t\t\t\t\t\t\t\t\t\t\t? 'rgba(245, 158, 11, 0.7)'\n\t\t\t\t\t\t\t\t\t\t\t: 'rgba(148, 163, 184, 0.7)'),\n\t\t\t\t\t\t\t\t\tbarPercentage: 1,\n\t\t\t\t\t\t\t\t\tcategoryPercentage: 1\n\t\t\t\t\t\t\t\t}]\n\t\t\t\t\t\t\t},\n\t\t\t\t\t\t\toptions: {\n\t\t\t\t\t\t\t\tscales: {\n\t\t\t\t\t\t\t\t\tx: { title: { display: true, text: 'Products, ranked by revenue' } },\n\t\t\t\t\t\t\t\t\ty: { beginAtZero: true, ticks: { callback: value => value + '%' } },\n\t\t\t\t\t\t\t\t\tcumulative: {\n\t\t\t\t\t\t\t\t\t\tposition: 'right',\n\t\t\t\t\t\t\t\t\t\tmin: 0,\n\t\t\t\t\t\t\t\t\t\tmax: 100,\n\t\t\t\t\t\t\t\t\t\tgrid: { drawOnChartArea: false },\n\t\t\t\t\t\t\t\t\t\tticks: { callback: value => value + '%' }\n\t\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t});\n\t\t\t\t\t} else {\n\t\t\t\t\t\tconsole.error('Pareto chart: Canvas not found or invalid data', {canvas, data});\n\t\t\t\t\t}\n\t\t\t\t}, 100);\n\t\t\t};\n\n\t\t\t// Values a dashboard link leaves out; they match the defaults of\n\t\t\t// handlers.DashboardStateFromQuery.\n\t\t\tconst dashboardDefaults = {\n\t\t\t\tgranularity: 'month',\n\t\t\t\tmetric: 'revenue',\n\t\t\t\tdist_metric: 'total_price',\n\t\t\t\tmargin_dimension: 'country',\n\t\t\t\tproducts_rank_by: 'orders',\n\t\t\t\tproducts_limit: '20',\n\t\t\t\tregions_rank_by: 'revenue',\n\t\t\t\tregions_limit: '30'\n\t\t\t};\n\n\t\t\t// Mirror the dashboard state in the address bar so a reload or a\n\t\t\t// shared link shows the same dashboard.\n\t\t\twindow.syncDashboardURL = (state) => {\n\t\t\t\tconst params = new URLSearchParams();\n\t\t\t\tfor (const [name, value] of Object.entries(state)) {\n\t\t\t\t\tconst text = value == null ? '' : String(value);\n\t\t\t\t\tif (text !== '' && text !== (dashboardDefaults[name] ?? '')) {\n\t\t\t\t\t\tparams.set(name, text);\n\t\t\t\t\t}\n\t\t\t\t}\n\t\t\t\tconst query = params.toString();\n\t\t\t\thistory.replaceState(null, '', query ? '?' + query : location.pathname);\n\t\t\t};\n\n\t\t\tif (typeof EventSource !== 'undefined') {\n\t\t\t\tsetTimeout(() => {\n\t\t\t\t\tconsole.log('Dashboard initialized with Datastar SSE support');\n\t\t\t\t}, 1000);\n\t\t\t}\n\t\t</script></body></html>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
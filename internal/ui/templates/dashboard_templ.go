@@ -13,7 +13,7 @@ import (
 	"fmt"
 )
 
-func Dashboard() templ.Component {
+func Dashboard(state models.DashboardState) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -46,7 +46,126 @@ func Dashboard() templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"grid\"><div class=\"card\" id=\"country-table\"><h3>📊 Country Revenue Analysis</h3><div data-on-load=\"@get('/sse/country-revenue')\" id=\"country-content\"><div class=\"loading\">Loading country revenue data...</div></div></div><div class=\"card\"><h3>📈 Top 20 Products by Transactions</h3><div class=\"chart\"><canvas id=\"products-chart\"></canvas></div><div data-on-load=\"@get('/sse/top-products')\" data-effect=\"$productsData && initProductsChart($productsData)\" id=\"products-content\"><div class=\"loading\">Loading products data...</div></div></div></div><div class=\"grid\"><div class=\"card\"><h3>💰 Monthly Sales Volume</h3><div class=\"chart\"><canvas id=\"monthly-chart\"></canvas></div><div data-on-load=\"@get('/sse/monthly-sales')\" data-effect=\"$monthlyData && initMonthlyChart($monthlyData)\" id=\"monthly-content\"><div class=\"loading\">Loading monthly sales data...</div></div></div><div class=\"card\"><h3>🌍 Top 30 Regions by Revenue</h3><div class=\"chart\"><canvas id=\"regions-chart\"></canvas></div><div data-on-load=\"@get('/sse/top-regions')\" data-effect=\"$regionsData && initRegionsChart($regionsData)\" id=\"regions-content\"><div class=\"loading\">Loading regions data...</div></div></div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"card toolbar\" data-signals=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 string
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSONString(map[string]any{
+				"viewName":       state.ViewName,
+				"rangeFrom":      state.RangeFrom,
+				"rangeTo":        state.RangeTo,
+				"compareFrom":    state.CompareFrom,
+				"compareTo":      state.CompareTo,
+				"filterCountry":  state.FilterCountry,
+				"filterRegion":   state.FilterRegion,
+				"filterCategory": state.FilterCategory,
+				"comparisonData": nil,
+			}))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 22, Col: 5}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\"><h3>🔀 Period Comparison</h3><div class=\"card-controls\"><label>View <select id=\"view-switcher\" data-bind-view-name data-on-change=\"@get('/sse/views')\"><option value=\"\">No saved view</option></select></label> <label>Period <input type=\"date\" data-bind-range-from> – <input type=\"date\" data-bind-range-to></label> <label>vs <input type=\"date\" data-bind-compare-from> – <input type=\"date\" data-bind-compare-to></label></div><div class=\"card-controls\"><input type=\"text\" placeholder=\"All countries\" data-bind-filter-country> <input type=\"text\" placeholder=\"All regions\" data-bind-filter-region> <input type=\"text\" placeholder=\"All categories\" data-bind-filter-category> <button class=\"btn\" data-on-click=\"@get('/sse/compare')\">Compare</button> <button class=\"btn secondary\" data-on-click=\"$comparisonData = null; initProductsChart($productsData, $productsRankBy); initTimeSeriesChart($timeseriesData); initRegionsChart($regionsData, $regionsRankBy); @get('/sse/country-revenue')\">Clear</button></div><div id=\"view-status\" class=\"compare-status\" data-on-load=\"@get('/sse/views')\"></div><div id=\"compare-status\" class=\"compare-status\">Leave the comparison dates empty to compare with the same period last year.</div><div data-effect=\"$comparisonData && initComparisonCharts($comparisonData)\"></div></div><div class=\"card toolbar\" data-signals='{\"anomalyCount\": 0}'><h3>🚨 Sales Anomalies <span class=\"category-badge\" data-show=\"$anomalyCount > 0\" data-text=\"$anomalyCount\"></span></h3><div data-on-load=\"@get('/sse/anomalies')\" id=\"anomalies-content\"><div class=\"loading\">Checking recent sales for anomalies...</div></div></div><div class=\"grid\"><div class=\"card\" id=\"country-table\" data-signals=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var4 string
+			templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSONString(map[string]any{"drillPath": state.DrillPath, "drillOpen": state.DrillPath != "", "tableMetric": state.TableMetric}))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 63, Col: 151}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\"><h3>📊 Country Revenue Analysis</h3><div class=\"card-controls\"><select data-bind-table-metric data-on-change=\"@get($rangeFrom && $rangeTo ? '/sse/compare' : '/sse/country-revenue'); $drillOpen && @get('/sse/drilldown')\"><option value=\"\" selected>No custom metric</option> <optgroup id=\"table-custom-metrics\" label=\"Custom metrics\"></optgroup></select></div><div class=\"drilldown\" data-show=\"$drillOpen\"><div id=\"drilldown-content\" data-on-load=\"$drillOpen && @get('/sse/drilldown')\"></div></div><div data-on-load=\"@get($rangeFrom && $rangeTo ? '/sse/compare' : '/sse/country-revenue')\" id=\"country-content\"><div class=\"loading\">Loading country revenue data...</div></div></div><div class=\"card\" data-signals=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var5 string
+			templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSONString(map[string]any{"productsRankBy": state.ProductsRankBy, "productsLimit": state.ProductsLimit}))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 84, Col: 129}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\"><h3>📈 Top Products</h3><div class=\"card-controls\"><select data-bind-products-rank-by data-on-change=\"@get('/sse/top-products')\"><option value=\"revenue\">Revenue</option> <option value=\"orders\" selected>Orders</option> <option value=\"units\">Units</option> <option value=\"customers\">Customers</option> <optgroup id=\"products-custom-metrics\" label=\"Custom metrics\"></optgroup></select> <select data-bind-products-limit data-on-change=\"@get('/sse/top-products')\"><option value=\"10\">Top 10</option> <option value=\"20\" selected>Top 20</option> <option value=\"50\">Top 50</option> <option value=\"100\">Top 100</option></select></div><div class=\"chart\"><canvas id=\"products-chart\"></canvas></div><div data-on-load=\"@get('/sse/top-products')\" data-effect=\"$productsData && initProductsChart($productsData, $productsRankBy)\" id=\"products-content\"><div class=\"loading\">Loading products data...</div></div></div></div><div class=\"card\"><h3>🗺️ Revenue by Country</h3><div data-on-load=\"@get('/sse/country-map')\" id=\"country-map-content\"><div class=\"loading\">Loading map...</div></div></div><div class=\"grid\"><div class=\"card\" data-signals=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var6 string
+			templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSONString(map[string]any{"tsGranularity": state.TSGranularity, "tsMetric": state.TSMetric}))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 121, Col: 134}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\"><h3>💰 Monthly Sales Volume</h3><div class=\"card-controls\"><select data-bind-ts-granularity data-on-change=\"@get('/sse/timeseries')\"><option value=\"day\">Daily</option> <option value=\"week\">Weekly</option> <option value=\"month\" selected>Monthly</option> <option value=\"quarter\">Quarterly</option> <option value=\"year\">Yearly</option></select> <select data-bind-ts-metric data-on-change=\"@get('/sse/timeseries')\"><option value=\"revenue\" selected>Revenue</option> <option value=\"orders\">Orders</option> <option value=\"units\">Units</option> <optgroup id=\"ts-custom-metrics\" label=\"Custom metrics\"></optgroup></select></div><div class=\"chart\"><canvas id=\"monthly-chart\"></canvas></div><div data-on-load=\"@get('/sse/timeseries')\" data-effect=\"$timeseriesData && initTimeSeriesChart($timeseriesData)\" id=\"monthly-content\"><div class=\"loading\">Loading sales time series...</div></div></div><div class=\"card\" data-signals=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var7 string
+			templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSONString(map[string]any{"regionsRankBy": state.RegionsRankBy, "regionsLimit": state.RegionsLimit}))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 151, Col: 125}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "\"><h3>🌍 Top Regions</h3><div class=\"card-controls\"><select data-bind-regions-rank-by data-on-change=\"@get('/sse/top-regions')\"><option value=\"revenue\" selected>Revenue</option> <option value=\"orders\">Orders</option> <option value=\"units\">Units</option> <option value=\"customers\">Customers</option> <optgroup id=\"regions-custom-metrics\" label=\"Custom metrics\"></optgroup></select> <select data-bind-regions-limit data-on-change=\"@get('/sse/top-regions')\"><option value=\"10\">Top 10</option> <option value=\"30\" selected>Top 30</option> <option value=\"50\">Top 50</option> <option value=\"100\">Top 100</option></select></div><div class=\"chart\"><canvas id=\"regions-chart\"></canvas></div><div data-on-load=\"@get('/sse/top-regions')\" data-effect=\"$regionsData && initRegionsChart($regionsData, $regionsRankBy)\" id=\"regions-content\"><div class=\"loading\">Loading regions data...</div></div></div></div><div class=\"card toolbar\" data-signals=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var8 string
+			templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSONString(map[string]any{"cohortCountry": state.CohortCountry}))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 181, Col: 113}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "\"><h3>👥 Customer Cohort Retention</h3><div class=\"card-controls\"><input type=\"text\" placeholder=\"All countries\" data-bind-cohort-country data-on-change=\"@get('/sse/cohorts')\"></div><div data-on-load=\"@get('/sse/cohorts')\" id=\"cohorts-content\"><div class=\"loading\">Loading cohorts...</div></div></div><div class=\"card\"><h3>📦 Stock-out Risk</h3><div data-on-load=\"@get('/sse/inventory')\" id=\"inventory-content\"><div class=\"loading\">Loading inventory...</div></div></div><div class=\"card\" data-signals=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var9 string
+			templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSONString(map[string]any{
+				"distMetric":       state.DistMetric,
+				"distDimension":    state.DistDimension,
+				"distValue":        state.DistValue,
+				"distributionData": nil,
+			}))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 203, Col: 5}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "\"><h3>📐 Value Distribution</h3><div class=\"card-controls\"><select data-bind-dist-metric data-on-change=\"@get('/sse/distribution')\"><option value=\"total_price\" selected>Order total</option> <option value=\"quantity\">Quantity</option> <option value=\"price\">Unit price</option></select> <select data-bind-dist-dimension data-on-change=\"@get('/sse/distribution')\"><option value=\"\" selected>All transactions</option> <option value=\"country\">Country</option> <option value=\"category\">Category</option></select> <input type=\"text\" placeholder=\"Country or category\" data-bind-dist-value data-on-change=\"@get('/sse/distribution')\"></div><div class=\"chart\"><canvas id=\"distribution-chart\"></canvas></div><div data-effect=\"$distributionData && initDistributionChart($distributionData)\"></div><div data-on-load=\"@get('/sse/distribution')\" id=\"distribution-content\"><div class=\"loading\">Loading distribution...</div></div></div><div class=\"card\" data-signals='{\"paretoData\": null}'><h3>🏷️ ABC Product Classification</h3><div class=\"chart\"><canvas id=\"pareto-chart\"></canvas></div><div data-effect=\"$paretoData && initParetoChart($paretoData)\"></div><div data-on-load=\"@get('/sse/pareto')\" id=\"pareto-content\"><div class=\"loading\">Classifying products...</div></div></div><div class=\"card\" data-signals=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var10 string
+			templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSONString(map[string]any{"marginDimension": state.MarginDimension}))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 237, Col: 109}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "\"><h3>💹 Gross Margin</h3><div class=\"card-controls\"><select data-bind-margin-dimension data-on-change=\"@get('/sse/margins')\"><option value=\"country\" selected>Country</option> <option value=\"category\">Category</option> <option value=\"brand\">Brand</option> <option value=\"subcategory\">Subcategory</option></select></div><div data-on-load=\"@get('/sse/margins')\" id=\"margins-content\"><div class=\"loading\">Loading margins...</div></div></div><div class=\"card\"><h3>🚀 Recent Launches</h3><div data-on-load=\"@get('/sse/launches')\" id=\"launches-content\"><div class=\"loading\">Loading launches...</div></div></div><div class=\"card\"><h3>🧮 Custom Metrics</h3><div data-on-load=\"@get('/sse/metrics')\" id=\"metrics-content\"><div class=\"loading\">Loading metrics...</div></div></div><div data-effect=\"syncDashboardURL({view: $viewName, from: $rangeFrom, to: $rangeTo, compare_from: $compareFrom, compare_to: $compareTo, filter_country: $filterCountry, filter_region: $filterRegion, filter_category: $filterCategory, granularity: $tsGranularity, metric: $tsMetric, country: $cohortCountry, path: $drillOpen ? $drillPath : '', dist_metric: $distMetric, dist_dimension: $distDimension, dist_value: $distValue, margin_dimension: $marginDimension, products_rank_by: $productsRankBy, products_limit: $productsLimit, regions_rank_by: $regionsRankBy, regions_limit: $regionsLimit, table_metric: $tableMetric})\"></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -76,89 +195,89 @@ func countryRevenueTable(data []models.CountryRevenue) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var3 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var3 == nil {
-			templ_7745c5c3_Var3 = templ.NopComponent
+		templ_7745c5c3_Var11 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var11 == nil {
+			templ_7745c5c3_Var11 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<div class=\"table-container\"><table class=\"modern-table\"><thead><tr><th>Country</th><th>Product</th><th>Category</th><th>Revenue</th><th>Orders</th></tr></thead> <tbody>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<div class=\"table-container\"><table class=\"modern-table\"><thead><tr><th>Country</th><th>Product</th><th>Category</th><th>Revenue</th><th>Orders</th></tr></thead> <tbody>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		for i, r := range data {
 			if i < 50 {
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<tr><td>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<tr><td>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var4 string
-				templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(r.Country)
+				var templ_7745c5c3_Var12 string
+				templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(r.Country)
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 81, Col: 22}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 285, Col: 22}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</td><td>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</td><td>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var5 string
-				templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(r.ProductName)
+				var templ_7745c5c3_Var13 string
+				templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinStringErrs(r.ProductName)
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 82, Col: 26}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 286, Col: 26}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</td><td><span class=\"category-badge\">")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "</td><td><span class=\"category-badge\">")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var6 string
-				templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(r.Category)
+				var templ_7745c5c3_Var14 string
+				templ_7745c5c3_Var14, templ_7745c5c3_Err = templ.JoinStringErrs(r.Category)
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 83, Col: 52}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 287, Col: 52}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var14))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</span></td><td><strong>$")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "</span></td><td><strong>$")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var7 string
-				templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%.2f", r.TotalRevenue))
+				var templ_7745c5c3_Var15 string
+				templ_7745c5c3_Var15, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%.2f", r.TotalRevenue))
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 84, Col: 57}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 288, Col: 57}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var15))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</strong></td><td>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "</strong></td><td>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var8 string
-				templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%d", r.Transactions))
+				var templ_7745c5c3_Var16 string
+				templ_7745c5c3_Var16, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%d", r.Transactions))
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 85, Col: 46}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/ui/templates/dashboard.templ`, Line: 289, Col: 46}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var16))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</td></tr>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "</td></tr>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "</tbody></table></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "</tbody></table></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}